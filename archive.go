@@ -0,0 +1,184 @@
+package rc2sb
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/rc"
+)
+
+// IsArchive reports whether name has the extension of an archive that
+// ConvertArchive can read: .zip, .tar.gz, or .tgz.
+func IsArchive(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".zip") || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+// ConvertArchive converts an RC repository packed in the zip or gzipped tar
+// archive at archivePath to SB format, writing output to outDir. The archive
+// is read in memory, without unpacking it to disk. manifest.yaml may be at the
+// archive root or, as in Door43 and GitHub snapshots, in its single top-level
+// directory. Archives with entries outside the archive root (e.g., "../x")
+// are rejected.
+// Result.InDir is archivePath.
+func ConvertArchive(ctx context.Context, archivePath, outDir string, opts Options) (Result, error) {
+	// Check context
+	if err := ctx.Err(); err != nil {
+		return Result{}, fmt.Errorf("context error: %w", err)
+	}
+
+	fsys, closeArchive, err := openArchive(archivePath)
+	if err != nil {
+		return Result{}, err
+	}
+	defer closeArchive()
+
+	root, err := archiveRoot(fsys, archivePath)
+	if err != nil {
+		return Result{}, err
+	}
+	if root != "." {
+		if fsys, err = fs.Sub(fsys, root); err != nil {
+			return Result{}, err
+		}
+	}
+
+	// Load the RC manifest
+	manifest, err := rc.LoadManifestFS(fsys)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, err := convert(ctx, manifest, fsys, filepath.Join(archivePath, root), outDir, nil, opts)
+	result.InDir = archivePath
+	return result, err
+}
+
+// openArchive opens the zip or gzipped tar archive at name as an fs.FS,
+// returning a function that releases it.
+func openArchive(name string) (fs.FS, func() error, error) {
+	if strings.HasSuffix(strings.ToLower(name), ".zip") {
+		zr, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening archive: %w", err)
+		}
+		for _, f := range zr.File {
+			if err := checkArchivePath(f.Name); err != nil {
+				zr.Close()
+				return nil, nil, err
+			}
+		}
+		return zr, zr.Close, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening archive: %w", err)
+	}
+	defer f.Close()
+	zr, err := tarGzToZip(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading archive %s: %w", name, err)
+	}
+	return zr, func() error { return nil }, nil
+}
+
+// tarGzToZip reads a gzipped tar archive into an in-memory zip archive, which
+// provides an fs.FS. Only regular files and directories are kept; links and
+// other special entries are skipped.
+func tarGzToZip(r io.Reader) (*zip.Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(hdr.Name, "./")
+		if name == "" || name == "." {
+			continue
+		}
+		if err := checkArchivePath(name); err != nil {
+			return nil, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if _, err := zw.Create(strings.TrimSuffix(name, "/") + "/"); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+			if err != nil {
+				return nil, err
+			}
+			if _, err := io.Copy(w, tr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+// checkArchivePath returns an error if the archive entry name is not a
+// relative path inside the archive root, e.g., "../x" or "/etc/x".
+func checkArchivePath(name string) error {
+	clean := strings.TrimSuffix(name, "/")
+	if !fs.ValidPath(clean) || strings.Contains(clean, `\`) {
+		return fmt.Errorf("archive entry %q is outside the archive root", name)
+	}
+	return nil
+}
+
+// archiveRoot returns the directory in fsys holding manifest.yaml: "." or
+// the single top-level directory. archivePath is only used in errors.
+func archiveRoot(fsys fs.FS, archivePath string) (string, error) {
+	if _, err := fs.Stat(fsys, "manifest.yaml"); err == nil {
+		return ".", nil
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return "", fmt.Errorf("reading archive %s: %w", archivePath, err)
+	}
+	var dirs []string
+	for _, e := range entries {
+		// Skip the resource forks macOS adds to zip files
+		if e.IsDir() && e.Name() != "__MACOSX" {
+			dirs = append(dirs, e.Name())
+		}
+	}
+	if len(dirs) == 1 {
+		if _, err := fs.Stat(fsys, path.Join(dirs[0], "manifest.yaml")); err == nil {
+			return dirs[0], nil
+		}
+	}
+	return "", &rc.ManifestError{
+		Op:       "reading",
+		Location: archivePath + " or its single top-level directory",
+		Err:      fs.ErrNotExist,
+	}
+}
@@ -0,0 +1,61 @@
+package rc2sb
+
+import (
+	"context"
+	"sync"
+
+	"github.com/unfoldingWord/go-rc2sb/books"
+)
+
+// Job is a single conversion in a batch run by ConvertAll.
+type Job struct {
+	// InDir is the RC repository to convert.
+	InDir string
+
+	// OutDir is where the SB output is written.
+	OutDir string
+}
+
+// JobResult is the outcome of one Job.
+type JobResult struct {
+	Job    Job
+	Result Result
+
+	// Err is the conversion error, or nil if the job succeeded.
+	Err error
+}
+
+// ConvertAll converts each job with Convert using up to workers concurrent
+// conversions (at least one), all with the same opts. A failed job does not
+// stop the others. The results are returned in the same order as jobs.
+// Unless opts.USFMNames is set, the jobs share one cache of USFM book names,
+// so a USFM directory common to them is read once.
+func ConvertAll(ctx context.Context, jobs []Job, opts Options, workers int) []JobResult {
+	if workers < 1 {
+		workers = 1
+	}
+	if opts.USFMNames == nil {
+		opts.USFMNames = books.NewUSFMNameCache()
+	}
+
+	results := make([]JobResult, len(jobs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				result, err := Convert(ctx, jobs[i].InDir, jobs[i].OutDir, opts)
+				results[i] = JobResult{Job: jobs[i], Result: result, Err: err}
+			}
+		}()
+	}
+	for i := range jobs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
@@ -4,8 +4,12 @@ package books
 import (
 	"bufio"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/unfoldingWord/go-rc2sb/sb"
@@ -28,7 +32,8 @@ type BookInfo struct {
 	Long  string // long name (e.g., "The Book of Genesis")
 }
 
-// AllBooks is the ordered list of all 66 Bible books.
+// AllBooks is the ordered list of all 66 Bible books, and of any books added
+// with RegisterBook.
 var AllBooks = []BookInfo{
 	{ID: "gen", Code: "GEN", Sort: 1, Abbr: "Gen", Short: "Genesis", Long: "The Book of Genesis"},
 	{ID: "exo", Code: "EXO", Sort: 2, Abbr: "Exo", Short: "Exodus", Long: "The Book of Exodus"},
@@ -105,6 +110,11 @@ var bookByID map[string]*BookInfo
 var bookByCode map[string]*BookInfo
 
 func init() {
+	indexBooks()
+}
+
+// indexBooks builds the lookup maps from AllBooks.
+func indexBooks() {
 	bookByID = make(map[string]*BookInfo, len(AllBooks))
 	bookByCode = make(map[string]*BookInfo, len(AllBooks))
 	for i := range AllBooks {
@@ -114,6 +124,44 @@ func init() {
 	}
 }
 
+// RegisterBook adds a book to AllBooks, such as extra-canonical material
+// (e.g., "XXA") or apocrypha used by a site, so that ByID, ByCode, and
+// IsBookID find it and it is recognized in project identifiers and USFM
+// filenames. The book is placed in AllBooks by its Sort, after any books
+// with the same Sort; if Sort is 0, it is placed after the last book. The
+// code is upper-cased, and the ID, lower-cased, defaults to the code.
+//
+// It returns an error if the code is empty or the code or ID is already
+// taken. Like handler.Register, it is meant to be called from init(),
+// before any conversion runs.
+func RegisterBook(b BookInfo) error {
+	b.Code = strings.ToUpper(b.Code)
+	if b.ID == "" {
+		b.ID = b.Code
+	}
+	b.ID = strings.ToLower(b.ID)
+	if b.Code == "" {
+		return fmt.Errorf("registering book: empty code")
+	}
+	if prev := bookByCode[b.Code]; prev != nil {
+		return fmt.Errorf("registering book %s: code already used by %s", b.Code, prev.Short)
+	}
+	if prev := bookByID[b.ID]; prev != nil {
+		return fmt.Errorf("registering book %s: ID %q already used by %s", b.Code, b.ID, prev.Short)
+	}
+	if b.Sort == 0 {
+		for _, other := range AllBooks {
+			b.Sort = max(b.Sort, other.Sort)
+		}
+		b.Sort++
+	}
+
+	i := sort.Search(len(AllBooks), func(i int) bool { return AllBooks[i].Sort > b.Sort })
+	AllBooks = slices.Insert(AllBooks, i, b)
+	indexBooks()
+	return nil
+}
+
 // ByID returns the BookInfo for a lowercase identifier (e.g., "gen"), or nil if not found.
 func ByID(id string) *BookInfo {
 	return bookByID[strings.ToLower(id)]
@@ -166,8 +214,12 @@ func LocalizedNameEntryWithNames(id string, lang string, projectTitle string, us
 	if b == nil {
 		return "", sb.LocalizedName{}
 	}
-	key := "book-" + b.ID
+	return "book-" + b.ID, localizedName(b, lang, projectTitle, usfmNames)
+}
 
+// localizedName builds the LocalizedName of b as LocalizedNameEntryWithNames
+// describes.
+func localizedName(b *BookInfo, lang string, projectTitle string, usfmNames *LocalizedBookNames) sb.LocalizedName {
 	ln := sb.LocalizedName{
 		Abbr:  make(map[string]string),
 		Short: make(map[string]string),
@@ -194,7 +246,7 @@ func LocalizedNameEntryWithNames(id string, lang string, projectTitle string, us
 				ln.Abbr["en"] = usfmNames.Abbr
 			}
 		}
-		return key, ln
+		return ln
 	}
 
 	// For non-English languages, build localized entries under the lang key.
@@ -227,15 +279,21 @@ func LocalizedNameEntryWithNames(id string, lang string, projectTitle string, us
 		ln.Abbr[lang] = usfmNames.Abbr
 	}
 
-	return key, ln
+	return ln
 }
 
 // ParseUSFMBookNames reads the first 20 lines of a USFM file and extracts
 // \toc1, \toc2, \toc3 markers for localized book names. Falls back to \mt1/\mt
-// for the long name and \h for the short name if toc markers are missing.
-// Returns nil if the file doesn't exist or contains no useful markers.
+// for the long name and \h for the short name if toc markers are missing or
+// empty. Returns nil if the file doesn't exist or contains no useful markers.
 func ParseUSFMBookNames(filePath string) *LocalizedBookNames {
-	f, err := os.Open(filePath)
+	return ParseUSFMBookNamesFS(os.DirFS(filepath.Dir(filePath)), filepath.Base(filePath))
+}
+
+// ParseUSFMBookNamesFS is like ParseUSFMBookNames but reads the USFM file
+// named name from fsys.
+func ParseUSFMBookNamesFS(fsys fs.FS, name string) *LocalizedBookNames {
+	f, err := fsys.Open(name)
 	if err != nil {
 		return nil
 	}
@@ -291,34 +349,129 @@ func ParseUSFMBookNames(filePath string) *LocalizedBookNames {
 }
 
 // FindUSFMFile searches for a USFM file matching a book code in a directory.
-// It looks for patterns like "NN-CODE.usfm" (e.g., "01-GEN.usfm") or "CODE.usfm".
+// It looks for patterns like "NN-CODE.usfm" (e.g., "01-GEN.usfm") or "CODE.usfm",
+// then for any name CodeFromUSFMFilename maps to the book (e.g., "01_GEN.usfm").
 // Returns the full path if found, or empty string if not found.
 func FindUSFMFile(usfmDir string, bookID string) string {
-	code := CodeFromProjectID(bookID)
+	name := FindUSFMFileFS(os.DirFS(usfmDir), bookID)
+	if name == "" {
+		return ""
+	}
+	return filepath.Join(usfmDir, filepath.FromSlash(name))
+}
+
+// FindUSFMFileFS is like FindUSFMFile but searches the root of fsys.
+// Returns the file's name within fsys, or empty string if not found.
+func FindUSFMFileFS(fsys fs.FS, bookID string) string {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return ""
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return findUSFMName(names, CodeFromProjectID(bookID))
+}
+
+// findUSFMName returns the name of the USFM file for the book code among
+// names, the sorted entries of a directory, or "" if there is none.
+func findUSFMName(names []string, code string) string {
+	first := func(pattern string) string {
+		for _, name := range names {
+			if ok, _ := path.Match(pattern, name); ok {
+				return name
+			}
+		}
+		return ""
+	}
 
 	// Try NN-CODE.usfm pattern first (most common)
-	matches, err := filepath.Glob(filepath.Join(usfmDir, fmt.Sprintf("*-%s.usfm", code)))
-	if err == nil && len(matches) > 0 {
-		return matches[0]
+	if name := first(fmt.Sprintf("*-%s.usfm", code)); name != "" {
+		return name
 	}
 
 	// Try CODE.usfm
-	direct := filepath.Join(usfmDir, code+".usfm")
-	if _, err := os.Stat(direct); err == nil {
+	if direct := code + ".usfm"; slices.Contains(names, direct) {
 		return direct
 	}
 
 	// Try lowercase variants
-	matches, err = filepath.Glob(filepath.Join(usfmDir, fmt.Sprintf("*-%s.usfm", strings.ToLower(code))))
-	if err == nil && len(matches) > 0 {
-		return matches[0]
+	if name := first(fmt.Sprintf("*-%s.usfm", strings.ToLower(code))); name != "" {
+		return name
+	}
+
+	// Try any other numbering, e.g., "41_MRK.usfm" or "MRK_41.usfm"
+	for _, name := range names {
+		if ok, _ := path.Match("*.usfm", name); ok && CodeFromUSFMFilename(name) == code {
+			return name
+		}
 	}
 
 	return ""
 }
 
+// BooksInDir scans a directory for USFM files and returns the recognized
+// Bible books in canonical order. Filenames may use any pattern accepted by
+// CodeFromUSFMFilename (e.g., "NN-CODE.usfm" or "CODE.usfm"), in either
+// case. Files that do not map to a known book (e.g., "A0-FRT.usfm") are
+// ignored. Returns nil if the directory cannot be read.
+func BooksInDir(usfmDir string) []*BookInfo {
+	entries, err := os.ReadDir(usfmDir)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var result []*BookInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".usfm") {
+			continue
+		}
+		b := ByCode(CodeFromUSFMFilename(entry.Name()))
+		if b == nil || seen[b.ID] {
+			continue
+		}
+		seen[b.ID] = true
+		result = append(result, b)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Sort < result[j].Sort
+	})
+	return result
+}
+
+// CodeFromUSFMFilename extracts the book code from a USFM filename.
+// "01-GEN.usfm" -> "GEN", "41_MRK.usfm" -> "MRK", "MRK_41.usfm" -> "MRK",
+// "GEN.usfm" -> "GEN", "A0-FRT.usfm" -> "FRT", "67-XXA.usfm" -> "XXA"
+//
+// A recognized Bible book code anywhere in the name, separated by "-", "_",
+// ".", or a space, is returned in uppercase. Otherwise (e.g., for extra
+// material such as FRT) the text after the first "-" is returned, or the whole
+// name if there is none.
+func CodeFromUSFMFilename(filename string) string {
+	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+	fields := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || r == '.' || r == ' '
+	})
+	for _, field := range fields {
+		if b := ByCode(field); b != nil {
+			return b.Code
+		}
+	}
+
+	parts := strings.SplitN(name, "-", 2)
+	if len(parts) == 2 {
+		return parts[1]
+	}
+	return name
+}
+
 // extractUSFMMarker extracts the value after a USFM marker like "\toc1 VALUE".
-// Returns empty string if the line doesn't start with the marker.
+// Returns empty string if the line doesn't start with the marker, or if the
+// marker has no value or only whitespace (e.g., a stray "\toc1 "); callers
+// treat such markers as missing.
 func extractUSFMMarker(line, marker string) string {
 	// The marker must be at the start of the line and followed by a space
 	if !strings.HasPrefix(line, marker+" ") && line != marker {
@@ -1,9 +1,14 @@
 package books_test
 
 import (
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/unfoldingWord/go-rc2sb/books"
 )
@@ -197,6 +202,35 @@ func TestParseUSFMBookNames_FallbackToMtAndH(t *testing.T) {
 	}
 }
 
+func TestParseUSFMBookNames_EmptyMarkersFallBack(t *testing.T) {
+	dir := t.TempDir()
+	usfmPath := filepath.Join(dir, "01-GEN.usfm")
+	// \toc1 and \toc2 are present but empty; a later empty \toc3 must not
+	// clobber the earlier one
+	content := "\\id GEN\n\\toc1 \t \n\\toc2\n\\toc3 Gen\n\\toc3 \n\\h Short Name\n\\mt1 Long Title\n"
+	os.WriteFile(usfmPath, []byte(content), 0644)
+
+	names := books.ParseUSFMBookNames(usfmPath)
+	if names == nil {
+		t.Fatal("ParseUSFMBookNames returned nil")
+	}
+	if names.Long != "Long Title" {
+		t.Errorf("Long = %q; want %q (empty \\toc1 should fall back to \\mt1)", names.Long, "Long Title")
+	}
+	if names.Short != "Short Name" {
+		t.Errorf("Short = %q; want %q (empty \\toc2 should fall back to \\h)", names.Short, "Short Name")
+	}
+	if names.Abbr != "Gen" {
+		t.Errorf("Abbr = %q; want %q", names.Abbr, "Gen")
+	}
+
+	// A file with only empty markers has no useful names
+	os.WriteFile(usfmPath, []byte("\\id GEN\n\\toc1 \n\\toc2  \n\\h\n"), 0644)
+	if names := books.ParseUSFMBookNames(usfmPath); names != nil {
+		t.Errorf("ParseUSFMBookNames = %+v; want nil for empty markers only", names)
+	}
+}
+
 func TestParseUSFMBookNames_FallbackMtWithoutNumber(t *testing.T) {
 	dir := t.TempDir()
 	usfmPath := filepath.Join(dir, "01-GEN.usfm")
@@ -368,6 +402,18 @@ func TestFindUSFMFile_DirectPattern(t *testing.T) {
 	}
 }
 
+func TestFindUSFMFile_UnderscorePattern(t *testing.T) {
+	dir := t.TempDir()
+	// Create a NN_CODE.usfm file, as some USFM 3 projects use
+	usfmPath := filepath.Join(dir, "41_MRK.usfm")
+	os.WriteFile(usfmPath, []byte("\\id MRK\n"), 0644)
+
+	found := books.FindUSFMFile(dir, "mrk")
+	if found != usfmPath {
+		t.Errorf("FindUSFMFile = %q; want %q", found, usfmPath)
+	}
+}
+
 func TestFindUSFMFile_NotFound(t *testing.T) {
 	dir := t.TempDir()
 	found := books.FindUSFMFile(dir, "gen")
@@ -375,3 +421,195 @@ func TestFindUSFMFile_NotFound(t *testing.T) {
 		t.Errorf("FindUSFMFile should return empty string when file not found; got %q", found)
 	}
 }
+
+func TestFindUSFMFileFS_Precedence(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{[]string{"GEN.usfm", "01-GEN.usfm"}, "01-GEN.usfm"},
+		{[]string{"01_GEN.usfm", "GEN.usfm"}, "GEN.usfm"},
+		{[]string{"01_GEN.usfm", "01-gen.usfm"}, "01-gen.usfm"},
+		{[]string{"GEN_01.usfm", "README.md"}, "GEN_01.usfm"},
+		{[]string{"02-EXO.usfm"}, ""},
+	}
+	for _, tt := range tests {
+		fsys := fstest.MapFS{}
+		for _, name := range tt.files {
+			fsys[name] = &fstest.MapFile{Data: []byte("\\id GEN\n")}
+		}
+		if got := books.FindUSFMFileFS(fsys, "gen"); got != tt.want {
+			t.Errorf("FindUSFMFileFS(%v) = %q; want %q", tt.files, got, tt.want)
+		}
+	}
+}
+
+// --- USFMNameCache tests ---
+
+// countingFS counts the opens of each name in an fs.FS.
+type countingFS struct {
+	fs.FS
+	mu    sync.Mutex
+	opens map[string]int
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.mu.Lock()
+	c.opens[name]++
+	c.mu.Unlock()
+	return c.FS.Open(name)
+}
+
+func TestUSFMNameCache_OpensEachFileOnce(t *testing.T) {
+	files := fstest.MapFS{
+		"01-GEN.usfm": {Data: []byte("\\id GEN\n\\toc1 Mwanzo\n\\toc2 Mwa\n")},
+		"02-EXO.usfm": {Data: []byte("\\id EXO\n\\h Kutoka\n")},
+		"41_MRK.usfm": {Data: []byte("\\id MRK\n\\c 1\n")},
+		"README.md":   {Data: []byte("# ULT\n")},
+	}
+	counting := &countingFS{FS: files, opens: make(map[string]int)}
+	cache := books.NewUSFMNameCacheFS(func(dir string) fs.FS {
+		if dir != "sw_ulb" {
+			t.Errorf("opened directory %q; want sw_ulb", dir)
+		}
+		return counting
+	})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, id := range []string{"gen", "exo", "mrk", "lev", "gen"} {
+				want := books.ParseUSFMBookNamesFS(files, books.FindUSFMFileFS(files, id))
+				if got := cache.BookNames("sw_ulb", id); !reflect.DeepEqual(got, want) {
+					t.Errorf("BookNames(%s) = %+v; want %+v", id, got, want)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for name, n := range counting.opens {
+		if n > 1 {
+			t.Errorf("%s opened %d times; want once", name, n)
+		}
+	}
+	if counting.opens["."] != 1 || counting.opens["01-GEN.usfm"] != 1 {
+		t.Errorf("opens = %v; want the directory and each book found once", counting.opens)
+	}
+}
+
+// blockingFS blocks opens of name, after signalling opened, until release
+// is closed.
+type blockingFS struct {
+	fs.FS
+	name            string
+	opened, release chan struct{}
+}
+
+func (b *blockingFS) Open(name string) (fs.File, error) {
+	if name == b.name {
+		close(b.opened)
+		<-b.release
+	}
+	return b.FS.Open(name)
+}
+
+func TestUSFMNameCache_ParsesWithoutLock(t *testing.T) {
+	files := fstest.MapFS{"01-GEN.usfm": {Data: []byte("\\id GEN\n\\toc1 Genesis\n")}}
+	slow := &blockingFS{FS: files, name: "01-GEN.usfm", opened: make(chan struct{}), release: make(chan struct{})}
+	cache := books.NewUSFMNameCacheFS(func(dir string) fs.FS {
+		if dir == "slow" {
+			return slow
+		}
+		return files
+	})
+
+	done := make(chan *books.LocalizedBookNames)
+	go func() { done <- cache.BookNames("slow", "gen") }()
+	<-slow.opened
+
+	// A lookup elsewhere does not wait for the slow parse
+	fast := make(chan *books.LocalizedBookNames)
+	go func() { fast <- cache.BookNames("fast", "gen") }()
+	select {
+	case got := <-fast:
+		if got == nil || got.Long != "Genesis" {
+			t.Errorf("BookNames(fast) = %+v; want Genesis", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("BookNames(fast) waited for another directory's parse")
+	}
+
+	close(slow.release)
+	if got := <-done; got == nil || got.Long != "Genesis" {
+		t.Errorf("BookNames(slow) = %+v; want Genesis", got)
+	}
+}
+
+func TestUSFMNameCache_Nil(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "01-GEN.usfm"), []byte("\\id GEN\n\\toc1 Genesis\n"), 0644)
+
+	var cache *books.USFMNameCache
+	got := cache.BookNames(dir, "gen")
+	if got == nil || got.Long != "Genesis" {
+		t.Errorf("BookNames = %+v; want Genesis", got)
+	}
+	if got := cache.BookNames(dir, "exo"); got != nil {
+		t.Errorf("BookNames(exo) = %+v; want nil", got)
+	}
+	if got := books.NewUSFMNameCache().BookNames(dir, "gen"); got == nil || got.Long != "Genesis" {
+		t.Errorf("cached BookNames = %+v; want Genesis", got)
+	}
+}
+
+// --- BooksInDir tests ---
+
+func TestBooksInDir_CanonicalOrder(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"40-MAT.usfm", "01-GEN.usfm", "A0-FRT.usfm", "README.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("\\id X\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found := books.BooksInDir(dir)
+	if len(found) != 2 {
+		t.Fatalf("BooksInDir returned %d books; want 2", len(found))
+	}
+	if found[0].ID != "gen" || found[1].ID != "mat" {
+		t.Errorf("BooksInDir = [%s %s]; want [gen mat]", found[0].ID, found[1].ID)
+	}
+}
+
+func TestBooksInDir_MissingDir(t *testing.T) {
+	if found := books.BooksInDir(filepath.Join(t.TempDir(), "nope")); found != nil {
+		t.Errorf("BooksInDir should return nil for a missing directory; got %d books", len(found))
+	}
+}
+
+func TestCodeFromUSFMFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"01-GEN.usfm", "GEN"},
+		{"A0-FRT.usfm", "FRT"},
+		{"GEN.usfm", "GEN"},
+		{"41_MRK.usfm", "MRK"},
+		{"MRK_41.usfm", "MRK"},
+		{"MRK.usfm", "MRK"},
+		{"41-mrk.usfm", "MRK"},
+		{"67-XXA.usfm", "XXA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			if got := books.CodeFromUSFMFilename(tt.filename); got != tt.want {
+				t.Errorf("CodeFromUSFMFilename(%q) = %q; want %q", tt.filename, got, tt.want)
+			}
+		})
+	}
+}
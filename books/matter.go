@@ -0,0 +1,46 @@
+package books
+
+import (
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// AllMatter lists the USFM peripheral books: front and back matter and other
+// material that is not a book of the Bible (e.g., "A0-FRT.usfm"). They have
+// no Sort, are not recognized by ByID, ByCode, or IsBookID, and are given no
+// scope.
+var AllMatter = []BookInfo{
+	{ID: "frt", Code: "FRT", Abbr: "Front", Short: "Front Matter", Long: "Front Matter"},
+	{ID: "int", Code: "INT", Abbr: "Intro", Short: "Introductions", Long: "Introductions"},
+	{ID: "bak", Code: "BAK", Abbr: "Back", Short: "Back Matter", Long: "Back Matter"},
+	{ID: "cnc", Code: "CNC", Abbr: "Conc", Short: "Concordance", Long: "Concordance"},
+	{ID: "glo", Code: "GLO", Abbr: "Glos", Short: "Glossary", Long: "Glossary"},
+	{ID: "tdx", Code: "TDX", Abbr: "Topics", Short: "Topical Index", Long: "Topical Index"},
+	{ID: "ndx", Code: "NDX", Abbr: "Names", Short: "Names Index", Long: "Names Index"},
+	{ID: "oth", Code: "OTH", Abbr: "Other", Short: "Other Matter", Long: "Other Matter"},
+}
+
+// MatterByID returns the AllMatter entry for an identifier or code, in any
+// case (e.g., "frt" or "FRT"), or nil if it is not peripheral matter.
+func MatterByID(id string) *BookInfo {
+	for i := range AllMatter {
+		if strings.EqualFold(AllMatter[i].ID, id) {
+			return &AllMatter[i]
+		}
+	}
+	return nil
+}
+
+// MatterNameEntry returns the SB localizedNames key (e.g., "matter-frt") and
+// LocalizedName for the peripheral matter id, built as by
+// LocalizedNameEntryWithNames, or "" if id is not peripheral matter. The
+// key is distinct from those of books, so that matter is not taken for
+// canonical content.
+func MatterNameEntry(id string, lang string, projectTitle string, usfmNames *LocalizedBookNames) (string, sb.LocalizedName) {
+	m := MatterByID(id)
+	if m == nil {
+		return "", sb.LocalizedName{}
+	}
+	return "matter-" + m.ID, localizedName(m, lang, projectTitle, usfmNames)
+}
@@ -0,0 +1,74 @@
+package books
+
+import (
+	"slices"
+	"testing"
+)
+
+// restoreBooks restores AllBooks and its lookup maps when t ends.
+func restoreBooks(t *testing.T) {
+	saved := slices.Clone(AllBooks)
+	t.Cleanup(func() {
+		AllBooks = saved
+		indexBooks()
+	})
+}
+
+func TestRegisterBook(t *testing.T) {
+	restoreBooks(t)
+
+	if err := RegisterBook(BookInfo{Code: "xxa", Abbr: "XXA", Short: "Extra A", Long: "Extra Material A"}); err != nil {
+		t.Fatalf("RegisterBook failed: %v", err)
+	}
+	b := ByCode("XXA")
+	if b == nil {
+		t.Fatal("ByCode(XXA) = nil after RegisterBook")
+	}
+	if b.ID != "xxa" || b.Sort != 67 || b.Short != "Extra A" {
+		t.Errorf("ByCode(XXA) = %+v; want ID xxa, Sort 67, Short Extra A", *b)
+	}
+	if ByID("xxa") != b || !IsBookID("XXA") {
+		t.Error("ByID(xxa) and IsBookID(XXA) do not find the registered book")
+	}
+	if CodeFromUSFMFilename("68-XXA.usfm") != "XXA" {
+		t.Errorf("CodeFromUSFMFilename(68-XXA.usfm) = %q; want XXA", CodeFromUSFMFilename("68-XXA.usfm"))
+	}
+	if key, _ := LocalizedNameEntry("xxa"); key != "book-xxa" {
+		t.Errorf("LocalizedNameEntry(xxa) key = %q; want book-xxa", key)
+	}
+
+	// A book between the Testaments is sorted there
+	if err := RegisterBook(BookInfo{ID: "tob", Code: "TOB", Sort: 39, Short: "Tobit"}); err != nil {
+		t.Fatalf("RegisterBook failed: %v", err)
+	}
+	var codes []string
+	for _, b := range AllBooks[37:41] {
+		codes = append(codes, b.Code)
+	}
+	if want := []string{"ZEC", "MAL", "TOB", "MAT"}; !slices.Equal(codes, want) {
+		t.Errorf("AllBooks[37:41] = %v; want %v", codes, want)
+	}
+	if AllBooks[len(AllBooks)-1].Code != "XXA" {
+		t.Errorf("last book = %s; want XXA", AllBooks[len(AllBooks)-1].Code)
+	}
+	if b := ByCode("GEN"); b == nil || b.Short != "Genesis" {
+		t.Errorf("ByCode(GEN) = %+v after RegisterBook; want Genesis", b)
+	}
+}
+
+func TestRegisterBook_Duplicate(t *testing.T) {
+	restoreBooks(t)
+
+	for _, b := range []BookInfo{
+		{Code: "GEN", Short: "Genesis again"},
+		{ID: "gen", Code: "XXB", Short: "Another Genesis"},
+		{Short: "No code"},
+	} {
+		if err := RegisterBook(b); err == nil {
+			t.Errorf("RegisterBook(%+v) succeeded; want an error", b)
+		}
+	}
+	if len(AllBooks) != 66 {
+		t.Errorf("len(AllBooks) = %d after failed registrations; want 66", len(AllBooks))
+	}
+}
@@ -0,0 +1,106 @@
+package books
+
+import (
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// USFMNameCache memoizes the localized book names read from USFM directories,
+// so that converting many projects, or many repositories in a batch, against
+// the same directory (e.g., en_ult) lists it once and opens each USFM file at
+// most once. It is safe for concurrent use.
+//
+// A nil *USFMNameCache caches nothing: each lookup searches the directory and
+// parses the file again, as FindUSFMFile and ParseUSFMBookNames do.
+type USFMNameCache struct {
+	open func(dir string) fs.FS
+
+	mu   sync.Mutex
+	dirs map[string]*usfmDirIndex
+}
+
+// usfmDirIndex is what a USFMNameCache knows of one directory. The
+// directory is listed once, by the first lookup in it, and each file is
+// parsed once, by the first lookup of it; neither holds the cache's lock.
+type usfmDirIndex struct {
+	listed sync.Once
+	fsys   fs.FS
+	names  []string // the directory's entries, sorted
+
+	// Guarded by the cache's lock
+	files  map[string]string          // book code -> USFM file name, "" if none
+	parsed map[string]*usfmNamesEntry // file name -> its parse
+}
+
+// usfmNamesEntry is the parse of one USFM file, made once.
+type usfmNamesEntry struct {
+	once  sync.Once
+	names *LocalizedBookNames // nil if the file names nothing
+}
+
+// NewUSFMNameCache returns an empty USFMNameCache that reads directories on
+// disk.
+func NewUSFMNameCache() *USFMNameCache {
+	return NewUSFMNameCacheFS(func(dir string) fs.FS { return os.DirFS(dir) })
+}
+
+// NewUSFMNameCacheFS is like NewUSFMNameCache but reads each directory
+// through the file system open returns for it.
+func NewUSFMNameCacheFS(open func(dir string) fs.FS) *USFMNameCache {
+	return &USFMNameCache{open: open, dirs: make(map[string]*usfmDirIndex)}
+}
+
+// BookNames returns the localized names of the book bookID (e.g., "gen")
+// from its USFM file in dir, found as by FindUSFMFile and parsed as by
+// ParseUSFMBookNames, or nil if there is no such file or it names nothing.
+// The result is shared by later lookups and must not be modified.
+func (c *USFMNameCache) BookNames(dir, bookID string) *LocalizedBookNames {
+	if c == nil {
+		file := FindUSFMFile(dir, bookID)
+		if file == "" {
+			return nil
+		}
+		return ParseUSFMBookNames(file)
+	}
+
+	c.mu.Lock()
+	d := c.dirs[dir]
+	if d == nil {
+		d = &usfmDirIndex{files: make(map[string]string), parsed: make(map[string]*usfmNamesEntry)}
+		c.dirs[dir] = d
+	}
+	c.mu.Unlock()
+
+	d.listed.Do(func() {
+		d.fsys = c.open(dir)
+		entries, _ := fs.ReadDir(d.fsys, ".")
+		for _, entry := range entries {
+			d.names = append(d.names, entry.Name())
+		}
+	})
+
+	code := CodeFromProjectID(bookID)
+	c.mu.Lock()
+	name, ok := d.files[code]
+	if !ok {
+		name = findUSFMName(d.names, code)
+		d.files[code] = name
+	}
+	var entry *usfmNamesEntry
+	if name != "" {
+		if entry = d.parsed[name]; entry == nil {
+			entry = &usfmNamesEntry{}
+			d.parsed[name] = entry
+		}
+	}
+	c.mu.Unlock()
+	if entry == nil {
+		return nil
+	}
+
+	entry.once.Do(func() {
+		entry.names = ParseUSFMBookNamesFS(d.fsys, name)
+	})
+	return entry.names
+}
@@ -0,0 +1,357 @@
+package rc2sb
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/unfoldingWord/go-rc2sb/books"
+	"github.com/unfoldingWord/go-rc2sb/rc"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+	"github.com/unfoldingWord/go-rc2sb/versification"
+)
+
+// Severity classifies a CheckIssue.
+type Severity string
+
+const (
+	// SeverityError marks a problem that makes the conversion fail or leave
+	// out content, e.g., an unsupported subject or a missing project file.
+	SeverityError Severity = "error"
+
+	// SeverityWarning marks a problem the conversion works around, e.g., a
+	// missing LICENSE.md, which is replaced by the default license.
+	SeverityWarning Severity = "warning"
+)
+
+// CheckIssue is one problem found by Check.
+type CheckIssue struct {
+	Severity Severity
+
+	// Path is the file the issue is about, relative to inDir, or "" if it is
+	// about the repository as a whole.
+	Path string
+
+	Message string
+}
+
+// String formats the issue as "severity: path: message".
+func (i CheckIssue) String() string {
+	if i.Path == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Path, i.Message)
+}
+
+// CheckReport is the result of Check.
+type CheckReport struct {
+	// Subject is the subject the repository would be converted as, and
+	// Identifier its dublin_core.identifier. Both are empty if the manifest
+	// could not be read.
+	Subject    string
+	Identifier string
+
+	// Issues lists the problems found, in the order they were found.
+	Issues []CheckIssue
+}
+
+// OK reports whether no issue has SeverityError.
+func (r CheckReport) OK() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+// Check checks the RC repository at inDir for problems that would make
+// converting it fail or leave out content, without converting it or writing
+// anything. It checks that manifest.yaml parses, that the subject (or
+// opts.SubjectOverride) is supported, that every project path exists, that a
+// license file (LICENSE.md, LICENSE, or LICENSE.txt) is present, and that TSV
+// headers and USFM \id markers look sane. ValidateRC makes these checks and
+// checks the content as well.
+// Problems are returned in the report; the error is only non-nil if the check
+// itself could not be done, e.g., because ctx was canceled.
+func Check(ctx context.Context, inDir string, opts Options) (CheckReport, error) {
+	lintReport, err := lint(ctx, inDir, opts, false)
+	report := CheckReport{Subject: lintReport.Subject, Identifier: lintReport.Identifier}
+	for _, f := range lintReport.Findings {
+		report.Issues = append(report.Issues, CheckIssue{Severity: f.Severity, Path: f.Path, Message: f.Message})
+	}
+	return report, err
+}
+
+// sniffTSV reports whether the file name of fsys looks tab-separated.
+func sniffTSV(fsys fs.FS, name string) bool {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 4096)
+	n, _ := io.ReadFull(f, head)
+	return sb.IsTabSeparated(head[:n])
+}
+
+// sourceProblem describes why a source file could not be opened.
+func sourceProblem(err error) string {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return "file not found"
+	case errors.Is(err, fs.ErrPermission):
+		return "file not readable"
+	default:
+		return err.Error()
+	}
+}
+
+// bookBounds is a Bible book and the versification its references are
+// checked against.
+type bookBounds struct {
+	code   string
+	scheme *versification.Scheme
+}
+
+// checkedBook returns the bounds references in project are checked against:
+// the versification it declares, or eng if it declares none or ufw, which
+// numbers verses as English Bibles do. It returns nil, checking nothing, for a
+// project that is not a Bible book or declares a versification with no
+// built-in scheme.
+func checkedBook(project rc.Project) *bookBounds {
+	b := books.ByID(project.Identifier)
+	if b == nil {
+		return nil
+	}
+	name := project.Versification
+	if name == "" || strings.EqualFold(name, "ufw") {
+		name = "eng"
+	}
+	scheme := versification.Lookup(name)
+	if scheme == nil {
+		return nil
+	}
+	return &bookBounds{code: b.Code, scheme: scheme}
+}
+
+// checkReference returns the problem with the TSV reference ref (e.g.,
+// "1:1", "1:3-5", or "1:intro") if it is beyond the book, or "" if it is not.
+func (b *bookBounds) checkReference(ref string) string {
+	for _, point := range referencePoints(ref) {
+		chapter, verse := point[0], point[1]
+		if n := b.scheme.Chapters(b.code); chapter < 1 || chapter > n {
+			return fmt.Sprintf("reference %s is beyond %s, which has %d chapters in the %s versification", ref, b.code, n, b.scheme.Name)
+		}
+		if !b.scheme.Contains(b.code, chapter, verse) {
+			return fmt.Sprintf("reference %s is beyond %s %d, which has %d verses in the %s versification",
+				ref, b.code, chapter, b.scheme.Verses(b.code, chapter), b.scheme.Name)
+		}
+	}
+	return ""
+}
+
+// referencePoints returns the chapter and verse of each point of the TSV
+// reference ref (e.g., "1:3-5" is 1:3 and 1:5, and "1:20-2:3" is 1:20 and
+// 2:3), with a verse of 0 for a chapter as a whole (e.g., "1:intro" or "2").
+// Points whose chapter is not a number, such as "front:intro", are left out.
+func referencePoints(ref string) [][2]int {
+	var points [][2]int
+	chapter := 0
+	for _, part := range strings.FieldsFunc(ref, func(r rune) bool { return r == ',' || r == ';' || r == '-' || r == '–' }) {
+		part = strings.TrimSpace(part)
+		if c, v, ok := strings.Cut(part, ":"); ok {
+			n, err := strconv.Atoi(c)
+			if err != nil {
+				chapter = 0
+				continue
+			}
+			chapter, part = n, v
+		} else if chapter == 0 {
+			// A chapter on its own
+			if n, err := strconv.Atoi(part); err == nil {
+				points = append(points, [2]int{n, 0})
+			}
+			continue
+		}
+		// A verse, possibly with a part letter (e.g., "3a"), or a word
+		// such as "intro"
+		verse, _ := strconv.Atoi(strings.TrimRightFunc(part, unicode.IsLetter))
+		points = append(points, [2]int{chapter, verse})
+	}
+	return points
+}
+
+// tsvProblem is a problem with a TSV file found by the rule.
+type tsvProblem struct {
+	rule, message string
+}
+
+// checkTSV returns the problems with the TSV file name: a header with blank
+// or repeated column names, rows with a different number of columns than the
+// header (only the first such row is reported), and, if book is not nil,
+// references in the Reference column that are beyond the book. If expected
+// is not nil, it also returns the expected columns the header lacks and
+// repeated values of the ID column.
+func checkTSV(fsys fs.FS, name string, book *bookBounds, expected []string) []tsvProblem {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return []tsvProblem{{"tsv-header", sourceProblem(err)}}
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	text, err := readTSVLine(r)
+	if err == io.EOF {
+		return []tsvProblem{{"tsv-header", "empty TSV file"}}
+	} else if err != nil {
+		return []tsvProblem{{"tsv-header", err.Error()}}
+	}
+
+	var problems []tsvProblem
+	add := func(rule, format string, args ...any) {
+		problems = append(problems, tsvProblem{rule, fmt.Sprintf(format, args...)})
+	}
+	header := strings.Split(strings.TrimPrefix(text, "\ufeff"), "\t")
+	if len(header) < 2 {
+		add("tsv-header", "header has a single column; is the file tab-separated?")
+	}
+	seen := make(map[string]bool)
+	refColumn, idColumn := -1, -1
+	for i, column := range header {
+		switch column {
+		case "Reference":
+			refColumn = i
+		case "ID":
+			idColumn = i
+		}
+		switch {
+		case strings.TrimSpace(column) == "":
+			add("tsv-header", "header column %d is blank", i+1)
+		case seen[column]:
+			add("tsv-header", "header column %q is repeated", column)
+		}
+		seen[column] = true
+	}
+	for _, column := range expected {
+		if !seen[column] {
+			add("tsv-expected-columns", "header has no %s column", column)
+		}
+	}
+
+	columnsReported := false
+	ids := make(map[string]int)
+	for line := 2; ; line++ {
+		text, err := readTSVLine(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			add("tsv-columns", "%s", err.Error())
+			break
+		}
+		if text == "" {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if n := len(fields); n != len(header) && !columnsReported {
+			add("tsv-columns", "line %d has %d columns; the header has %d", line, n, len(header))
+			columnsReported = true
+		}
+		if book != nil && refColumn >= 0 && refColumn < len(fields) {
+			if problem := book.checkReference(fields[refColumn]); problem != "" {
+				add("tsv-reference", "line %d: %s", line, problem)
+			}
+		}
+		if expected != nil && idColumn >= 0 && idColumn < len(fields) && fields[idColumn] != "" {
+			id := fields[idColumn]
+			if prev, ok := ids[id]; ok {
+				add("tsv-id", "line %d: ID %q is already used on line %d", line, id, prev)
+			} else {
+				ids[id] = line
+			}
+		}
+	}
+	return problems
+}
+
+// readTSVLine returns the next line of r without its line ending, however
+// long it is, or io.EOF once r has no more lines.
+func readTSVLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), err
+}
+
+// checkUSFM returns the problem with the \id marker of the USFM file name, or
+// "" if it names the book of the project identifier. Projects that are not
+// Bible books (e.g., front matter) only need an \id marker.
+func checkUSFM(fsys fs.FS, name, identifier string) string {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return sourceProblem(err)
+	}
+	defer f.Close()
+
+	// The \id marker must be the first marker in the file
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if fields[0] != `\id` || len(fields) < 2 {
+			return `does not start with an \id marker`
+		}
+		if b := books.ByID(identifier); b != nil && !strings.EqualFold(fields[1], b.Code) {
+			return fmt.Sprintf(`\id is %s but the project is %s`, fields[1], b.Code)
+		}
+		return ""
+	}
+	if err := scanner.Err(); err != nil {
+		return err.Error()
+	}
+	return "empty USFM file"
+}
+
+// checkUSFMChapters returns a problem for each \c marker of the USFM file
+// name beyond the chapters of book, if book is not nil.
+func checkUSFMChapters(fsys fs.FS, name string, book *bookBounds) []string {
+	if book == nil {
+		return nil
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return []string{sourceProblem(err)}
+	}
+	defer f.Close()
+
+	var problems []string
+	n := book.scheme.Chapters(book.code)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		for i := 0; i+1 < len(fields); i++ {
+			if fields[i] != `\c` {
+				continue
+			}
+			if chapter, err := strconv.Atoi(fields[i+1]); err == nil && (chapter < 1 || chapter > n) {
+				problems = append(problems, fmt.Sprintf(`\c %d is beyond %s, which has %d chapters in the %s versification`, chapter, book.code, n, book.scheme.Name))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
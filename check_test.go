@@ -0,0 +1,256 @@
+package rc2sb_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+)
+
+// checkRepoFiles is a small TN repo with no problems.
+func checkRepoFiles() map[string]string {
+	return map[string]string{
+		"manifest.yaml": `dublin_core:
+  subject: 'TSV Translation Notes'
+  identifier: 'tn'
+  title: 'Test TN'
+  language:
+    identifier: 'en'
+    title: 'English'
+    direction: 'ltr'
+projects:
+  - identifier: 'gen'
+    path: './tn_GEN.tsv'
+  - identifier: 'exo'
+    path: './tn_EXO.tsv'
+`,
+		"LICENSE.md": "# License\n",
+		"tn_GEN.tsv": "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n1:1\tabcd\t\t\t\t0\tNote\n",
+		"tn_EXO.tsv": "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n",
+	}
+}
+
+func TestCheck_Clean(t *testing.T) {
+	inDir := t.TempDir()
+	writeRepoFiles(t, inDir, checkRepoFiles())
+
+	report, err := rc2sb.Check(context.Background(), inDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !report.OK() || len(report.Issues) != 0 {
+		t.Errorf("issues = %v; want none", report.Issues)
+	}
+	if report.Subject != "TSV Translation Notes" || report.Identifier != "tn" {
+		t.Errorf("report = %+v", report)
+	}
+
+	// Nothing is written
+	entries, _ := os.ReadDir(inDir)
+	if len(entries) != len(checkRepoFiles()) {
+		t.Errorf("inDir has %d entries after Check; want %d", len(entries), len(checkRepoFiles()))
+	}
+}
+
+func TestCheck_MissingProjectFile(t *testing.T) {
+	inDir := t.TempDir()
+	writeRepoFiles(t, inDir, checkRepoFiles())
+	os.Remove(filepath.Join(inDir, "tn_EXO.tsv"))
+
+	report, err := rc2sb.Check(context.Background(), inDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	want := []rc2sb.CheckIssue{{Severity: rc2sb.SeverityError, Path: "tn_EXO.tsv", Message: `project "exo": file not found`}}
+	if !reflect.DeepEqual(report.Issues, want) {
+		t.Errorf("issues = %v; want %v", report.Issues, want)
+	}
+	if report.OK() {
+		t.Error("OK() = true; want false")
+	}
+}
+
+func TestCheck_UnsupportedSubject(t *testing.T) {
+	inDir := t.TempDir()
+	files := checkRepoFiles()
+	files["manifest.yaml"] = strings.Replace(files["manifest.yaml"], "TSV Translation Notes", "TSV Translation Nots", 1)
+	writeRepoFiles(t, inDir, files)
+
+	report, err := rc2sb.Check(context.Background(), inDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Severity != rc2sb.SeverityError ||
+		!strings.Contains(report.Issues[0].Message, `did you mean "TSV Translation Notes"?`) {
+		t.Errorf("issues = %v; want one unsupported subject error with a suggestion", report.Issues)
+	}
+
+	// The subject override is checked in place of the manifest's subject
+	report, err = rc2sb.Check(context.Background(), inDir, rc2sb.Options{SubjectOverride: "TSV Translation Notes"})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !report.OK() || report.Subject != "TSV Translation Notes" {
+		t.Errorf("with override: report = %+v; want no errors", report)
+	}
+}
+
+func TestCheck_Warnings(t *testing.T) {
+	inDir := t.TempDir()
+	writeRepoFiles(t, inDir, map[string]string{
+		"manifest.yaml": `dublin_core:
+  subject: 'Bible'
+  identifier: 'ulb'
+  language:
+    identifier: 'en'
+projects:
+  - identifier: 'gen'
+    path: './01-GEN.usfm'
+  - identifier: 'frt'
+    path: './A0-FRT.usfm'
+  - identifier: 'exo'
+    path: './02-EXO.usfm'
+`,
+		"01-GEN.usfm": "\\id EXO\n",
+		"A0-FRT.usfm": "\\id FRT\n",
+		"02-EXO.usfm": "\\c 1\n",
+	})
+
+	report, err := rc2sb.Check(context.Background(), inDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	want := []string{
+		"warning: LICENSE.md: missing; the default license will be used",
+		`warning: 01-GEN.usfm: \id is EXO but the project is GEN`,
+		`warning: 02-EXO.usfm: does not start with an \id marker`,
+	}
+	var got []string
+	for _, issue := range report.Issues {
+		got = append(got, issue.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %q; want %q", got, want)
+	}
+	if !report.OK() {
+		t.Error("OK() = false; want true for warnings only")
+	}
+}
+
+func TestCheck_TSVProblems(t *testing.T) {
+	inDir := t.TempDir()
+	files := checkRepoFiles()
+	files["tn_GEN.tsv"] = "Reference\tID\tID\t\n1:1\tabcd\n"
+	files["tn_EXO.tsv"] = "Reference,ID,Note\n"
+	writeRepoFiles(t, inDir, files)
+
+	report, err := rc2sb.Check(context.Background(), inDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	want := []string{
+		`warning: tn_GEN.tsv: header column "ID" is repeated`,
+		"warning: tn_GEN.tsv: header column 4 is blank",
+		"warning: tn_GEN.tsv: line 2 has 2 columns; the header has 4",
+		"warning: tn_EXO.tsv: header has a single column; is the file tab-separated?",
+	}
+	var got []string
+	for _, issue := range report.Issues {
+		got = append(got, issue.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %q; want %q", got, want)
+	}
+}
+
+func TestCheck_MissingManifest(t *testing.T) {
+	report, err := rc2sb.Check(context.Background(), t.TempDir(), rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Path != "manifest.yaml" || report.OK() {
+		t.Errorf("issues = %v; want a manifest.yaml error", report.Issues)
+	}
+}
+
+func TestCheck_LicenseTxt(t *testing.T) {
+	inDir := t.TempDir()
+	files := checkRepoFiles()
+	delete(files, "LICENSE.md")
+	files["LICENSE.txt"] = "License\n"
+	writeRepoFiles(t, inDir, files)
+
+	report, err := rc2sb.Check(context.Background(), inDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Errorf("issues = %v; want none for LICENSE.txt", report.Issues)
+	}
+}
+
+func TestCheck_ReferencesOutOfRange(t *testing.T) {
+	inDir := t.TempDir()
+	files := checkRepoFiles()
+	files["tn_GEN.tsv"] = "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n" +
+		"front:intro\ta1b2\t\t\t\t0\tIntro\n" +
+		"1:intro\tc3d4\t\t\t\t0\tIntro\n" +
+		"1:31-2:3\te5f6\t\t\t\t0\tNote\n" +
+		"1:32\tg7h8\t\t\t\t0\tNote\n" +
+		"51:1\tj9k0\t\t\t\t0\tNote\n"
+	writeRepoFiles(t, inDir, files)
+
+	report, err := rc2sb.Check(context.Background(), inDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	want := []string{
+		"warning: tn_GEN.tsv: line 5: reference 1:32 is beyond GEN 1, which has 31 verses in the eng versification",
+		"warning: tn_GEN.tsv: line 6: reference 51:1 is beyond GEN, which has 50 chapters in the eng versification",
+	}
+	var got []string
+	for _, issue := range report.Issues {
+		got = append(got, issue.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %q; want %q", got, want)
+	}
+}
+
+func TestCheck_USFMChaptersOutOfRange(t *testing.T) {
+	inDir := t.TempDir()
+	writeRepoFiles(t, inDir, map[string]string{
+		"manifest.yaml": `dublin_core:
+  subject: 'Bible'
+  identifier: 'ult'
+  title: 'Test Bible'
+  language:
+    identifier: 'en'
+    title: 'English'
+    direction: 'ltr'
+projects:
+  - identifier: 'gen'
+    path: './01-GEN.usfm'
+    versification: 'ufw'
+`,
+		"LICENSE.md":  "# License\n",
+		"01-GEN.usfm": "\\id GEN\n\\c 50\n\\v 1 Verse\n\\c 51\n\\v 1 Verse\n",
+	})
+
+	report, err := rc2sb.Check(context.Background(), inDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	want := []string{`warning: 01-GEN.usfm: \c 51 is beyond GEN, which has 50 chapters in the eng versification`}
+	var got []string
+	for _, issue := range report.Issues {
+		got = append(got, issue.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %q; want %q", got, want)
+	}
+}
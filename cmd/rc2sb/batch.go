@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+	"github.com/unfoldingWord/go-rc2sb/rc"
+)
+
+// defaultNameTemplate names each output directory in glob mode.
+const defaultNameTemplate = "{identifier}_{subject}"
+
+// runBatch runs the batch subcommand and returns the process exit code:
+// exitOK if every conversion succeeded, exitBatchFailed if any failed, or
+// exitUsage if the arguments, list file, or glob were bad.
+func runBatch(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rc2sb batch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	jobs := fs.Int("jobs", 1, "number of conversions to run at once")
+	glob := fs.String("glob", "", "convert every RC repo matching this glob instead of reading a list")
+	outRoot := fs.String("out-root", "", "directory for output in --glob mode")
+	name := fs.String("name", defaultNameTemplate, "output directory name in --glob mode; supports {identifier}, {subject}, and {language}")
+	configPath := fs.String("config", "", "read jobs and conversion settings from this YAML file (default ./rc2sb.yaml, if present)")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: rc2sb batch [flags] <listFile>\n")
+		fmt.Fprintf(stderr, "       rc2sb batch [flags] --glob <pattern> --out-root <dir>\n\n")
+		fmt.Fprintf(stderr, "Converts many RC repositories, continuing past failures.\n\n")
+		fmt.Fprintf(stderr, "Arguments:\n")
+		fmt.Fprintf(stderr, "  listFile  File of \"inDir outDir\" lines (\"-\" for stdin); blank lines and #comments are skipped\n\n")
+		fmt.Fprintf(stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	cfg, cfgWarnings, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "rc2sb batch: %v\n", err)
+		return exitUsage
+	}
+	if !flagsSet(fs)["jobs"] && cfg.Jobs > 0 {
+		*jobs = cfg.Jobs
+	}
+
+	var list []rc2sb.Job
+	switch {
+	case *glob != "" && *outRoot != "" && fs.NArg() == 0:
+		list, err = globJobs(*glob, *outRoot, *name)
+	case *glob == "" && fs.NArg() == 1:
+		list, err = readJobList(fs.Arg(0))
+	default:
+		fs.Usage()
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "rc2sb batch: %v\n", err)
+		return exitUsage
+	}
+
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	for _, w := range cfgWarnings {
+		logger.Warn(w)
+	}
+	opts := rc2sb.Options{
+		PayloadPath:     cfg.Payload,
+		USFMPath:        cfg.USFM,
+		IncludeGlobs:    cfg.Include,
+		ExcludeGlobs:    cfg.Exclude,
+		SHA256Checksums: cfg.SHA256,
+		Strict:          cfg.Strict,
+		Logger:          logger,
+	}
+	results := rc2sb.ConvertAll(context.Background(), list, opts, *jobs)
+
+	failed := 0
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tSTATUS\tINGREDIENTS\tDETAIL")
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(tw, "%s\tFAILED\t-\t%v\n", r.Job.InDir, r.Err)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\tok\t%d\t%s\n", r.Job.InDir, r.Result.Ingredients, r.Job.OutDir)
+	}
+	tw.Flush()
+	fmt.Fprintf(stdout, "%d converted, %d failed\n", len(results)-failed, failed)
+
+	if failed > 0 {
+		return exitBatchFailed
+	}
+	return exitOK
+}
+
+// readJobList reads "inDir outDir" pairs, one per line, from the file name
+// (or stdin for "-").
+func readJobList(name string) ([]rc2sb.Job, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var jobs []rc2sb.Job
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want \"inDir outDir\", got %q", name, lineNum, line)
+		}
+		jobs = append(jobs, rc2sb.Job{InDir: fields[0], OutDir: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// globJobs returns a job for each directory matching pattern, writing to a
+// directory under outRoot named by expanding the template from its manifest.
+// Directories whose manifest cannot be read are still included, named after
+// the directory itself, so that they are reported as failures.
+func globJobs(pattern, outRoot, template string) ([]rc2sb.Job, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []rc2sb.Job
+	seen := make(map[string]string)
+	for _, inDir := range matches {
+		if info, err := os.Stat(inDir); err != nil || !info.IsDir() {
+			continue
+		}
+		name := filepath.Base(inDir)
+		if manifest, err := rc.LoadManifest(inDir); err == nil {
+			name = expandNameTemplate(template, manifest)
+		}
+		outDir := filepath.Join(outRoot, name)
+		if prev, ok := seen[outDir]; ok {
+			return nil, fmt.Errorf("%s and %s would both be written to %s; use --name to make output names unique", prev, inDir, outDir)
+		}
+		seen[outDir] = inDir
+		jobs = append(jobs, rc2sb.Job{InDir: inDir, OutDir: outDir})
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no directories match %q", pattern)
+	}
+	return jobs, nil
+}
+
+// expandNameTemplate replaces {identifier}, {subject}, and {language} in
+// template with values from manifest, made safe for use in a file name.
+func expandNameTemplate(template string, manifest *rc.Manifest) string {
+	dc := manifest.DublinCore
+	safe := strings.NewReplacer(" ", "_", "/", "_", string(filepath.Separator), "_")
+	return strings.NewReplacer(
+		"{identifier}", safe.Replace(dc.Identifier),
+		"{subject}", safe.Replace(dc.Subject),
+		"{language}", safe.Replace(dc.Language.Identifier),
+	).Replace(template)
+}
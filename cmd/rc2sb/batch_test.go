@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeBatchFixtures creates three repos under a new directory: two valid
+// Translation Words repos and one without a manifest, which fails to convert.
+func writeBatchFixtures(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range []string{"en_tw", "fr_tw"} {
+		dir := writeTWRepo(t)
+		manifest, err := os.ReadFile(filepath.Join(dir, "manifest.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lang := strings.TrimSuffix(name, "_tw")
+		manifest = bytes.Replace(manifest, []byte("identifier: 'en'"), []byte("identifier: '"+lang+"'"), 1)
+		if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), manifest, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(dir, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "broken_tw"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestRunBatch_ListFile(t *testing.T) {
+	root := writeBatchFixtures(t)
+	outRoot := t.TempDir()
+
+	list := "# release batch\n\n"
+	for _, name := range []string{"en_tw", "broken_tw", "fr_tw"} {
+		list += filepath.Join(root, name) + " " + filepath.Join(outRoot, name) + "\n"
+	}
+	listFile := filepath.Join(t.TempDir(), "repos.txt")
+	if err := os.WriteFile(listFile, []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"batch", "--jobs", "2", listFile}, &stdout, &stderr); code != exitBatchFailed {
+		t.Errorf("run() = %d; want %d when a repo fails", code, exitBatchFailed)
+	}
+
+	out := stdout.String()
+	if !strings.Contains(out, "2 converted, 1 failed") {
+		t.Errorf("summary missing from output:\n%s", out)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "broken_tw") && !strings.Contains(line, "FAILED") {
+			t.Errorf("broken_tw not reported as failed: %q", line)
+		}
+	}
+	for _, name := range []string{"en_tw", "fr_tw"} {
+		if _, err := os.Stat(filepath.Join(outRoot, name, "metadata.json")); err != nil {
+			t.Errorf("%s was not converted: %v", name, err)
+		}
+	}
+}
+
+func TestRunBatch_Glob(t *testing.T) {
+	root := writeBatchFixtures(t)
+	outRoot := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"batch", "--glob", filepath.Join(root, "*_tw"), "--out-root", outRoot, "--name", "{language}_{identifier}"}
+	if code := run(args, &stdout, &stderr); code != exitBatchFailed {
+		t.Errorf("run() = %d; want %d when a repo fails", code, exitBatchFailed)
+	}
+	if !strings.Contains(stdout.String(), "2 converted, 1 failed") {
+		t.Errorf("summary missing from output:\n%s", stdout.String())
+	}
+	for _, name := range []string{"en_tw", "fr_tw"} {
+		if _, err := os.Stat(filepath.Join(outRoot, name, "metadata.json")); err != nil {
+			t.Errorf("%s was not converted to its templated name: %v", name, err)
+		}
+	}
+}
+
+func TestRunBatch_GlobNameCollision(t *testing.T) {
+	root := writeBatchFixtures(t)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"batch", "--glob", filepath.Join(root, "*_tw"), "--out-root", t.TempDir(), "--name", "{identifier}"}
+	if code := run(args, &stdout, &stderr); code != exitUsage {
+		t.Errorf("run() = %d; want %d", code, exitUsage)
+	}
+	if !strings.Contains(stderr.String(), "--name") {
+		t.Errorf("collision not reported: %q", stderr.String())
+	}
+}
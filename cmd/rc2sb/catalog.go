@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+	"github.com/unfoldingWord/go-rc2sb/dcs"
+)
+
+// newCatalog returns the Door43 catalog used by --payload-from-catalog and
+// --usfm-from-catalog: the server in RC2SB_DCS_URL, or dcs.DefaultServer.
+func newCatalog() *dcs.Catalog {
+	return &dcs.Catalog{Server: os.Getenv("RC2SB_DCS_URL")}
+}
+
+// cacheDir returns the directory companion resources are cached in:
+// RC2SB_CACHE_DIR, or rc2sb in the user's cache directory.
+func cacheDir() (string, error) {
+	if dir := os.Getenv("RC2SB_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("finding cache directory (set RC2SB_CACHE_DIR): %w", err)
+	}
+	return filepath.Join(dir, "rc2sb"), nil
+}
+
+// fetchCompanion finds the latest release of <lang>_<identifier> in catalog
+// and returns the directory it is cloned into in the cache, cloning it first
+// if it is not already cached.
+func fetchCompanion(ctx context.Context, catalog *dcs.Catalog, lang, identifier string, logger *slog.Logger) (string, error) {
+	res, err := catalog.FindResource(ctx, lang, identifier)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(root, res.Owner, res.Name, res.Tag)
+	if _, err := os.Stat(dir); err == nil {
+		logger.Debug("using cached companion resource", "repo", res.Owner+"/"+res.Name, "tag", res.Tag, "dir", dir)
+		return dir, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	// Clone beside the final directory and rename it into place, so that an
+	// interrupted clone is never mistaken for a cached one
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", fmt.Errorf("creating cache directory: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dir), ".clone-")
+	if err != nil {
+		return "", fmt.Errorf("creating cache directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fetcher := rc2sb.GitCLI{Token: os.Getenv("RC2SB_GIT_TOKEN")}
+	if _, err := fetcher.Fetch(ctx, res.CloneURL, res.Tag, tmpDir); err != nil {
+		return "", fmt.Errorf("fetching %s/%s %s: %w", res.Owner, res.Name, res.Tag, err)
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return "", fmt.Errorf("caching %s/%s: %w", res.Owner, res.Name, err)
+	}
+	logger.Info("fetched companion resource", "repo", res.Owner+"/"+res.Name, "tag", res.Tag, "dir", dir)
+	return dir, nil
+}
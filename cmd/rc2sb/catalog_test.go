@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun_PayloadFromCatalog(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	bare := gitBareRepo(t, writeTWRepo(t), "v80")
+
+	searches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/catalog/search" || r.URL.Query().Get("repo") != "en_tw" {
+			http.NotFound(w, r)
+			return
+		}
+		searches++
+		fmt.Fprintf(w, `{"ok": true, "data": [{"name": "en_tw", "owner": "unfoldingWord",
+			"repo": {"clone_url": "file://%s"}, "release": {"tag_name": "v80"}}]}`, filepath.ToSlash(bare))
+	}))
+	defer srv.Close()
+	cache := t.TempDir()
+	t.Setenv("RC2SB_DCS_URL", srv.URL)
+	t.Setenv("RC2SB_CACHE_DIR", cache)
+
+	inDir := writeTWRepo(t)
+	for i := range 2 {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"--payload-from-catalog", inDir, t.TempDir()}, &stdout, &stderr); code != exitOK {
+			t.Fatalf("run %d = %d; stderr: %s", i+1, code, stderr.String())
+		}
+	}
+
+	if searches != 2 {
+		t.Errorf("catalog searched %d times; want once per run", searches)
+	}
+	if _, err := os.Stat(filepath.Join(cache, "unfoldingWord", "en_tw", "v80", "manifest.yaml")); err != nil {
+		t.Errorf("en_tw v80 was not cached: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(cache, "unfoldingWord", "en_tw"))
+	if len(entries) != 1 {
+		t.Errorf("cache holds %d entries for en_tw; want only v80", len(entries))
+	}
+}
+
+func TestRun_FromCatalogErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": true, "data": []}`))
+	}))
+	defer srv.Close()
+	t.Setenv("RC2SB_DCS_URL", srv.URL)
+	t.Setenv("RC2SB_CACHE_DIR", t.TempDir())
+	inDir := writeTWRepo(t)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"with --payload", []string{"--payload-from-catalog", "--payload", inDir, inDir, t.TempDir()}, exitUsage},
+		{"with --usfm", []string{"--usfm-from-catalog", "ult", "--usfm", inDir, inDir, t.TempDir()}, exitUsage},
+		{"git URL", []string{"--payload-from-catalog", "https://git.door43.org/unfoldingWord/en_tn", t.TempDir()}, exitUsage},
+		{"not in catalog", []string{"--usfm-from-catalog", "ult", inDir, t.TempDir()}, exitConversion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != tt.want {
+				t.Errorf("run() = %d; want %d; stderr: %s", code, tt.want, stderr.String())
+			}
+		})
+	}
+}
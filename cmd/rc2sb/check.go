@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+)
+
+// runCheck runs the check subcommand and returns the process exit code:
+// exitOK if no errors were found (warnings are allowed), exitCheckFailed if
+// any were, or exitUsage for bad arguments.
+func runCheck(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rc2sb check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	subject := fs.String("subject", "", "check as this RC subject instead of the manifest's dublin_core.subject")
+	jsonOut := fs.Bool("json", false, "print the report to stdout as a single JSON object:\n"+
+		"{\"subject\", \"identifier\", \"ok\", \"issues\": [{\"rule\", \"severity\", \"path\", \"message\"}]}")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: rc2sb check [flags] <inDir>\n\n")
+		fmt.Fprintf(stderr, "Lints an RC repository without converting it: problems that would make converting it fail,\n")
+		fmt.Fprintf(stderr, "and content problems such as incomplete manifests, TSV columns, and unresolvable rc:// links.\n\n")
+		fmt.Fprintf(stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	report, err := rc2sb.ValidateRC(context.Background(), fs.Arg(0), rc2sb.Options{SubjectOverride: *subject})
+	if err != nil {
+		fmt.Fprintf(stderr, "rc2sb check: %v\n", err)
+		return exitConversion
+	}
+
+	if *jsonOut {
+		writeJSON(stdout, newJSONCheckReport(report))
+	} else {
+		errs := 0
+		for _, finding := range report.Findings {
+			if finding.Severity == rc2sb.SeverityError {
+				errs++
+			}
+			fmt.Fprintln(stdout, finding)
+		}
+		fmt.Fprintf(stdout, "%s: %d errors, %d warnings\n", fs.Arg(0), errs, len(report.Findings)-errs)
+	}
+
+	if !report.OK() {
+		return exitCheckFailed
+	}
+	return exitOK
+}
+
+// jsonCheckReport is the --json output of the check subcommand.
+type jsonCheckReport struct {
+	Subject    string           `json:"subject"`
+	Identifier string           `json:"identifier"`
+	OK         bool             `json:"ok"`
+	Issues     []jsonCheckIssue `json:"issues"`
+}
+
+type jsonCheckIssue struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
+
+func newJSONCheckReport(r rc2sb.LintReport) jsonCheckReport {
+	issues := make([]jsonCheckIssue, 0, len(r.Findings))
+	for _, f := range r.Findings {
+		issues = append(issues, jsonCheckIssue{Rule: f.Rule, Severity: string(f.Severity), Path: f.Path, Message: f.Message})
+	}
+	return jsonCheckReport{Subject: r.Subject, Identifier: r.Identifier, OK: r.OK(), Issues: issues}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunCheck(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"check", writeTWRepo(t)}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run() = %d; stderr: %s", code, stderr.String())
+	}
+	// The TW repo has no LICENSE.md, unrecognized rights, and four empty
+	// manifest fields, which are only warnings
+	if out := stdout.String(); !strings.Contains(out, "warning: LICENSE.md") || !strings.Contains(out, "[manifest-required]") || !strings.Contains(out, "0 errors, 6 warnings") {
+		t.Errorf("stdout = %q", out)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"check", "--json", "--subject", "Translation Wordz", writeTWRepo(t)}, &stdout, &stderr); code != exitCheckFailed {
+		t.Fatalf("run() = %d; want %d", code, exitCheckFailed)
+	}
+	var report jsonCheckReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("stdout is not a JSON report: %v\n%s", err, stdout.String())
+	}
+	if report.OK || len(report.Issues) == 0 || report.Issues[0].Severity != "error" || report.Issues[0].Rule != "subject" {
+		t.Errorf("report = %+v; want an unsupported subject error", report)
+	}
+
+	if code := run([]string{"check"}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("run() without inDir = %d; want %d", code, exitUsage)
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	var checkOut, validateOut, stderr bytes.Buffer
+	dir := writeTWRepo(t)
+	if code := run([]string{"check", dir}, &checkOut, &stderr); code != exitOK {
+		t.Fatalf("run(check) = %d; stderr: %s", code, stderr.String())
+	}
+	if code := run([]string{"validate", dir}, &validateOut, &stderr); code != exitOK {
+		t.Fatalf("run(validate) = %d; stderr: %s", code, stderr.String())
+	}
+	if validateOut.String() != checkOut.String() {
+		t.Errorf("validate printed %q; want the check output %q", validateOut.String(), checkOut.String())
+	}
+}
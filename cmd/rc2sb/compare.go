@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+)
+
+// runCompare runs the compare subcommand and returns the process exit code:
+// exitOK if the SB holds all the content of the RC, exitCompareFailed if not,
+// or exitUsage for bad arguments.
+func runCompare(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rc2sb compare", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	subject := fs.String("subject", "", "compare as this RC subject instead of the manifest's dublin_core.subject")
+	jsonOut := fs.Bool("json", false, "print the report to stdout as a single JSON object:\n"+
+		"{\"subject\", \"identifier\", \"ok\", \"matched\", \"transformed\", \"differing\",\n"+
+		"\"missingIngredients\", \"extraIngredients\"}")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: rc2sb compare [flags] <inDir> <sbDir>\n\n")
+		fmt.Fprintf(stderr, "Checks that the SB in sbDir, converted from the RC repository in inDir, holds every\n")
+		fmt.Fprintf(stderr, "project file of the RC with the same content.\n\n")
+		fmt.Fprintf(stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	report, err := rc2sb.CompareRCToSB(context.Background(), fs.Arg(0), fs.Arg(1), rc2sb.Options{SubjectOverride: *subject})
+	if err != nil {
+		fmt.Fprintf(stderr, "rc2sb compare: %v\n", err)
+		return exitCode(err)
+	}
+
+	if *jsonOut {
+		writeJSON(stdout, newJSONCompareReport(report))
+	} else {
+		for _, name := range report.Differing {
+			fmt.Fprintf(stdout, "differs: %s\n", name)
+		}
+		for _, name := range report.MissingIngredients {
+			fmt.Fprintf(stdout, "no ingredient: %s\n", name)
+		}
+		for _, key := range report.ExtraIngredients {
+			fmt.Fprintf(stdout, "no RC file: %s\n", key)
+		}
+		fmt.Fprintf(stdout, "%s: %d matched, %d transformed, %d differing, %d without ingredient, %d extra ingredients\n",
+			fs.Arg(0), report.Matched, len(report.Transformed), len(report.Differing),
+			len(report.MissingIngredients), len(report.ExtraIngredients))
+	}
+
+	if !report.OK() {
+		return exitCompareFailed
+	}
+	return exitOK
+}
+
+// jsonCompareReport is the --json output of the compare subcommand.
+type jsonCompareReport struct {
+	Subject            string   `json:"subject"`
+	Identifier         string   `json:"identifier"`
+	OK                 bool     `json:"ok"`
+	Matched            int      `json:"matched"`
+	Transformed        []string `json:"transformed"`
+	Differing          []string `json:"differing"`
+	MissingIngredients []string `json:"missingIngredients"`
+	ExtraIngredients   []string `json:"extraIngredients"`
+}
+
+func newJSONCompareReport(r rc2sb.CompareReport) jsonCompareReport {
+	nonNil := func(s []string) []string {
+		if s == nil {
+			return []string{}
+		}
+		return s
+	}
+	return jsonCompareReport{
+		Subject:            r.Subject,
+		Identifier:         r.Identifier,
+		OK:                 r.OK(),
+		Matched:            r.Matched,
+		Transformed:        nonNil(r.Transformed),
+		Differing:          nonNil(r.Differing),
+		MissingIngredients: nonNil(r.MissingIngredients),
+		ExtraIngredients:   nonNil(r.ExtraIngredients),
+	}
+}
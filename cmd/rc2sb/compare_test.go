@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCompare(t *testing.T) {
+	inDir, outDir := writeTWRepo(t), t.TempDir()
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--quiet", inDir, outDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("conversion run() = %d; stderr: %s", code, stderr.String())
+	}
+
+	if code := run([]string{"compare", inDir, outDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run() = %d; stdout: %s stderr: %s", code, stdout.String(), stderr.String())
+	}
+	if out := stdout.String(); !strings.Contains(out, "1 matched, 0 transformed, 0 differing") {
+		t.Errorf("stdout = %q", out)
+	}
+
+	// A changed article is reported
+	if err := os.WriteFile(filepath.Join(outDir, "ingredients", "kt", "god.md"), []byte("# Changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := run([]string{"compare", "--json", inDir, outDir}, &stdout, &stderr); code != exitCompareFailed {
+		t.Fatalf("run() = %d; want %d", code, exitCompareFailed)
+	}
+	var report jsonCompareReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("stdout is not a JSON report: %v\n%s", err, stdout.String())
+	}
+	if report.OK || len(report.Differing) != 1 || report.Differing[0] != "bible/kt/god.md" {
+		t.Errorf("report = %+v; want bible/kt/god.md differing", report)
+	}
+
+	if code := run([]string{"compare", inDir}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("run() without sbDir = %d; want %d", code, exitUsage)
+	}
+	if code := run([]string{"compare", t.TempDir(), outDir}, &stdout, &stderr); code != exitManifest {
+		t.Errorf("run() without a manifest = %d; want %d", code, exitManifest)
+	}
+}
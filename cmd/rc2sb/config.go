@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is the config file looked for in the current directory
+// when --config is not given.
+const defaultConfigFile = "rc2sb.yaml"
+
+// config holds the settings read from a config file. Its keys mirror the CLI
+// flags; flags given on the command line take precedence.
+type config struct {
+	Payload string   `yaml:"payload"`
+	USFM    string   `yaml:"usfm"`
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+	Jobs    int      `yaml:"jobs"`
+	Strict  bool     `yaml:"strict"`
+	SHA256  bool     `yaml:"sha256"`
+}
+
+// configKeys are the keys a config file may contain.
+var configKeys = map[string]bool{
+	"payload": true, "usfm": true, "include": true, "exclude": true, "jobs": true,
+	"strict": true, "sha256": true,
+}
+
+// loadConfig reads the config file name, or rc2sb.yaml in the current
+// directory if name is empty and that file exists. It returns a zero config if
+// there is no file to read. Relative payload and usfm paths are resolved
+// against the file's directory. Unknown keys are returned as warnings rather
+// than errors, so that older versions accept newer config files.
+func loadConfig(name string) (config, []string, error) {
+	var cfg config
+	if name == "" {
+		if _, err := os.Stat(defaultConfigFile); errors.Is(err, fs.ErrNotExist) {
+			return cfg, nil, nil
+		}
+		name = defaultConfigFile
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return cfg, nil, fmt.Errorf("reading config: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return cfg, nil, fmt.Errorf("parsing config %s: %w", name, err)
+	}
+	if len(doc.Content) == 0 || len(bytes.TrimSpace(data)) == 0 {
+		// An empty file sets nothing
+		return cfg, nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return cfg, nil, fmt.Errorf("parsing config %s: line %d: want a mapping of settings", name, root.Line)
+	}
+
+	var warnings []string
+	for i := 0; i < len(root.Content); i += 2 {
+		key := root.Content[i]
+		if !configKeys[key.Value] {
+			warnings = append(warnings, fmt.Sprintf("%s:%d: unknown config key %q ignored", name, key.Line, key.Value))
+		}
+	}
+	if err := root.Decode(&cfg); err != nil {
+		return config{}, nil, fmt.Errorf("parsing config %s: %w", name, err)
+	}
+	if cfg.Jobs < 0 {
+		return config{}, nil, fmt.Errorf("parsing config %s: jobs must not be negative", name)
+	}
+
+	dir := filepath.Dir(name)
+	for _, p := range []*string{&cfg.Payload, &cfg.USFM} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	return cfg, warnings, nil
+}
+
+// flagsSet returns the names of the flags given on the command line.
+func flagsSet(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// writeConfig writes content to rc2sb.yaml in a new directory and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "rc2sb.yaml")
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestLoadConfig(t *testing.T) {
+	name := writeConfig(t, `payload: ../en_tw
+usfm: /repos/en_ult
+exclude: ["**/*.bak"]
+jobs: 4
+strict: true
+sha256: true
+colour: auto
+`)
+
+	cfg, warnings, err := loadConfig(name)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	want := config{
+		Payload: filepath.Join(filepath.Dir(name), "..", "en_tw"),
+		USFM:    "/repos/en_ult",
+		Exclude: []string{"**/*.bak"},
+		Jobs:    4,
+		Strict:  true,
+		SHA256:  true,
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("config = %+v; want %+v", cfg, want)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `unknown config key "colour"`) {
+		t.Errorf("warnings = %q; want one for the unknown key", warnings)
+	}
+}
+
+func TestLoadConfig_Malformed(t *testing.T) {
+	for _, content := range []string{
+		"payload: [unclosed\n",
+		"- payload\n",
+		"jobs: many\n",
+		"jobs: -1\n",
+	} {
+		if _, _, err := loadConfig(writeConfig(t, content)); err == nil {
+			t.Errorf("loadConfig(%q) succeeded; want an error", content)
+		}
+	}
+
+	if _, _, err := loadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("loadConfig of a missing --config file succeeded; want an error")
+	}
+}
+
+func TestLoadConfig_DefaultFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg, _, err := loadConfig("")
+	if err != nil || !reflect.DeepEqual(cfg, config{}) {
+		t.Fatalf("loadConfig without rc2sb.yaml = %+v, %v; want an empty config", cfg, err)
+	}
+
+	os.WriteFile(defaultConfigFile, []byte("jobs: 2\n"), 0644)
+	if cfg, _, err := loadConfig(""); err != nil || cfg.Jobs != 2 {
+		t.Errorf("loadConfig with rc2sb.yaml = %+v, %v; want jobs 2", cfg, err)
+	}
+}
+
+func TestRun_ConfigFile(t *testing.T) {
+	inDir := writeTWRepo(t)
+	cfgPath := writeConfig(t, "exclude: [\"**/god.md\"]\n")
+
+	excluded := func(args ...string) []string {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		args = append([]string{"--json", "--config", cfgPath}, args...)
+		if code := run(append(args, inDir, t.TempDir()), &stdout, &stderr); code != exitOK {
+			t.Fatalf("run() = %d; stderr: %s", code, stderr.String())
+		}
+		var got jsonResult
+		if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+			t.Fatalf("stdout is not a JSON result: %v\n%s", err, stdout.String())
+		}
+		return got.Excluded
+	}
+
+	if got := excluded(); len(got) != 1 {
+		t.Errorf("excluded = %v; want god.md excluded by the config file", got)
+	}
+	// A flag overrides the config file's value
+	if got := excluded("--exclude", "**/*.bak"); len(got) != 0 {
+		t.Errorf("excluded with --exclude = %v; want the flag to replace the config value", got)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--config", writeConfig(t, "exclude: {\n"), inDir, t.TempDir()}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("run() with a malformed config = %d; want %d", code, exitUsage)
+	}
+	if !strings.Contains(stderr.String(), "parsing config") {
+		t.Errorf("stderr = %q; want a config parse error", stderr.String())
+	}
+}
+
+func TestRun_ConfigStrictAndSHA256(t *testing.T) {
+	inDir := writeStubTNRepo(t)
+	cfgPath := writeConfig(t, "strict: true\nsha256: true\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--quiet", "--config", cfgPath, inDir, t.TempDir()}, &stdout, &stderr); code != exitStrict {
+		t.Errorf("run() with strict in the config = %d; want %d", code, exitStrict)
+	}
+	// A flag overrides the config file's value
+	outDir := t.TempDir()
+	if code := run([]string{"--quiet", "--config", cfgPath, "--strict=false", inDir, outDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run() with --strict=false = %d; stderr: %s", code, stderr.String())
+	}
+	m, err := sb.LoadMetadata(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ing := m.Ingredients["ingredients/GEN.tsv"]; ing.Checksum.SHA256 == "" {
+		t.Errorf("ingredient = %+v; want a sha256 checksum from the config file", ing)
+	}
+
+	stderr.Reset()
+	if code := run([]string{"--quiet", "--strict", inDir, t.TempDir()}, &stdout, &stderr); code != exitStrict {
+		t.Errorf("run() with --strict = %d; want %d", code, exitStrict)
+	}
+	if !strings.Contains(stderr.String(), "has only a header row") {
+		t.Errorf("stderr = %q; want the stub ingredient named", stderr.String())
+	}
+}
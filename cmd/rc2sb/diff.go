@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+)
+
+// runDiff runs the diff subcommand and returns the process exit code: exitOK
+// if the two SBs are the same, exitDiffFound if not, or exitUsage for bad
+// arguments.
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rc2sb diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	timestamps := fs.Bool("timestamps", false, "also report differences in meta.dateCreated and the identification timestamps")
+	jsonOut := fs.Bool("json", false, "print the report to stdout as a single JSON object:\n"+
+		"{\"same\", \"added\", \"removed\", \"modified\", \"fields\"}")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: rc2sb diff [flags] <sbDirA> <sbDirB>\n\n")
+		fmt.Fprintf(stderr, "Reports how the SB in sbDirB differs from the SB in sbDirA (e.g., the output of two\n")
+		fmt.Fprintf(stderr, "rc2sb versions): ingredients added, removed, or modified, and other metadata.json fields.\n\n")
+		fmt.Fprintf(stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	report, err := rc2sb.DiffSB(fs.Arg(0), fs.Arg(1), rc2sb.DiffOptions{IncludeTimestamps: *timestamps})
+	if err != nil {
+		fmt.Fprintf(stderr, "rc2sb diff: %v\n", err)
+		return exitCode(err)
+	}
+
+	if *jsonOut {
+		writeJSON(stdout, newJSONDiffReport(report))
+	} else {
+		for _, d := range report.Added {
+			fmt.Fprintf(stdout, "added: %s (%d bytes)\n", d.Key, d.BSize)
+		}
+		for _, d := range report.Removed {
+			fmt.Fprintf(stdout, "removed: %s (%d bytes)\n", d.Key, d.ASize)
+		}
+		for _, d := range report.Modified {
+			fmt.Fprintf(stdout, "modified: %s (%d -> %d bytes)\n", d.Key, d.ASize, d.BSize)
+		}
+		for _, f := range report.Fields {
+			fmt.Fprintf(stdout, "changed: %s: %s -> %s\n", f.Path, diffValue(f.A), diffValue(f.B))
+		}
+		fmt.Fprintf(stdout, "%d added, %d removed, %d modified ingredients, %d changed fields\n",
+			len(report.Added), len(report.Removed), len(report.Modified), len(report.Fields))
+	}
+
+	if !report.Empty() {
+		return exitDiffFound
+	}
+	return exitOK
+}
+
+// diffValue returns the JSON value v of a changed field for printing.
+func diffValue(v string) string {
+	if v == "" {
+		return "(none)"
+	}
+	return v
+}
+
+// jsonDiffReport is the --json output of the diff subcommand.
+type jsonDiffReport struct {
+	Same     bool                 `json:"same"`
+	Added    []jsonIngredientDiff `json:"added"`
+	Removed  []jsonIngredientDiff `json:"removed"`
+	Modified []jsonIngredientDiff `json:"modified"`
+	Fields   []jsonFieldDiff      `json:"fields"`
+}
+
+// jsonIngredientDiff is an ingredient in the --json output of the diff
+// subcommand. Sizes are omitted for the side that does not have it.
+type jsonIngredientDiff struct {
+	Key   string `json:"key"`
+	ASize *int64 `json:"aSize,omitempty"`
+	BSize *int64 `json:"bSize,omitempty"`
+}
+
+// jsonFieldDiff is a changed field in the --json output of the diff
+// subcommand. Values are JSON text, or "" for a side without the field.
+type jsonFieldDiff struct {
+	Path string `json:"path"`
+	A    string `json:"a"`
+	B    string `json:"b"`
+}
+
+func newJSONDiffReport(r rc2sb.DiffReport) jsonDiffReport {
+	ingredients := func(diffs []rc2sb.IngredientDiff, a, b bool) []jsonIngredientDiff {
+		out := []jsonIngredientDiff{}
+		for _, d := range diffs {
+			j := jsonIngredientDiff{Key: d.Key}
+			if a {
+				j.ASize = &d.ASize
+			}
+			if b {
+				j.BSize = &d.BSize
+			}
+			out = append(out, j)
+		}
+		return out
+	}
+	fields := []jsonFieldDiff{}
+	for _, f := range r.Fields {
+		fields = append(fields, jsonFieldDiff{Path: f.Path, A: f.A, B: f.B})
+	}
+	return jsonDiffReport{
+		Same:     r.Empty(),
+		Added:    ingredients(r.Added, false, true),
+		Removed:  ingredients(r.Removed, true, false),
+		Modified: ingredients(r.Modified, true, true),
+		Fields:   fields,
+	}
+}
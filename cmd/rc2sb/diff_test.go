@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+func TestRunDiff(t *testing.T) {
+	inDir, aDir, bDir := writeTWRepo(t), t.TempDir(), t.TempDir()
+	var stdout, stderr bytes.Buffer
+	for _, dir := range []string{aDir, bDir} {
+		if code := run([]string{"--quiet", inDir, dir}, &stdout, &stderr); code != exitOK {
+			t.Fatalf("conversion run() = %d; stderr: %s", code, stderr.String())
+		}
+	}
+
+	// Two conversions differ only by their timestamps
+	if code := run([]string{"diff", aDir, bDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run() = %d; stdout: %s stderr: %s", code, stdout.String(), stderr.String())
+	}
+
+	m, err := sb.LoadMetadata(bDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Meta.Category = "derived"
+	if err := m.WriteToFile(bDir); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := run([]string{"diff", aDir, bDir}, &stdout, &stderr); code != exitDiffFound {
+		t.Fatalf("run() = %d; want %d", code, exitDiffFound)
+	}
+	if !strings.Contains(stdout.String(), `changed: meta.category: "source" -> "derived"`) {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"diff", "--json", aDir, bDir}, &stdout, &stderr); code != exitDiffFound {
+		t.Fatalf("run() = %d; want %d", code, exitDiffFound)
+	}
+	var got jsonDiffReport
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("stdout is not a JSON report: %v\n%s", err, stdout.String())
+	}
+	if got.Same || len(got.Fields) != 1 || got.Fields[0].Path != "meta.category" || got.Added == nil {
+		t.Errorf("report = %+v", got)
+	}
+
+	if code := run([]string{"diff", aDir}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("run() with one directory = %d; want %d", code, exitUsage)
+	}
+	if code := run([]string{"diff", aDir, t.TempDir()}, &stdout, &stderr); code != exitConversion {
+		t.Errorf("run() without metadata.json = %d; want %d", code, exitConversion)
+	}
+}
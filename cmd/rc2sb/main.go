@@ -3,59 +3,588 @@
 // Usage:
 //
 //	rc2sb [flags] <inDir> <outDir>
+//	rc2sb convert [flags] <inDir> <outDir>
 //	rc2sb --payload /path/to/en_tw <inDir> <outDir>
 //	rc2sb --usfm /path/to/en_ult <inDir> <outDir>
+//	rc2sb --zip out.zip <inDir>
+//	rc2sb https://git.door43.org/unfoldingWord/en_tn.git#v80 <outDir>
+//	rc2sb en_tn.zip <outDir>
+//	rc2sb --version
+//	rc2sb version
+//	rc2sb batch [--jobs N] <listFile>
+//	rc2sb batch [--jobs N] --glob '/repos/en_*' --out-root /out [--name '{identifier}_{subject}']
+//	rc2sb check <inDir>
+//	rc2sb compare <inDir> <sbDir>
+//	rc2sb refresh <sbDir>
+//	rc2sb diff <sbDirA> <sbDirB>
+//	rc2sb push [--tag v80] [--dry-run] <sbDir> https://git.door43.org/unfoldingWord/en_tn_sb
 //
 // Flags:
 //
 //	--payload <dir>   Path to a Translation Words directory (e.g., en_tw) for TWL payload creation.
-//	                  If not set, auto-detects <lang>_tw/ inside inDir.
+//	                  If not set, auto-detects <lang>_tw/ inside inDir, then beside it.
 //	--usfm <dir>      Path to a USFM directory for localized Bible book names in TSV repos.
 //	                  If not set, uses manifest project titles, then English fallback.
+//	--payload-from-catalog
+//	                  Fetch <lang>_tw, in the manifest's language, from the Door43 catalog
+//	                  and use it as --payload.
+//	--usfm-from-catalog <id>
+//	                  Fetch the Bible <lang>_<id> (e.g., "ult") from the Door43 catalog and
+//	                  use it as --usfm.
+//	--subject <name>  Convert as this RC subject (e.g., "TSV Translation Notes") instead of
+//	                  the manifest's dublin_core.subject, for repos where it is missing or wrong.
+//	--zip <file>      Write the SB output as a single zip archive instead of a directory.
+//	                  When set, outDir is omitted.
+//	--books <list>    Only convert these books of a Bible, TN, TQ, SN, or TWL: a comma-separated
+//	                  list of book IDs or codes (e.g., "gen,mat"). A TWL payload then holds
+//	                  only the articles those books link to.
+//	--include <glob>  Only copy ingredients whose key matches the glob (e.g., "ingredients/GEN.*").
+//	                  May be repeated.
+//	--exclude <glob>  Skip ingredients whose key matches the glob (e.g., "**/*.bak").
+//	                  May be repeated.
+//	--strict          Fail on a stub ingredient (a TSV with only a header row, or a USFM
+//	                  with no \c chapter marker) instead of warning of it.
+//	--sha256          Record a SHA-256 checksum beside the MD5 of every ingredient.
+//	--verbose         Log each file written, in addition to warnings, to stderr.
+//	--quiet           Print nothing but errors (to stderr). Cannot be combined with --verbose.
+//	--json            Print the result to stdout as one JSON object, and nothing else:
+//	                  {"subject", "identifier", "inDir", "outDir", "ingredients",
+//	                  "ingredientKeys", "totalBytes", "contentIngredients",
+//	                  "payloadIngredients", "rootIngredients", "warnings", "excluded",
+//	                  "durationMs"}, plus "commit" for a git URL,
+//	                  or {"error"} with a non-zero exit code, including for a
+//	                  usage error.
+//	--config <file>   Read default flag values from this YAML file. If not set, rc2sb.yaml in
+//	                  the current directory is read, if present.
+//	--version         Print the version, VCS revision, and build date, then exit.
+//
+// A config file sets defaults for the flags of the same name: payload and usfm
+// (relative to the file's directory), include and exclude (lists of globs),
+// strict and sha256 (booleans), and jobs (for rc2sb batch). Flags given on the
+// command line take precedence.
+// Environment variables never override either. Unknown keys are ignored with
+// a warning. For example:
+//
+//	payload: ../en_tw
+//	usfm: ../en_ult
+//	exclude: ["**/*.bak"]
+//	strict: true
+//	jobs: 4
+//
+// If inDir is a git URL (https://, http://, git://, ssh://, file://, or
+// user@host:path), the repository is shallow-cloned into a temporary directory
+// with the git command, converted, and removed. A "#ref" suffix selects a branch
+// or tag. The RC2SB_GIT_TOKEN environment variable, if set, is used as an access
+// token for private repositories.
+//
+// If inDir is a .zip, .tar.gz, or .tgz file, the RC repository is read from the
+// archive without unpacking it. manifest.yaml may be at the archive root or in its
+// single top-level directory.
+//
+// --zip cannot be used with a git URL or an archive.
+//
+// The *-from-catalog flags are the only ones besides a git URL that use the
+// network, and need inDir to be a local directory. They look up the latest
+// release in the catalog of the server in RC2SB_DCS_URL (default
+// https://git.door43.org) and clone it, once per release, into the cache
+// directory RC2SB_CACHE_DIR (default rc2sb in the user's cache directory).
+//
+// The check subcommand checks an RC repository for problems without converting
+// it: an unreadable manifest, an unsupported subject, missing project files,
+// a missing LICENSE.md, and malformed TSV headers or USFM \id markers. Each
+// problem is printed as an error or a warning; see rc2sb check -h. The
+// validate subcommand is the same as check.
+//
+// The compare subcommand checks that an SB directory converted from an RC
+// repository holds every project file of the RC with the same content, up to
+// the changes a conversion makes on purpose (e.g., TWL links rewritten to the
+// payload). Each file that differs, has no ingredient, or is an ingredient
+// with no RC file is printed; see rc2sb compare -h.
+//
+// The refresh subcommand recomputes the checksums and sizes in an SB
+// directory's metadata.json after its ingredients were edited in place,
+// adding new files beneath ingredients/ and dropping entries whose file is
+// gone, each with a warning; see rc2sb refresh -h.
+//
+// The diff subcommand reports how one SB directory differs from another
+// (e.g., the output of two rc2sb versions for the same RC): ingredients added,
+// removed, or modified, and other metadata.json fields that changed, ignoring
+// timestamps unless --timestamps is set; see rc2sb diff -h.
+//
+// The push subcommand commits an SB directory to a Gitea (e.g., DCS) repository
+// through its API, creating the repository if needed; see rc2sb push -h. Its
+// access token is taken from --token or RC2SB_GIT_TOKEN.
+//
+// Exit codes:
+//
+//	0  Success.
+//	1  One or more conversions in a batch failed, rc2sb check found errors,
+//	   rc2sb compare found lost or changed content, or rc2sb diff found
+//	   differences.
+//	2  Usage error: bad flags or arguments, or an invalid glob pattern.
+//	3  The input has no manifest.yaml, or it cannot be read or parsed.
+//	4  The subject (or --subject) is not supported.
+//	5  The conversion failed (e.g., an I/O error reading input or writing output).
+//	6  rc2sb push failed (e.g., the token was rejected).
+//	7  --strict failed the conversion on a stub ingredient.
 package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"path"
+	"strings"
+	"time"
 
 	rc2sb "github.com/unfoldingWord/go-rc2sb"
+	"github.com/unfoldingWord/go-rc2sb/handler"
+	"github.com/unfoldingWord/go-rc2sb/rc"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// Process exit codes. They are part of the CLI's interface and must not change.
+const (
+	exitOK            = 0
+	exitBatchFailed   = 1
+	exitCheckFailed   = 1
+	exitCompareFailed = 1
+	exitDiffFound     = 1
+	exitUsage         = 2
+	exitManifest      = 3
+	exitUnsupported   = 4
+	exitConversion    = 5
+	exitPushFailed    = 6
+	exitStrict        = 7
 )
 
 func main() {
-	payload := flag.String("payload", "", "path to a Translation Words directory (e.g., en_tw) for TWL payload creation")
-	usfm := flag.String("usfm", "", "path to a USFM directory for localized Bible book names in TSV repos")
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: rc2sb [flags] <inDir> <outDir>\n\n")
-		fmt.Fprintf(os.Stderr, "Converts a Resource Container (RC) repository to Scripture Burrito (SB) format.\n\n")
-		fmt.Fprintf(os.Stderr, "Arguments:\n")
-		fmt.Fprintf(os.Stderr, "  inDir    Path to the RC repository (must contain manifest.yaml)\n")
-		fmt.Fprintf(os.Stderr, "  outDir   Path where SB output will be written\n\n")
-		fmt.Fprintf(os.Stderr, "Flags:\n")
-		flag.PrintDefaults()
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command with the given arguments (excluding the program name)
+// and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 1 && args[0] == "version" {
+		printVersion(stdout)
+		return exitOK
+	}
+	if len(args) > 0 && args[0] == "batch" {
+		return runBatch(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "push" {
+		return runPush(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && (args[0] == "check" || args[0] == "validate") {
+		return runCheck(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "compare" {
+		return runCompare(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "refresh" {
+		return runRefresh(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "unpack" {
+		return runUnpack(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "verify" {
+		return runVerify(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "diff" {
+		return runDiff(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "convert" {
+		// The default: "rc2sb convert <inDir> <outDir>" is "rc2sb <inDir> <outDir>"
+		args = args[1:]
 	}
-	flag.Parse()
 
-	if flag.NArg() != 2 {
-		flag.Usage()
-		os.Exit(1)
+	fs := flag.NewFlagSet("rc2sb", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	payload := fs.String("payload", "", "path to a Translation Words directory (e.g., en_tw) for TWL payload creation")
+	usfm := fs.String("usfm", "", "path to a USFM directory for localized Bible book names in TSV repos\n(default: a sibling <lang>_irv, _ult, _ulb, _glt, or _reg directory beside inDir)")
+	payloadFromCatalog := fs.Bool("payload-from-catalog", false, "fetch <lang>_tw from the Door43 catalog for TWL payload creation (uses the network)")
+	usfmFromCatalog := fs.String("usfm-from-catalog", "", "fetch the Bible <lang>_<id> (e.g., ult) from the Door43 catalog for localized book names (uses the network)")
+	subject := fs.String("subject", "", "convert as this RC subject instead of the manifest's dublin_core.subject")
+	zipPath := fs.String("zip", "", "write the SB output as a zip archive to this file instead of outDir")
+	version := fs.Bool("version", false, "print the version and exit")
+	configPath := fs.String("config", "", "read default flag values from this YAML file (default ./rc2sb.yaml, if present)")
+	verbose := fs.Bool("verbose", false, "log each file written to stderr")
+	quiet := fs.Bool("quiet", false, "print nothing but errors (to stderr)")
+	jsonOut := fs.Bool("json", false, "print the result to stdout as a single JSON object:\n"+
+		"{\"subject\", \"identifier\", \"inDir\", \"outDir\", \"ingredients\", \"ingredientKeys\", \"totalBytes\",\n"+
+		"\"contentIngredients\", \"payloadIngredients\", \"rootIngredients\", \"warnings\", \"excluded\", \"durationMs\"}\n"+
+		"or {\"error\"} on failure")
+	bookList := fs.String("books", "", "only convert these books of a per-book subject: a comma-separated list of IDs or codes (e.g., gen,mat)")
+	var include, exclude globList
+	fs.Var(&include, "include", "only copy ingredients whose key matches this glob (repeatable)")
+	fs.Var(&exclude, "exclude", "skip ingredients whose key matches this glob (repeatable)")
+	strict := fs.Bool("strict", false, "fail on a stub ingredient (a header-only TSV or a USFM with no \\c) instead of warning of it")
+	sha256 := fs.Bool("sha256", false, "record a SHA-256 checksum beside the MD5 of every ingredient")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: rc2sb [convert] [flags] <inDir> <outDir>\n")
+		fmt.Fprintf(stderr, "       rc2sb [convert] [flags] --zip <file> <inDir>\n")
+		fmt.Fprintf(stderr, "       rc2sb batch [flags] <listFile>   (see rc2sb batch -h)\n")
+		fmt.Fprintf(stderr, "       rc2sb check [flags] <inDir>   (see rc2sb check -h; validate is the same)\n")
+		fmt.Fprintf(stderr, "       rc2sb compare [flags] <inDir> <sbDir>   (see rc2sb compare -h)\n")
+		fmt.Fprintf(stderr, "       rc2sb refresh [flags] <sbDir>   (see rc2sb refresh -h)\n")
+		fmt.Fprintf(stderr, "       rc2sb diff [flags] <sbDirA> <sbDirB>   (see rc2sb diff -h)\n")
+		fmt.Fprintf(stderr, "       rc2sb push [flags] <sbDir> <repoURL>   (see rc2sb push -h)\n")
+		fmt.Fprintf(stderr, "       rc2sb unpack [flags] <sbDir> <outDir>   (see rc2sb unpack -h)\n")
+		fmt.Fprintf(stderr, "       rc2sb verify <sbDir>   (see rc2sb verify -h)\n")
+		fmt.Fprintf(stderr, "       rc2sb version\n\n")
+		fmt.Fprintf(stderr, "Converts a Resource Container (RC) repository to Scripture Burrito (SB) format.\n\n")
+		fmt.Fprintf(stderr, "Arguments:\n")
+		fmt.Fprintf(stderr, "  inDir    Path to the RC repository (must contain manifest.yaml), its git URL with an optional #ref,\n")
+		fmt.Fprintf(stderr, "           or a .zip/.tar.gz archive of it\n")
+		fmt.Fprintf(stderr, "  outDir   Path where SB output will be written\n\n")
+		fmt.Fprintf(stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	// usageError reports a usage error, also as a JSON error object on
+	// stdout with --json, and returns exitUsage
+	usageError := func(msg string, printUsage bool) int {
+		if printUsage {
+			fs.Usage()
+		}
+		if *jsonOut {
+			writeJSON(stdout, jsonError{Error: msg})
+		}
+		return exitUsage
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return usageError(err.Error(), false)
 	}
 
-	inDir := flag.Arg(0)
-	outDir := flag.Arg(1)
+	if *version {
+		printVersion(stdout)
+		return exitOK
+	}
+
+	wantArgs := 2
+	if *zipPath != "" {
+		wantArgs = 1
+	}
+	local := !rc2sb.IsGitURL(fs.Arg(0)) && !rc2sb.IsArchive(fs.Arg(0))
+	switch {
+	case fs.NArg() != wantArgs && *zipPath != "":
+		return usageError("want <inDir> with --zip", true)
+	case fs.NArg() != wantArgs:
+		return usageError("want <inDir> <outDir>", true)
+	case *verbose && *quiet:
+		return usageError("--verbose and --quiet cannot be combined", true)
+	case *zipPath != "" && !local:
+		return usageError("--zip cannot be used with a git URL or an archive", true)
+	}
+	fromCatalog := *payloadFromCatalog || *usfmFromCatalog != ""
+	switch {
+	case *payloadFromCatalog && *payload != "":
+		return usageError("--payload-from-catalog cannot be combined with --payload", true)
+	case *usfmFromCatalog != "" && *usfm != "":
+		return usageError("--usfm-from-catalog cannot be combined with --usfm", true)
+	case fromCatalog && !local:
+		return usageError("the *-from-catalog flags need inDir to be a local directory", true)
+	}
+
+	level := slog.LevelInfo
+	switch {
+	case *verbose:
+		level = slog.LevelDebug
+	case *quiet:
+		level = slog.LevelError
+	}
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+
+	cfg, cfgWarnings, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "rc2sb: %v\n", err)
+		return usageError(err.Error(), false)
+	}
+	for _, w := range cfgWarnings {
+		logger.Warn(w)
+	}
+	// Flags given on the command line override the config file
+	set := flagsSet(fs)
+	if !set["payload"] && !*payloadFromCatalog {
+		*payload = cfg.Payload
+	}
+	if !set["usfm"] && *usfmFromCatalog == "" {
+		*usfm = cfg.USFM
+	}
+	if !set["include"] {
+		include = cfg.Include
+	}
+	if !set["exclude"] {
+		exclude = cfg.Exclude
+	}
+	if !set["strict"] {
+		*strict = cfg.Strict
+	}
+	if !set["sha256"] {
+		*sha256 = cfg.SHA256
+	}
+
+	inDir := fs.Arg(0)
 
 	opts := rc2sb.Options{
-		PayloadPath: *payload,
-		USFMPath:    *usfm,
+		PayloadPath:     *payload,
+		USFMPath:        *usfm,
+		SubjectOverride: *subject,
+		Books:           splitList(*bookList),
+		IncludeGlobs:    include,
+		ExcludeGlobs:    exclude,
+		SHA256Checksums: *sha256,
+		Strict:          *strict,
+		Logger:          logger,
 	}
 
-	result, err := rc2sb.Convert(context.Background(), inDir, outDir, opts)
+	start := time.Now()
+	var result rc2sb.Result
+	if fromCatalog {
+		err = fetchCompanions(context.Background(), inDir, *payloadFromCatalog, *usfmFromCatalog, &opts)
+	}
+	switch {
+	case err != nil:
+		// Fetching a companion resource failed
+	case *zipPath != "":
+		result, err = convertToZipFile(inDir, *zipPath, opts)
+	case rc2sb.IsGitURL(inDir):
+		opts.Fetcher = rc2sb.GitCLI{Token: os.Getenv("RC2SB_GIT_TOKEN")}
+		result, err = rc2sb.ConvertGit(context.Background(), inDir, fs.Arg(1), opts)
+	case rc2sb.IsArchive(inDir):
+		result, err = rc2sb.ConvertArchive(context.Background(), inDir, fs.Arg(1), opts)
+	default:
+		result, err = rc2sb.Convert(context.Background(), inDir, fs.Arg(1), opts)
+	}
+	if *jsonOut {
+		if err != nil {
+			writeJSON(stdout, jsonError{Error: err.Error()})
+			return exitCode(err)
+		}
+		writeJSON(stdout, newJSONResult(result, time.Since(start)))
+		return exitOK
+	}
 	if err != nil {
-		log.Fatal(err)
+		logger.Error(err.Error())
+		return exitCode(err)
 	}
 
-	fmt.Printf("Converted %s (%s) with %d ingredients\n",
+	if *quiet {
+		return exitOK
+	}
+	fmt.Fprintf(stdout, "Converted %s (%s) with %d ingredients",
 		result.Subject, result.Identifier, result.Ingredients)
+	if len(result.Warnings) > 0 {
+		fmt.Fprintf(stdout, " (%d warnings)", len(result.Warnings))
+	}
+	fmt.Fprintln(stdout)
+	if summary := summarize(result); summary != "" {
+		fmt.Fprintln(stdout, summary)
+	}
+	if result.Commit != "" {
+		fmt.Fprintf(stdout, "Commit %s\n", result.Commit)
+	}
+	if len(result.Excluded) > 0 {
+		fmt.Fprintf(stdout, "Excluded %d files\n", len(result.Excluded))
+	}
+	return exitOK
+}
+
+// summaryPhrases are the phrases, singular and plural, that summarize the
+// substitutions and skips of a conversion by kind, in the order printed.
+var summaryPhrases = []struct {
+	kind      string
+	one, many string
+}{
+	{handler.SubstitutedLicense, "default license injected", "default licenses injected"},
+	{handler.SkippedProject, "project skipped", "projects skipped"},
+	{handler.SkippedRootFile, "root file absent", "root files absent"},
+	{handler.SkippedPayload, "payload not found", "payloads not found"},
+}
+
+// summarize counts the substitutions and skips of result by kind (e.g., "1
+// default license injected, 2 projects skipped"), or returns "" if there
+// are none.
+func summarize(result rc2sb.Result) string {
+	counts := make(map[string]int)
+	for _, s := range result.Substitutions {
+		counts[s.Kind]++
+	}
+	for _, s := range result.Skips {
+		counts[s.Kind]++
+	}
+	var parts []string
+	for _, p := range summaryPhrases {
+		switch n := counts[p.kind]; n {
+		case 0:
+		case 1:
+			parts = append(parts, "1 "+p.one)
+		default:
+			parts = append(parts, fmt.Sprintf("%d %s", n, p.many))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fetchCompanions fetches the companion resources for the RC repository in
+// inDir from the Door43 catalog, in its language: <lang>_tw if payload is set
+// and the Bible <lang>_<usfm> if usfm is set. It sets the matching paths in
+// opts.
+func fetchCompanions(ctx context.Context, inDir string, payload bool, usfm string, opts *rc2sb.Options) error {
+	manifest, err := rc.LoadManifest(inDir)
+	if err != nil {
+		return err
+	}
+	lang := manifest.DublinCore.Language.Identifier
+	catalog := newCatalog()
+	if payload {
+		if opts.PayloadPath, err = fetchCompanion(ctx, catalog, lang, "tw", opts.Logger); err != nil {
+			return err
+		}
+	}
+	if usfm != "" {
+		if opts.USFMPath, err = fetchCompanion(ctx, catalog, lang, usfm, opts.Logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exitCode classifies a conversion error as a process exit code.
+func exitCode(err error) int {
+	var manifestErr *rc.ManifestError
+	switch {
+	case errors.As(err, &manifestErr):
+		return exitManifest
+	case errors.Is(err, handler.ErrUnsupportedSubject):
+		return exitUnsupported
+	case errors.Is(err, rc2sb.ErrStrict):
+		return exitStrict
+	case errors.Is(err, path.ErrBadPattern):
+		return exitUsage
+	default:
+		return exitConversion
+	}
+}
+
+// jsonResult is the --json output for a successful conversion. Its field
+// names are part of the CLI's interface and must not change.
+type jsonResult struct {
+	Subject            string   `json:"subject"`
+	Identifier         string   `json:"identifier"`
+	InDir              string   `json:"inDir"`
+	OutDir             string   `json:"outDir"`
+	Commit             string   `json:"commit,omitempty"`
+	Ingredients        int      `json:"ingredients"`
+	IngredientKeys     []string `json:"ingredientKeys"`
+	TotalBytes         int64    `json:"totalBytes"`
+	ContentIngredients int      `json:"contentIngredients"`
+	PayloadIngredients int      `json:"payloadIngredients"`
+	RootIngredients    int      `json:"rootIngredients"`
+	Warnings           []string `json:"warnings"`
+	Excluded           []string `json:"excluded"`
+	DurationMs         int64    `json:"durationMs"`
+}
+
+// jsonError is the --json output for a failed conversion.
+type jsonError struct {
+	Error string `json:"error"`
+}
+
+func newJSONResult(result rc2sb.Result, elapsed time.Duration) jsonResult {
+	// Always emit arrays, never null, so consumers can rely on the type
+	warnings := result.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+	excluded := result.Excluded
+	if excluded == nil {
+		excluded = []string{}
+	}
+	keys := result.IngredientKeys
+	if keys == nil {
+		keys = []string{}
+	}
+	return jsonResult{
+		Subject:            result.Subject,
+		Identifier:         result.Identifier,
+		InDir:              result.InDir,
+		OutDir:             result.OutDir,
+		Commit:             result.Commit,
+		Ingredients:        result.Ingredients,
+		IngredientKeys:     keys,
+		TotalBytes:         result.TotalBytes,
+		ContentIngredients: result.ContentIngredients,
+		PayloadIngredients: result.PayloadIngredients,
+		RootIngredients:    result.RootIngredients,
+		Warnings:           warnings,
+		Excluded:           excluded,
+		DurationMs:         elapsed.Milliseconds(),
+	}
+}
+
+// writeJSON writes v to w as a single line of JSON.
+func writeJSON(w io.Writer, v any) {
+	json.NewEncoder(w).Encode(v)
+}
+
+// printVersion writes the version, VCS revision, and build date to w.
+// The version is the same one recorded as the generator in metadata.json.
+func printVersion(w io.Writer) {
+	info := sb.ReadBuildInfo()
+	fmt.Fprintf(w, "rc2sb %s", info.Version)
+	if info.Revision != "" {
+		fmt.Fprintf(w, " (revision %s", info.Revision)
+		if info.Date != "" {
+			fmt.Fprintf(w, ", built %s", info.Date)
+		}
+		fmt.Fprint(w, ")")
+	}
+	fmt.Fprintln(w)
+}
+
+// globList is a flag.Value collecting each occurrence of a repeatable flag.
+type globList []string
+
+func (g *globList) String() string { return strings.Join(*g, ",") }
+
+func (g *globList) Set(value string) error {
+	*g = append(*g, value)
+	return nil
+}
+
+// splitList splits a comma-separated flag value into its non-empty,
+// trimmed items.
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// convertToZipFile converts inDir into a zip archive at zipPath. If the
+// conversion fails, the partial archive is removed.
+func convertToZipFile(inDir, zipPath string, opts rc2sb.Options) (rc2sb.Result, error) {
+	f, err := os.Create(zipPath)
+	if err != nil {
+		return rc2sb.Result{}, err
+	}
+	result, err := rc2sb.ConvertToZip(context.Background(), inDir, f, opts)
+	if cerr := f.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(zipPath)
+	}
+	return result, err
 }
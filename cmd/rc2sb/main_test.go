@@ -0,0 +1,541 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+func TestRun_Version(t *testing.T) {
+	for _, args := range [][]string{{"--version"}, {"version"}} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != 0 {
+			t.Errorf("run(%v) = %d; want 0", args, code)
+		}
+		if stderr.Len() != 0 {
+			t.Errorf("run(%v) wrote to stderr: %q", args, stderr.String())
+		}
+		out := stdout.String()
+		if !strings.HasPrefix(out, "rc2sb "+sb.ReadBuildInfo().Version) {
+			t.Errorf("run(%v) printed %q; want the generator version %q", args, out, sb.ReadBuildInfo().Version)
+		}
+		if strings.Count(out, "\n") != 1 {
+			t.Errorf("run(%v) printed %q; want a single line", args, out)
+		}
+	}
+}
+
+func TestRun_MissingArgs(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(nil, &stdout, &stderr); code != exitUsage {
+		t.Errorf("run() = %d; want %d", code, exitUsage)
+	}
+	if !strings.Contains(stderr.String(), "Usage:") {
+		t.Errorf("usage not printed to stderr: %q", stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("unexpected stdout: %q", stdout.String())
+	}
+}
+
+func TestRun_ConvertSubcommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	outDir := t.TempDir()
+	if code := run([]string{"convert", "--quiet", writeTWRepo(t), outDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run(convert) = %d; stderr: %s", code, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "metadata.json")); err != nil {
+		t.Errorf("convert did not write metadata.json: %v", err)
+	}
+	if code := run([]string{"convert"}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("run(convert) without dirs = %d; want %d", code, exitUsage)
+	}
+}
+
+// writeTWRepo writes a minimal Translation Words repo with no LICENSE.md and
+// rights that have no embedded license, so converting it produces a warning.
+func writeTWRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	manifest := `dublin_core:
+  subject: 'Translation Words'
+  identifier: 'tw'
+  title: 'Test TW'
+  rights: 'Freely Given'
+  language:
+    identifier: 'en'
+    title: 'English'
+    direction: 'ltr'
+`
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "bible", "kt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bible", "kt", "god.md"), []byte("# God\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// writeStubTNRepo writes a minimal TSV Translation Notes repo whose only TSV
+// has a header row and no notes, so converting it with --strict fails.
+func writeStubTNRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	manifest := `dublin_core:
+  subject: 'TSV Translation Notes'
+  identifier: 'tn'
+  title: 'Test TN'
+  rights: 'CC BY-SA 4.0'
+  language:
+    identifier: 'en'
+    title: 'English'
+    direction: 'ltr'
+projects:
+  - identifier: 'gen'
+    path: './tn_GEN.tsv'
+`
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	header := "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n"
+	if err := os.WriteFile(filepath.Join(dir, "tn_GEN.tsv"), []byte(header), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRun_Verbosity(t *testing.T) {
+	inDir := writeTWRepo(t)
+
+	tests := []struct {
+		name        string
+		flags       []string
+		wantSummary bool
+		wantWarning bool
+		wantDebug   bool
+	}{
+		{"default", nil, true, true, false},
+		{"verbose", []string{"--verbose"}, true, true, true},
+		{"quiet", []string{"--quiet"}, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := append(append([]string{}, tt.flags...), inDir, t.TempDir())
+			if code := run(args, &stdout, &stderr); code != 0 {
+				t.Fatalf("run(%v) = %d; stderr: %s", args, code, stderr.String())
+			}
+
+			if got := strings.Contains(stdout.String(), "Converted Translation Words (tw) with 2 ingredients (2 warnings)"); got != tt.wantSummary {
+				t.Errorf("summary line present = %v; want %v (stdout %q)", got, tt.wantSummary, stdout.String())
+			}
+			if got := strings.Contains(stdout.String(), "1 default license injected, 2 root files absent\n"); got != tt.wantSummary {
+				t.Errorf("substitution summary present = %v; want %v (stdout %q)", got, tt.wantSummary, stdout.String())
+			}
+			if got := strings.Contains(stderr.String(), "level=WARN"); got != tt.wantWarning {
+				t.Errorf("warning on stderr = %v; want %v (stderr %q)", got, tt.wantWarning, stderr.String())
+			}
+			if got := strings.Contains(stderr.String(), "name=ingredients/kt/god.md"); got != tt.wantDebug {
+				t.Errorf("per-file debug log = %v; want %v (stderr %q)", got, tt.wantDebug, stderr.String())
+			}
+			if strings.Contains(stdout.String(), "level=") {
+				t.Errorf("log output leaked to stdout: %q", stdout.String())
+			}
+		})
+	}
+}
+
+func TestRun_QuietStillReportsErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--quiet", t.TempDir(), t.TempDir()}, &stdout, &stderr); code != exitManifest {
+		t.Errorf("run() = %d; want %d", code, exitManifest)
+	}
+	if !strings.Contains(stderr.String(), "manifest.yaml") {
+		t.Errorf("error not reported on stderr: %q", stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("unexpected stdout: %q", stdout.String())
+	}
+}
+
+func TestRun_JSON(t *testing.T) {
+	inDir := writeTWRepo(t)
+	outDir := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--json", inDir, outDir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d; stderr: %s", code, stderr.String())
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("stdout is not a JSON object: %v\n%s", err, stdout.String())
+	}
+	for _, key := range []string{"subject", "identifier", "inDir", "outDir", "ingredients", "ingredientKeys", "totalBytes",
+		"contentIngredients", "payloadIngredients", "rootIngredients", "warnings", "excluded", "durationMs"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("JSON output missing %q: %s", key, stdout.String())
+		}
+	}
+	if got["subject"] != "Translation Words" || got["outDir"] != outDir || got["ingredients"] != float64(2) {
+		t.Errorf("unexpected result: %s", stdout.String())
+	}
+	if warnings, ok := got["warnings"].([]any); !ok || len(warnings) != 2 {
+		t.Errorf("warnings = %v; want the rights and license warnings", got["warnings"])
+	}
+	if excluded, ok := got["excluded"].([]any); !ok || len(excluded) != 0 {
+		t.Errorf("excluded = %v; want an empty array", got["excluded"])
+	}
+}
+
+func TestRun_JSONError(t *testing.T) {
+	inDir := t.TempDir()
+	manifest := "dublin_core:\n  subject: 'Not A Real Subject'\n  identifier: 'x'\n"
+	if err := os.WriteFile(filepath.Join(inDir, "manifest.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--json", inDir, t.TempDir()}, &stdout, &stderr); code == 0 {
+		t.Fatal("run() = 0; want non-zero for unsupported subject")
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("stdout is not a JSON object: %v\n%s", err, stdout.String())
+	}
+	msg, ok := got["error"].(string)
+	if !ok || !strings.Contains(msg, "Not A Real Subject") {
+		t.Errorf("error = %v; want message naming the subject", got["error"])
+	}
+	if len(got) != 1 {
+		t.Errorf("error object has extra fields: %s", stdout.String())
+	}
+}
+
+func TestRun_JSONUsageError(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"unknown flag", []string{"--json", "--no-such-flag", "in", "out"}, "no-such-flag"},
+		{"missing outDir", []string{"--json", "in"}, "want <inDir> <outDir>"},
+		{"verbose and quiet", []string{"--json", "--verbose", "--quiet", "in", "out"}, "cannot be combined"},
+		{"malformed config", []string{"--json", "--config", writeConfig(t, "exclude: {\n"), "in", "out"}, "parsing config"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != exitUsage {
+				t.Errorf("run() = %d; want %d", code, exitUsage)
+			}
+			var got jsonError
+			if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+				t.Fatalf("stdout is not a JSON error: %v\n%s", err, stdout.String())
+			}
+			if !strings.Contains(got.Error, tt.want) {
+				t.Errorf("error = %q; want it to contain %q", got.Error, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_SubjectOverride(t *testing.T) {
+	inDir := t.TempDir()
+	manifest := `dublin_core:
+  subject: 'bible notes'
+  identifier: 'tn'
+  title: 'Forked Notes'
+  rights: 'CC BY-SA 4.0'
+  language:
+    identifier: 'en'
+    title: 'English'
+    direction: 'ltr'
+projects:
+  - identifier: 'gen'
+    title: 'Genesis'
+    path: './tn_GEN.tsv'
+`
+	files := map[string]string{
+		"manifest.yaml": manifest,
+		"tn_GEN.tsv":    "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n1:1\tabcd\t\t\tבְּ⁠רֵאשִׁ֖ית\t1\tNote\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(inDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without the override, the misspelled subject is rejected
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{inDir, t.TempDir()}, &stdout, &stderr); code != exitUnsupported {
+		t.Fatalf("run() without --subject = %d; want %d", code, exitUnsupported)
+	}
+
+	outDir := t.TempDir()
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"--json", "--subject", "TSV Translation Notes", inDir, outDir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d; stderr: %s", code, stderr.String())
+	}
+	var got jsonResult
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("stdout is not a JSON result: %v\n%s", err, stdout.String())
+	}
+	if got.Subject != "TSV Translation Notes" {
+		t.Errorf("subject = %q; want the override", got.Subject)
+	}
+	if len(got.Warnings) != 1 || !strings.Contains(got.Warnings[0], `"bible notes"`) {
+		t.Errorf("warnings = %q; want one naming the manifest subject", got.Warnings)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m sb.Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Ingredients["ingredients/GEN.tsv"]; !ok {
+		t.Errorf("ingredients = %v; want ingredients/GEN.tsv", m.Ingredients)
+	}
+}
+
+func TestRun_SubjectOverrideInvalid(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--subject", "TSV Translation Note", writeTWRepo(t), t.TempDir()}, &stdout, &stderr); code != exitUnsupported {
+		t.Fatalf("run() = %d; want %d", code, exitUnsupported)
+	}
+	if !strings.Contains(stderr.String(), `did you mean \"TSV Translation Notes\"?`) {
+		t.Errorf("error does not suggest a subject: %s", stderr.String())
+	}
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	writeManifest := func(t *testing.T, manifest string) string {
+		t.Helper()
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+
+	tests := []struct {
+		name string
+		args func(t *testing.T) []string
+		want int
+	}{
+		{
+			name: "success",
+			args: func(t *testing.T) []string { return []string{writeTWRepo(t), t.TempDir()} },
+			want: exitOK,
+		},
+		{
+			name: "unknown flag",
+			args: func(t *testing.T) []string { return []string{"--no-such-flag", writeTWRepo(t), t.TempDir()} },
+			want: exitUsage,
+		},
+		{
+			name: "too many arguments",
+			args: func(t *testing.T) []string { return []string{writeTWRepo(t), t.TempDir(), "extra"} },
+			want: exitUsage,
+		},
+		{
+			name: "zip with git URL",
+			args: func(t *testing.T) []string {
+				return []string{"--zip", filepath.Join(t.TempDir(), "out.zip"), "https://git.door43.org/unfoldingWord/en_tw.git"}
+			},
+			want: exitUsage,
+		},
+		{
+			name: "invalid glob",
+			args: func(t *testing.T) []string { return []string{"--exclude", "[", writeTWRepo(t), t.TempDir()} },
+			want: exitUsage,
+		},
+		{
+			name: "input directory does not exist",
+			args: func(t *testing.T) []string {
+				return []string{filepath.Join(t.TempDir(), "missing"), t.TempDir()}
+			},
+			want: exitManifest,
+		},
+		{
+			name: "malformed manifest",
+			args: func(t *testing.T) []string {
+				return []string{writeManifest(t, "dublin_core: [unclosed\n"), t.TempDir()}
+			},
+			want: exitManifest,
+		},
+		{
+			name: "unsupported subject",
+			args: func(t *testing.T) []string {
+				return []string{writeManifest(t, "dublin_core:\n  subject: 'Hymnal'\n"), t.TempDir()}
+			},
+			want: exitUnsupported,
+		},
+		{
+			name: "unsupported subject with --json",
+			args: func(t *testing.T) []string {
+				return []string{"--json", writeManifest(t, "dublin_core:\n  subject: 'Hymnal'\n"), t.TempDir()}
+			},
+			want: exitUnsupported,
+		},
+		{
+			name: "stub ingredient with --strict",
+			args: func(t *testing.T) []string { return []string{"--strict", writeStubTNRepo(t), t.TempDir()} },
+			want: exitStrict,
+		},
+		{
+			name: "output directory is a file",
+			args: func(t *testing.T) []string {
+				outFile := filepath.Join(t.TempDir(), "out")
+				if err := os.WriteFile(outFile, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return []string{writeTWRepo(t), outFile}
+			},
+			want: exitConversion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args(t), &stdout, &stderr); code != tt.want {
+				t.Errorf("run() = %d; want %d (stderr: %s)", code, tt.want, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRun_Zip(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	zipPath := filepath.Join(t.TempDir(), "out.zip")
+	if code := run([]string{"--quiet", "--zip", zipPath, writeTWRepo(t)}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run(--zip) = %d; stderr: %s", code, stderr.String())
+	}
+	if _, err := os.Stat(zipPath); err != nil {
+		t.Errorf("archive not written: %v", err)
+	}
+
+	// A conversion that fails part-way leaves no partial archive behind
+	zipPath = filepath.Join(t.TempDir(), "out.zip")
+	if code := run([]string{"--quiet", "--strict", "--zip", zipPath, writeStubTNRepo(t)}, &stdout, &stderr); code != exitStrict {
+		t.Fatalf("run(--zip --strict) = %d; want %d", code, exitStrict)
+	}
+	if _, err := os.Stat(zipPath); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("partial archive left at %s (stat error %v)", zipPath, err)
+	}
+}
+
+func TestRun_GitURL(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	bare := gitBareRepo(t, writeTWRepo(t), "")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--json", "file://" + filepath.ToSlash(bare), t.TempDir()}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run() = %d; stderr: %s", code, stderr.String())
+	}
+	var got jsonResult
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("stdout is not a JSON result: %v\n%s", err, stdout.String())
+	}
+	if got.Subject != "Translation Words" || len(got.Commit) != 40 {
+		t.Errorf("result = %+v; want a Translation Words conversion with a commit hash", got)
+	}
+}
+
+// gitBareRepo commits the files in work and returns a bare clone of it,
+// with the commit tagged tag if it is not empty.
+func gitBareRepo(t *testing.T, work, tag string) string {
+	t.Helper()
+	bare := filepath.Join(t.TempDir(), filepath.Base(work)+".git")
+	cmds := [][]string{
+		{"-C", work, "init", "--quiet"},
+		{"-C", work, "add", "-A"},
+		{"-C", work, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "Initial"},
+	}
+	if tag != "" {
+		cmds = append(cmds, []string{"-C", work, "tag", tag})
+	}
+	cmds = append(cmds, []string{"clone", "--quiet", "--bare", work, bare})
+	for _, args := range cmds {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return bare
+}
+
+func TestRun_Books(t *testing.T) {
+	inDir := t.TempDir()
+	files := map[string]string{
+		"manifest.yaml": `dublin_core:
+  subject: 'TSV Translation Notes'
+  identifier: 'tn'
+  title: 'Notes'
+  rights: 'CC BY-SA 4.0'
+  language:
+    identifier: 'en'
+    title: 'English'
+    direction: 'ltr'
+projects:
+  - identifier: 'gen'
+    path: './tn_GEN.tsv'
+  - identifier: 'exo'
+    path: './tn_EXO.tsv'
+  - identifier: 'mat'
+    path: './tn_MAT.tsv'
+`,
+		"tn_GEN.tsv": "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n1:1\tabcd\t\t\t\t0\tNote\n",
+		"tn_EXO.tsv": "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n1:1\tefgh\t\t\t\t0\tNote\n",
+		"tn_MAT.tsv": "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n1:1\tijkl\t\t\t\t0\tNote\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(inDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	outDir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--json", "--books", " EXO, ", inDir, outDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run() = %d; stderr: %s", code, stderr.String())
+	}
+	var got jsonResult
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("stdout is not a JSON result: %v\n%s", err, stdout.String())
+	}
+	if len(got.Warnings) != 0 {
+		t.Errorf("warnings = %q; want none", got.Warnings)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m sb.Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Ingredients["ingredients/EXO.tsv"]; !ok || len(m.Ingredients) != 2 {
+		t.Errorf("ingredients = %v; want ingredients/EXO.tsv and the license", m.Ingredients)
+	}
+	if _, ok := m.Type.FlavorType.CurrentScope["EXO"]; !ok || len(m.Type.FlavorType.CurrentScope) != 1 {
+		t.Errorf("currentScope = %v; want only EXO", m.Type.FlavorType.CurrentScope)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/unfoldingWord/go-rc2sb/dcs"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// runPush runs the push subcommand and returns the process exit code:
+// exitOK on success, exitUsage for bad arguments, or exitPushFailed if the
+// upload failed.
+func runPush(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rc2sb push", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	token := fs.String("token", os.Getenv("RC2SB_GIT_TOKEN"), "Gitea access token (default $RC2SB_GIT_TOKEN)")
+	branch := fs.String("branch", "", "branch to commit to (default the repository's default branch)")
+	message := fs.String("message", "", "commit message (default names --source, --source-version, and the rc2sb version)")
+	source := fs.String("source", "", "source RC repository named in the default commit message (default the SB's name)")
+	sourceVersion := fs.String("source-version", "", "source RC version named in the default commit message")
+	tag := fs.String("tag", "", "create a release with this tag at the new commit, or at the branch head if nothing changed")
+	dryRun := fs.Bool("dry-run", false, "print the changes that would be made without making them")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: rc2sb push [flags] <sbDir> <repoURL>\n\n")
+		fmt.Fprintf(stderr, "Commits a converted SB directory to a Gitea (e.g., DCS) repository, creating it if needed.\n\n")
+		fmt.Fprintf(stderr, "Arguments:\n")
+		fmt.Fprintf(stderr, "  sbDir    SB directory written by rc2sb\n")
+		fmt.Fprintf(stderr, "  repoURL  Target repository, e.g., https://git.door43.org/unfoldingWord/en_tn_sb\n\n")
+		fmt.Fprintf(stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+	sbDir, repoURL := fs.Arg(0), fs.Arg(1)
+
+	metadata, err := readMetadata(sbDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "rc2sb push: %v\n", err)
+		return exitUsage
+	}
+	if *message == "" {
+		*message = dcs.CommitMessage(metadata, *source, *sourceVersion)
+	}
+
+	result, err := dcs.Push(context.Background(), sbDir, dcs.PushOptions{
+		RepoURL: repoURL,
+		Token:   *token,
+		Branch:  *branch,
+		Message: *message,
+		Tag:     *tag,
+		DryRun:  *dryRun,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "rc2sb push: %v\n", err)
+		if errors.Is(err, dcs.ErrUnauthorized) && *token == "" {
+			fmt.Fprintf(stderr, "rc2sb push: no token given; set --token or RC2SB_GIT_TOKEN\n")
+		}
+		return exitPushFailed
+	}
+
+	verb := "Pushed"
+	if *dryRun {
+		verb = "Would push"
+	}
+	if result.Created {
+		fmt.Fprintf(stdout, "%s to new repository %s\n", verb, repoURL)
+	} else {
+		fmt.Fprintf(stdout, "%s to %s\n", verb, repoURL)
+	}
+	for _, name := range result.Added {
+		fmt.Fprintf(stdout, "  add     %s\n", name)
+	}
+	for _, name := range result.Updated {
+		fmt.Fprintf(stdout, "  update  %s\n", name)
+	}
+	for _, name := range result.Deleted {
+		fmt.Fprintf(stdout, "  delete  %s\n", name)
+	}
+	switch {
+	case !result.Changed():
+		fmt.Fprintf(stdout, "Nothing changed\n")
+	case *dryRun:
+		fmt.Fprintf(stdout, "Message %s\n", *message)
+	default:
+		fmt.Fprintf(stdout, "Commit  %s\n", result.Commit)
+	}
+	if *tag != "" {
+		fmt.Fprintf(stdout, "Tag     %s\n", *tag)
+	}
+	return exitOK
+}
+
+// readMetadata reads and parses sbDir/metadata.json, which also checks that
+// sbDir holds an SB.
+func readMetadata(sbDir string) (*sb.Metadata, error) {
+	data, err := os.ReadFile(filepath.Join(sbDir, "metadata.json"))
+	if err != nil {
+		return nil, fmt.Errorf("not a Scripture Burrito: %w", err)
+	}
+	var m sb.Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Join(sbDir, "metadata.json"), err)
+	}
+	return &m, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// convertTW converts a TW test repo and returns the SB output directory.
+func convertTW(t *testing.T) string {
+	t.Helper()
+	outDir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--quiet", writeTWRepo(t), outDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run() = %d; stderr: %s", code, stderr.String())
+	}
+	return outDir
+}
+
+func TestRunPush_DryRun(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	sbDir := convertTW(t)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"push", "--token", "secret", "--dry-run", "--source", "unfoldingWord/en_tw", "--source-version", "v80", sbDir, srv.URL + "/unfoldingWord/en_tw_sb"}
+	if code := run(args, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run() = %d; stderr: %s", code, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{"Would push to new repository", "add     metadata.json", "Message Convert unfoldingWord/en_tw v80 to Scripture Burrito"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("stdout missing %q:\n%s", want, out)
+		}
+	}
+	for _, m := range methods {
+		if m != http.MethodGet {
+			t.Errorf("dry run sent a %s request", m)
+		}
+	}
+}
+
+func TestRunPush_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	sbDir := convertTW(t)
+	t.Setenv("RC2SB_GIT_TOKEN", "")
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantErr  string
+	}{
+		{"missing args", []string{"push", sbDir}, exitUsage, "Usage: rc2sb push"},
+		{"not an SB", []string{"push", t.TempDir(), srv.URL + "/o/r"}, exitUsage, "not a Scripture Burrito"},
+		{"no token", []string{"push", sbDir, srv.URL + "/o/r"}, exitPushFailed, "set --token or RC2SB_GIT_TOKEN"},
+		{"bad token", []string{"push", "--token", "wrong", sbDir, srv.URL + "/o/r"}, exitPushFailed, "authentication failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Errorf("run() = %d, want %d; stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("stderr missing %q:\n%s", tt.wantErr, stderr.String())
+			}
+		})
+	}
+}
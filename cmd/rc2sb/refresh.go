@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+)
+
+// runRefresh runs the refresh subcommand and returns the process exit code:
+// exitOK on success, exitUsage for bad arguments, or the exit code for the
+// error that stopped it.
+func runRefresh(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rc2sb refresh", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	quiet := fs.Bool("quiet", false, "print nothing but errors (to stderr)")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: rc2sb refresh [flags] <sbDir>\n\n")
+		fmt.Fprintf(stderr, "Recomputes the checksums and sizes in sbDir/metadata.json after ingredients were edited\n")
+		fmt.Fprintf(stderr, "in place, adding new files beneath ingredients/ and dropping entries whose file is gone.\n\n")
+		fmt.Fprintf(stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	level := slog.LevelWarn
+	if *quiet {
+		level = slog.LevelError
+	}
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+	if err := rc2sb.UpdateMetadata(context.Background(), fs.Arg(0), rc2sb.Options{Logger: logger}); err != nil {
+		fmt.Fprintf(stderr, "rc2sb refresh: %v\n", err)
+		return exitCode(err)
+	}
+	if !*quiet {
+		fmt.Fprintf(stdout, "Refreshed %s\n", fs.Arg(0))
+	}
+	return exitOK
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+func TestRunRefresh(t *testing.T) {
+	inDir, outDir := writeTWRepo(t), t.TempDir()
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--quiet", inDir, outDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("conversion run() = %d; stderr: %s", code, stderr.String())
+	}
+
+	article := filepath.Join(outDir, "ingredients", "kt", "god.md")
+	if err := os.WriteFile(article, []byte("# God, fixed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"refresh", outDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run() = %d; stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Refreshed "+outDir) {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	m, err := sb.LoadMetadata(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Ingredients["ingredients/kt/god.md"].Size; got != int64(len("# God, fixed\n")) {
+		t.Errorf("refreshed size = %d", got)
+	}
+
+	if code := run([]string{"refresh"}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("run() without sbDir = %d; want %d", code, exitUsage)
+	}
+	if code := run([]string{"refresh", t.TempDir()}, &stdout, &stderr); code != exitConversion {
+		t.Errorf("run() without metadata.json = %d; want %d", code, exitConversion)
+	}
+}
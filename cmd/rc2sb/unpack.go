@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+)
+
+// runUnpack runs the unpack subcommand and returns the process exit code:
+// exitOK on success, exitUsage for bad arguments, or the exit code for the
+// error that stopped it.
+func runUnpack(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rc2sb unpack", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	subject := fs.String("subject", "", "unpack as this RC subject instead of the one the SB flavor implies")
+	quiet := fs.Bool("quiet", false, "print nothing but errors (to stderr)")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: rc2sb unpack [flags] <sbDir> <outDir>\n\n")
+		fmt.Fprintf(stderr, "Converts a Scripture Burrito back to a Resource Container: rebuilds manifest.yaml from\n")
+		fmt.Fprintf(stderr, "metadata.json and renames the ingredients to RC conventions (e.g., ingredients/GEN.tsv\n")
+		fmt.Fprintf(stderr, "to tn_GEN.tsv).\n\n")
+		fmt.Fprintf(stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	level := slog.LevelWarn
+	if *quiet {
+		level = slog.LevelError
+	}
+	opts := rc2sb.Options{
+		SubjectOverride: *subject,
+		Logger:          slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level})),
+	}
+	result, err := rc2sb.ConvertSBToRC(context.Background(), fs.Arg(0), fs.Arg(1), opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "rc2sb unpack: %v\n", err)
+		return exitCode(err)
+	}
+	if !*quiet {
+		fmt.Fprintf(stdout, "Unpacked %s (%s) to %s\n", result.Subject, result.Identifier, fs.Arg(1))
+	}
+	return exitOK
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeOBSRepo writes an OBS test repo and returns its directory.
+func writeOBSRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"manifest.yaml": `dublin_core:
+  subject: 'Open Bible Stories'
+  identifier: 'obs'
+  title: 'Test OBS'
+  rights: 'CC BY-SA 4.0'
+  language:
+    identifier: 'en'
+    title: 'English'
+    direction: 'ltr'
+projects:
+  - identifier: 'obs'
+    title: 'Open Bible Stories'
+    path: './content'
+`,
+		"content/01.md":          "# 1. The Creation\n",
+		"content/front/title.md": "Open Bible Stories\n",
+		"LICENSE.md":             "# License\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunUnpack_OBS(t *testing.T) {
+	sbDir, rcDir := t.TempDir(), filepath.Join(t.TempDir(), "rc")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--quiet", writeOBSRepo(t), sbDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("conversion run() = %d; stderr: %s", code, stderr.String())
+	}
+
+	if code := run([]string{"unpack", sbDir, rcDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run() = %d; stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Unpacked Open Bible Stories (obs) to "+rcDir) {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	manifest, err := os.ReadFile(filepath.Join(rcDir, "manifest.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(manifest), "Open Bible Stories") {
+		t.Errorf("manifest.yaml = %q", manifest)
+	}
+	story, err := os.ReadFile(filepath.Join(rcDir, "content", "01.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(story) != "# 1. The Creation\n" {
+		t.Errorf("content/01.md = %q", story)
+	}
+
+	if code := run([]string{"unpack", sbDir}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("run() without outDir = %d; want %d", code, exitUsage)
+	}
+	if code := run([]string{"unpack", t.TempDir(), t.TempDir()}, &stdout, &stderr); code != exitConversion {
+		t.Errorf("run() without metadata.json = %d; want %d", code, exitConversion)
+	}
+}
+
+func TestRunVerify(t *testing.T) {
+	sbDir := convertTW(t)
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"verify", sbDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run() = %d; stdout: %s stderr: %s", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "0 of ") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+
+	if err := os.WriteFile(filepath.Join(sbDir, "ingredients", "kt", "god.md"), []byte("# God, edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := run([]string{"verify", sbDir}, &stdout, &stderr); code != exitCheckFailed {
+		t.Fatalf("run() after an edit = %d; want %d", code, exitCheckFailed)
+	}
+	if !strings.Contains(stdout.String(), "ingredients/kt/god.md: size is") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+
+	if code := run([]string{"verify"}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("run() without sbDir = %d; want %d", code, exitUsage)
+	}
+	if code := run([]string{"verify", t.TempDir()}, &stdout, &stderr); code != exitConversion {
+		t.Errorf("run() without metadata.json = %d; want %d", code, exitConversion)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// runVerify runs the verify subcommand and returns the process exit code:
+// exitOK if every ingredient matches metadata.json, exitCheckFailed if any
+// does not, exitUsage for bad arguments, or exitConversion if the metadata
+// cannot be read.
+func runVerify(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("rc2sb verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: rc2sb verify <sbDir>\n\n")
+		fmt.Fprintf(stderr, "Checks that each ingredient in sbDir/metadata.json exists with the recorded size and\n")
+		fmt.Fprintf(stderr, "checksums, printing those that do not.\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+	sbDir := flags.Arg(0)
+
+	m, err := sb.LoadMetadata(sbDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "rc2sb verify: %v\n", err)
+		return exitConversion
+	}
+
+	bad := 0
+	for _, key := range slices.Sorted(maps.Keys(m.Ingredients)) {
+		if problem := verifyIngredient(sbDir, key, m.Ingredients[key]); problem != "" {
+			fmt.Fprintf(stdout, "%s: %s\n", key, problem)
+			bad++
+		}
+	}
+	fmt.Fprintf(stdout, "%s: %d of %d ingredients do not match\n", sbDir, bad, len(m.Ingredients))
+	if bad > 0 {
+		return exitCheckFailed
+	}
+	return exitOK
+}
+
+// verifyIngredient returns what is wrong with the file of the ingredient key
+// in sbDir, given its recorded entry want, or "" if it matches.
+func verifyIngredient(sbDir, key string, want sb.Ingredient) string {
+	if !fs.ValidPath(key) {
+		return "invalid ingredient path"
+	}
+	p := filepath.Join(sbDir, filepath.FromSlash(key))
+	w := sb.NewIngredientWriter(p)
+	if want.Checksum.SHA256 != "" {
+		w.WithSHA256()
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "file is missing"
+	}
+	if err != nil {
+		return err.Error()
+	}
+	_, err = io.Copy(w, f)
+	f.Close()
+	if err != nil {
+		return err.Error()
+	}
+	got := w.Ingredient()
+	switch {
+	case got.Size != want.Size:
+		return fmt.Sprintf("size is %d; metadata.json records %d", got.Size, want.Size)
+	case got.Checksum.MD5 != want.Checksum.MD5:
+		return fmt.Sprintf("md5 is %s; metadata.json records %s", got.Checksum.MD5, want.Checksum.MD5)
+	case got.Checksum.SHA256 != want.Checksum.SHA256:
+		return fmt.Sprintf("sha256 is %s; metadata.json records %s", got.Checksum.SHA256, want.Checksum.SHA256)
+	}
+	return ""
+}
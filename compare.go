@@ -0,0 +1,240 @@
+package rc2sb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/books"
+	"github.com/unfoldingWord/go-rc2sb/handler"
+	"github.com/unfoldingWord/go-rc2sb/rc"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// CompareReport is the result of CompareRCToSB. File lists are sorted.
+type CompareReport struct {
+	// Subject is the subject the RC repository was compared as, and
+	// Identifier its dublin_core.identifier.
+	Subject    string
+	Identifier string
+
+	// Matched is the number of RC project files whose ingredient has
+	// identical bytes.
+	Matched int
+
+	// Transformed lists the RC project files whose ingredient differs only by
+	// a transformation Convert makes on purpose: TWL links rewritten to the
+	// payload, or a leading UTF-8 BOM stripped (Options.StripBOM).
+	Transformed []string
+
+	// Differing lists the RC project files whose ingredient has other content.
+	Differing []string
+
+	// MissingIngredients lists the RC project files with no ingredient, or
+	// whose ingredient is also expected for an earlier file.
+	MissingIngredients []string
+
+	// ExtraIngredients lists the keys of the ingredients beneath ingredients/
+	// that no RC project file maps to, other than ingredients/LICENSE.md, the
+	// TWL payload, and a source manifest (Options.IncludeSourceManifest).
+	ExtraIngredients []string
+}
+
+// OK reports whether every RC project file maps to exactly one ingredient
+// with the same content, up to the known transformations, and no ingredient
+// is left over.
+func (r CompareReport) OK() bool {
+	return len(r.Differing) == 0 && len(r.MissingIngredients) == 0 && len(r.ExtraIngredients) == 0
+}
+
+// CompareRCToSB checks that converting the RC repository at inDir to the
+// Scripture Burrito at sbDir lost no content. Every file of every manifest
+// project is mapped to the ingredient key the subject's handler gives it
+// (e.g., tn_GEN.tsv to ingredients/GEN.tsv, 01-GEN.usfm to
+// ingredients/GEN.usfm, content/01.md to ingredients/content/01.md, or
+// bible/kt/god.md to ingredients/kt/god.md), or to the ingredient whose
+// x-source is that file, and the two are compared byte for byte. The subject
+// is the manifest's dublin_core.subject, or opts.SubjectOverride if set; no
+// other option is used.
+//
+// Differences are returned in the report; the error is only non-nil if the
+// comparison itself could not be done, e.g., because either manifest could
+// not be read.
+func CompareRCToSB(ctx context.Context, inDir, sbDir string, opts Options) (CompareReport, error) {
+	// Check context
+	if err := ctx.Err(); err != nil {
+		return CompareReport{}, fmt.Errorf("context error: %w", err)
+	}
+
+	manifest, err := rc.LoadManifest(inDir)
+	if err != nil {
+		return CompareReport{}, err
+	}
+	m, err := sb.LoadMetadata(sbDir)
+	if err != nil {
+		return CompareReport{}, err
+	}
+
+	report := CompareReport{Subject: manifest.DublinCore.Subject, Identifier: manifest.DublinCore.Identifier}
+	if opts.SubjectOverride != "" {
+		report.Subject = opts.SubjectOverride
+	}
+	layout, ok := rcLayouts[report.Subject]
+	if !ok {
+		return CompareReport{}, fmt.Errorf("%w %q for comparison", handler.ErrUnsupportedSubject, report.Subject)
+	}
+
+	sources := make(map[string]string)
+	for key, ing := range m.Ingredients {
+		if ing.Source != "" {
+			sources[path.Clean(ing.Source)] = key
+		}
+	}
+
+	projects := manifest.Projects
+	if len(projects) == 0 && layout.kind == articleTree {
+		// The handler copies its usual directory, as if it were the project
+		projects = []rc.Project{{Identifier: layout.project, Path: "./" + layout.rcDir}}
+	}
+
+	rcFS, sbFS := os.DirFS(inDir), os.DirFS(sbDir)
+	claimed := make(map[string]bool)
+	for _, project := range projects {
+		names, err := projectFiles(rcFS, project)
+		if err != nil {
+			return report, err
+		}
+		for _, name := range names {
+			if err := ctx.Err(); err != nil {
+				return report, fmt.Errorf("context error: %w", err)
+			}
+
+			key, ok := sources[name]
+			if !ok {
+				key = expectedIngredient(layout, project, name)
+			}
+			if _, ok := m.Ingredients[key]; !ok || claimed[key] {
+				report.MissingIngredients = append(report.MissingIngredients, name)
+				continue
+			}
+			claimed[key] = true
+
+			rcData, err := fs.ReadFile(rcFS, name)
+			if err != nil {
+				return report, fmt.Errorf("reading %s: %w", name, err)
+			}
+			sbData, err := fs.ReadFile(sbFS, key)
+			if errors.Is(err, fs.ErrNotExist) {
+				report.MissingIngredients = append(report.MissingIngredients, name)
+				continue
+			} else if err != nil {
+				return report, fmt.Errorf("reading %s: %w", key, err)
+			}
+
+			switch {
+			case bytes.Equal(rcData, sbData):
+				report.Matched++
+			case layout.payload && m.Ingredients[key].MimeType == "text/tab-separated-values" && bytes.Equal(bytes.TrimPrefix(rcData, utf8BOM), unrewriteTWLLinks(sbData)),
+				bytes.Equal(bytes.TrimPrefix(rcData, utf8BOM), sbData):
+				report.Transformed = append(report.Transformed, name)
+			default:
+				report.Differing = append(report.Differing, name)
+			}
+		}
+	}
+
+	for key := range m.Ingredients {
+		rel, ok := strings.CutPrefix(key, "ingredients/")
+		if !ok || claimed[key] || rel == "LICENSE.md" || (layout.payload && strings.HasPrefix(rel, "payload/")) {
+			continue
+		}
+		if m.Ingredients[key].Role == handler.SourceManifestRole {
+			continue
+		}
+		report.ExtraIngredients = append(report.ExtraIngredients, key)
+	}
+
+	slices.Sort(report.Transformed)
+	slices.Sort(report.Differing)
+	slices.Sort(report.MissingIngredients)
+	slices.Sort(report.ExtraIngredients)
+	return report, nil
+}
+
+// utf8BOM is the byte order mark Options.StripBOM drops.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// projectFiles returns the files of project in fsys: its path if that is a
+// file, or every file beneath it if it is a directory. A project at the root
+// (OBS content at ".") leaves out the same repository files the OBS handler
+// does. A project whose path does not exist has no files, as Convert skips it.
+func projectFiles(fsys fs.FS, project rc.Project) ([]string, error) {
+	dir := path.Clean(strings.TrimPrefix(project.Path, "./"))
+	if !fs.ValidPath(dir) {
+		return nil, fmt.Errorf("project %q has an invalid path %q", project.Identifier, project.Path)
+	}
+	info, err := fs.Stat(fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{dir}, nil
+	}
+
+	var names []string
+	err = fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if dir == "." && name != "." && !strings.Contains(name, "/") {
+			base := path.Base(name)
+			switch {
+			case d.IsDir() && strings.HasPrefix(base, "."):
+				return fs.SkipDir
+			case !d.IsDir() && (strings.HasSuffix(base, ".yaml") || strings.HasSuffix(base, ".yml")):
+				return nil
+			case !d.IsDir() && (base == "README.md" || base == ".gitignore" || slices.Contains(handler.LicenseNames, base)):
+				return nil
+			}
+		}
+		if !d.IsDir() {
+			names = append(names, name)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// expectedIngredient returns the key of the ingredient the subject's handler
+// writes the file name of project to.
+func expectedIngredient(layout rcLayout, project rc.Project, name string) string {
+	base := path.Base(name)
+	switch layout.kind {
+	case bookFiles:
+		if layout.ext == ".usfm" {
+			return "ingredients/" + books.CodeFromUSFMFilename(base) + ".usfm"
+		}
+		return "ingredients/" + strings.TrimPrefix(base, layout.prefix)
+	case singleFile:
+		return "ingredients/" + strings.TrimPrefix(base, layout.prefix)
+	case articleTree:
+		dir := path.Clean(strings.TrimPrefix(project.Path, "./"))
+		rel := name
+		if dir != "." {
+			rel = strings.TrimPrefix(name, dir+"/")
+		}
+		return layout.sbDir + "/" + rel
+	case projectDirs:
+		dir := path.Clean(strings.TrimPrefix(project.Path, "./"))
+		return "ingredients/" + project.Identifier + "/" + strings.TrimPrefix(name, dir+"/")
+	}
+	return "ingredients/" + name
+}
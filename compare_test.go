@@ -0,0 +1,129 @@
+package rc2sb_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+	"github.com/unfoldingWord/go-rc2sb/handler"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// compareTNFiles is a minimal TN repository.
+var compareTNFiles = map[string]string{
+	"manifest.yaml": roundTripManifest("TSV Translation Notes", "tn", "gen ./tn_GEN.tsv", "mat ./tn_MAT.tsv"),
+	"tn_GEN.tsv":    "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n1:1\tabcd\t\t\t\t0\tNote\n",
+	"tn_MAT.tsv":    "\ufeffReference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n1:1\tefgh\t\t\t\t0\tNote\n",
+	"LICENSE.md":    "License\n",
+}
+
+// verifyCompare fails the test unless CompareRCToSB finds the SB at sbDir
+// holds all the content of the RC at inDir.
+func verifyCompare(t *testing.T, inDir, sbDir string) rc2sb.CompareReport {
+	t.Helper()
+	report, err := rc2sb.CompareRCToSB(context.Background(), inDir, sbDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("CompareRCToSB failed: %v", err)
+	}
+	if !report.OK() {
+		t.Errorf("CompareRCToSB report = %+v; want OK", report)
+	}
+	return report
+}
+
+func TestCompareRCToSB(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		opts        rc2sb.Options
+		matched     int
+		transformed []string
+	}{
+		{"OBS", archiveOBSFiles, rc2sb.Options{}, 2, nil},
+		{"TN", compareTNFiles, rc2sb.Options{}, 2, nil},
+		{"TN with BOM stripped", compareTNFiles, rc2sb.Options{StripBOM: true}, 1, []string{"tn_MAT.tsv"}},
+		{"TWL with payload", convertFSTestFiles, rc2sb.Options{}, 0, []string{"twl_GEN.tsv"}},
+		{"TN with sources recorded", map[string]string{
+			"manifest.yaml": roundTripManifest("TSV Translation Notes", "tn", "gen ./notes/GEN.tsv"),
+			"notes/GEN.tsv": "Reference\tID\n1:1\tabcd\n",
+		}, rc2sb.Options{RecordSources: true}, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inDir, sbDir := t.TempDir(), t.TempDir()
+			writeRepoFiles(t, inDir, tt.files)
+			if _, err := rc2sb.Convert(context.Background(), inDir, sbDir, tt.opts); err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+
+			report := verifyCompare(t, inDir, sbDir)
+			if report.Matched != tt.matched || !reflect.DeepEqual(report.Transformed, tt.transformed) {
+				t.Errorf("matched %d, transformed %v; want %d, %v", report.Matched, report.Transformed, tt.matched, tt.transformed)
+			}
+		})
+	}
+}
+
+func TestCompareRCToSB_ReportsLostContent(t *testing.T) {
+	ctx := context.Background()
+	inDir, sbDir := t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, archiveOBSFiles)
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	// Change one ingredient, lose another, and add one with no RC file
+	if err := os.WriteFile(filepath.Join(sbDir, "ingredients", "content", "01.md"), []byte("# 1. Changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeRepoFiles(t, inDir, map[string]string{"content/02.md": "# 2. Sin Enters the World\n"})
+	m, err := sb.LoadMetadata(sbDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Ingredients["ingredients/content/50.md"] = sb.Ingredient{}
+	if err := m.WriteToFile(sbDir); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := rc2sb.CompareRCToSB(ctx, inDir, sbDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("CompareRCToSB failed: %v", err)
+	}
+	want := rc2sb.CompareReport{
+		Subject:            "Open Bible Stories",
+		Identifier:         "obs",
+		Matched:            1,
+		Differing:          []string{"content/01.md"},
+		MissingIngredients: []string{"content/02.md"},
+		ExtraIngredients:   []string{"ingredients/content/50.md"},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report = %+v; want %+v", report, want)
+	}
+	if report.OK() {
+		t.Error("OK() = true; want false")
+	}
+}
+
+func TestCompareRCToSB_Errors(t *testing.T) {
+	ctx := context.Background()
+	inDir, sbDir := t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, compareTNFiles)
+
+	if _, err := rc2sb.CompareRCToSB(ctx, inDir, sbDir, rc2sb.Options{}); err == nil {
+		t.Error("expected error for an SB with no metadata.json")
+	}
+
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	_, err := rc2sb.CompareRCToSB(ctx, inDir, sbDir, rc2sb.Options{SubjectOverride: "Translation Wordz"})
+	if !errors.Is(err, handler.ErrUnsupportedSubject) {
+		t.Errorf("error = %v; want ErrUnsupportedSubject", err)
+	}
+}
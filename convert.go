@@ -2,63 +2,611 @@
 package rc2sb
 
 import (
+	"archive/zip"
+	"bufio"
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/unfoldingWord/go-rc2sb/books"
 	"github.com/unfoldingWord/go-rc2sb/handler"
+	"github.com/unfoldingWord/go-rc2sb/languages"
 	"github.com/unfoldingWord/go-rc2sb/rc"
+	"github.com/unfoldingWord/go-rc2sb/sb"
 
 	// Import all handlers to register them.
 	_ "github.com/unfoldingWord/go-rc2sb/handler/subjects"
 )
 
+// ErrAlreadySB is returned when the input of a conversion is already a
+// Scripture Burrito, with a metadata.json and an ingredients/ directory,
+// rather than an RC repository.
+var ErrAlreadySB = errors.New("input is already a Scripture Burrito (it has metadata.json and ingredients/); use UpdateMetadata to refresh its metadata instead")
+
+// ErrStrict is returned, wrapped with the stub ingredients it found, when
+// Options.Strict fails a conversion.
+var ErrStrict = errors.New("strict")
+
+// checkNotSB returns ErrAlreadySB if the root of fsys holds a Scripture
+// Burrito, so that it is not converted again into one with
+// ingredients/ingredients/.
+func checkNotSB(fsys fs.FS) error {
+	if _, err := fs.Stat(fsys, "metadata.json"); err != nil {
+		return nil
+	}
+	if info, err := fs.Stat(fsys, "ingredients"); err != nil || !info.IsDir() {
+		return nil
+	}
+	return ErrAlreadySB
+}
+
 // Convert converts an RC repository at inDir to SB format, writing output to outDir.
+// An inDir that is already an SB is refused with ErrAlreadySB.
 func Convert(ctx context.Context, inDir string, outDir string, opts Options) (Result, error) {
 	// Check context
 	if err := ctx.Err(); err != nil {
 		return Result{}, fmt.Errorf("context error: %w", err)
 	}
 
+	if err := checkNotSB(os.DirFS(inDir)); err != nil {
+		return Result{}, fmt.Errorf("%s: %w", inDir, err)
+	}
+
+	// Load the RC manifest
+	manifest, err := rc.LoadManifest(inDir)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return convert(ctx, manifest, nil, inDir, outDir, nil, opts)
+}
+
+// ConvertFS converts an RC repository read from fsys to SB format, writing output to outDir.
+// The root of fsys must contain the RC's manifest.yaml. Output is always written
+// to the real filesystem. PayloadPath and USFMPath in opts still refer to
+// directories on disk.
+func ConvertFS(ctx context.Context, fsys fs.FS, outDir string, opts Options) (Result, error) {
+	// Check context
+	if err := ctx.Err(); err != nil {
+		return Result{}, fmt.Errorf("context error: %w", err)
+	}
+
+	if err := checkNotSB(fsys); err != nil {
+		return Result{}, err
+	}
+
+	// Load the RC manifest
+	manifest, err := rc.LoadManifestFS(fsys)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, err := convert(ctx, manifest, fsys, ".", outDir, nil, opts)
+	result.InDir = ""
+	return result, err
+}
+
+// ConvertToZip converts an RC repository at inDir to SB format, streaming the
+// result to w as a zip archive instead of writing a directory. Ingredients are
+// added to the archive as they are computed, and metadata.json is added last.
+// Entries are written in the same order on every run and carry a fixed
+// modification time, so the archive layout is reproducible. The archive is
+// always finalized, even when the conversion fails part-way.
+// Result.OutDir is empty.
+func ConvertToZip(ctx context.Context, inDir string, w io.Writer, opts Options) (result Result, err error) {
+	// Check context
+	if err := ctx.Err(); err != nil {
+		return Result{}, fmt.Errorf("context error: %w", err)
+	}
+
+	if err := checkNotSB(os.DirFS(inDir)); err != nil {
+		return Result{}, fmt.Errorf("%s: %w", inDir, err)
+	}
+
 	// Load the RC manifest
 	manifest, err := rc.LoadManifest(inDir)
 	if err != nil {
 		return Result{}, err
 	}
 
+	zw := zip.NewWriter(w)
+	defer func() {
+		// Write the central directory even if the conversion failed
+		if cerr := zw.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("finalizing zip archive: %w", cerr)
+		}
+	}()
+
+	return convert(ctx, manifest, nil, inDir, "", handler.ZipOutput(zw), opts)
+}
+
+// ConvertToWriter streams the SB output for the RC repository at inDir to w
+// as a zip archive (e.g., directly to an HTTP response). It is equivalent to
+// ConvertToZip.
+func ConvertToWriter(ctx context.Context, inDir string, w io.Writer, opts Options) (Result, error) {
+	return ConvertToZip(ctx, inDir, w, opts)
+}
+
+// convert runs the handler for the manifest's subject and writes metadata.json.
+// If fsys is nil, the RC repository is read from inDir on disk; otherwise inDir
+// only names the repository in source paths. If out is nil, output is written
+// to outDir on disk.
+func convert(ctx context.Context, manifest *rc.Manifest, fsys fs.FS, inDir, outDir string, out handler.Output, opts Options) (Result, error) {
+	start := time.Now()
 	subject := manifest.DublinCore.Subject
+	if opts.SubjectOverride != "" {
+		subject = opts.SubjectOverride
+	}
 
 	// Look up the handler for this subject
 	h, err := handler.Lookup(subject)
 	if err != nil {
+		if opts.SubjectOverride != "" {
+			return Result{}, fmt.Errorf("subject override: %w", err)
+		}
+		return Result{}, err
+	}
+
+	// Duplicate projects would overwrite each other's ingredients
+	var duplicates []rc.DuplicateProject
+	if opts.SkipDuplicateProjects {
+		duplicates = manifest.RemoveDuplicateProjects()
+	} else if err := manifest.Validate(); err != nil {
 		return Result{}, err
 	}
 
-	// Ensure the output directory exists
-	if err := os.MkdirAll(outDir, 0755); err != nil {
-		return Result{}, fmt.Errorf("creating output directory: %w", err)
+	var filter *handler.Filter
+	if len(opts.IncludeGlobs) > 0 || len(opts.ExcludeGlobs) > 0 {
+		if filter, err = handler.NewFilter(opts.IncludeGlobs, opts.ExcludeGlobs); err != nil {
+			return Result{}, err
+		}
+	}
+
+	if opts.Category != "" && !slices.Contains(categories, opts.Category) {
+		return Result{}, fmt.Errorf("invalid category %q: must be one of %s", opts.Category, strings.Join(categories, ", "))
+	}
+
+	mimes, err := newMimeOverrides(opts.MimeOverrides)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var normalizer *handler.Normalizer
+	if opts.NormalizeUnicode {
+		normalizer = handler.NewNormalizer()
+	}
+
+	if opts.IDAuthority.Key != "" && opts.IDAuthority.URL == "" {
+		return Result{}, fmt.Errorf("ID authority %q has no URL", opts.IDAuthority.Key)
+	}
+
+	var reused []string
+	if out == nil {
+		// Ensure the output directory exists
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			return Result{}, fmt.Errorf("creating output directory: %w", err)
+		}
+		out = newDirOutput(outDir, opts, &reused)
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	var written []string
+	out = loggingOutput{out: out, logger: logger, written: &written}
+
+	var warnings []string
+	warn := func(msg string) {
+		logger.Warn(msg)
+		warnings = append(warnings, msg)
+	}
+	logger.Debug("loaded manifest", "subject", manifest.DublinCore.Subject, "identifier", manifest.DublinCore.Identifier,
+		"language", manifest.DublinCore.Language.Identifier, "projects", len(manifest.Projects))
+	if opts.SubjectOverride != "" {
+		warn(fmt.Sprintf("using subject %q in place of manifest subject %q", subject, manifest.DublinCore.Subject))
+	}
+	lang := manifest.DublinCore.Language
+	if direction, ok := languages.ScriptDirection(lang.Identifier, lang.Direction); !ok {
+		warn(fmt.Sprintf("unrecognized language direction %q; using %q", lang.Direction, direction))
+	}
+	if _, ok := handler.SPDXLicense(manifest.DublinCore.Rights); !ok && manifest.DublinCore.Rights != "" {
+		warn(fmt.Sprintf("unrecognized rights %q; the content may not be openly licensed", manifest.DublinCore.Rights))
+	}
+
+	for _, d := range duplicates {
+		warn(fmt.Sprintf("skipping duplicate project: %s", d))
+	}
+
+	if len(opts.Books) > 0 {
+		if layout, ok := rcLayouts[subject]; !ok || layout.kind != bookFiles {
+			warn(fmt.Sprintf("books apply only to subjects split by book; converting all of %s", subject))
+		} else if missing := missingBooks(manifest, opts.Books); len(missing) > 0 {
+			warn(fmt.Sprintf("requested books not found in the manifest: %s", strings.Join(missing, ", ")))
+		}
+	}
+
+	encoding := handler.NewEncodingChecker(opts.TranscodeLatin1)
+	stubs := handler.NewStubDetector()
+	report := handler.NewReport()
+
+	var tracker *handler.SourceTracker
+	if !opts.IgnoreUnreferenced {
+		tracker = handler.NewSourceTracker()
 	}
 
 	// Run the handler
 	handlerOpts := handler.Options{
-		PayloadPath: opts.PayloadPath,
-		USFMPath:    opts.USFMPath,
+		FS:                    fsys,
+		Filter:                filter,
+		EncodingChecker:       encoding,
+		Normalizer:            normalizer,
+		StubDetector:          stubs,
+		Output:                out,
+		PayloadPath:           opts.PayloadPath,
+		USFMPath:              opts.USFMPath,
+		USFMSiblings:          opts.USFMSiblings,
+		USFMNames:             cmp.Or(opts.USFMNames, books.NewUSFMNameCache()),
+		CopyrightStatement:    opts.CopyrightStatement,
+		StripBOM:              opts.StripBOM,
+		SHA256:                opts.SHA256Checksums,
+		RecordSources:         opts.RecordSources,
+		ExtraRootFiles:        opts.ExtraRootFiles,
+		ExtraRootDirs:         opts.ExtraRootDirs,
+		IncludeSourceManifest: opts.IncludeSourceManifest,
+		Tracker:               tracker,
+		Report:                report,
+		Books:                 opts.Books,
+		Timestamp:             opts.FixedTimestamp,
+		IDAuthority:           handler.IDAuthority(opts.IDAuthority),
+		Warn:                  warn,
+		Logger:                logger,
 	}
 	metadata, err := h.Convert(ctx, manifest, inDir, outDir, handlerOpts)
 	if err != nil {
 		return Result{}, fmt.Errorf("converting %s: %w", subject, err)
 	}
 
+	// Downstream tools assume UTF-8
+	for _, invalid := range encoding.Invalid() {
+		warn(fmt.Sprintf("%s is not valid UTF-8: invalid byte at offset %d", invalid.Key, invalid.Offset))
+	}
+	for _, key := range encoding.Transcoded() {
+		warn(fmt.Sprintf("%s was transcoded from Latin-1 to UTF-8", key))
+	}
+
+	// Empty and stub ingredients make a valid-looking burrito with no content
+	var problems []string
+	for _, stub := range stubs.Stubs() {
+		problem := fmt.Sprintf("ingredient %s %s", stub.Key, stub.Problem)
+		if opts.Strict && !stub.Empty {
+			problems = append(problems, problem)
+			continue
+		}
+		warn(problem)
+	}
+	if len(problems) > 0 {
+		return Result{}, fmt.Errorf("%w: %s", ErrStrict, strings.Join(problems, "; "))
+	}
+
+	// Report files of the RC that reached neither the SB nor a project
+	if tracker != nil {
+		srcFS, skip := fsys, ""
+		if srcFS == nil {
+			srcFS = os.DirFS(inDir)
+			if rel, err := filepath.Rel(inDir, outDir); err == nil && outDir != "" && filepath.IsLocal(rel) {
+				skip = filepath.ToSlash(rel)
+			}
+		}
+		unreferenced, err := unreferencedFiles(srcFS, manifest, tracker, skip)
+		if err != nil {
+			return Result{}, err
+		}
+		for _, msg := range unreferenced {
+			warn(msg)
+		}
+	}
+
+	// A book in currentScope with no ingredient means metadata.json claims
+	// content the burrito does not have
+	for _, book := range metadata.UnscopedBooks() {
+		warn(fmt.Sprintf("currentScope lists %s, but no ingredient has that scope", book))
+	}
+
+	// Flag content that has not reached the publisher's checking level
+	if slices.Contains(opts.ConfidentialCheckingLevels, manifest.Checking.CheckingLevel) {
+		metadata.Confidential = true
+	}
+	if opts.Category != "" {
+		metadata.Meta.Category = opts.Category
+	}
+	applyLanguageOverrides(metadata, opts.LanguageOverrides)
+	mimes.apply(metadata)
+	if opts.Strict {
+		// Catch a MIME type set by mistake; overrides are intended
+		for _, key := range metadata.MIMEMismatches() {
+			if mimes.mimeType(key) == "" {
+				warn(fmt.Sprintf("ingredient %s has MIME type %q, not %q for its extension", key, metadata.Ingredients[key].MimeType, sb.MIMETypeForExt(path.Ext(key))))
+			}
+		}
+	}
+	applyAbbreviations(metadata, manifest, opts)
+	if !opts.NoProvenance {
+		dir := inDir
+		if fsys != nil {
+			// Not a directory on disk
+			dir = ""
+		}
+		metadata.Meta.Provenance = readProvenance(dir, opts)
+	}
+
 	// Write metadata.json
-	if err := metadata.WriteToFile(outDir); err != nil {
+	if err := writeMetadata(out, metadata); err != nil {
 		return Result{}, err
 	}
 
-	return Result{
-		Subject:     subject,
-		Identifier:  manifest.DublinCore.Identifier,
-		InDir:       inDir,
-		OutDir:      outDir,
-		Ingredients: len(metadata.Ingredients),
-	}, nil
+	if opts.BagItManifest {
+		if err := writeBagItManifest(out, metadata); err != nil {
+			return Result{}, err
+		}
+	}
+
+	for _, key := range filter.Excluded() {
+		logger.Info("excluded file", "key", key)
+	}
+	logger.Debug("converted", "subject", subject, "ingredients", len(metadata.Ingredients), "excluded", len(filter.Excluded()),
+		"normalized", len(normalizer.Changed()))
+
+	result := Result{
+		Subject:    subject,
+		Identifier: manifest.DublinCore.Identifier,
+		InDir:      inDir,
+		OutDir:     outDir,
+		Excluded:   filter.Excluded(),
+		Normalized: normalizer.Changed(),
+		Reused:     slices.Sorted(slices.Values(reused)),
+		Warnings:   warnings,
+
+		Substitutions: report.Substitutions(),
+		Skips:         report.Skips(),
+	}
+	result.setIngredients(metadata)
+	slices.Sort(written)
+	result.Written = slices.DeleteFunc(slices.Compact(written), func(name string) bool {
+		_, ok := slices.BinarySearch(result.Reused, name)
+		return ok
+	})
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// setIngredients sets the ingredient count, keys, total size, and counts by
+// kind of r from the SB metadata m.
+func (r *Result) setIngredients(m *sb.Metadata) {
+	r.Ingredients = len(m.Ingredients)
+	r.IngredientKeys = slices.Sorted(maps.Keys(m.Ingredients))
+	r.TotalBytes = 0
+	r.ContentIngredients, r.PayloadIngredients, r.RootIngredients = 0, 0, 0
+	for key, ing := range m.Ingredients {
+		r.TotalBytes += ing.Size
+		switch {
+		case strings.HasPrefix(key, "ingredients/payload/"):
+			r.PayloadIngredients++
+		case strings.HasPrefix(key, "ingredients/"):
+			r.ContentIngredients++
+		default:
+			r.RootIngredients++
+		}
+	}
+}
+
+// applyLanguageOverrides applies to the language entries of m the overrides
+// for their tags.
+func applyLanguageOverrides(m *sb.Metadata, overrides map[string]LanguageOverride) {
+	for tag, o := range overrides {
+		for i := range m.Languages {
+			lang := &m.Languages[i]
+			if !strings.EqualFold(lang.Tag, tag) {
+				continue
+			}
+			if o.Name != nil {
+				lang.Name = o.Name
+			}
+			lang.Script = cmp.Or(o.Script, lang.Script)
+			lang.ScriptDirection = cmp.Or(o.ScriptDirection, lang.ScriptDirection)
+			lang.Region = cmp.Or(o.Region, lang.Region)
+			lang.NumberingSystem = cmp.Or(o.NumberingSystem, lang.NumberingSystem)
+		}
+	}
+}
+
+// mimeOverrides holds Options.MimeOverrides, split into glob patterns, in
+// sorted order, and extensions, in lower case.
+type mimeOverrides struct {
+	patterns   []string
+	types      map[string]string
+	extensions map[string]string
+}
+
+// newMimeOverrides returns the mimeOverrides for overrides, reporting an
+// invalid glob pattern as an error.
+func newMimeOverrides(overrides map[string]string) (mimeOverrides, error) {
+	o := mimeOverrides{types: make(map[string]string), extensions: make(map[string]string)}
+	for key, mimeType := range overrides {
+		if strings.HasPrefix(key, ".") && !strings.ContainsAny(key, "/*?[") {
+			o.extensions[strings.ToLower(key)] = mimeType
+			continue
+		}
+		o.patterns = append(o.patterns, key)
+		o.types[key] = mimeType
+	}
+	slices.Sort(o.patterns)
+	if _, err := handler.NewFilter(o.patterns, nil); err != nil {
+		return mimeOverrides{}, fmt.Errorf("invalid MIME override: %w", err)
+	}
+	return o, nil
+}
+
+// mimeType returns the MIME type overriding the built-in one for the
+// ingredient key, or "" if there is no override.
+func (o mimeOverrides) mimeType(key string) string {
+	for _, pattern := range o.patterns {
+		if handler.MatchGlob(pattern, key) {
+			return o.types[pattern]
+		}
+	}
+	return o.extensions[strings.ToLower(path.Ext(key))]
+}
+
+// apply sets the MIME type of each ingredient of m that has an override.
+func (o mimeOverrides) apply(m *sb.Metadata) {
+	for key, ing := range m.Ingredients {
+		if mimeType := o.mimeType(key); mimeType != "" {
+			ing.MimeType = mimeType
+			m.Ingredients[key] = ing
+		}
+	}
+}
+
+// applyAbbreviations adds to the identification abbreviations of m the one
+// derived from the manifest title, if opts.DeriveAbbreviation is set, and
+// then opts.Abbreviations.
+func applyAbbreviations(m *sb.Metadata, manifest *rc.Manifest, opts Options) {
+	if opts.DeriveAbbreviation {
+		lang := manifest.DublinCore.Language.Identifier
+		if abbr := titleAbbreviation(manifest.DublinCore.Title); abbr != "" && lang != "" && lang != "en" {
+			m.Identification.Abbreviation[lang] = abbr
+		}
+	}
+	maps.Copy(m.Identification.Abbreviation, opts.Abbreviations)
+}
+
+// titleSeparators lists what may separate an abbreviation at the start of a
+// title from the rest (e.g., "IRV - Indian Revised Version").
+var titleSeparators = []string{" - ", " – ", " — ", ": "}
+
+// titleAbbreviation returns the abbreviation title begins with, or "" if it
+// does not begin with one. An abbreviation is a word of 2 to 12 characters
+// before one of titleSeparators that is either in upper case (e.g., "IRV")
+// or has dots between its letters (e.g., "इ.रि.सं.").
+func titleAbbreviation(title string) string {
+	for _, sep := range titleSeparators {
+		word, _, ok := strings.Cut(strings.TrimSpace(title), sep)
+		if !ok || strings.ContainsFunc(word, unicode.IsSpace) {
+			continue
+		}
+		if n := utf8.RuneCountInString(word); n < 2 || n > 12 {
+			continue
+		}
+		dotted := strings.Contains(strings.TrimSuffix(word, "."), ".")
+		upper := strings.ContainsFunc(word, unicode.IsUpper) && !strings.ContainsFunc(word, unicode.IsLower)
+		if dotted || upper {
+			return word
+		}
+	}
+	return ""
+}
+
+// categories lists the values of the SB meta.category.
+var categories = []string{"source", "derived", "template"}
+
+// missingBooks returns the books, as requested, that no project of manifest
+// has as its identifier.
+func missingBooks(manifest *rc.Manifest, books []string) []string {
+	var missing []string
+	for _, book := range books {
+		if !slices.ContainsFunc(manifest.Projects, func(p rc.Project) bool { return strings.EqualFold(p.Identifier, book) }) {
+			missing = append(missing, book)
+		}
+	}
+	return missing
+}
+
+// writeMetadata writes metadata.json to out.
+func writeMetadata(out handler.Output, metadata *sb.Metadata) error {
+	w, err := out.Create("metadata.json")
+	if err != nil {
+		return fmt.Errorf("writing metadata.json: %w", err)
+	}
+	defer w.Close()
+
+	if _, err := metadata.WriteTo(w); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("writing metadata.json: %w", err)
+	}
+	return nil
+}
+
+// bagItManifest is the name of the BagIt payload manifest written by
+// Options.BagItManifest.
+const bagItManifest = "manifest-md5.txt"
+
+// refreshBagItManifest rewrites the BagIt payload manifest of the SB at dir
+// from metadata, if the SB has one, so that it lists the SB's ingredients as
+// they now are. Anything that changes the ingredients of an existing SB calls
+// it after writing metadata.json.
+func refreshBagItManifest(dir string, metadata *sb.Metadata) error {
+	if _, err := os.Stat(filepath.Join(dir, bagItManifest)); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("writing %s: %w", bagItManifest, err)
+	}
+	return writeBagItManifest(handler.DirOutput(dir), metadata)
+}
+
+// writeBagItManifest writes to out a BagIt payload manifest listing the MD5
+// checksum and key of each ingredient of metadata, sorted by key, with the
+// characters BagIt requires percent-encoded in paths.
+func writeBagItManifest(out handler.Output, metadata *sb.Metadata) error {
+	w, err := out.Create(bagItManifest)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", bagItManifest, err)
+	}
+	defer w.Close()
+
+	encode := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	bw := bufio.NewWriter(w)
+	for _, key := range slices.Sorted(maps.Keys(metadata.Ingredients)) {
+		fmt.Fprintf(bw, "%s  %s\n", metadata.Ingredients[key].Checksum.MD5, encode.Replace(key))
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("writing %s: %w", bagItManifest, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", bagItManifest, err)
+	}
+	return nil
+}
+
+// loggingOutput logs each file written to out at debug level and appends its
+// name to written once it is created.
+type loggingOutput struct {
+	out     handler.Output
+	logger  *slog.Logger
+	written *[]string
+}
+
+func (o loggingOutput) Create(name string) (io.WriteCloser, error) {
+	o.logger.Debug("writing file", "name", name)
+	w, err := o.out.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	*o.written = append(*o.written, name)
+	return w, nil
 }
@@ -0,0 +1,117 @@
+package dcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultServer is the Door43 Content Service server.
+const DefaultServer = "https://git.door43.org"
+
+// ErrResourceNotFound is returned (wrapped) when the catalog has no matching
+// resource.
+var ErrResourceNotFound = errors.New("resource not found in catalog")
+
+// Resource is a released RC repository listed in the Door43 catalog.
+type Resource struct {
+	Owner    string // repository owner (e.g., "unfoldingWord")
+	Name     string // repository name (e.g., "en_tw")
+	Subject  string // RC subject (e.g., "Translation Words")
+	CloneURL string // git clone URL
+	Tag      string // latest release tag (e.g., "v80")
+}
+
+// Catalog looks up resources in the catalog of a Door43 (Gitea) server.
+type Catalog struct {
+	// Server is the server's base URL. If empty, DefaultServer is used.
+	Server string
+
+	// Owner, if set, only matches resources owned by this user or
+	// organization (e.g., "unfoldingWord").
+	Owner string
+
+	// Client is the HTTP client used for API requests. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+}
+
+// FindResource returns the latest production release of the resource
+// identifier (e.g., "tw") in the language lang (e.g., "en"), using the default
+// Catalog.
+func FindResource(ctx context.Context, lang, identifier string) (Resource, error) {
+	return (&Catalog{}).FindResource(ctx, lang, identifier)
+}
+
+// FindResource returns the latest production release of the resource
+// identifier (e.g., "tw") in the language lang (e.g., "en"): the repository
+// named "<lang>_<identifier>". If several owners publish it, the first listed
+// by the catalog is returned.
+func (c *Catalog) FindResource(ctx context.Context, lang, identifier string) (Resource, error) {
+	server := c.Server
+	if server == "" {
+		server = DefaultServer
+	}
+	httpClient := c.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	api := &client{http: httpClient, api: strings.TrimSuffix(server, "/") + "/api/v1"}
+
+	name := lang + "_" + identifier
+	query := url.Values{
+		"lang":         {lang},
+		"repo":         {name},
+		"stage":        {"prod"},
+		"metadataType": {"rc"},
+	}
+	if c.Owner != "" {
+		query.Set("owner", c.Owner)
+	}
+
+	var resp struct {
+		Data []catalogEntry `json:"data"`
+	}
+	if err := api.do(ctx, http.MethodGet, "/catalog/search?"+query.Encode(), nil, &resp); err != nil {
+		if errors.Is(err, errNotFound) {
+			return Resource{}, fmt.Errorf("%w: %s", ErrResourceNotFound, name)
+		}
+		return Resource{}, fmt.Errorf("searching catalog for %s: %w", name, err)
+	}
+
+	// The search matches repository names by substring, so check each entry
+	for _, e := range resp.Data {
+		if !strings.EqualFold(e.Name, name) || (c.Owner != "" && !strings.EqualFold(e.Owner, c.Owner)) {
+			continue
+		}
+		tag := e.Release.TagName
+		if tag == "" {
+			tag = e.BranchOrTagName
+		}
+		return Resource{
+			Owner:    e.Owner,
+			Name:     e.Name,
+			Subject:  e.Subject,
+			CloneURL: e.Repo.CloneURL,
+			Tag:      tag,
+		}, nil
+	}
+	return Resource{}, fmt.Errorf("%w: %s", ErrResourceNotFound, name)
+}
+
+// catalogEntry is the part of a Door43 catalog entry we use.
+type catalogEntry struct {
+	Name            string `json:"name"`
+	Owner           string `json:"owner"`
+	Subject         string `json:"subject"`
+	BranchOrTagName string `json:"branch_or_tag_name"`
+	Repo            struct {
+		CloneURL string `json:"clone_url"`
+	} `json:"repo"`
+	Release struct {
+		TagName string `json:"tag_name"`
+	} `json:"release"`
+}
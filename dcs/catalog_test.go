@@ -0,0 +1,103 @@
+package dcs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// catalogResponse is a trimmed /api/v1/catalog/search response for "en_tw",
+// which also matches repositories whose names merely contain it.
+const catalogResponse = `{
+  "ok": true,
+  "data": [
+    {
+      "name": "en_tw_sb",
+      "owner": "unfoldingWord",
+      "subject": "Translation Words",
+      "branch_or_tag_name": "v3",
+      "repo": {"clone_url": "https://git.door43.org/unfoldingWord/en_tw_sb.git"},
+      "release": {"tag_name": "v3"}
+    },
+    {
+      "name": "en_tw",
+      "owner": "Door43-Catalog",
+      "subject": "Translation Words",
+      "branch_or_tag_name": "v79",
+      "repo": {"clone_url": "https://git.door43.org/Door43-Catalog/en_tw.git"},
+      "release": {"tag_name": "v79"}
+    },
+    {
+      "name": "en_tw",
+      "owner": "unfoldingWord",
+      "subject": "Translation Words",
+      "branch_or_tag_name": "v80",
+      "repo": {"clone_url": "https://git.door43.org/unfoldingWord/en_tw.git"},
+      "release": {"tag_name": "v80"}
+    }
+  ]
+}`
+
+func newFakeCatalog(t *testing.T) (*httptest.Server, *[]string) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/catalog/search" {
+			http.NotFound(w, r)
+			return
+		}
+		queries = append(queries, r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("repo") == "en_tw" {
+			w.Write([]byte(catalogResponse))
+			return
+		}
+		w.Write([]byte(`{"ok": true, "data": []}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &queries
+}
+
+func TestCatalog_FindResource(t *testing.T) {
+	srv, queries := newFakeCatalog(t)
+
+	tests := []struct {
+		owner string
+		want  Resource
+	}{
+		{"", Resource{
+			Owner: "Door43-Catalog", Name: "en_tw", Subject: "Translation Words",
+			CloneURL: "https://git.door43.org/Door43-Catalog/en_tw.git", Tag: "v79",
+		}},
+		{"unfoldingWord", Resource{
+			Owner: "unfoldingWord", Name: "en_tw", Subject: "Translation Words",
+			CloneURL: "https://git.door43.org/unfoldingWord/en_tw.git", Tag: "v80",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run("owner="+tt.owner, func(t *testing.T) {
+			c := &Catalog{Server: srv.URL + "/", Owner: tt.owner}
+			got, err := c.FindResource(context.Background(), "en", "tw")
+			if err != nil {
+				t.Fatalf("FindResource() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("FindResource() = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if q := (*queries)[0]; q != "lang=en&metadataType=rc&repo=en_tw&stage=prod" {
+		t.Errorf("query = %q; want only production RC releases of en_tw", q)
+	}
+}
+
+func TestCatalog_FindResourceNotFound(t *testing.T) {
+	srv, _ := newFakeCatalog(t)
+
+	_, err := (&Catalog{Server: srv.URL}).FindResource(context.Background(), "xyz", "tw")
+	if !errors.Is(err, ErrResourceNotFound) {
+		t.Errorf("FindResource() error = %v; want ErrResourceNotFound", err)
+	}
+}
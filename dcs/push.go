@@ -0,0 +1,430 @@
+// Package dcs is a small client for Door43 Content Service (DCS,
+// git.door43.org) and other Gitea servers. It finds companion resources in the
+// Door43 catalog and uploads converted Scripture Burrito (SB) directories.
+package dcs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// ErrUnauthorized is returned (wrapped) when the server rejects the token.
+var ErrUnauthorized = errors.New("authentication failed")
+
+// PushOptions configures Push.
+type PushOptions struct {
+	// RepoURL is the target repository (e.g.,
+	// "https://git.door43.org/unfoldingWord/en_tn_sb"). It is created, owned by
+	// the user or organization in the URL, if it does not exist.
+	RepoURL string
+
+	// Token is a Gitea access token with write access to the repository.
+	Token string
+
+	// Branch is the branch to commit to. If empty, the repository's default
+	// branch is used.
+	Branch string
+
+	// Message is the commit message.
+	Message string
+
+	// Tag, if set, names a release created at the new commit.
+	Tag string
+
+	// DryRun reports the changes Push would make without making them.
+	// The server is still read, so the token must be valid.
+	DryRun bool
+
+	// Client is the HTTP client used for API requests. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+}
+
+// PushResult describes the changes made (or, for a dry run, that would be
+// made) by Push. File paths are slash-separated and relative to the SB root.
+type PushResult struct {
+	// Created reports whether the repository was created.
+	Created bool
+
+	// Added, Updated, and Deleted list the files changed in the commit. Files
+	// whose content is unchanged are left out. Deleted files are those in
+	// the repository but not in the SB directory.
+	Added   []string
+	Updated []string
+	Deleted []string
+
+	// Commit is the hash of the new commit. It is empty for a dry run or if
+	// nothing changed.
+	Commit string
+}
+
+// Changed reports whether any file was added, updated, or deleted.
+func (r PushResult) Changed() bool {
+	return len(r.Added)+len(r.Updated)+len(r.Deleted) > 0
+}
+
+// CommitMessage returns a commit message for pushing the SB described by m,
+// naming the source RC repository and its version when known.
+func CommitMessage(m *sb.Metadata, source, version string) string {
+	if source == "" {
+		source = m.Identification.Name["en"]
+		if source == "" {
+			for _, name := range m.Identification.Name {
+				source = name
+				break
+			}
+		}
+	}
+	msg := "Convert " + source
+	if version != "" {
+		msg += " " + version
+	}
+	gen := m.Meta.Generator
+	return fmt.Sprintf("%s to Scripture Burrito (%s %s)", msg, gen.SoftwareName, gen.SoftwareVersion)
+}
+
+// Push commits the contents of the SB directory sbDir to the repository at
+// opts.RepoURL through the Gitea API, creating the repository if needed, so
+// that the branch holds exactly the files in sbDir. Unchanged files are not
+// re-uploaded and no commit is made if nothing changed. If opts.Tag is set, a
+// release with that tag is created at the resulting commit, or at the head of
+// the branch if nothing changed.
+func Push(ctx context.Context, sbDir string, opts PushOptions) (PushResult, error) {
+	c, owner, repo, err := newClient(opts)
+	if err != nil {
+		return PushResult{}, err
+	}
+
+	local, err := readLocalFiles(sbDir)
+	if err != nil {
+		return PushResult{}, err
+	}
+
+	var result PushResult
+	var info repoInfo
+	err = c.do(ctx, http.MethodGet, "/repos/"+owner+"/"+repo, nil, &info)
+	switch {
+	case errors.Is(err, errNotFound):
+		result.Created = true
+		if !opts.DryRun {
+			if info, err = c.createRepo(ctx, owner, repo); err != nil {
+				return PushResult{}, err
+			}
+		}
+		info.Empty = true
+	case err != nil:
+		return PushResult{}, err
+	}
+
+	branch := opts.Branch
+	if branch == "" {
+		branch = info.DefaultBranch
+	}
+
+	remote := make(map[string]string)
+	if !info.Empty && branch != "" {
+		if remote, err = c.listFiles(ctx, owner, repo, branch); err != nil {
+			return PushResult{}, err
+		}
+	}
+
+	var changes []fileChange
+	for _, name := range slices.Sorted(maps.Keys(local)) {
+		content := local[name]
+		sha, exists := remote[name]
+		switch {
+		case !exists:
+			result.Added = append(result.Added, name)
+			changes = append(changes, fileChange{Operation: "create", Path: name, Content: base64.StdEncoding.EncodeToString(content)})
+		case sha != blobSHA(content):
+			result.Updated = append(result.Updated, name)
+			changes = append(changes, fileChange{Operation: "update", Path: name, Content: base64.StdEncoding.EncodeToString(content), SHA: sha})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(remote)) {
+		if _, ok := local[name]; !ok {
+			result.Deleted = append(result.Deleted, name)
+			changes = append(changes, fileChange{Operation: "delete", Path: name, SHA: remote[name]})
+		}
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	target := ""
+	if len(changes) > 0 {
+		var resp changeFilesResponse
+		req := changeFilesRequest{Branch: branch, Message: opts.Message, Files: changes}
+		if err := c.do(ctx, http.MethodPost, "/repos/"+owner+"/"+repo+"/contents", req, &resp); err != nil {
+			return result, fmt.Errorf("committing files: %w", err)
+		}
+		result.Commit = resp.Commit.SHA
+		target = result.Commit
+	} else if opts.Tag != "" {
+		// Nothing changed: tag the commit already at the head of the branch
+		if target, err = c.branchCommit(ctx, owner, repo, branch); err != nil {
+			return result, fmt.Errorf("creating release %s: %w", opts.Tag, err)
+		}
+	}
+
+	if opts.Tag != "" {
+		release := createReleaseRequest{TagName: opts.Tag, Target: target, Name: opts.Tag}
+		if err := c.do(ctx, http.MethodPost, "/repos/"+owner+"/"+repo+"/releases", release, nil); err != nil {
+			return result, fmt.Errorf("creating release %s: %w", opts.Tag, err)
+		}
+	}
+	return result, nil
+}
+
+// client makes Gitea API requests.
+type client struct {
+	http  *http.Client
+	api   string
+	token string
+}
+
+// newClient returns a client for the server hosting opts.RepoURL, along with
+// the repository's owner and name.
+func newClient(opts PushOptions) (*client, string, string, error) {
+	u, err := url.Parse(opts.RepoURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, "", "", fmt.Errorf("invalid repository URL %q", opts.RepoURL)
+	}
+	parts := strings.Split(strings.Trim(strings.TrimSuffix(u.Path, ".git"), "/"), "/")
+	if len(parts) < 2 {
+		return nil, "", "", fmt.Errorf("repository URL %q must name an owner and repository", opts.RepoURL)
+	}
+	owner, repo := parts[len(parts)-2], parts[len(parts)-1]
+	u.Path = strings.Join(parts[:len(parts)-2], "/")
+	u.Path = strings.TrimSuffix("/"+u.Path, "/") + "/api/v1"
+	u.RawQuery, u.Fragment = "", ""
+
+	httpClient := opts.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &client{http: httpClient, api: u.String(), token: opts.Token}, owner, repo, nil
+}
+
+// errNotFound is returned by do for a 404 response.
+var errNotFound = errors.New("not found")
+
+// do sends an API request with body (if non-nil) encoded as JSON and decodes
+// the response into out (if non-nil).
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.api+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "token "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("%w: %s rejected the token (401); check that it is valid and not expired", ErrUnauthorized, req.URL.Host)
+	case resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: the token does not have permission for %s %s (403)", ErrUnauthorized, method, path)
+	case resp.StatusCode == http.StatusNotFound:
+		return errNotFound
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("%s %s: %s%s", method, path, resp.Status, apiMessage(resp.Body))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: decoding response: %w", method, path, err)
+	}
+	return nil
+}
+
+// apiMessage returns ": " followed by the message in a Gitea error response
+// body, or "" if it has none.
+func apiMessage(r io.Reader) string {
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.NewDecoder(io.LimitReader(r, 1<<16)).Decode(&body) != nil || body.Message == "" {
+		return ""
+	}
+	return ": " + body.Message
+}
+
+// repoInfo is the part of a Gitea repository we use.
+type repoInfo struct {
+	DefaultBranch string `json:"default_branch"`
+	Empty         bool   `json:"empty"`
+}
+
+// createRepo creates owner/repo, owned by the authenticated user if that is
+// owner and by the organization owner otherwise.
+func (c *client) createRepo(ctx context.Context, owner, repo string) (repoInfo, error) {
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/user", nil, &user); err != nil {
+		return repoInfo{}, fmt.Errorf("creating repository: %w", err)
+	}
+
+	path := "/orgs/" + owner + "/repos"
+	if strings.EqualFold(user.Login, owner) {
+		path = "/user/repos"
+	}
+	var info repoInfo
+	if err := c.do(ctx, http.MethodPost, path, map[string]any{"name": repo}, &info); err != nil {
+		if errors.Is(err, errNotFound) {
+			return repoInfo{}, fmt.Errorf("creating repository: no user or organization %q", owner)
+		}
+		return repoInfo{}, fmt.Errorf("creating repository: %w", err)
+	}
+	return info, nil
+}
+
+// listFiles returns the git blob hash of each file on branch, keyed by path.
+func (c *client) listFiles(ctx context.Context, owner, repo, branch string) (map[string]string, error) {
+	files := make(map[string]string)
+	for page := 1; ; page++ {
+		var tree struct {
+			Tree []struct {
+				Path string `json:"path"`
+				Type string `json:"type"`
+				SHA  string `json:"sha"`
+			} `json:"tree"`
+			TotalCount int `json:"total_count"`
+		}
+		path := "/repos/" + owner + "/" + repo + "/git/trees/" + url.PathEscape(branch) + "?recursive=true&per_page=1000&page=" + strconv.Itoa(page)
+		if err := c.do(ctx, http.MethodGet, path, nil, &tree); err != nil {
+			if errors.Is(err, errNotFound) {
+				// The branch does not exist yet
+				return files, nil
+			}
+			return nil, fmt.Errorf("listing files on %s: %w", branch, err)
+		}
+		entries := 0
+		for _, e := range tree.Tree {
+			entries++
+			if e.Type == "blob" {
+				files[e.Path] = e.SHA
+			}
+		}
+		if entries == 0 || page*1000 >= tree.TotalCount {
+			return files, nil
+		}
+	}
+}
+
+// branchCommit returns the hash of the commit at the head of branch.
+func (c *client) branchCommit(ctx context.Context, owner, repo, branch string) (string, error) {
+	var b struct {
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/repos/"+owner+"/"+repo+"/branches/"+url.PathEscape(branch), nil, &b); err != nil {
+		if errors.Is(err, errNotFound) {
+			return "", fmt.Errorf("branch %s has no commit to tag", branch)
+		}
+		return "", fmt.Errorf("looking up branch %s: %w", branch, err)
+	}
+	return b.Commit.ID, nil
+}
+
+// fileChange is one file operation in a Gitea change-files request.
+type fileChange struct {
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+	Content   string `json:"content,omitempty"`
+	SHA       string `json:"sha,omitempty"`
+}
+
+type changeFilesRequest struct {
+	Branch  string       `json:"branch,omitempty"`
+	Message string       `json:"message"`
+	Files   []fileChange `json:"files"`
+}
+
+type changeFilesResponse struct {
+	Commit struct {
+		SHA string `json:"sha"`
+	} `json:"commit"`
+}
+
+type createReleaseRequest struct {
+	TagName string `json:"tag_name"`
+	Target  string `json:"target_commitish"`
+	Name    string `json:"name"`
+}
+
+// readLocalFiles reads every file beneath dir, keyed by slash-separated
+// relative path. The .git directory is skipped.
+func readLocalFiles(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	fsys := os.DirFS(dir)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		files[name] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filepath.Clean(dir), err)
+	}
+	return files, nil
+}
+
+// blobSHA returns the git blob hash of content.
+func blobSHA(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
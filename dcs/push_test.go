@@ -0,0 +1,334 @@
+package dcs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// fakeGitea is an in-memory Gitea server holding repositories of files on
+// their default branch, "master".
+type fakeGitea struct {
+	t     *testing.T
+	token string
+	user  string
+
+	mu       sync.Mutex
+	repos    map[string]map[string][]byte // "owner/repo" -> path -> content
+	created  []string                     // POST paths used to create repos
+	commits  []changeFilesRequest
+	releases []createReleaseRequest
+}
+
+func newFakeGitea(t *testing.T) (*fakeGitea, *httptest.Server) {
+	g := &fakeGitea{t: t, token: "secret", user: "alice", repos: make(map[string]map[string][]byte)}
+	srv := httptest.NewServer(http.HandlerFunc(g.serve))
+	t.Cleanup(srv.Close)
+	return g, srv
+}
+
+func (g *fakeGitea) serve(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "token "+g.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"token is required"}`))
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && path == "/user":
+		writeJSON(w, map[string]string{"login": g.user})
+
+	case r.Method == http.MethodPost && (path == "/user/repos" || len(parts) == 3 && parts[0] == "orgs" && parts[2] == "repos"):
+		var body struct{ Name string }
+		json.NewDecoder(r.Body).Decode(&body)
+		owner := g.user
+		if parts[0] == "orgs" {
+			owner = parts[1]
+		}
+		g.repos[owner+"/"+body.Name] = make(map[string][]byte)
+		g.created = append(g.created, path)
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, repoInfo{DefaultBranch: "master", Empty: true})
+
+	case len(parts) >= 3 && parts[0] == "repos":
+		files, ok := g.repos[parts[1]+"/"+parts[2]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		switch rest := strings.Join(parts[3:], "/"); {
+		case r.Method == http.MethodGet && rest == "":
+			writeJSON(w, repoInfo{DefaultBranch: "master", Empty: len(files) == 0})
+		case r.Method == http.MethodGet && rest == "branches/master":
+			if len(files) == 0 {
+				http.NotFound(w, r)
+				return
+			}
+			writeJSON(w, map[string]any{"name": "master", "commit": map[string]string{"id": "beef"}})
+		case r.Method == http.MethodGet && rest == "git/trees/master":
+			var tree []map[string]string
+			for name, content := range files {
+				tree = append(tree, map[string]string{"path": name, "type": "blob", "sha": blobSHA(content)})
+			}
+			writeJSON(w, map[string]any{"tree": tree, "total_count": len(tree)})
+		case r.Method == http.MethodPost && rest == "contents":
+			var req changeFilesRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			for _, f := range req.Files {
+				switch f.Operation {
+				case "create":
+					files[f.Path], _ = base64.StdEncoding.DecodeString(f.Content)
+				case "update":
+					if f.SHA != blobSHA(files[f.Path]) {
+						g.t.Errorf("update %s: sha %s does not match the file", f.Path, f.SHA)
+					}
+					files[f.Path], _ = base64.StdEncoding.DecodeString(f.Content)
+				case "delete":
+					delete(files, f.Path)
+				}
+			}
+			g.commits = append(g.commits, req)
+			w.WriteHeader(http.StatusCreated)
+			writeJSON(w, map[string]any{"commit": map[string]string{"sha": "c0ffee"}})
+		case r.Method == http.MethodPost && rest == "releases":
+			var req createReleaseRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			g.releases = append(g.releases, req)
+			w.WriteHeader(http.StatusCreated)
+			writeJSON(w, map[string]any{})
+		default:
+			http.NotFound(w, r)
+		}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeSB writes files (path -> content) under a new temporary directory.
+func writeSB(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPush_CreatesRepository(t *testing.T) {
+	g, srv := newFakeGitea(t)
+	sbDir := writeSB(t, map[string]string{
+		"metadata.json":       `{}`,
+		"ingredients/GEN.tsv": "Reference\tNote\n",
+		".git/HEAD":           "ref: refs/heads/master\n",
+	})
+
+	result, err := Push(context.Background(), sbDir, PushOptions{
+		RepoURL: srv.URL + "/unfoldingWord/en_tn_sb.git",
+		Token:   "secret",
+		Message: "Convert en_tn v80",
+		Tag:     "v80",
+	})
+	if err != nil {
+		t.Fatalf("Push() error: %v", err)
+	}
+
+	if !result.Created {
+		t.Error("Created = false, want true")
+	}
+	if want := []string{"ingredients/GEN.tsv", "metadata.json"}; !reflect.DeepEqual(result.Added, want) {
+		t.Errorf("Added = %v, want %v", result.Added, want)
+	}
+	if result.Commit != "c0ffee" {
+		t.Errorf("Commit = %q, want %q", result.Commit, "c0ffee")
+	}
+	if want := []string{"/orgs/unfoldingWord/repos"}; !reflect.DeepEqual(g.created, want) {
+		t.Errorf("created with %v, want %v", g.created, want)
+	}
+	if got := string(g.repos["unfoldingWord/en_tn_sb"]["ingredients/GEN.tsv"]); got != "Reference\tNote\n" {
+		t.Errorf("uploaded GEN.tsv = %q", got)
+	}
+	if len(g.commits) != 1 || g.commits[0].Message != "Convert en_tn v80" {
+		t.Errorf("commits = %+v, want one with the given message", g.commits)
+	}
+	if len(g.releases) != 1 || g.releases[0].TagName != "v80" || g.releases[0].Target != "c0ffee" {
+		t.Errorf("releases = %+v, want v80 at c0ffee", g.releases)
+	}
+}
+
+func TestPush_CreatesUserRepository(t *testing.T) {
+	g, srv := newFakeGitea(t)
+	sbDir := writeSB(t, map[string]string{"metadata.json": `{}`})
+
+	if _, err := Push(context.Background(), sbDir, PushOptions{RepoURL: srv.URL + "/alice/en_tn_sb", Token: "secret"}); err != nil {
+		t.Fatalf("Push() error: %v", err)
+	}
+	if want := []string{"/user/repos"}; !reflect.DeepEqual(g.created, want) {
+		t.Errorf("created with %v, want %v", g.created, want)
+	}
+}
+
+func TestPush_UpdatesRepository(t *testing.T) {
+	g, srv := newFakeGitea(t)
+	g.repos["unfoldingWord/en_tn_sb"] = map[string][]byte{
+		"metadata.json":       []byte(`{"old":true}`),
+		"LICENSE.md":          []byte("license\n"),
+		"ingredients/OLD.tsv": []byte("stale\n"),
+	}
+	sbDir := writeSB(t, map[string]string{
+		"metadata.json":       `{}`,
+		"LICENSE.md":          "license\n",
+		"ingredients/GEN.tsv": "Reference\tNote\n",
+	})
+	opts := PushOptions{RepoURL: srv.URL + "/unfoldingWord/en_tn_sb", Token: "secret", Message: "update"}
+
+	result, err := Push(context.Background(), sbDir, opts)
+	if err != nil {
+		t.Fatalf("Push() error: %v", err)
+	}
+
+	want := PushResult{
+		Added:   []string{"ingredients/GEN.tsv"},
+		Updated: []string{"metadata.json"},
+		Deleted: []string{"ingredients/OLD.tsv"},
+		Commit:  "c0ffee",
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+	if len(g.created) != 0 {
+		t.Errorf("created %v, want no new repository", g.created)
+	}
+	if len(g.commits) != 1 || len(g.commits[0].Files) != 3 {
+		t.Fatalf("commits = %+v, want one changing 3 files (LICENSE.md unchanged)", g.commits)
+	}
+
+	// Pushing the same files again changes nothing
+	result, err = Push(context.Background(), sbDir, opts)
+	if err != nil {
+		t.Fatalf("second Push() error: %v", err)
+	}
+	if result.Changed() || result.Commit != "" {
+		t.Errorf("second push result = %+v, want no changes", result)
+	}
+	if len(g.commits) != 1 {
+		t.Errorf("second push made a commit")
+	}
+}
+
+func TestPush_TagWithNoChanges(t *testing.T) {
+	g, srv := newFakeGitea(t)
+	g.repos["unfoldingWord/en_tn_sb"] = map[string][]byte{"metadata.json": []byte(`{}`)}
+	sbDir := writeSB(t, map[string]string{"metadata.json": `{}`})
+
+	result, err := Push(context.Background(), sbDir, PushOptions{
+		RepoURL: srv.URL + "/unfoldingWord/en_tn_sb",
+		Token:   "secret",
+		Tag:     "v81",
+	})
+	if err != nil {
+		t.Fatalf("Push() error: %v", err)
+	}
+	if result.Changed() || result.Commit != "" {
+		t.Errorf("result = %+v, want no changes", result)
+	}
+	if len(g.commits) != 0 {
+		t.Errorf("commits = %+v, want none", g.commits)
+	}
+	if len(g.releases) != 1 || g.releases[0].TagName != "v81" || g.releases[0].Target != "beef" {
+		t.Errorf("releases = %+v, want v81 at the branch head beef", g.releases)
+	}
+}
+
+func TestPush_DryRun(t *testing.T) {
+	g, srv := newFakeGitea(t)
+	sbDir := writeSB(t, map[string]string{"metadata.json": `{}`})
+
+	result, err := Push(context.Background(), sbDir, PushOptions{
+		RepoURL: srv.URL + "/unfoldingWord/en_tn_sb",
+		Token:   "secret",
+		Tag:     "v1",
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatalf("Push() error: %v", err)
+	}
+	if !result.Created || !reflect.DeepEqual(result.Added, []string{"metadata.json"}) {
+		t.Errorf("result = %+v, want repository created and metadata.json added", result)
+	}
+	if len(g.created)+len(g.commits)+len(g.releases) != 0 {
+		t.Errorf("dry run changed the server: created %v, commits %v, releases %v", g.created, g.commits, g.releases)
+	}
+}
+
+func TestPush_Unauthorized(t *testing.T) {
+	g, srv := newFakeGitea(t)
+	g.repos["unfoldingWord/en_tn_sb"] = map[string][]byte{}
+	sbDir := writeSB(t, map[string]string{"metadata.json": `{}`})
+
+	_, err := Push(context.Background(), sbDir, PushOptions{RepoURL: srv.URL + "/unfoldingWord/en_tn_sb", Token: "wrong"})
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Push() error = %v, want ErrUnauthorized", err)
+	}
+	if !strings.Contains(err.Error(), "401") {
+		t.Errorf("error %q should mention the 401 status", err)
+	}
+	if len(g.commits) != 0 {
+		t.Error("a commit was made with a rejected token")
+	}
+}
+
+func TestPush_InvalidRepoURL(t *testing.T) {
+	sbDir := writeSB(t, map[string]string{"metadata.json": `{}`})
+	for _, u := range []string{"", "not a url", "https://git.door43.org/unfoldingWord"} {
+		if _, err := Push(context.Background(), sbDir, PushOptions{RepoURL: u}); err == nil {
+			t.Errorf("Push(%q) succeeded, want an error", u)
+		}
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	m := &sb.Metadata{}
+	m.Identification.Name = map[string]string{"en": "unfoldingWord Translation Notes"}
+	m.Meta.Generator.SoftwareName = "rc2sb"
+	m.Meta.Generator.SoftwareVersion = "1.2.3"
+
+	tests := []struct {
+		source, version, want string
+	}{
+		{"unfoldingWord/en_tn", "v80", "Convert unfoldingWord/en_tn v80 to Scripture Burrito (rc2sb 1.2.3)"},
+		{"", "", "Convert unfoldingWord Translation Notes to Scripture Burrito (rc2sb 1.2.3)"},
+	}
+	for _, tt := range tests {
+		if got := CommitMessage(m, tt.source, tt.version); got != tt.want {
+			t.Errorf("CommitMessage(%q, %q) = %q, want %q", tt.source, tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package rc2sb
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// DiffReport is the result of DiffSB. Ingredient lists are sorted by key, and
+// Fields by path.
+type DiffReport struct {
+	// Added lists the ingredients of the second SB that the first does not
+	// have, and Removed those of the first that the second does not have.
+	Added   []IngredientDiff
+	Removed []IngredientDiff
+
+	// Modified lists the ingredients of both whose checksum or size differs.
+	Modified []IngredientDiff
+
+	// Fields lists the other fields of metadata.json that differ, including
+	// the mimeType, scope, role, and x-source of ingredients in both.
+	Fields []FieldDiff
+}
+
+// Empty reports whether the two SBs were found to be the same.
+func (r DiffReport) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Modified) == 0 && len(r.Fields) == 0
+}
+
+// IngredientDiff is an ingredient added, removed, or modified between two SBs.
+type IngredientDiff struct {
+	Key string
+
+	// ASize and BSize are the ingredient's size in bytes in the first and
+	// second SB, or 0 in the one that does not have it.
+	ASize, BSize int64
+}
+
+// FieldDiff is a metadata.json field that differs between two SBs.
+type FieldDiff struct {
+	// Path locates the field, e.g., "meta.category" or
+	// `ingredients["ingredients/GEN.tsv"].role`.
+	Path string
+
+	// A and B are the field's JSON values in the first and second SB, or ""
+	// in the one that does not have it.
+	A, B string
+}
+
+// DiffOptions configures DiffSB.
+type DiffOptions struct {
+	// IncludeTimestamps also reports differences in meta.dateCreated and the
+	// identification timestamps, which differ between any two conversions.
+	IncludeTimestamps bool
+}
+
+// DiffSB compares the Scripture Burritos at aDir and bDir (e.g., the output of
+// two rc2sb versions for the same RC) by their metadata.json: the ingredients
+// added, removed, or modified, going by their checksums and sizes, and every
+// other field that differs. Ingredient files themselves are not read.
+func DiffSB(aDir, bDir string, opts DiffOptions) (DiffReport, error) {
+	a, err := sb.LoadMetadata(aDir)
+	if err != nil {
+		return DiffReport{}, fmt.Errorf("%s: %w", aDir, err)
+	}
+	b, err := sb.LoadMetadata(bDir)
+	if err != nil {
+		return DiffReport{}, fmt.Errorf("%s: %w", bDir, err)
+	}
+
+	var report DiffReport
+	for _, key := range slices.Sorted(maps.Keys(a.Ingredients)) {
+		ia := a.Ingredients[key]
+		ib, ok := b.Ingredients[key]
+		switch {
+		case !ok:
+			report.Removed = append(report.Removed, IngredientDiff{Key: key, ASize: ia.Size})
+		case ia.Checksum != ib.Checksum || ia.Size != ib.Size:
+			report.Modified = append(report.Modified, IngredientDiff{Key: key, ASize: ia.Size, BSize: ib.Size})
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(b.Ingredients)) {
+		if _, ok := a.Ingredients[key]; !ok {
+			report.Added = append(report.Added, IngredientDiff{Key: key, BSize: b.Ingredients[key].Size})
+		}
+	}
+
+	ta, err := metadataTree(a, b)
+	if err != nil {
+		return DiffReport{}, err
+	}
+	tb, err := metadataTree(b, a)
+	if err != nil {
+		return DiffReport{}, err
+	}
+	ignore := func(path string) bool {
+		return !opts.IncludeTimestamps && (path == "meta.dateCreated" ||
+			strings.HasPrefix(path, "identification.primary") && strings.HasSuffix(path, ".timestamp"))
+	}
+	diffTree("", ta, tb, ignore, &report.Fields)
+	slices.SortFunc(report.Fields, func(x, y FieldDiff) int { return strings.Compare(x.Path, y.Path) })
+	return report, nil
+}
+
+// metadataTree returns m as decoded JSON, keeping only the ingredients other
+// also has, without the checksums and sizes that DiffSB compares itself.
+func metadataTree(m, other *sb.Metadata) (map[string]any, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling metadata.json: %w", err)
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("decoding metadata.json: %w", err)
+	}
+	ingredients, _ := tree["ingredients"].(map[string]any)
+	for key, ing := range ingredients {
+		if _, ok := other.Ingredients[key]; !ok {
+			delete(ingredients, key)
+			continue
+		}
+		if fields, ok := ing.(map[string]any); ok {
+			delete(fields, "checksum")
+			delete(fields, "size")
+		}
+	}
+	return tree, nil
+}
+
+// diffTree appends to diffs the fields of the decoded JSON values a and b,
+// at path, that differ and are not ignored. A field only one has is reported
+// with "" for the other.
+func diffTree(path string, a, b any, ignore func(path string) bool, diffs *[]FieldDiff) {
+	if ignore(path) {
+		return
+	}
+	ma, aIsMap := a.(map[string]any)
+	mb, bIsMap := b.(map[string]any)
+	if aIsMap && bIsMap {
+		keys := slices.Collect(maps.Keys(ma))
+		for key := range mb {
+			if _, ok := ma[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		for _, key := range keys {
+			diffTree(fieldPath(path, key), ma[key], mb[key], ignore, diffs)
+		}
+		return
+	}
+	la, aIsList := a.([]any)
+	lb, bIsList := b.([]any)
+	if aIsList && bIsList {
+		for i := range max(len(la), len(lb)) {
+			var ea, eb any
+			if i < len(la) {
+				ea = la[i]
+			}
+			if i < len(lb) {
+				eb = lb[i]
+			}
+			diffTree(path+"["+strconv.Itoa(i)+"]", ea, eb, ignore, diffs)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		*diffs = append(*diffs, FieldDiff{Path: path, A: jsonValue(a), B: jsonValue(b)})
+	}
+}
+
+// plainKey matches object keys that can follow a "." in a field path.
+var plainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// fieldPath returns the path of the field key of the object at path.
+func fieldPath(path, key string) string {
+	if !plainKey.MatchString(key) {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonValue returns v encoded as JSON, or "" if v is absent.
+func jsonValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,108 @@
+package rc2sb_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+func TestDiffSB(t *testing.T) {
+	ctx := context.Background()
+	inDir, aDir, bDir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, compareTNFiles)
+	if _, err := rc2sb.Convert(ctx, inDir, aDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if err := os.CopyFS(bDir, os.DirFS(aDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := rc2sb.DiffSB(aDir, bDir, rc2sb.DiffOptions{})
+	if err != nil {
+		t.Fatalf("DiffSB failed: %v", err)
+	}
+	if !report.Empty() {
+		t.Errorf("report for a copy = %+v; want empty", report)
+	}
+
+	// Modify one file, refreshing its checksum, and tweak the metadata
+	const note = "Reference\tID\n1:1\tabcd\n"
+	if err := os.WriteFile(filepath.Join(bDir, "ingredients", "GEN.tsv"), []byte(note), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rc2sb.UpdateMetadata(ctx, bDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	m, err := sb.LoadMetadata(bDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := sb.LoadMetadata(aDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Meta.Category = "derived"
+	if err := m.WriteToFile(bDir); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = rc2sb.DiffSB(aDir, bDir, rc2sb.DiffOptions{})
+	if err != nil {
+		t.Fatalf("DiffSB failed: %v", err)
+	}
+	want := rc2sb.DiffReport{
+		Modified: []rc2sb.IngredientDiff{{Key: "ingredients/GEN.tsv", ASize: a.Ingredients["ingredients/GEN.tsv"].Size, BSize: int64(len(note))}},
+		Fields:   []rc2sb.FieldDiff{{Path: "meta.category", A: `"source"`, B: `"derived"`}},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report = %+v; want %+v", report, want)
+	}
+
+	// Timestamps are only reported on request
+	m.Meta.DateCreated = "2000-01-01T00:00:00.000Z"
+	delete(m.Ingredients, "ingredients/MAT.tsv")
+	m.Ingredients["ingredients/EXO.tsv"] = sb.Ingredient{Size: 3, Role: "x-notes"}
+	if err := m.WriteToFile(bDir); err != nil {
+		t.Fatal(err)
+	}
+	report, err = rc2sb.DiffSB(aDir, bDir, rc2sb.DiffOptions{IncludeTimestamps: true})
+	if err != nil {
+		t.Fatalf("DiffSB failed: %v", err)
+	}
+	if len(report.Fields) != 2 || report.Fields[1].Path != "meta.dateCreated" || report.Fields[1].B != `"2000-01-01T00:00:00.000Z"` {
+		t.Errorf("fields = %+v; want meta.category and meta.dateCreated", report.Fields)
+	}
+	if !reflect.DeepEqual(report.Added, []rc2sb.IngredientDiff{{Key: "ingredients/EXO.tsv", BSize: 3}}) ||
+		len(report.Removed) != 1 || report.Removed[0].Key != "ingredients/MAT.tsv" {
+		t.Errorf("added %+v, removed %+v; want EXO.tsv added and MAT.tsv removed", report.Added, report.Removed)
+	}
+
+	if _, err := rc2sb.DiffSB(aDir, t.TempDir(), rc2sb.DiffOptions{}); err == nil {
+		t.Error("expected error for an SB with no metadata.json")
+	}
+}
+
+func TestDiffSB_IngredientFields(t *testing.T) {
+	aDir, bDir := t.TempDir(), t.TempDir()
+	for dir, role := range map[string]string{aDir: "x-links", bDir: "x-payload"} {
+		m := sb.NewMetadata()
+		m.Ingredients["ingredients/GEN.tsv"] = sb.Ingredient{Size: 1, Role: role}
+		if err := m.WriteToFile(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := rc2sb.DiffSB(aDir, bDir, rc2sb.DiffOptions{})
+	if err != nil {
+		t.Fatalf("DiffSB failed: %v", err)
+	}
+	want := []rc2sb.FieldDiff{{Path: `ingredients["ingredients/GEN.tsv"].role`, A: `"x-links"`, B: `"x-payload"`}}
+	if !reflect.DeepEqual(report.Fields, want) || len(report.Modified) != 0 {
+		t.Errorf("report = %+v; want only %+v", report, want)
+	}
+}
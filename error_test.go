@@ -2,6 +2,8 @@ package rc2sb_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -91,6 +93,76 @@ projects:
 	}
 }
 
+// cancelAfterCtx is a context whose Err starts returning context.Canceled
+// after a fixed number of calls, simulating a cancellation mid-conversion.
+type cancelAfterCtx struct {
+	context.Context
+	remaining int
+}
+
+func (c *cancelAfterCtx) Err() error {
+	if c.remaining <= 0 {
+		return context.Canceled
+	}
+	c.remaining--
+	return nil
+}
+
+func TestConvert_CancelledMidConversion(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	yaml := `dublin_core:
+  subject: 'Translation Words'
+  identifier: 'tw'
+  title: 'Test'
+  language:
+    identifier: 'en'
+    title: 'English'
+    direction: 'ltr'
+projects:
+  - identifier: 'bible'
+    path: './bible'
+`
+	if err := os.WriteFile(filepath.Join(inDir, "manifest.yaml"), []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Generate a large TW tree
+	const total = 2000
+	for i := 0; i < total; i++ {
+		dir := filepath.Join(inDir, "bible", fmt.Sprintf("cat%02d", i%20))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("article%04d.md", i)), []byte("# Article\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := &cancelAfterCtx{Context: context.Background(), remaining: 100}
+
+	_, err := rc2sb.Convert(ctx, inDir, outDir, rc2sb.Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled error, got %v", err)
+	}
+
+	// The walk must stop promptly rather than copying the whole tree.
+	copied := 0
+	filepath.Walk(filepath.Join(outDir, "ingredients"), func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			copied++
+		}
+		return nil
+	})
+	if copied >= total {
+		t.Errorf("copied %d of %d files after cancellation; expected the copy to stop early", copied, total)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "metadata.json")); !os.IsNotExist(err) {
+		t.Error("metadata.json should not be written when the conversion is cancelled")
+	}
+}
+
 func TestConvert_InvalidYAML(t *testing.T) {
 	inDir := t.TempDir()
 	outDir := t.TempDir()
@@ -104,3 +176,66 @@ func TestConvert_InvalidYAML(t *testing.T) {
 		t.Fatal("expected error for invalid YAML")
 	}
 }
+
+func TestConvert_UnsupportedSubjectSuggestion(t *testing.T) {
+	inDir := t.TempDir()
+	yaml := "dublin_core:\n  subject: 'translation words'\n  identifier: 'tw'\n"
+	if err := os.WriteFile(filepath.Join(inDir, "manifest.yaml"), []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := rc2sb.Convert(context.Background(), inDir, t.TempDir(), rc2sb.Options{})
+	if err == nil {
+		t.Fatal("expected error for unsupported subject")
+	}
+	if !strings.Contains(err.Error(), `did you mean "Translation Words"?`) {
+		t.Errorf("error should suggest the closest subject: %v", err)
+	}
+}
+
+func TestConvert_RefusesSB(t *testing.T) {
+	inDir := t.TempDir()
+	writeRepoFiles(t, inDir, archiveOBSFiles)
+	sbDir := t.TempDir()
+	if _, err := rc2sb.Convert(context.Background(), inDir, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	outDir := t.TempDir()
+	_, err := rc2sb.Convert(context.Background(), sbDir, outDir, rc2sb.Options{})
+	if !errors.Is(err, rc2sb.ErrAlreadySB) {
+		t.Fatalf("error = %v; want ErrAlreadySB", err)
+	}
+	if !strings.Contains(err.Error(), "UpdateMetadata") {
+		t.Errorf("error should suggest UpdateMetadata: %v", err)
+	}
+	if _, err := rc2sb.ConvertFS(context.Background(), os.DirFS(sbDir), outDir, rc2sb.Options{}); !errors.Is(err, rc2sb.ErrAlreadySB) {
+		t.Errorf("ConvertFS error = %v; want ErrAlreadySB", err)
+	}
+	if entries, _ := os.ReadDir(outDir); len(entries) != 0 {
+		t.Errorf("outDir has %d entries; want none", len(entries))
+	}
+}
+
+func TestConvert_IDAuthorityWithoutURL(t *testing.T) {
+	inDir := t.TempDir()
+	writeRepoFiles(t, inDir, archiveOBSFiles)
+	outDir := t.TempDir()
+
+	opts := rc2sb.Options{IDAuthority: rc2sb.IDAuthority{Key: "myOrg"}}
+	_, err := rc2sb.Convert(context.Background(), inDir, outDir, opts)
+	if err == nil || !strings.Contains(err.Error(), `ID authority "myOrg" has no URL`) {
+		t.Fatalf("error = %v; want a missing URL error", err)
+	}
+}
+
+func TestConvert_InvalidMimeOverride(t *testing.T) {
+	inDir := t.TempDir()
+	writeRepoFiles(t, inDir, archiveOBSFiles)
+	outDir := t.TempDir()
+
+	opts := rc2sb.Options{MimeOverrides: map[string]string{"ingredients/[": "text/plain"}}
+	if _, err := rc2sb.Convert(context.Background(), inDir, outDir, opts); err == nil || !strings.Contains(err.Error(), "invalid MIME override") {
+		t.Fatalf("error = %v; want an invalid MIME override error", err)
+	}
+}
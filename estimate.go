@@ -0,0 +1,180 @@
+package rc2sb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/handler"
+	"github.com/unfoldingWord/go-rc2sb/rc"
+)
+
+// EstimateSize returns roughly how many bytes converting the RC repository
+// at inDir with opts would write, without reading or writing any content. It
+// adds up the sizes of the files Convert would copy: the content of each
+// project whose ingredient IncludeGlobs and ExcludeGlobs select (and, for
+// subjects split by book, that Books selects), the TWL payload, the common
+// root files, and the license, or the default LICENSE.md if the repository
+// has none. metadata.json is not counted.
+//
+// The estimate is exact for files copied as they are, but not for those
+// Convert changes on the way, such as TWL files whose links are rewritten;
+// and with Books, the whole TWL payload is counted, not only the articles the
+// books link to. Only the subjects ConvertSBToRC covers can be estimated, and
+// an inDir that is already an SB is refused with ErrAlreadySB.
+func EstimateSize(ctx context.Context, inDir string, opts Options) (int64, error) {
+	// Check context
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("context error: %w", err)
+	}
+
+	fsys := os.DirFS(inDir)
+	if err := checkNotSB(fsys); err != nil {
+		return 0, fmt.Errorf("%s: %w", inDir, err)
+	}
+	manifest, err := rc.LoadManifest(inDir)
+	if err != nil {
+		return 0, err
+	}
+
+	subject := manifest.DublinCore.Subject
+	if opts.SubjectOverride != "" {
+		subject = opts.SubjectOverride
+	}
+	layout, ok := rcLayouts[subject]
+	if !ok {
+		return 0, fmt.Errorf("%w %q for size estimates", handler.ErrUnsupportedSubject, subject)
+	}
+
+	var filter *handler.Filter
+	if len(opts.IncludeGlobs) > 0 || len(opts.ExcludeGlobs) > 0 {
+		if filter, err = handler.NewFilter(opts.IncludeGlobs, opts.ExcludeGlobs); err != nil {
+			return 0, err
+		}
+	}
+
+	var size int64
+
+	// Root files, and the license, which subjects with a tree of articles
+	// also copy to the SB root
+	size += fileSize(fsys, "README.md") + fileSize(fsys, ".gitignore")
+	for _, dir := range []string{".gitea", ".github"} {
+		n, err := treeSize(ctx, fsys, dir, "", nil)
+		if err != nil {
+			return 0, err
+		}
+		size += n
+	}
+	license := int64(len(handler.DefaultLicense(manifest.DublinCore.Rights)))
+	for _, name := range handler.LicenseNames {
+		if info, err := fs.Stat(fsys, name); err == nil && !info.IsDir() {
+			license = info.Size()
+			break
+		}
+	}
+	size += license
+	if layout.kind == articleTree || layout.kind == projectDirs {
+		size += license
+	}
+	if opts.IncludeSourceManifest {
+		size += fileSize(fsys, "manifest.yaml") + fileSize(fsys, "media.yaml")
+	}
+
+	// Content
+	projects := manifest.Projects
+	if len(projects) == 0 && layout.kind == articleTree {
+		projects = []rc.Project{{Identifier: layout.project, Path: "./" + layout.rcDir}}
+	}
+	for _, project := range projects {
+		if layout.kind == bookFiles && len(opts.Books) > 0 &&
+			!slices.ContainsFunc(opts.Books, func(book string) bool { return strings.EqualFold(book, project.Identifier) }) {
+			continue
+		}
+		names, err := projectFiles(fsys, project)
+		if err != nil {
+			return 0, err
+		}
+		for _, name := range names {
+			if err := ctx.Err(); err != nil {
+				return 0, fmt.Errorf("context error: %w", err)
+			}
+			if filter.Allows(expectedIngredient(layout, project, name)) {
+				size += fileSize(fsys, name)
+			}
+		}
+	}
+
+	// TWL payload
+	if layout.payload {
+		if dir := twPayloadDir(inDir, manifest.DublinCore.Language.Identifier, opts); dir != "" {
+			n, err := treeSize(ctx, os.DirFS(dir), ".", "ingredients/payload", filter)
+			if err != nil {
+				return 0, err
+			}
+			size += n
+		}
+	}
+	return size, nil
+}
+
+// fileSize returns the size of the file name in fsys, or 0 if it is missing
+// or a directory.
+func fileSize(fsys fs.FS, name string) int64 {
+	info, err := fs.Stat(fsys, name)
+	if err != nil || info.IsDir() {
+		return 0
+	}
+	return info.Size()
+}
+
+// treeSize returns the total size of the files beneath root in fsys, if it
+// exists, leaving out those whose key (keyPrefix followed by the path beneath
+// root) filter does not allow. Symbolic links to directories are not
+// followed, as Convert does not follow them.
+func treeSize(ctx context.Context, fsys fs.FS, root, keyPrefix string, filter *handler.Filter) (int64, error) {
+	var size int64
+	err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if name == root && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("context error: %w", err)
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if keyPrefix != "" && !filter.Allows(path.Join(keyPrefix, strings.TrimPrefix(name, root+"/"))) {
+			return nil
+		}
+		size += fileSize(fsys, name)
+		return nil
+	})
+	return size, err
+}
+
+// twPayloadDir returns the bible/ directory the TWL handler copies the
+// payload from: that of opts.PayloadPath, or of <lang>_tw in inDir or beside
+// it, or "" if there is none.
+func twPayloadDir(inDir, lang string, opts Options) string {
+	dirs := []string{filepath.Join(inDir, lang+"_tw")}
+	if opts.PayloadPath != "" {
+		dirs = []string{opts.PayloadPath}
+	} else if abs, err := filepath.Abs(inDir); err == nil {
+		dirs = append(dirs, filepath.Join(filepath.Dir(abs), lang+"_tw"))
+	}
+	for _, dir := range dirs {
+		if info, err := os.Stat(filepath.Join(dir, "bible")); err == nil && info.IsDir() {
+			return filepath.Join(dir, "bible")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package rc2sb_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+)
+
+func TestEstimateSize_MatchesConversion(t *testing.T) {
+	noLicense := checkRepoFiles()
+	delete(noLicense, "LICENSE.md")
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		opts  rc2sb.Options
+
+		// tolerance is how far off the estimate may be, for files Convert
+		// changes as it copies them
+		tolerance int64
+	}{
+		{"TN", checkRepoFiles(), rc2sb.Options{}, 0},
+		{"TN with default LICENSE.md", noLicense, rc2sb.Options{}, 0},
+		// The TWLink rc://*/tw/dict/bible/kt/god becomes ./payload/kt/god.md
+		{"TWL with payload", convertFSTestFiles, rc2sb.Options{}, 8},
+		{"OBS", archiveOBSFiles, rc2sb.Options{}, 0},
+		{"OBS excluding front matter", archiveOBSFiles, rc2sb.Options{ExcludeGlobs: []string{"ingredients/content/front.md"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			inDir := t.TempDir()
+			writeRepoFiles(t, inDir, tt.files)
+
+			estimate, err := rc2sb.EstimateSize(ctx, inDir, tt.opts)
+			if err != nil {
+				t.Fatalf("EstimateSize failed: %v", err)
+			}
+
+			outDir := t.TempDir()
+			if _, err := rc2sb.Convert(ctx, inDir, outDir, tt.opts); err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+			var actual int64
+			err = filepath.WalkDir(outDir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() || path == filepath.Join(outDir, "metadata.json") {
+					return err
+				}
+				info, err := d.Info()
+				if err != nil {
+					return err
+				}
+				actual += info.Size()
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := estimate - actual; diff < -tt.tolerance || diff > tt.tolerance {
+				t.Errorf("EstimateSize = %d; Convert wrote %d bytes besides metadata.json", estimate, actual)
+			}
+		})
+	}
+}
+
+func TestEstimateSize_NoManifest(t *testing.T) {
+	if _, err := rc2sb.EstimateSize(context.Background(), t.TempDir(), rc2sb.Options{}); err == nil {
+		t.Error("expected error for a directory with no manifest.yaml")
+	}
+}
+
+func TestEstimateSize_AlreadySB(t *testing.T) {
+	inDir, sbDir := t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, archiveOBSFiles)
+	if _, err := rc2sb.Convert(context.Background(), inDir, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if _, err := rc2sb.EstimateSize(context.Background(), sbDir, rc2sb.Options{}); !errors.Is(err, rc2sb.ErrAlreadySB) {
+		t.Errorf("EstimateSize(sbDir) error = %v; want ErrAlreadySB", err)
+	}
+}
@@ -0,0 +1,134 @@
+package rc2sb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Fetcher fetches a remote RC repository for ConvertGit.
+type Fetcher interface {
+	// Fetch checks out ref (the default branch if empty) of the repository at
+	// url into the empty directory dir and returns the commit hash checked out.
+	Fetch(ctx context.Context, url, ref, dir string) (commit string, err error)
+}
+
+// GitCLI is a Fetcher that makes a shallow clone with the git command, which
+// must be on the PATH.
+type GitCLI struct {
+	// Token, if set, authenticates HTTP(S) requests, e.g., with a Door43
+	// (Gitea) or GitHub access token for a private repository.
+	Token string
+}
+
+// Fetch implements Fetcher. The ref must name a branch or tag.
+func (g GitCLI) Fetch(ctx context.Context, url, ref, dir string) (string, error) {
+	args := []string{"clone", "--quiet", "--depth", "1", "--single-branch"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	args = append(args, "--", url, dir)
+	if _, err := g.run(ctx, "", args...); err != nil {
+		return "", err
+	}
+
+	commit, err := g.run(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(commit), nil
+}
+
+// run runs git with args in dir and returns its standard output. The error
+// includes whatever git printed to standard error.
+func (g GitCLI) run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if g.Token != "" {
+		// Pass the header through the environment so the token does not
+		// appear in the process list
+		auth := base64.StdEncoding.EncodeToString([]byte(g.Token + ":x-oauth-basic"))
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+		)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// scpLikeURL matches git's scp-like SSH syntax, e.g., "git@host:owner/repo.git".
+var scpLikeURL = regexp.MustCompile(`^[\w.-]+@[\w.-]+:`)
+
+// IsGitURL reports whether s is a git repository URL (http, https, git, ssh,
+// file, or scp-like "user@host:path") rather than a local directory, ignoring
+// any "#ref" suffix.
+func IsGitURL(s string) bool {
+	for _, scheme := range []string{"https://", "http://", "git://", "ssh://", "file://"} {
+		if strings.HasPrefix(s, scheme) {
+			return true
+		}
+	}
+	return scpLikeURL.MatchString(s)
+}
+
+// ConvertGit fetches the RC repository at the git URL rawURL into a temporary
+// directory, converts it to SB format in outDir, and removes the temporary
+// directory. A "#ref" suffix on rawURL selects a branch or tag; otherwise the
+// default branch is used. The repository is fetched with opts.Fetcher, or
+// with GitCLI if it is nil.
+// Result.InDir is rawURL and Result.Commit is the commit that was converted.
+func ConvertGit(ctx context.Context, rawURL, outDir string, opts Options) (Result, error) {
+	// Check context
+	if err := ctx.Err(); err != nil {
+		return Result{}, fmt.Errorf("context error: %w", err)
+	}
+
+	url, ref, _ := strings.Cut(rawURL, "#")
+	fetcher := opts.Fetcher
+	if fetcher == nil {
+		fetcher = GitCLI{}
+	}
+
+	// Clone into a subdirectory of a fresh temporary directory, so that the
+	// clone has no siblings: the <lang>_tw and <lang>_ult lookups beside it
+	// must not pick up whatever else happens to be in $TMPDIR.
+	tmpDir, err := os.MkdirTemp("", "rc2sb-git-")
+	if err != nil {
+		return Result{}, fmt.Errorf("creating clone directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	cloneDir := filepath.Join(tmpDir, "repo")
+	if err := os.Mkdir(cloneDir, 0755); err != nil {
+		return Result{}, fmt.Errorf("creating clone directory: %w", err)
+	}
+
+	commit, err := fetcher.Fetch(ctx, url, ref, cloneDir)
+	if err != nil {
+		return Result{}, fmt.Errorf("fetching %s: %w", rawURL, err)
+	}
+	if opts.Logger != nil {
+		opts.Logger.Info("fetched repository", "url", url, "ref", ref, "commit", commit)
+	}
+
+	result, err := Convert(ctx, cloneDir, outDir, opts)
+	result.InDir = rawURL
+	result.Commit = commit
+	return result, err
+}
@@ -3,3 +3,5 @@ module github.com/unfoldingWord/go-rc2sb
 go 1.25.0
 
 require gopkg.in/yaml.v3 v3.0.1
+
+require golang.org/x/text v0.41.0
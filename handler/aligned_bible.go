@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"path"
+	"regexp"
 	"strings"
 
 	"github.com/unfoldingWord/go-rc2sb/books"
@@ -25,6 +27,14 @@ type bibleHandler struct {
 	subject string
 }
 
+// originalLanguageTags maps the subjects of original-language Bibles to the
+// language tag their localized book names are recorded under, whatever the
+// manifest's language identifier (e.g., "el-x-koine" for the UGNT).
+var originalLanguageTags = map[string]string{
+	"Greek New Testament":  "grc",
+	"Hebrew Old Testament": "hbo",
+}
+
 func (h *bibleHandler) Subject() string {
 	return h.subject
 }
@@ -34,10 +44,14 @@ func (h *bibleHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir
 		return nil, err
 	}
 
-	m := BuildBaseMetadata(manifest, "uWBurritos", "")
+	m := MapManifest(manifest, MetadataOptions{
+		IDAuthority:        AuthorityUW,
+		Authority:          opts.IDAuthority,
+		CopyrightStatement: opts.CopyrightStatement,
+		Date:               opts.date(),
+	})
 
 	// Set type - scripture/textTranslation
-	currentScope := make(map[string][]string)
 	m.Type = sb.Type{
 		FlavorType: sb.FlavorType{
 			Name: "scripture",
@@ -51,80 +65,163 @@ func (h *bibleHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir
 		},
 	}
 
-	m.Copyright = BuildCopyright(manifest, false)
-
 	lang := manifest.DublinCore.Language.Identifier
+	if tag, ok := originalLanguageTags[h.subject]; ok {
+		lang = tag
+	}
+	src := newRCSource(inDir, opts)
+	src.alignment = &alignmentDetector{}
+	out := newOutput(outDir, opts)
+	license := defaultLicenseFor(src, manifest, opts)
 
 	// Process each project
 	for _, project := range manifest.Projects {
 		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
+		if !opts.includesBook(project.Identifier) {
+			continue
+		}
 
 		// Get the source file path
-		srcPath := filepath.Join(inDir, strings.TrimPrefix(project.Path, "./"))
-		if _, err := os.Stat(srcPath); os.IsNotExist(err) {
+		srcName := projectFile(project.Path)
+		if !src.exists(srcName) {
+			src.skipProject(project, srcName)
 			continue
 		}
-		srcFilename := filepath.Base(srcPath)
+		srcFilename := path.Base(srcName)
 
-		// Convert filename: "01-GEN.usfm" -> "GEN.usfm"
+		// Convert filename: "01-GEN.usfm" or "01_GEN.usfm" -> "GEN.usfm"
 		bookCode := extractBookCode(srcFilename)
 		destFilename := bookCode + ".usfm"
 		ingredientKey := "ingredients/" + destFilename
+		if !src.allows(ingredientKey) {
+			// Filtered out: leave the book out of scope and localized names too
+			continue
+		}
 
 		// Determine scope
 		bookID := strings.ToLower(project.Identifier)
 		var scope map[string][]string
+		matter := books.MatterByID(bookID)
+		if matter == nil && !books.IsBookID(bookID) {
+			matter = books.MatterByID(bookCode)
+		}
 
-		if books.IsBookID(bookID) {
+		switch {
+		case books.IsBookID(bookID):
 			code := books.CodeFromProjectID(bookID)
 			scope = map[string][]string{code: {}}
-			currentScope[code] = []string{}
 
 			// Parse USFM file for localized book names (\toc1, \toc2, \toc3)
-			usfmNames := books.ParseUSFMBookNames(srcPath)
+			usfmNames := books.ParseUSFMBookNamesFS(src.fsys, srcName)
 
 			// Add localized name using: USFM > manifest project title > English fallback
 			key, localizedName := books.LocalizedNameEntryWithNames(bookID, lang, project.Title, usfmNames)
 			if key != "" {
 				m.LocalizedNames[key] = localizedName
 			}
+		case matter != nil:
+			// Front matter and the like: no scope, and named apart from the books
+			usfmNames := books.ParseUSFMBookNamesFS(src.fsys, srcName)
+			key, localizedName := books.MatterNameEntry(matter.ID, lang, project.Title, usfmNames)
+			m.LocalizedNames[key] = localizedName
+			opts.warn("project %q is %s, not a book of the Bible; copying %s without a scope", project.Identifier, strings.ToLower(matter.Short), ingredientKey)
+		default:
+			opts.warn("project %q is not a recognized book; copying %s without a scope or localized name", project.Identifier, ingredientKey)
 		}
 
 		// Copy file with scope
-		ing, err := CopyFileWithScope(srcPath, outDir, ingredientKey, scope)
-		if err != nil {
+		if err := addFileIngredient(ctx, m, src, srcName, out, ingredientKey, scope); err != nil {
 			return nil, fmt.Errorf("copying %s: %w", srcFilename, err)
 		}
-		m.Ingredients[ingredientKey] = ing
+		if matter != nil {
+			setIngredientRole(m, ingredientKey, PeripheralRole)
+		}
+		setVersification(m, ingredientKey, project, opts)
 	}
 
-	// Set the currentScope
-	m.Type.FlavorType.CurrentScope = currentScope
+	// One aligned book marks the whole Bible as aligned
+	m.Type.FlavorType.Flavor.Aligned = src.alignment.aligned
+
+	// The currentScope spans every book copied as an ingredient
+	m.Type.FlavorType.CurrentScope = m.AggregateScope()
 
 	// Copy common root files (README.md, .gitignore, .gitea, .github)
-	if err := CopyCommonRootFiles(inDir, outDir, m); err != nil {
+	if err := copyCommonRootFiles(ctx, src, out, m); err != nil {
 		return nil, err
 	}
 
 	// Copy LICENSE.md to ingredients/
-	licIng, err := CopyLicenseIngredient(inDir, outDir)
-	if err != nil {
+	if err := addLicenseIngredient(ctx, m, src, out, license); err != nil {
 		return nil, fmt.Errorf("copying LICENSE.md: %w", err)
 	}
-	m.Ingredients["ingredients/LICENSE.md"] = licIng
 
 	return m, nil
 }
 
+// PeripheralRole is the role of an ingredient of peripheral matter (e.g.,
+// front matter from A0-FRT.usfm) in a Bible, which is not canonical content.
+const PeripheralRole = "x-peripheral"
+
 // extractBookCode extracts the book code from a USFM filename.
-// "01-GEN.usfm" -> "GEN", "A0-FRT.usfm" -> "FRT"
+// "01-GEN.usfm" -> "GEN", "41_MRK.usfm" -> "MRK", "A0-FRT.usfm" -> "FRT"
 func extractBookCode(filename string) string {
-	name := strings.TrimSuffix(filename, filepath.Ext(filename))
-	parts := strings.SplitN(name, "-", 2)
-	if len(parts) == 2 {
-		return parts[1]
+	return books.CodeFromUSFMFilename(filename)
+}
+
+// usfmAlignmentRegexp matches a word alignment marker in USFM: a \zaln-s
+// milestone, or a \w word with x- attributes (e.g., \w In|x-occurrence="1"\w*).
+// A match begins with the only backslash in it.
+var usfmAlignmentRegexp = regexp.MustCompile(`\\zaln-s|\\w [^|\\]*\|[^\\]*x-`)
+
+// maxAlignmentTail bounds the text after the last backslash of a write that
+// an alignmentScanner keeps for a marker split across writes.
+const maxAlignmentTail = 4096
+
+// alignmentDetector notes whether any USFM ingredient copied through it has
+// word alignment markers.
+//
+// A nil *alignmentDetector notes nothing.
+type alignmentDetector struct {
+	aligned bool
+}
+
+// reader returns a reader for the contents of r that scans them for
+// alignment markers as they are read, if key is a USFM ingredient and no
+// aligned one has been seen yet.
+func (d *alignmentDetector) reader(key string, r io.Reader) io.Reader {
+	if d == nil || d.aligned || !strings.HasPrefix(key, "ingredients/") || strings.ToLower(path.Ext(key)) != ".usfm" {
+		return r
+	}
+	return io.TeeReader(r, &alignmentScanner{detector: d})
+}
+
+// alignmentScanner is written the content of a USFM ingredient and marks its
+// detector aligned once it sees an alignment marker.
+type alignmentScanner struct {
+	detector *alignmentDetector
+	tail     []byte // the text from the last backslash of the last write
+}
+
+// Write scans p, following the tail of the last write, for a marker.
+func (s *alignmentScanner) Write(p []byte) (int, error) {
+	if s.detector.aligned {
+		return len(p), nil
+	}
+	text := p
+	if len(s.tail) > 0 {
+		text = append(s.tail, p...)
+	}
+	if usfmAlignmentRegexp.Match(text) {
+		s.detector.aligned = true
+		s.tail = nil
+		return len(p), nil
+	}
+	if i := bytes.LastIndexByte(text, '\\'); i >= 0 && len(text)-i <= maxAlignmentTail {
+		s.tail = append(s.tail[:0:0], text[i:]...)
+	} else {
+		s.tail = s.tail[:0]
 	}
-	return name
+	return len(p), nil
 }
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/books"
+	"github.com/unfoldingWord/go-rc2sb/rc"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// bookTSVConfig holds the configuration for a TSV subject with one file per
+// Bible book.
+type bookTSVConfig struct {
+	subject      string // e.g., "TSV Translation Notes"
+	flavorName   string // e.g., "x-bcvnotes"
+	abbreviation string // e.g., "TN"
+	tsvPrefix    string // e.g., "tn_"
+}
+
+// bookTSVHandler handles conversion for TSV subjects with one file per book.
+type bookTSVHandler struct {
+	config bookTSVConfig
+}
+
+func (h *bookTSVHandler) Subject() string {
+	return h.config.subject
+}
+
+func (h *bookTSVHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir, outDir string, opts Options) (*sb.Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m := MapManifest(manifest, MetadataOptions{
+		IDAuthority:        AuthorityUW,
+		Authority:          opts.IDAuthority,
+		Abbreviation:       h.config.abbreviation,
+		CopyrightStatement: opts.CopyrightStatement,
+		Date:               opts.date(),
+	})
+
+	// Set type - parascriptural/x-bcvnotes, x-bcvquestions, ...
+	m.Type = sb.Type{
+		FlavorType: sb.FlavorType{
+			Name: "parascriptural",
+			Flavor: sb.Flavor{
+				Name: h.config.flavorName,
+			},
+		},
+	}
+
+	lang := manifest.DublinCore.Language.Identifier
+	src := newRCSource(inDir, opts)
+	out := newOutput(outDir, opts)
+	license := defaultLicenseFor(src, manifest, opts)
+	usfmPath := usfmDir(inDir, lang, opts)
+
+	// Process each project (TSV file per book)
+	for _, project := range manifest.Projects {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !opts.includesBook(project.Identifier) {
+			continue
+		}
+
+		srcName := projectFile(project.Path)
+		if !src.exists(srcName) {
+			src.skipProject(project, srcName)
+			continue
+		}
+		srcFilename := path.Base(srcName)
+
+		// Strip the prefix: "tn_GEN.tsv" -> "GEN.tsv"
+		destFilename := strings.TrimPrefix(srcFilename, h.config.tsvPrefix)
+		ingredientKey := "ingredients/" + destFilename
+		if !src.allows(ingredientKey) {
+			// Filtered out: leave the book out of scope and localized names too
+			continue
+		}
+
+		// Get book code for scope
+		bookID := strings.ToLower(project.Identifier)
+		bookCode := books.CodeFromProjectID(bookID)
+
+		scope := map[string][]string{bookCode: {}}
+
+		// Add localized name: try USFM from USFMPath or a sibling USFM
+		// repository, then manifest title, then English
+		var usfmNames *books.LocalizedBookNames
+		if usfmPath != "" {
+			usfmNames = opts.USFMNames.BookNames(usfmPath, bookID)
+		}
+		key, localizedName := books.LocalizedNameEntryWithNames(bookID, lang, project.Title, usfmNames)
+		if key != "" {
+			m.LocalizedNames[key] = localizedName
+		}
+
+		// Copy TSV file with scope
+		if err := addFileIngredient(ctx, m, src, srcName, out, ingredientKey, scope); err != nil {
+			return nil, fmt.Errorf("copying %s: %w", srcFilename, err)
+		}
+		setVersification(m, ingredientKey, project, opts)
+	}
+
+	// The currentScope spans every book copied as an ingredient
+	m.Type.FlavorType.CurrentScope = m.AggregateScope()
+
+	// Copy common root files (README.md, .gitignore, .gitea, .github)
+	if err := copyCommonRootFiles(ctx, src, out, m); err != nil {
+		return nil, err
+	}
+
+	// Copy LICENSE.md to ingredients/
+	if err := addLicenseIngredient(ctx, m, src, out, license); err != nil {
+		return nil, fmt.Errorf("copying LICENSE.md: %w", err)
+	}
+
+	return m, nil
+}
+
+// NewBookTSVHandler creates a new handler for a TSV subject with one file per
+// Bible book, each named with tsvPrefix (e.g., "tn_GEN.tsv").
+func NewBookTSVHandler(subject, flavorName, abbreviation, tsvPrefix string) Handler {
+	return &bookTSVHandler{
+		config: bookTSVConfig{
+			subject:      subject,
+			flavorName:   flavorName,
+			abbreviation: abbreviation,
+			tsvPrefix:    tsvPrefix,
+		},
+	}
+}
+
+// NewTNHandler creates a new TSV Translation Notes handler.
+func NewTNHandler() Handler {
+	return NewBookTSVHandler("TSV Translation Notes", "x-bcvnotes", "TN", "tn_")
+}
+
+// NewTQHandler creates a new TSV Translation Questions handler.
+func NewTQHandler() Handler {
+	return NewBookTSVHandler("TSV Translation Questions", "x-bcvquestions", "TQ", "tq_")
+}
+
+// NewSNHandler creates a new TSV Study Notes handler.
+func NewSNHandler() Handler {
+	return NewBookTSVHandler("TSV Study Notes", "x-bcvnotes", "SN", "sn_")
+}
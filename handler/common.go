@@ -1,102 +1,514 @@
 package handler
 
 import (
-	_ "embed"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/unfoldingWord/go-rc2sb/languages"
 	"github.com/unfoldingWord/go-rc2sb/rc"
 	"github.com/unfoldingWord/go-rc2sb/sb"
 )
 
-// defaultLicense is the embedded CC BY-SA 4.0 LICENSE.md used as a fallback
-// when the RC repository does not include its own LICENSE.md file.
-//
-//go:embed default_license.md
-var defaultLicense []byte
+// rcSource is a file system that RC files are read from, together with the
+// directory it represents. The directory is only used to report source paths.
+// The filter selects which of its files become ingredients, and stripBOM
+// removes a leading UTF-8 BOM from text ingredients as they are copied.
+// If recordSources is set, each ingredient records its source path, which is
+// prefix joined with the file's name in fsys. The extra root files and
+// directories are copied to the SB root as ingredients, beside README.md.
+// The encoding checker, if set, checks that text files are UTF-8, the
+// normalizer, if set, normalizes text ingredients and keys to NFC, the stub
+// detector, if set, flags empty and stub ingredients, the alignment detector,
+// if set, notes whether USFM ingredients are aligned, the story headings, if
+// set, note the heading of each OBS story, the tracker, if set, records the
+// files copied from the repository, and the report, if set, records what was
+// substituted or skipped.
+type rcSource struct {
+	fsys           fs.FS
+	dir            string
+	prefix         string
+	filter         *Filter
+	encoding       *EncodingChecker
+	normalizer     *Normalizer
+	stubs          *StubDetector
+	alignment      *alignmentDetector
+	headings       *storyHeadings
+	stripBOM       bool
+	sha256         bool
+	recordSources  bool
+	extraRootFiles []string
+	extraRootDirs  []string
 
-// CopyFile copies a file from src to dst, creating any necessary directories.
-func CopyFile(src, dst string) error {
-	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
-		return fmt.Errorf("creating directory for %s: %w", dst, err)
+	includeSourceManifest bool
+	tracker               *SourceTracker
+	report                *Report
+}
+
+// newRCSource returns the source for the RC repository at inDir, reading
+// through opts.FS when it is set and copying files as opts specifies.
+func newRCSource(inDir string, opts Options) rcSource {
+	src := rcSource{
+		fsys:           opts.FS,
+		dir:            inDir,
+		filter:         opts.Filter,
+		encoding:       opts.EncodingChecker,
+		normalizer:     opts.Normalizer,
+		stubs:          opts.StubDetector,
+		stripBOM:       opts.StripBOM,
+		sha256:         opts.SHA256,
+		recordSources:  opts.RecordSources,
+		extraRootFiles: opts.ExtraRootFiles,
+		extraRootDirs:  opts.ExtraRootDirs,
+
+		includeSourceManifest: opts.IncludeSourceManifest,
+		tracker:               opts.Tracker,
+		report:                opts.Report,
 	}
+	if src.fsys == nil {
+		src.fsys = os.DirFS(inDir)
+	}
+	return src
+}
+
+// dirSource returns a source reading from the directory dir on disk.
+func dirSource(dir string) rcSource {
+	return rcSource{fsys: os.DirFS(dir), dir: dir}
+}
+
+// onDisk returns a source reading from the directory dir on disk that
+// copies files with the same settings as s.
+func (s rcSource) onDisk(dir string) rcSource {
+	s.fsys = os.DirFS(dir)
+	s.dir = dir
+	s.prefix = ""
+	s.tracker = nil
+	return s
+}
 
-	in, err := os.Open(src)
+// path returns the path of name within the source, for messages.
+func (s rcSource) path(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(name))
+}
+
+// sub returns the source rooted at the subdirectory dir.
+func (s rcSource) sub(dir string) (rcSource, error) {
+	fsys, err := fs.Sub(s.fsys, dir)
 	if err != nil {
-		return fmt.Errorf("opening source %s: %w", src, err)
+		return rcSource{}, err
+	}
+	s.fsys = fsys
+	s.dir = s.path(dir)
+	s.prefix = path.Join(s.prefix, dir)
+	return s, nil
+}
+
+// sourceName returns the path of name relative to the repository it is read
+// from, to record as an ingredient's source, or "" if sources are not recorded.
+func (s rcSource) sourceName(name string) string {
+	if !s.recordSources {
+		return ""
+	}
+	return path.Join(s.prefix, name)
+}
+
+// skipProject records project as skipped because its file or directory
+// name is missing.
+func (s rcSource) skipProject(project rc.Project, name string) {
+	s.report.skip(SkippedProject, project.Identifier, path.Join(s.prefix, name)+" not found")
+}
+
+// use records name as copied from the repository, if it is tracked.
+func (s rcSource) use(name string) {
+	s.tracker.record(path.Join(s.prefix, name))
+}
+
+// allows reports whether a file may be copied to ingredientKey.
+func (s rcSource) allows(ingredientKey string) bool {
+	return s.filter.Allows(ingredientKey)
+}
+
+// stripsBOM reports whether a leading BOM is removed when copying name.
+// Only text ingredients (Markdown, TSV, and USFM) are affected.
+func (s rcSource) stripsBOM(name string) bool {
+	if !s.stripBOM {
+		return false
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".tsv", ".usfm":
+		return true
+	}
+	return false
+}
+
+// exists reports whether name exists in the source.
+func (s rcSource) exists(name string) bool {
+	_, err := fs.Stat(s.fsys, name)
+	return err == nil
+}
+
+// LicenseNames lists the names an RC repository's license may have, in order
+// of preference. Whichever is found is written to the SB as LICENSE.md.
+var LicenseNames = []string{"LICENSE.md", "LICENSE", "LICENSE.txt"}
+
+// license returns the name of the RC repository's license file, the first of
+// LicenseNames that exists, or "" if there is none.
+func (s rcSource) license() string {
+	for _, name := range LicenseNames {
+		if info, err := fs.Stat(s.fsys, name); err == nil && !info.IsDir() {
+			return name
+		}
+	}
+	return ""
+}
+
+// projectFile converts a manifest project path (e.g., "./tn_GEN.tsv") into a
+// file name usable with an fs.FS (e.g., "tn_GEN.tsv").
+func projectFile(projectPath string) string {
+	return path.Clean(strings.TrimPrefix(projectPath, "./"))
+}
+
+// relName returns name relative to root, where both are fs.FS paths and
+// name is root or lies beneath it.
+func relName(root, name string) string {
+	if root == "." {
+		return name
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+}
+
+// CopyFile copies the file name from fsys to dst, creating any necessary directories.
+// The copy is aborted with ctx.Err() if ctx is cancelled while data is being copied.
+func CopyFile(ctx context.Context, fsys fs.FS, name, dst string) error {
+	_, err := copyToOutput(ctx, rcSource{fsys: fsys}, name, DirOutput(filepath.Dir(dst)), filepath.Base(dst), false)
+	return err
+}
+
+// copyToOutput copies the file name from src to dstName in out, computing
+// the ingredient entry for the written bytes in the same pass. If stripBOM is
+// set, a leading UTF-8 BOM is left out of the copy and its checksum. Text is
+// checked for UTF-8 if the source has an encoding checker, normalized to NFC
+// if it has a normalizer, and flagged if empty or a stub if it has a stub
+// detector.
+// Errors name both the source path and dstName.
+// The copy is aborted with ctx.Err() if ctx is cancelled while data is being copied.
+func copyToOutput(ctx context.Context, src rcSource, name string, out Output, dstName string, stripBOM bool) (sb.Ingredient, error) {
+	if err := ctx.Err(); err != nil {
+		return sb.Ingredient{}, err
+	}
+
+	in, err := src.fsys.Open(name)
+	if err != nil {
+		return sb.Ingredient{}, fmt.Errorf("copying %s to %s: %w", src.path(name), dstName, sourceError(err))
 	}
 	defer in.Close()
+	src.use(name)
+
+	var r io.Reader = &ctxReader{ctx: ctx, r: in}
+	r, checked := src.encoding.reader(dstName, r)
+	if stripBOM {
+		if r, err = skipBOM(r); err != nil {
+			return sb.Ingredient{}, fmt.Errorf("copying %s to %s: %w", src.path(name), dstName, sourceError(err))
+		}
+	}
+	r, normalized := src.normalizer.reader(dstName, r)
+	r, scanned := src.stubs.reader(dstName, r)
+	r = src.alignment.reader(dstName, r)
+	r, headed := src.headings.reader(dstName, r)
+	ing, err := writeIngredient(out, dstName, r, src.ingredientWriter(dstName))
+	if err != nil {
+		return sb.Ingredient{}, fmt.Errorf("copying %s to %s: %w", src.path(name), dstName, err)
+	}
+	checked()
+	normalized()
+	scanned()
+	headed()
+	return ing, nil
+}
+
+// sourceError rewords err from opening or reading a source file or directory
+// to say whether it is missing or unreadable. Callers add the path.
+func sourceError(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("source not found: %w", err)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("source not readable: %w", err)
+	}
+	return err
+}
+
+// walkError describes err, passed by fs.WalkDir for name in src, with the
+// source path.
+func walkError(src rcSource, name string, err error) error {
+	return fmt.Errorf("reading %s: %w", src.path(name), sourceError(err))
+}
 
-	out, err := os.Create(dst)
+// writeIngredient writes the contents of r to dstName in out and returns the
+// ingredient entry iw computes from the bytes written. The content is
+// checksummed as it is written, so it is read only once.
+func writeIngredient(out Output, dstName string, r io.Reader, iw *sb.IngredientWriter) (sb.Ingredient, error) {
+	w, err := out.Create(dstName)
 	if err != nil {
-		return fmt.Errorf("creating destination %s: %w", dst, err)
+		return sb.Ingredient{}, err
+	}
+	defer w.Close()
+
+	buf := copyBufPool.Get().(*[]byte)
+	defer copyBufPool.Put(buf)
+	if _, err := io.CopyBuffer(io.MultiWriter(w, iw), r, *buf); err != nil {
+		return sb.Ingredient{}, err
+	}
+	return iw.Ingredient(), w.Close()
+}
+
+// copyBufPool holds the buffers writeIngredient copies through, so that
+// copying a tree of many small files does not allocate one per file.
+var copyBufPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 32*1024)
+		return &buf
+	},
+}
+
+// linksToDir reports whether the entry d, found walking fsys, is a symbolic
+// link to a directory. Walks do not follow such links, so tree copies skip
+// them; a link to a file is copied as the file it points to.
+func linksToDir(fsys fs.FS, name string, d fs.DirEntry) bool {
+	if d.Type()&fs.ModeSymlink == 0 {
+		return false
 	}
-	defer out.Close()
+	info, err := fs.Stat(fsys, name)
+	return err == nil && info.IsDir()
+}
 
-	if _, err := io.Copy(out, in); err != nil {
-		return fmt.Errorf("copying %s to %s: %w", src, dst, err)
+// ingredientWriter returns the IngredientWriter for content copied to key,
+// computing a SHA-256 checksum as well if the source asks for one.
+func (s rcSource) ingredientWriter(key string) *sb.IngredientWriter {
+	w := sb.NewIngredientWriter(key)
+	if s.sha256 {
+		w.WithSHA256()
 	}
+	return w
+}
 
-	return out.Close()
+// utf8BOM is the UTF-8 encoding of U+FEFF.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// skipBOM returns a reader for the contents of r without a leading UTF-8 BOM.
+func skipBOM(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	prefix, err := br.Peek(len(utf8BOM))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(prefix, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	return br, nil
 }
 
-// CopyFileAndComputeIngredient copies a file and computes its ingredient entry.
+// ctxReader wraps an io.Reader and fails with the context's error once the
+// context is cancelled, so long copies stop between buffer-sized reads.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// CopyFileAndComputeIngredient copies the file name from fsys to ingredientKey in out
+// and computes its ingredient entry.
 // Returns the ingredient key (relative path in SB) and the Ingredient.
-func CopyFileAndComputeIngredient(src, outDir, ingredientKey string) (sb.Ingredient, error) {
-	dst := filepath.Join(outDir, ingredientKey)
-	if err := CopyFile(src, dst); err != nil {
+func CopyFileAndComputeIngredient(ctx context.Context, fsys fs.FS, name string, out Output, ingredientKey string) (sb.Ingredient, error) {
+	return copyToOutput(ctx, rcSource{fsys: fsys}, name, out, ingredientKey, false)
+}
+
+// CopyFileWithScope copies the file name from fsys to ingredientKey in out
+// and computes its ingredient entry with scope.
+func CopyFileWithScope(ctx context.Context, fsys fs.FS, name string, out Output, ingredientKey string, scope map[string][]string) (sb.Ingredient, error) {
+	ing, err := copyToOutput(ctx, rcSource{fsys: fsys}, name, out, ingredientKey, false)
+	if err != nil {
 		return sb.Ingredient{}, err
 	}
-	return sb.ComputeIngredient(dst)
+	ing.Scope = scope
+	return ing, nil
 }
 
-// CopyFileWithScope copies a file and computes its ingredient entry with scope.
-func CopyFileWithScope(src, outDir, ingredientKey string, scope map[string][]string) (sb.Ingredient, error) {
-	dst := filepath.Join(outDir, ingredientKey)
-	if err := CopyFile(src, dst); err != nil {
-		return sb.Ingredient{}, err
+// addFileIngredient copies name from src to ingredientKey in out and records
+// the resulting ingredient, with the given scope (may be nil), in m.Ingredients.
+// Files rejected by the source's filter are skipped, and the key is
+// normalized to NFC if the source has a normalizer.
+// The key is claimed before copying, so a key already produced from a
+// different source is reported as an error instead of clobbering that file.
+func addFileIngredient(ctx context.Context, m *sb.Metadata, src rcSource, name string, out Output, ingredientKey string, scope map[string][]string) error {
+	ingredientKey = src.normalizer.key(ingredientKey)
+	if !src.allows(ingredientKey) {
+		return nil
+	}
+	if err := m.ClaimIngredient(ingredientKey, src.path(name)); err != nil {
+		return err
 	}
-	return sb.ComputeIngredientWithScope(dst, scope)
+	ing, err := copyToOutput(ctx, src, name, out, ingredientKey, src.stripsBOM(name))
+	if err != nil {
+		return err
+	}
+	ing.Scope = scope
+	ing.Source = src.sourceName(name)
+	m.Ingredients[ingredientKey] = ing
+	return nil
 }
 
-// BuildBaseMetadata creates a base SB Metadata from an RC manifest with common fields set.
-func BuildBaseMetadata(manifest *rc.Manifest, idAuthority, abbreviation string) *sb.Metadata {
+// addLicenseIngredient copies the license to ingredients/LICENSE.md like
+// CopyLicenseIngredient, falling back to license when the RC repo has none,
+// and records it in m.Ingredients, guarding against another source having
+// already produced that key.
+func addLicenseIngredient(ctx context.Context, m *sb.Metadata, src rcSource, out Output, license []byte) error {
+	const key = "ingredients/LICENSE.md"
+	name := src.license()
+	if name == "" {
+		name = "LICENSE.md"
+	}
+	if err := m.ClaimIngredient(key, src.path(name)); err != nil {
+		return err
+	}
+	ing, err := copyLicenseIngredient(ctx, src, out, license)
+	if err != nil {
+		return err
+	}
+	m.Ingredients[key] = ing
+	return nil
+}
+
+// The intents handlers choose an SB ID authority by: the unfoldingWord
+// authority, for the uW resources, or the community one, for OBS. Each is also
+// the key its default authority is recorded under.
+const (
+	AuthorityUW        = "uWBurritos"
+	AuthorityCommunity = "BurritoTruck"
+)
+
+// IDAuthority is an SB ID authority: the key it is recorded under in
+// idAuthorities and identification.primary, its ID URL, and its names by
+// locale.
+type IDAuthority struct {
+	Key  string
+	URL  string
+	Name map[string]string
+}
+
+// DefaultIDAuthority returns the Door43 authority for intent, AuthorityUW or
+// AuthorityCommunity. Any other intent is recorded under its own key with
+// the unfoldingWord URL and name.
+func DefaultIDAuthority(intent string) IDAuthority {
+	if intent == AuthorityCommunity {
+		return IDAuthority{
+			Key:  intent,
+			URL:  "https://git.door43.org/BurritoTruck",
+			Name: map[string]string{"en": "Door43 Burrito Truck"},
+		}
+	}
+	return IDAuthority{
+		Key:  intent,
+		URL:  "https://git.door43.org/uW",
+		Name: map[string]string{"en": "Door43 uW Burritos"},
+	}
+}
+
+// MetadataOptions holds the parts of the base metadata that do not come from
+// the RC manifest.
+type MetadataOptions struct {
+	// IDAuthority is the intent the SB ID authority is chosen by:
+	// AuthorityUW or AuthorityCommunity.
+	IDAuthority string
+
+	// Authority, if its Key is set, replaces the default authority for
+	// IDAuthority. If its Name is empty, the key is used as the English name.
+	Authority IDAuthority
+
+	// Abbreviation is the SB abbreviation. If empty, the upper-cased RC
+	// identifier is used.
+	Abbreviation string
+
+	// OBSCopyright selects the OBS copyright statement format.
+	OBSCopyright bool
+
+	// CopyrightStatement overrides the generated copyright short statement.
+	CopyrightStatement string
+
+	// Generator, if set, replaces the default generator (go-rc2sb and its version).
+	Generator sb.Generator
+
+	// Category is the SB meta.category (e.g., "derived"). If empty, "source"
+	// is used.
+	Category string
+
+	// Date is recorded as the creation date and the identification timestamp.
+	Date time.Time
+}
+
+// MapManifest maps an RC manifest to the base SB Metadata, with the ID
+// authority, identification, language, and copyright populated. It performs
+// no I/O; handlers add the type, scope, and ingredients on top.
+func MapManifest(manifest *rc.Manifest, opts MetadataOptions) *sb.Metadata {
 	m := sb.NewMetadata()
+	if opts.Generator != (sb.Generator{}) {
+		m.Meta.Generator = opts.Generator
+	}
+	if opts.Category != "" {
+		m.Meta.Category = opts.Category
+	}
 
-	now := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
-	m.Meta.DateCreated = now
+	date := opts.Date.UTC().Format("2006-01-02T15:04:05.000Z")
+	m.Meta.DateCreated = date
 
 	dc := manifest.DublinCore
 
 	// Set ID authority
-	if idAuthority == "BurritoTruck" {
-		m.IDAuthorities[idAuthority] = sb.IDAuthority{
-			ID:   "https://git.door43.org/BurritoTruck",
-			Name: map[string]string{"en": "Door43 Burrito Truck"},
-		}
-	} else {
-		m.IDAuthorities[idAuthority] = sb.IDAuthority{
-			ID:   "https://git.door43.org/uW",
-			Name: map[string]string{"en": "Door43 uW Burritos"},
+	authority := DefaultIDAuthority(opts.IDAuthority)
+	if opts.Authority.Key != "" {
+		authority = opts.Authority
+		if len(authority.Name) == 0 {
+			authority.Name = map[string]string{"en": authority.Key}
 		}
 	}
+	m.IDAuthorities[authority.Key] = sb.IDAuthority{
+		ID:   authority.URL,
+		Name: authority.Name,
+	}
 
 	// Set identification
-	abbr := abbreviation
+	abbr := opts.Abbreviation
 	if abbr == "" {
 		abbr = strings.ToUpper(dc.Identifier)
 	}
 
 	m.Identification = sb.Identification{
 		Primary: map[string]map[string]sb.PrimaryEntry{
-			idAuthority: {
+			authority.Key: {
 				abbr: {
 					Revision:  "1",
-					Timestamp: now,
+					Timestamp: date,
 				},
 			},
 		},
@@ -104,108 +516,190 @@ func BuildBaseMetadata(manifest *rc.Manifest, idAuthority, abbreviation string)
 		Description:  map[string]string{"en": dc.Title},
 		Abbreviation: map[string]string{"en": abbr},
 	}
+	if dc.Identifier != "" {
+		m.LocalizedNames["resource-"+strings.ToLower(dc.Identifier)] = resourceName(dc, abbr)
+	}
 
 	// Set language
+	direction, _ := languages.ScriptDirection(dc.Language.Identifier, dc.Language.Direction)
 	m.Languages = []sb.LanguageEntry{
 		{
 			Tag:             dc.Language.Identifier,
-			Name:            map[string]string{"en": dc.Language.Title},
-			ScriptDirection: dc.Language.Direction,
+			Name:            languages.Names(dc.Language.Identifier, dc.Language.Title),
+			Script:          languages.Script(dc.Language.Identifier),
+			ScriptDirection: direction,
+			Region:          languages.Region(dc.Language.Identifier),
+			NumberingSystem: languages.NumberingSystem(dc.Language.Identifier),
 		},
 	}
 
+	m.Copyright = BuildCopyright(manifest, opts.OBSCopyright, opts.CopyrightStatement)
+
 	return m
 }
 
+// resourceName returns the localized name of the resource dc describes: its
+// title, tagged with its language (falling back to "en"), and, for a resource
+// in another language, its subject without a "TSV " prefix (e.g.,
+// "Translation Notes") as the English name.
+func resourceName(dc rc.DublinCore, abbr string) sb.LocalizedName {
+	lang := dc.Language.Identifier
+	if lang == "" {
+		lang = "en"
+	}
+	english := strings.TrimPrefix(dc.Subject, "TSV ")
+	title := dc.Title
+	if title == "" {
+		title = english
+	}
+	ln := sb.LocalizedName{
+		Abbr:  map[string]string{lang: abbr},
+		Short: map[string]string{lang: title},
+		Long:  map[string]string{lang: title},
+	}
+	if lang != "en" && english != "" {
+		ln.Abbr["en"] = abbr
+		ln.Short["en"] = english
+		ln.Long["en"] = english
+	}
+	return ln
+}
+
+// BuildBaseMetadata creates a base SB Metadata from an RC manifest with common
+// fields and the default copyright set, dated now. See MapManifest.
+func BuildBaseMetadata(manifest *rc.Manifest, idAuthority, abbreviation string) *sb.Metadata {
+	return MapManifest(manifest, MetadataOptions{
+		IDAuthority:  idAuthority,
+		Abbreviation: abbreviation,
+		Date:         time.Now(),
+	})
+}
+
 // BuildCopyright generates a copyright statement from the RC manifest.
 // Uses the format "© {publisher} {year}, {rights}" for most types,
 // or "Copyright © {year} by {publisher}" for OBS.
-func BuildCopyright(manifest *rc.Manifest, isOBS bool) sb.Copyright {
+// If statement is non-empty, it is used verbatim instead of the generated text,
+// allowing a localized statement to be supplied. The statement is tagged with
+// the manifest's language identifier (falling back to "en"). The SPDX
+// identifier of the rights declaration, if recognized, is also recorded.
+func BuildCopyright(manifest *rc.Manifest, isOBS bool, statement string) sb.Copyright {
 	dc := manifest.DublinCore
 	year := dc.Issued
 	if len(year) >= 4 {
 		year = year[:4]
 	}
 
-	if isOBS {
-		return sb.Copyright{
-			ShortStatements: []sb.CopyrightStatement{
-				{
-					Statement: fmt.Sprintf("Copyright \u00a9 %s by %s", year, dc.Publisher),
-				},
-			},
+	lang := dc.Language.Identifier
+	if lang == "" {
+		lang = "en"
+	}
+
+	if statement == "" {
+		if isOBS {
+			statement = fmt.Sprintf("Copyright \u00a9 %s by %s", year, dc.Publisher)
+		} else {
+			statement = fmt.Sprintf("\u00a9 %s %s, %s", dc.Publisher, year, dc.Rights)
 		}
 	}
 
+	spdx, _ := SPDXLicense(dc.Rights)
 	return sb.Copyright{
 		ShortStatements: []sb.CopyrightStatement{
 			{
-				Statement: fmt.Sprintf("\u00a9 %s %s, %s", dc.Publisher, year, dc.Rights),
+				Statement: statement,
 				MimeType:  "text/plain",
-				Lang:      "en",
+				Lang:      lang,
 			},
 		},
+		SPDX: spdx,
 	}
 }
 
-// CopyLicenseIngredient copies LICENSE.md from the RC repo to ingredients/LICENSE.md
-// and returns the ingredient. If the RC repo does not contain a LICENSE.md file,
-// the embedded default CC BY-SA 4.0 license is used instead.
-func CopyLicenseIngredient(inDir, outDir string) (sb.Ingredient, error) {
-	src := filepath.Join(inDir, "LICENSE.md")
-	if _, err := os.Stat(src); os.IsNotExist(err) {
+// CopyLicenseIngredient copies the license from the RC repo (LICENSE.md, LICENSE,
+// or LICENSE.txt; see LicenseNames) to ingredients/LICENSE.md and returns the
+// ingredient. If the RC repo has none of them, the embedded default CC BY-SA 4.0
+// license is used instead.
+func CopyLicenseIngredient(ctx context.Context, inDir, outDir string) (sb.Ingredient, error) {
+	return copyLicenseIngredient(ctx, dirSource(inDir), DirOutput(outDir), defaultLicense)
+}
+
+// copyLicenseIngredient is CopyLicenseIngredient reading the RC repo from src,
+// writing to out, and using license as the default.
+func copyLicenseIngredient(ctx context.Context, src rcSource, out Output, license []byte) (sb.Ingredient, error) {
+	name := src.license()
+	if name == "" {
 		// Use the embedded default LICENSE.md
-		return writeDefaultLicenseIngredient(outDir)
+		return writeDefaultLicenseIngredient(src, out, license)
 	}
-	return CopyFileAndComputeIngredient(src, outDir, "ingredients/LICENSE.md")
+	ing, err := copyToOutput(ctx, src, name, out, "ingredients/LICENSE.md", src.stripsBOM("LICENSE.md"))
+	if err != nil {
+		return sb.Ingredient{}, err
+	}
+	ing.Source = src.sourceName(name)
+	return ing, nil
 }
 
-// writeDefaultLicenseIngredient writes the embedded default LICENSE.md
+// writeDefaultLicenseIngredient writes the embedded default license
 // to ingredients/LICENSE.md and computes its ingredient entry.
-func writeDefaultLicenseIngredient(outDir string) (sb.Ingredient, error) {
-	dst := filepath.Join(outDir, "ingredients", "LICENSE.md")
-	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
-		return sb.Ingredient{}, fmt.Errorf("creating directory for %s: %w", dst, err)
-	}
-	if err := os.WriteFile(dst, defaultLicense, 0644); err != nil {
+func writeDefaultLicenseIngredient(src rcSource, out Output, license []byte) (sb.Ingredient, error) {
+	const key = "ingredients/LICENSE.md"
+	ing, err := writeIngredient(out, key, bytes.NewReader(license), src.ingredientWriter(key))
+	if err != nil {
 		return sb.Ingredient{}, fmt.Errorf("writing default LICENSE.md: %w", err)
 	}
-	return sb.ComputeIngredient(dst)
+	return ing, nil
+}
+
+// CopyLicenseToRoot copies the license from the RC repo (see LicenseNames) to
+// LICENSE.md in the SB output root directory. If the RC repo has none, the
+// embedded default is used instead.
+func CopyLicenseToRoot(ctx context.Context, inDir, outDir string) error {
+	return copyLicenseToRoot(ctx, dirSource(inDir), DirOutput(outDir), defaultLicense)
 }
 
-// CopyLicenseToRoot copies LICENSE.md from the RC repo to the SB output root directory.
-// If the RC repo does not contain a LICENSE.md file, the embedded default is used instead.
-func CopyLicenseToRoot(inDir, outDir string) error {
-	src := filepath.Join(inDir, "LICENSE.md")
-	dst := filepath.Join(outDir, "LICENSE.md")
-	if _, err := os.Stat(src); os.IsNotExist(err) {
+// copyLicenseToRoot is CopyLicenseToRoot reading the RC repo from src,
+// writing to out, and using license as the default.
+func copyLicenseToRoot(ctx context.Context, src rcSource, out Output, license []byte) error {
+	name := src.license()
+	if name == "" {
 		// Use the embedded default LICENSE.md
-		return os.WriteFile(dst, defaultLicense, 0644)
+		_, err := writeIngredient(out, "LICENSE.md", bytes.NewReader(license), src.ingredientWriter("LICENSE.md"))
+		return err
 	}
-	return CopyFile(src, dst)
+	_, err := copyToOutput(ctx, src, name, out, "LICENSE.md", false)
+	return err
 }
 
 // CopyRootFile copies a root-level file from RC to SB root and returns the ingredient.
-func CopyRootFile(inDir, outDir, filename string) (sb.Ingredient, error) {
-	src := filepath.Join(inDir, filename)
-	if _, err := os.Stat(src); os.IsNotExist(err) {
+func CopyRootFile(ctx context.Context, inDir, outDir, filename string) (sb.Ingredient, error) {
+	fsys := os.DirFS(inDir)
+	if _, err := fs.Stat(fsys, filename); errors.Is(err, fs.ErrNotExist) {
 		return sb.Ingredient{}, nil // File doesn't exist, skip silently
 	}
-	return CopyFileAndComputeIngredient(src, outDir, filename)
+	return CopyFileAndComputeIngredient(ctx, fsys, filename, DirOutput(outDir), filename)
 }
 
 // CopyCommonRootFiles copies common root-level files from the RC repo to the SB output
 // if they exist: README.md, .gitea, .github, .gitignore (but NOT .git).
 // Files are copied to the SB root but are intentionally NOT added to metadata ingredients.
-func CopyCommonRootFiles(inDir, outDir string, _ *sb.Metadata) error {
+func CopyCommonRootFiles(ctx context.Context, inDir, outDir string, m *sb.Metadata) error {
+	return copyCommonRootFiles(ctx, dirSource(inDir), DirOutput(outDir), m)
+}
+
+// copyCommonRootFiles is CopyCommonRootFiles reading the RC repo from src and
+// writing to out. It also copies the source's extra root files and
+// directories, which, unlike the common ones, are recorded in m.Ingredients
+// under their own paths (e.g., ".apps/config.yaml"), and, if the source
+// includes it, the RC manifest.
+func copyCommonRootFiles(ctx context.Context, src rcSource, out Output, m *sb.Metadata) error {
 	// Individual files to copy
 	files := []string{"README.md", ".gitignore"}
 	for _, name := range files {
-		src := filepath.Join(inDir, name)
-		if _, err := os.Stat(src); os.IsNotExist(err) {
+		if _, err := fs.Stat(src.fsys, name); errors.Is(err, fs.ErrNotExist) {
+			src.report.skip(SkippedRootFile, name, "not in the RC")
 			continue
 		}
-		if err := CopyFile(src, filepath.Join(outDir, name)); err != nil {
+		if _, err := copyToOutput(ctx, src, name, out, name, false); err != nil {
 			return fmt.Errorf("copying root file %s: %w", name, err)
 		}
 	}
@@ -213,38 +707,122 @@ func CopyCommonRootFiles(inDir, outDir string, _ *sb.Metadata) error {
 	// Directories to copy recursively
 	dirs := []string{".gitea", ".github"}
 	for _, dirName := range dirs {
-		src := filepath.Join(inDir, dirName)
-		info, err := os.Stat(src)
-		if os.IsNotExist(err) || !info.IsDir() {
+		info, err := fs.Stat(src.fsys, dirName)
+		if errors.Is(err, fs.ErrNotExist) || !info.IsDir() {
+			continue
+		}
+		if err := copyTree(ctx, src, dirName, out, dirName); err != nil {
+			return fmt.Errorf("copying root directory %s: %w", dirName, err)
+		}
+	}
+
+	for _, name := range src.extraRootFiles {
+		name, ok, err := extraRootName(name)
+		if err != nil {
+			return err
+		}
+		if info, err := fs.Stat(src.fsys, name); !ok || err != nil || info.IsDir() {
 			continue
 		}
-		if err := copyTree(src, outDir, dirName); err != nil {
+		if err := addFileIngredient(ctx, m, src, name, out, name, nil); err != nil {
+			return fmt.Errorf("copying root file %s: %w", name, err)
+		}
+	}
+	for _, dirName := range src.extraRootDirs {
+		dirName, ok, err := extraRootName(dirName)
+		if err != nil {
+			return err
+		}
+		if info, err := fs.Stat(src.fsys, dirName); !ok || err != nil || !info.IsDir() {
+			continue
+		}
+		if err := copyTreeToIngredients(ctx, src, dirName, out, dirName, m); err != nil {
 			return fmt.Errorf("copying root directory %s: %w", dirName, err)
 		}
 	}
 
+	if src.includeSourceManifest {
+		if err := addSourceManifest(ctx, src, out, m); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// copyTree recursively copies srcDir into outDir/destPrefix without adding metadata entries.
-func copyTree(srcDir, outDir, destPrefix string) error {
-	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// SourceManifestRole is the role of the RC manifest files in an SB
+// converted with Options.IncludeSourceManifest.
+const SourceManifestRole = "x-source-manifest"
+
+// addSourceManifest copies the RC's manifest.yaml, and its media.yaml if it
+// has one, to ingredients/source/ and records them in m.Ingredients with the
+// role x-source-manifest.
+func addSourceManifest(ctx context.Context, src rcSource, out Output, m *sb.Metadata) error {
+	for _, name := range []string{"manifest.yaml", "media.yaml"} {
+		if _, err := fs.Stat(src.fsys, name); errors.Is(err, fs.ErrNotExist) {
+			continue
 		}
-		if info.IsDir() {
-			return nil
+		key := "ingredients/source/" + name
+		if err := addFileIngredient(ctx, m, src, name, out, key, nil); err != nil {
+			return fmt.Errorf("copying source manifest %s: %w", name, err)
 		}
+		setIngredientRole(m, key, SourceManifestRole)
+	}
+	return nil
+}
+
+// extraRootName cleans an extra root file or directory name (e.g., "./.apps/")
+// into a name usable with an fs.FS (e.g., ".apps"). It returns false for .git
+// and anything in it, which is never copied, and an error for a name outside
+// the repository.
+func extraRootName(name string) (string, bool, error) {
+	name = path.Clean(strings.TrimPrefix(filepath.ToSlash(name), "./"))
+	if !fs.ValidPath(name) || name == "." {
+		return "", false, fmt.Errorf("invalid extra root path %q", name)
+	}
+	if name == ".git" || strings.HasPrefix(name, ".git/") {
+		return name, false, nil
+	}
+	return name, true, nil
+}
 
-		relPath, err := filepath.Rel(srcDir, path)
+// copyTree recursively copies the directory root in src into destPrefix in out
+// without adding metadata entries.
+func copyTree(ctx context.Context, src rcSource, root string, out Output, destPrefix string) error {
+	return fs.WalkDir(src.fsys, root, func(name string, d fs.DirEntry, err error) error {
 		if err != nil {
+			return walkError(src, name, err)
+		}
+		if err := ctx.Err(); err != nil {
 			return err
 		}
-
-		dstPath := filepath.Join(outDir, destPrefix, relPath)
-		if err := CopyFile(path, dstPath); err != nil {
-			return fmt.Errorf("copying %s: %w", relPath, err)
+		if d.IsDir() || linksToDir(src.fsys, name, d) {
+			return nil
 		}
-		return nil
+
+		relPath := relName(root, name)
+
+		_, err = copyToOutput(ctx, src, name, out, destPrefix+"/"+relPath, false)
+		return err
 	})
 }
+
+// knownVersifications lists the versifications a project may declare: the
+// Paratext ones (original, Septuagint, Vulgate, English, Russian Protestant,
+// and Russian Orthodox), KJV, and unfoldingWord's own.
+var knownVersifications = []string{"org", "lxx", "vul", "eng", "rsc", "rso", "kjv", "ufw"}
+
+// setVersification records the versification project declares, if any, on
+// the ingredient key, if it was copied, with a warning if it is not one of
+// knownVersifications.
+func setVersification(m *sb.Metadata, key string, project rc.Project, opts Options) {
+	ing, ok := m.Ingredients[key]
+	if !ok || project.Versification == "" {
+		return
+	}
+	if !slices.Contains(knownVersifications, strings.ToLower(project.Versification)) {
+		opts.warn("project %s has unknown versification %q", project.Identifier, project.Versification)
+	}
+	ing.Versification = project.Versification
+	m.Ingredients[key] = ing
+}
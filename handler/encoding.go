@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"io"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// EncodingChecker checks that text files (Markdown, TSV, USFM, and YAML) are
+// valid UTF-8 as they are copied, recording the first invalid byte of each
+// file that is not, since downstream tools assume UTF-8. If it transcodes
+// Latin-1, a file whose first non-ASCII byte does not start a UTF-8 sequence
+// is taken to be Latin-1 and converted to UTF-8 as it is copied, so its
+// checksum is of the converted bytes.
+//
+// A nil *EncodingChecker checks nothing.
+type EncodingChecker struct {
+	transcodeLatin1 bool
+	invalid         []InvalidUTF8
+	transcoded      []string
+}
+
+// InvalidUTF8 is a file that an EncodingChecker found not to be valid UTF-8.
+type InvalidUTF8 struct {
+	// Key is the name the file was copied to (e.g.,
+	// "ingredients/LICENSE.md"), and Offset the offset in the source file
+	// of its first invalid byte.
+	Key    string
+	Offset int64
+}
+
+// NewEncodingChecker returns an EncodingChecker that has checked no files,
+// and that transcodes Latin-1 files to UTF-8 if transcodeLatin1 is set.
+func NewEncodingChecker(transcodeLatin1 bool) *EncodingChecker {
+	return &EncodingChecker{transcodeLatin1: transcodeLatin1}
+}
+
+// Invalid returns the files found not to be valid UTF-8, in the order they
+// were copied.
+func (c *EncodingChecker) Invalid() []InvalidUTF8 {
+	if c == nil {
+		return nil
+	}
+	return c.invalid
+}
+
+// Transcoded returns the names of the files transcoded from Latin-1, in the
+// order they were copied.
+func (c *EncodingChecker) Transcoded() []string {
+	if c == nil {
+		return nil
+	}
+	return c.transcoded
+}
+
+// checksEncoding reports whether the file key is checked: c is not nil and
+// key is a text file.
+func (c *EncodingChecker) checksEncoding(key string) bool {
+	if c == nil {
+		return false
+	}
+	switch strings.ToLower(path.Ext(key)) {
+	case ".md", ".tsv", ".usfm", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// reader returns a reader for the contents of r, transcoded to UTF-8 if it is
+// Latin-1 and c transcodes it, if the file key is checked, otherwise r
+// itself. The returned function is to be called once all of r has been read;
+// it records key if it was not valid UTF-8 or was transcoded.
+func (c *EncodingChecker) reader(key string, r io.Reader) (io.Reader, func()) {
+	if !c.checksEncoding(key) {
+		return r, func() {}
+	}
+	t := &utf8Checker{transcodeLatin1: c.transcodeLatin1, invalid: -1}
+	return transform.NewReader(r, t), func() {
+		if t.invalid >= 0 {
+			c.invalid = append(c.invalid, InvalidUTF8{Key: key, Offset: t.invalid})
+		}
+		if t.latin1 {
+			c.transcoded = append(c.transcoded, key)
+		}
+	}
+}
+
+// utf8Checker is a transform.Transformer that copies UTF-8 text unchanged,
+// noting the offset of its first invalid byte, or, if transcodeLatin1 is set
+// and the first non-ASCII byte does not start a UTF-8 sequence, converts the
+// text from Latin-1.
+type utf8Checker struct {
+	transcodeLatin1 bool
+	decided         bool // a non-ASCII byte has been seen
+	latin1          bool
+	offset          int64
+	invalid         int64
+}
+
+// Transform implements transform.Transformer.
+func (t *utf8Checker) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		b := src[nSrc]
+		switch {
+		case b < utf8.RuneSelf:
+			if nDst == len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			dst[nDst] = b
+			nDst++
+			nSrc++
+			t.offset++
+			continue
+		case t.latin1:
+			if nDst+2 > len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			nDst += utf8.EncodeRune(dst[nDst:], rune(b))
+			nSrc++
+			t.offset++
+			continue
+		}
+
+		if !atEOF && !utf8.FullRune(src[nSrc:]) {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if r == utf8.RuneError && size == 1 {
+			if !t.decided && t.transcodeLatin1 {
+				t.decided, t.latin1 = true, true
+				continue
+			}
+			if t.invalid < 0 {
+				t.invalid = t.offset
+			}
+		}
+		t.decided = true
+		if nDst+size > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], src[nSrc:nSrc+size])
+		nSrc += size
+		t.offset += int64(size)
+	}
+	return nDst, nSrc, nil
+}
+
+// Reset implements transform.Transformer.
+func (t *utf8Checker) Reset() {
+	t.decided, t.latin1 = false, false
+	t.offset, t.invalid = 0, -1
+}
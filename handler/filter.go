@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Filter selects which files are copied as ingredients, by matching
+// doublestar-style glob patterns against their ingredient keys
+// (e.g., "ingredients/GEN.usfm"). A "**" path segment matches zero or more
+// directories; other segments use path.Match syntax.
+//
+// A nil *Filter allows every file.
+type Filter struct {
+	include []string
+	exclude []string
+
+	excluded []string
+	seen     map[string]bool
+}
+
+// NewFilter returns a Filter for the given patterns. If include is non-empty,
+// only keys matching one of its patterns are allowed. Keys matching any
+// exclude pattern are never allowed.
+func NewFilter(include, exclude []string) (*Filter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return &Filter{include: include, exclude: exclude, seen: make(map[string]bool)}, nil
+}
+
+// Allows reports whether the file with the given ingredient key should be
+// copied, recording the key as excluded if not.
+func (f *Filter) Allows(key string) bool {
+	if f == nil {
+		return true
+	}
+	if f.matches(key) {
+		return true
+	}
+	if !f.seen[key] {
+		f.seen[key] = true
+		f.excluded = append(f.excluded, key)
+	}
+	return false
+}
+
+func (f *Filter) matches(key string) bool {
+	for _, pattern := range f.exclude {
+		if matchGlob(pattern, key) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, pattern := range f.include {
+		if matchGlob(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Excluded returns the ingredient keys that were not allowed, in the order
+// they were encountered.
+func (f *Filter) Excluded() []string {
+	if f == nil {
+		return nil
+	}
+	return f.excluded
+}
+
+// MatchGlob reports whether the slash-separated name (e.g., an ingredient
+// key) matches the doublestar-style pattern, as a Filter matches them.
+func MatchGlob(pattern, name string) bool {
+	return matchGlob(pattern, name)
+}
+
+// matchGlob reports whether the slash-separated name matches pattern.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range name {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
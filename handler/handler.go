@@ -3,13 +3,46 @@ package handler
 
 import (
 	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/unfoldingWord/go-rc2sb/books"
 	"github.com/unfoldingWord/go-rc2sb/rc"
 	"github.com/unfoldingWord/go-rc2sb/sb"
 )
 
 // Options holds conversion options passed to handlers.
 type Options struct {
+	// FS is the file system the RC repository is read from. If nil, the
+	// repository is read from inDir on disk; otherwise inDir is only used to
+	// report source paths.
+	FS fs.FS
+
+	// Filter selects which files are copied as ingredients. If nil, all
+	// files are copied.
+	Filter *Filter
+
+	// EncodingChecker, if set, checks that text files are UTF-8 as they are
+	// copied. See rc2sb.Options.TranscodeLatin1 for details.
+	EncodingChecker *EncodingChecker
+
+	// Normalizer, if set, normalizes text ingredients and ingredient keys to
+	// NFC as they are copied.
+	// See rc2sb.Options.NormalizeUnicode for details.
+	Normalizer *Normalizer
+
+	// StubDetector, if set, flags empty and stub ingredients as they are
+	// copied. See rc2sb.Options.Strict for details.
+	StubDetector *StubDetector
+
+	// Output is the destination SB files are written to. If nil, files are
+	// written beneath outDir on disk; otherwise outDir is unused.
+	Output Output
+
 	// PayloadPath is the path to a Translation Words directory for TWL conversion.
 	// See rc2sb.Options.PayloadPath for details.
 	PayloadPath string
@@ -17,6 +50,111 @@ type Options struct {
 	// USFMPath is the path to a directory containing USFM files for localized book names.
 	// See rc2sb.Options.USFMPath for details.
 	USFMPath string
+
+	// USFMSiblings lists the resource identifiers tried for a sibling USFM
+	// repository when USFMPath is empty. If nil, DefaultUSFMSiblings is used.
+	// See rc2sb.Options.USFMSiblings for details.
+	USFMSiblings []string
+
+	// USFMNames, if set, caches the localized book names read from USFM
+	// directories, so that conversions sharing it read each USFM file once.
+	USFMNames *books.USFMNameCache
+
+	// CopyrightStatement overrides the generated copyright short statement.
+	// See rc2sb.Options.CopyrightStatement for details.
+	CopyrightStatement string
+
+	// StripBOM removes a leading UTF-8 BOM from text ingredients as they are copied.
+	// See rc2sb.Options.StripBOM for details.
+	StripBOM bool
+
+	// SHA256 records a SHA-256 checksum for each ingredient beside its MD5,
+	// computed in the same pass as the copy.
+	// See rc2sb.Options.SHA256Checksums for details.
+	SHA256 bool
+
+	// RecordSources records each ingredient's source path in its x-source field.
+	// See rc2sb.Options.RecordSources for details.
+	RecordSources bool
+
+	// ExtraRootFiles and ExtraRootDirs list further root files and
+	// directories (e.g., ".apps") to copy and record as ingredients.
+	// See rc2sb.Options.ExtraRootFiles for details.
+	ExtraRootFiles []string
+	ExtraRootDirs  []string
+
+	// IncludeSourceManifest copies the RC manifest.yaml (and media.yaml) to
+	// ingredients/source/ and records them as ingredients.
+	// See rc2sb.Options.IncludeSourceManifest for details.
+	IncludeSourceManifest bool
+
+	// Report, if set, records what the conversion substituted for content
+	// missing from the RC and what it skipped.
+	Report *Report
+
+	// Tracker, if set, records the files of the repository at inDir that
+	// are copied.
+	Tracker *SourceTracker
+
+	// Books, if non-empty, restricts per-book handlers to the projects whose
+	// identifier is one of these book IDs or codes, in any case.
+	// See rc2sb.Options.Books for details.
+	Books []string
+
+	// Timestamp, if not zero, is the time recorded as the creation date and
+	// the identification timestamp, in place of the time of conversion.
+	// See rc2sb.Options.FixedTimestamp for details.
+	Timestamp time.Time
+
+	// IDAuthority, if its Key is set, replaces the default SB ID authority
+	// the handler chooses.
+	// See rc2sb.Options.IDAuthority for details.
+	IDAuthority IDAuthority
+
+	// Warn, if set, is called with non-fatal problems found during conversion.
+	Warn func(msg string)
+
+	// Logger, if set, receives informational messages, such as which sibling
+	// USFM repository was chosen, and each major step at debug level.
+	Logger *slog.Logger
+}
+
+// warn reports a non-fatal problem through o.Warn, if set.
+func (o Options) warn(format string, args ...any) {
+	if o.Warn != nil {
+		o.Warn(fmt.Sprintf(format, args...))
+	}
+}
+
+// info logs msg at info level through o.Logger, if set.
+func (o Options) info(msg string, args ...any) {
+	if o.Logger != nil {
+		o.Logger.Info(msg, args...)
+	}
+}
+
+// debug logs msg at debug level through o.Logger, if set.
+func (o Options) debug(msg string, args ...any) {
+	if o.Logger != nil {
+		o.Logger.Debug(msg, args...)
+	}
+}
+
+// date returns the time recorded as the SB's creation date: o.Timestamp if
+// set, otherwise now.
+func (o Options) date() time.Time {
+	if !o.Timestamp.IsZero() {
+		return o.Timestamp
+	}
+	return time.Now()
+}
+
+// includesBook reports whether the project with identifier id is converted
+// under o.Books.
+func (o Options) includesBook(id string) bool {
+	return len(o.Books) == 0 || slices.ContainsFunc(o.Books, func(book string) bool {
+		return strings.EqualFold(book, id)
+	})
 }
 
 // Handler is the interface that each subject-specific converter implements.
@@ -1,11 +1,23 @@
 package handler_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/unfoldingWord/go-rc2sb/handler"
 	"github.com/unfoldingWord/go-rc2sb/rc"
@@ -30,7 +42,7 @@ func TestCopyCommonRootFiles_CopiesREADMEAndGitignore(t *testing.T) {
 	}
 
 	m := sb.NewMetadata()
-	if err := handler.CopyCommonRootFiles(inDir, outDir, m); err != nil {
+	if err := handler.CopyCommonRootFiles(context.Background(), inDir, outDir, m); err != nil {
 		t.Fatalf("CopyCommonRootFiles failed: %v", err)
 	}
 
@@ -65,7 +77,7 @@ func TestCopyCommonRootFiles_CopiesGiteaDir(t *testing.T) {
 	}
 
 	m := sb.NewMetadata()
-	if err := handler.CopyCommonRootFiles(inDir, outDir, m); err != nil {
+	if err := handler.CopyCommonRootFiles(context.Background(), inDir, outDir, m); err != nil {
 		t.Fatalf("CopyCommonRootFiles failed: %v", err)
 	}
 
@@ -92,7 +104,7 @@ func TestCopyCommonRootFiles_CopiesGithubDir(t *testing.T) {
 	}
 
 	m := sb.NewMetadata()
-	if err := handler.CopyCommonRootFiles(inDir, outDir, m); err != nil {
+	if err := handler.CopyCommonRootFiles(context.Background(), inDir, outDir, m); err != nil {
 		t.Fatalf("CopyCommonRootFiles failed: %v", err)
 	}
 
@@ -111,7 +123,7 @@ func TestCopyCommonRootFiles_SkipsMissingFiles(t *testing.T) {
 
 	// No files at all — should succeed without copying anything
 	m := sb.NewMetadata()
-	if err := handler.CopyCommonRootFiles(inDir, outDir, m); err != nil {
+	if err := handler.CopyCommonRootFiles(context.Background(), inDir, outDir, m); err != nil {
 		t.Fatalf("CopyCommonRootFiles should not fail when no root files exist: %v", err)
 	}
 
@@ -134,7 +146,7 @@ func TestCopyCommonRootFiles_DoesNotCopyGitDir(t *testing.T) {
 	}
 
 	m := sb.NewMetadata()
-	if err := handler.CopyCommonRootFiles(inDir, outDir, m); err != nil {
+	if err := handler.CopyCommonRootFiles(context.Background(), inDir, outDir, m); err != nil {
 		t.Fatalf("CopyCommonRootFiles failed: %v", err)
 	}
 
@@ -159,7 +171,7 @@ func TestCopyCommonRootFiles_DoesNotAddRootFilesToIngredients(t *testing.T) {
 	}
 
 	m := sb.NewMetadata()
-	if err := handler.CopyCommonRootFiles(inDir, outDir, m); err != nil {
+	if err := handler.CopyCommonRootFiles(context.Background(), inDir, outDir, m); err != nil {
 		t.Fatalf("CopyCommonRootFiles failed: %v", err)
 	}
 
@@ -515,6 +527,40 @@ func TestTWL_AutoDetectsPayload(t *testing.T) {
 	}
 }
 
+func TestTWL_IngredientRoles(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	manifest := writeTWLManifest(t, inDir)
+	tsvContent := "Reference\tID\tTags\tOrigWords\tOccurrence\tTWLink\n" +
+		"1:1\tabcd\t\tword\t1\trc://*/tw/dict/bible/names/adam\n"
+	os.WriteFile(filepath.Join(inDir, "twl_GEN.tsv"), []byte(tsvContent), 0644)
+	os.WriteFile(filepath.Join(inDir, "LICENSE.md"), []byte("License"), 0644)
+	os.MkdirAll(filepath.Join(inDir, "en_tw", "bible", "names"), 0755)
+	os.WriteFile(filepath.Join(inDir, "en_tw", "bible", "names", "adam.md"), []byte("# Adam\n"), 0644)
+
+	h, err := handler.Lookup("TSV Translation Words Links")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	tsvRole := metadata.Ingredients["ingredients/GEN.tsv"].Role
+	payloadRole := metadata.Ingredients["ingredients/payload/names/adam.md"].Role
+	if tsvRole == "" || payloadRole == "" {
+		t.Fatalf("roles not set: tsv %q, payload %q", tsvRole, payloadRole)
+	}
+	if tsvRole == payloadRole {
+		t.Errorf("tsv and payload ingredients share role %q", tsvRole)
+	}
+	if role := metadata.Ingredients["ingredients/LICENSE.md"].Role; role != "" {
+		t.Errorf("LICENSE.md role = %q; want none", role)
+	}
+}
+
 func TestTWL_ExplicitPayloadPath(t *testing.T) {
 	inDir := t.TempDir()
 	outDir := t.TempDir()
@@ -672,6 +718,267 @@ func TestTWL_LinkRewriteMultipleLinks(t *testing.T) {
 	}
 }
 
+func TestTWL_LinkRewriteExtraCategory(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	manifest := writeTWLManifest(t, inDir)
+
+	// figs is not one of the standard kt/other/names categories
+	tsvContent := "Reference\tID\tTags\tOrigWords\tOccurrence\tTWLink\n" +
+		"1:1\ta001\t\tword1\t1\trc://*/tw/dict/bible/figs/metaphor\n" +
+		"1:2\ta002\t\tword2\t1\trc://*/tw/dict/bible/kt/god\n"
+	os.WriteFile(filepath.Join(inDir, "twl_GEN.tsv"), []byte(tsvContent), 0644)
+	os.WriteFile(filepath.Join(inDir, "LICENSE.md"), []byte("License"), 0644)
+	for _, path := range []string{"figs/metaphor.md", "kt/god.md"} {
+		fullPath := filepath.Join(inDir, "en_tw", "bible", path)
+		os.MkdirAll(filepath.Dir(fullPath), 0755)
+		os.WriteFile(fullPath, []byte("# Article\n"), 0644)
+	}
+
+	h, err := handler.Lookup("TSV Translation Words Links")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if _, ok := metadata.Ingredients["ingredients/payload/figs/metaphor.md"]; !ok {
+		t.Error("Missing payload ingredient: ingredients/payload/figs/metaphor.md")
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "ingredients", "payload", "figs", "metaphor.md")); err != nil {
+		t.Errorf("payload article not written: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "ingredients", "GEN.tsv"))
+	if err != nil {
+		t.Fatalf("Reading output TSV: %v", err)
+	}
+	want := "Reference\tID\tTags\tOrigWords\tOccurrence\tTWLink\n" +
+		"1:1\ta001\t\tword1\t1\t./payload/figs/metaphor.md\n" +
+		"1:2\ta002\t\tword2\t1\t./payload/kt/god.md\n"
+	if string(data) != want {
+		t.Errorf("GEN.tsv = %q; want %q", data, want)
+	}
+}
+
+func TestTWL_LinkRewriteTrailingNewline(t *testing.T) {
+	tests := []struct {
+		name, tsv, want string
+	}{
+		{
+			"no trailing newline",
+			"Reference\tTWLink\n1:1\trc://*/tw/dict/bible/kt/god",
+			"Reference\tTWLink\n1:1\t./payload/kt/god.md",
+		},
+		{
+			"LF",
+			"Reference\tTWLink\n1:1\trc://*/tw/dict/bible/kt/god\n",
+			"Reference\tTWLink\n1:1\t./payload/kt/god.md\n",
+		},
+		{
+			"CRLF",
+			"Reference\tTWLink\r\n1:1\trc://*/tw/dict/bible/kt/god\r\n",
+			"Reference\tTWLink\n1:1\t./payload/kt/god.md\n",
+		},
+		{
+			"CRLF without trailing newline",
+			"Reference\tTWLink\r\n1:1\trc://*/tw/dict/bible/kt/god",
+			"Reference\tTWLink\n1:1\t./payload/kt/god.md",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inDir := t.TempDir()
+			outDir := t.TempDir()
+			manifest := writeTWLManifest(t, inDir)
+			os.WriteFile(filepath.Join(inDir, "twl_GEN.tsv"), []byte(tt.tsv), 0644)
+			os.MkdirAll(filepath.Join(inDir, "en_tw", "bible", "kt"), 0755)
+			os.WriteFile(filepath.Join(inDir, "en_tw", "bible", "kt", "god.md"), []byte("# God\n"), 0644)
+
+			h, err := handler.Lookup("TSV Translation Words Links")
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+			metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+			if err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(outDir, "ingredients", "GEN.tsv"))
+			if err != nil {
+				t.Fatalf("Reading output TSV: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("GEN.tsv = %q; want %q", data, tt.want)
+			}
+			ing := metadata.Ingredients["ingredients/GEN.tsv"]
+			if want := fmt.Sprintf("%x", md5.Sum(data)); ing.Checksum.MD5 != want || ing.Size != int64(len(data)) {
+				t.Errorf("ingredient = %s, %d bytes; want %s, %d bytes", ing.Checksum.MD5, ing.Size, want, len(data))
+			}
+		})
+	}
+}
+
+func TestTWL_LinkRewriteBOMBeforeTWLinkColumn(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	manifest := writeTWLManifest(t, inDir)
+
+	// The BOM is kept, so the header's first column is "\uFEFFTWLink"
+	tsvContent := "\uFEFFTWLink\tReference\tID\n" +
+		"rc://*/tw/dict/bible/kt/god\t1:1\ta001\n"
+	os.WriteFile(filepath.Join(inDir, "twl_GEN.tsv"), []byte(tsvContent), 0644)
+	os.MkdirAll(filepath.Join(inDir, "en_tw", "bible", "kt"), 0755)
+	os.WriteFile(filepath.Join(inDir, "en_tw", "bible", "kt", "god.md"), []byte("# God\n"), 0644)
+
+	h, err := handler.Lookup("TSV Translation Words Links")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if _, ok := metadata.Ingredients["ingredients/payload/kt/god.md"]; !ok {
+		t.Error("Missing payload ingredient: ingredients/payload/kt/god.md")
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "ingredients", "GEN.tsv"))
+	if err != nil {
+		t.Fatalf("Reading output TSV: %v", err)
+	}
+	want := "\uFEFFTWLink\tReference\tID\n./payload/kt/god.md\t1:1\ta001\n"
+	if string(data) != want {
+		t.Errorf("GEN.tsv = %q; want %q", data, want)
+	}
+}
+
+func TestTW_ExtraCategory(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	for _, path := range []string{"kt/god.md", "figs/metaphor.md"} {
+		fullPath := filepath.Join(inDir, "bible", filepath.FromSlash(path))
+		os.MkdirAll(filepath.Dir(fullPath), 0755)
+		os.WriteFile(fullPath, []byte("# Article\n"), 0644)
+	}
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Translation Words",
+			Identifier: "tw",
+			Title:      "Test TW",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+	}
+	h, err := handler.Lookup("Translation Words")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	for _, key := range []string{"ingredients/kt/god.md", "ingredients/figs/metaphor.md"} {
+		if _, ok := metadata.Ingredients[key]; !ok {
+			t.Errorf("Missing ingredient: %s", key)
+		}
+	}
+}
+
+func TestTW_Symlinks(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	shared := filepath.Join(t.TempDir(), "shared")
+	os.MkdirAll(shared, 0755)
+	os.WriteFile(filepath.Join(shared, "grace.md"), []byte("# Grace\n"), 0644)
+	os.MkdirAll(filepath.Join(inDir, "bible", "kt"), 0755)
+	os.WriteFile(filepath.Join(inDir, "bible", "kt", "god.md"), []byte("# God\n"), 0644)
+
+	// A link to a file is copied as that file; a link to a directory is
+	// not followed
+	if err := os.Symlink(filepath.Join(shared, "grace.md"), filepath.Join(inDir, "bible", "kt", "grace.md")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(shared, filepath.Join(inDir, "bible", "shared")); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Translation Words",
+			Identifier: "tw",
+			Title:      "Test TW",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+	}
+	h, err := handler.Lookup("Translation Words")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "ingredients", "kt", "grace.md"))
+	if err != nil || string(data) != "# Grace\n" {
+		t.Errorf("linked file copied as %q, %v; want its target's content", data, err)
+	}
+	for key := range metadata.Ingredients {
+		if strings.HasPrefix(key, "ingredients/shared") {
+			t.Errorf("linked directory copied as %s", key)
+		}
+	}
+}
+
+func TestTWL_LinkRewriteTWLinkNotLastColumn(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	manifest := writeTWLManifest(t, inDir)
+
+	// TWLink is followed by another column, and another field contains quotes
+	// and an rc:// link that must not be touched.
+	tsvContent := "Reference\tID\tTags\tOrigWords\tOccurrence\tTWLink\tNote\n" +
+		"1:1\ta001\t\t\"word1\"\t1\trc://*/tw/dict/bible/names/adam\trc://*/tw/dict/bible/kt/god\n" +
+		"1:2\ta002\t\tword2\t1\trc://*/tw/dict/bible/kt/god\t\n"
+	os.WriteFile(filepath.Join(inDir, "twl_GEN.tsv"), []byte(tsvContent), 0644)
+	os.WriteFile(filepath.Join(inDir, "LICENSE.md"), []byte("License"), 0644)
+
+	for _, path := range []string{"names/adam.md", "kt/god.md"} {
+		fullPath := filepath.Join(inDir, "en_tw", "bible", path)
+		os.MkdirAll(filepath.Dir(fullPath), 0755)
+		os.WriteFile(fullPath, []byte("# Article\n"), 0644)
+	}
+
+	h, err := handler.Lookup("TSV Translation Words Links")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+
+	if _, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "ingredients", "GEN.tsv"))
+	if err != nil {
+		t.Fatalf("Reading output TSV: %v", err)
+	}
+
+	want := "Reference\tID\tTags\tOrigWords\tOccurrence\tTWLink\tNote\n" +
+		"1:1\ta001\t\t\"word1\"\t1\t./payload/names/adam.md\trc://*/tw/dict/bible/kt/god\n" +
+		"1:2\ta002\t\tword2\t1\t./payload/kt/god.md\t\n"
+	if string(data) != want {
+		t.Errorf("rewritten TSV mismatch:\ngot:\n%q\nwant:\n%q", string(data), want)
+	}
+}
+
 func TestTWL_StripsTWLPrefix(t *testing.T) {
 	inDir := t.TempDir()
 	outDir := t.TempDir()
@@ -807,6 +1114,47 @@ func TestTA_DoesNotCopyManifestOrMediaToRoot(t *testing.T) {
 	}
 }
 
+func TestTA_OmitsEmptyLocalizedNames(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	// With no identifier there is no resource name, so localizedNames is empty
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:  "Translation Academy",
+			Title:    "Test TA",
+			Language: rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+		Projects: []rc.Project{{Identifier: "intro", Path: "./intro"}},
+	}
+	os.MkdirAll(filepath.Join(inDir, "intro"), 0755)
+	os.WriteFile(filepath.Join(inDir, "intro", "01.md"), []byte("# Intro\n"), 0644)
+
+	h, err := handler.Lookup("Translation Academy")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if len(metadata.LocalizedNames) != 0 {
+		t.Fatalf("localizedNames = %v; want none", metadata.LocalizedNames)
+	}
+
+	data, err := metadata.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(`"localizedNames"`)) {
+		t.Errorf("metadata.json has an empty localizedNames:\n%s", data)
+	}
+	// idAuthorities is required by the SB schema, so it is always written
+	if !bytes.Contains(data, []byte(`"idAuthorities"`)) {
+		t.Errorf("metadata.json has no idAuthorities:\n%s", data)
+	}
+}
+
 func TestOBS_DoesNotCopyManifestOrMediaToRoot(t *testing.T) {
 	inDir := t.TempDir()
 	outDir := t.TempDir()
@@ -877,9 +1225,11 @@ func TestLookup_AllRegisteredSubjects(t *testing.T) {
 		"Hebrew Old Testament",
 		"Greek New Testament",
 		"Translation Words",
+		"OBS Translation Words",
 		"Translation Academy",
 		"TSV Translation Notes",
 		"TSV Translation Questions",
+		"TSV Study Notes",
 		"TSV Translation Words Links",
 		"TSV OBS Study Notes",
 		"TSV OBS Study Questions",
@@ -902,8 +1252,8 @@ func TestLookup_AllRegisteredSubjects(t *testing.T) {
 
 func TestSupportedSubjects_Count(t *testing.T) {
 	subjects := handler.SupportedSubjects()
-	if len(subjects) != 14 {
-		t.Errorf("SupportedSubjects() returned %d subjects; want 14. Got: %v", len(subjects), subjects)
+	if len(subjects) != 16 {
+		t.Errorf("SupportedSubjects() returned %d subjects; want 16. Got: %v", len(subjects), subjects)
 	}
 }
 
@@ -923,7 +1273,7 @@ func TestCopyLicenseIngredient_MissingLicenseUsesDefault(t *testing.T) {
 	inDir := t.TempDir()  // No LICENSE.md
 	outDir := t.TempDir()
 
-	ing, err := handler.CopyLicenseIngredient(inDir, outDir)
+	ing, err := handler.CopyLicenseIngredient(context.Background(), inDir, outDir)
 	if err != nil {
 		t.Fatalf("CopyLicenseIngredient should not fail when LICENSE.md is missing: %v", err)
 	}
@@ -960,7 +1310,7 @@ func TestCopyLicenseIngredient_ExistingLicensePreferred(t *testing.T) {
 	customContent := "Custom License Content"
 	os.WriteFile(filepath.Join(inDir, "LICENSE.md"), []byte(customContent), 0644)
 
-	_, err := handler.CopyLicenseIngredient(inDir, outDir)
+	_, err := handler.CopyLicenseIngredient(context.Background(), inDir, outDir)
 	if err != nil {
 		t.Fatalf("CopyLicenseIngredient failed: %v", err)
 	}
@@ -979,7 +1329,7 @@ func TestCopyLicenseToRoot_MissingLicenseUsesDefault(t *testing.T) {
 	inDir := t.TempDir()  // No LICENSE.md
 	outDir := t.TempDir()
 
-	err := handler.CopyLicenseToRoot(inDir, outDir)
+	err := handler.CopyLicenseToRoot(context.Background(), inDir, outDir)
 	if err != nil {
 		t.Fatalf("CopyLicenseToRoot should not fail when LICENSE.md is missing: %v", err)
 	}
@@ -1001,7 +1351,7 @@ func TestCopyLicenseToRoot_ExistingLicensePreferred(t *testing.T) {
 	customContent := "My Custom License"
 	os.WriteFile(filepath.Join(inDir, "LICENSE.md"), []byte(customContent), 0644)
 
-	err := handler.CopyLicenseToRoot(inDir, outDir)
+	err := handler.CopyLicenseToRoot(context.Background(), inDir, outDir)
 	if err != nil {
 		t.Fatalf("CopyLicenseToRoot failed: %v", err)
 	}
@@ -1533,3 +1883,2110 @@ func TestOBS_ContentSubdirectory_StillWorks(t *testing.T) {
 		}
 	}
 }
+
+// --- Ingredient key collision tests ---
+
+func TestTN_TwoProjectsSameBookCollide(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	// Two different source files that both map to ingredients/GEN.tsv
+	tsvContent := "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n"
+	os.WriteFile(filepath.Join(inDir, "tn_GEN.tsv"), []byte(tsvContent+"1:1\ta001\t\t\tword\t1\tFirst\n"), 0644)
+	os.WriteFile(filepath.Join(inDir, "GEN.tsv"), []byte(tsvContent+"1:1\ta002\t\t\tword\t1\tSecond\n"), 0644)
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "TSV Translation Notes",
+			Identifier: "tn",
+			Title:      "Test TN",
+			Language: rc.Language{
+				Identifier: "en",
+				Title:      "English",
+				Direction:  "ltr",
+			},
+		},
+		Projects: []rc.Project{
+			{Identifier: "gen", Path: "./tn_GEN.tsv", Sort: 1, Title: "Genesis"},
+			{Identifier: "gen", Path: "./GEN.tsv", Sort: 1, Title: "Genesis"},
+		},
+	}
+
+	h, err := handler.Lookup("TSV Translation Notes")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+
+	_, err = h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err == nil {
+		t.Fatal("expected an ingredient collision error for two projects mapping to GEN.tsv")
+	}
+	for _, want := range []string{"ingredients/GEN.tsv", "tn_GEN.tsv", filepath.Join(inDir, "GEN.tsv")} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("collision error %q should mention %q", err, want)
+		}
+	}
+
+	// The first file must not have been overwritten by the second
+	data, err := os.ReadFile(filepath.Join(outDir, "ingredients", "GEN.tsv"))
+	if err != nil {
+		t.Fatalf("Reading output TSV: %v", err)
+	}
+	if !strings.Contains(string(data), "First") {
+		t.Error("ingredients/GEN.tsv was overwritten by the colliding project")
+	}
+}
+
+func TestBible_SameProjectListedTwiceDoesNotCollide(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	os.WriteFile(filepath.Join(inDir, "01-GEN.usfm"), []byte("\\id GEN\n\\toc1 Genesis\n"), 0644)
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Bible",
+			Identifier: "ult",
+			Title:      "Test Bible",
+			Language: rc.Language{
+				Identifier: "en",
+				Title:      "English",
+				Direction:  "ltr",
+			},
+		},
+		Projects: []rc.Project{
+			{Identifier: "gen", Path: "./01-GEN.usfm", Sort: 1, Title: "Genesis"},
+			{Identifier: "gen", Path: "./01-GEN.usfm", Sort: 1, Title: "Genesis"},
+		},
+	}
+
+	h, err := handler.Lookup("Bible")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+
+	if _, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{}); err != nil {
+		t.Fatalf("the same source listed twice should not be a collision: %v", err)
+	}
+}
+
+// --- Copyright tests ---
+
+func hindiOBSManifest() *rc.Manifest {
+	return &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Open Bible Stories",
+			Identifier: "obs",
+			Title:      "खुली बाइबल कहानियाँ",
+			Issued:     "2024-01-01",
+			Publisher:  "unfoldingWord",
+			Rights:     "CC BY-SA 4.0",
+			Language: rc.Language{
+				Identifier: "hi",
+				Title:      "हिन्दी",
+				Direction:  "ltr",
+			},
+		},
+	}
+}
+
+func TestOBS_CopyrightUsesManifestLanguage(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(inDir, "content"), 0755)
+	os.WriteFile(filepath.Join(inDir, "content", "01.md"), []byte("# कहानी 1\n"), 0644)
+
+	h, err := handler.Lookup("Open Bible Stories")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+
+	metadata, err := h.Convert(context.Background(), hindiOBSManifest(), inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	stmts := metadata.Copyright.ShortStatements
+	if len(stmts) != 1 {
+		t.Fatalf("expected 1 short statement, got %d", len(stmts))
+	}
+	if stmts[0].Lang != "hi" {
+		t.Errorf("statement Lang = %q; want %q", stmts[0].Lang, "hi")
+	}
+	if stmts[0].MimeType != "text/plain" {
+		t.Errorf("statement MimeType = %q; want %q", stmts[0].MimeType, "text/plain")
+	}
+	if stmts[0].Statement != "Copyright © 2024 by unfoldingWord" {
+		t.Errorf("statement = %q; want generated OBS statement", stmts[0].Statement)
+	}
+}
+
+func TestOBS_CopyrightStatementOverride(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(inDir, "content"), 0755)
+	os.WriteFile(filepath.Join(inDir, "content", "01.md"), []byte("# कहानी 1\n"), 0644)
+
+	h, err := handler.Lookup("Open Bible Stories")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+
+	localized := "कॉपीराइट © 2024 unfoldingWord"
+	opts := handler.Options{CopyrightStatement: localized}
+	metadata, err := h.Convert(context.Background(), hindiOBSManifest(), inDir, outDir, opts)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	stmt := metadata.Copyright.ShortStatements[0]
+	if stmt.Statement != localized {
+		t.Errorf("statement = %q; want %q", stmt.Statement, localized)
+	}
+	if stmt.Lang != "hi" {
+		t.Errorf("statement Lang = %q; want %q", stmt.Lang, "hi")
+	}
+}
+
+// --- Include/exclude filter tests ---
+
+func TestFilter_Allows(t *testing.T) {
+	tests := []struct {
+		include []string
+		exclude []string
+		key     string
+		want    bool
+	}{
+		{nil, []string{"**/*.bak"}, "ingredients/kt/god.md.bak", false},
+		{nil, []string{"**/*.bak"}, "ingredients/notes.bak", false},
+		{nil, []string{"**/*.bak"}, "ingredients/kt/god.md", true},
+		{nil, []string{"ingredients/**/.vscode/**"}, "ingredients/translate/.vscode/settings.json", false},
+		{[]string{"ingredients/GEN.*"}, nil, "ingredients/GEN.usfm", true},
+		{[]string{"ingredients/GEN.*"}, nil, "ingredients/EXO.usfm", false},
+		{[]string{"ingredients/GEN.*"}, nil, "ingredients/payload/GEN.md", false},
+		{[]string{"ingredients/**"}, []string{"**/*.bak"}, "ingredients/a/b.bak", false},
+	}
+	for _, tt := range tests {
+		f, err := handler.NewFilter(tt.include, tt.exclude)
+		if err != nil {
+			t.Fatalf("NewFilter(%v, %v): %v", tt.include, tt.exclude, err)
+		}
+		if got := f.Allows(tt.key); got != tt.want {
+			t.Errorf("include %v, exclude %v: Allows(%q) = %v; want %v", tt.include, tt.exclude, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestNewFilter_InvalidPattern(t *testing.T) {
+	if _, err := handler.NewFilter(nil, []string{"ingredients/[GEN"}); err == nil {
+		t.Fatal("expected error for malformed pattern")
+	}
+}
+
+func TestTW_ExcludeGlobSkipsBackupFiles(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(inDir, "bible", "kt"), 0755)
+	os.WriteFile(filepath.Join(inDir, "bible", "kt", "god.md"), []byte("# God\n"), 0644)
+	os.WriteFile(filepath.Join(inDir, "bible", "kt", "god.md.bak"), []byte("# Old\n"), 0644)
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Translation Words",
+			Identifier: "tw",
+			Title:      "Test TW",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+	}
+
+	filter, err := handler.NewFilter(nil, []string{"**/*.bak"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := handler.Lookup("Translation Words")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{Filter: filter})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if _, ok := metadata.Ingredients["ingredients/kt/god.md"]; !ok {
+		t.Error("ingredients/kt/god.md should be copied")
+	}
+	if _, ok := metadata.Ingredients["ingredients/kt/god.md.bak"]; ok {
+		t.Error("ingredients/kt/god.md.bak should be excluded from metadata")
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "ingredients", "kt", "god.md.bak")); !os.IsNotExist(err) {
+		t.Error("ingredients/kt/god.md.bak should not be written")
+	}
+	if got := filter.Excluded(); len(got) != 1 || got[0] != "ingredients/kt/god.md.bak" {
+		t.Errorf("Excluded() = %v; want [ingredients/kt/god.md.bak]", got)
+	}
+}
+
+func TestBible_IncludeGlobRestrictsBooks(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	os.WriteFile(filepath.Join(inDir, "01-GEN.usfm"), []byte("\\id GEN\n\\toc1 Genesis\n"), 0644)
+	os.WriteFile(filepath.Join(inDir, "02-EXO.usfm"), []byte("\\id EXO\n\\toc1 Exodus\n"), 0644)
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Bible",
+			Identifier: "ult",
+			Title:      "Test Bible",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+		Projects: []rc.Project{
+			{Identifier: "gen", Path: "./01-GEN.usfm", Sort: 1, Title: "Genesis"},
+			{Identifier: "exo", Path: "./02-EXO.usfm", Sort: 2, Title: "Exodus"},
+		},
+	}
+
+	filter, err := handler.NewFilter([]string{"ingredients/GEN.*"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := handler.Lookup("Bible")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{Filter: filter})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if _, ok := metadata.Ingredients["ingredients/GEN.usfm"]; !ok {
+		t.Error("ingredients/GEN.usfm should be copied")
+	}
+	if _, ok := metadata.Ingredients["ingredients/EXO.usfm"]; ok {
+		t.Error("ingredients/EXO.usfm should be excluded")
+	}
+	if _, ok := metadata.Ingredients["ingredients/LICENSE.md"]; !ok {
+		t.Error("ingredients/LICENSE.md should always be included")
+	}
+	if _, ok := metadata.Type.FlavorType.CurrentScope["EXO"]; ok {
+		t.Error("excluded book EXO should not be in currentScope")
+	}
+	if got := filter.Excluded(); len(got) != 1 || got[0] != "ingredients/EXO.usfm" {
+		t.Errorf("Excluded() = %v; want [ingredients/EXO.usfm]", got)
+	}
+}
+
+// --- Default license selection tests ---
+
+func twManifestWithRights(rights string) *rc.Manifest {
+	return &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Translation Words",
+			Identifier: "tw",
+			Title:      "Test TW",
+			Rights:     rights,
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+	}
+}
+
+func TestDefaultLicense_MatchesDeclaredRights(t *testing.T) {
+	inDir := t.TempDir() // No LICENSE.md
+	outDir := t.TempDir()
+	os.MkdirAll(filepath.Join(inDir, "bible", "kt"), 0755)
+	os.WriteFile(filepath.Join(inDir, "bible", "kt", "god.md"), []byte("# God\n"), 0644)
+
+	h, err := handler.Lookup("Translation Words")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	var warnings []string
+	opts := handler.Options{Warn: func(msg string) { warnings = append(warnings, msg) }}
+	if _, err := h.Convert(context.Background(), twManifestWithRights("CC BY 4.0"), inDir, outDir, opts); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	for _, name := range []string{"LICENSE.md", filepath.Join("ingredients", "LICENSE.md")} {
+		data, err := os.ReadFile(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatalf("reading %s: %v", name, err)
+		}
+		if !strings.Contains(string(data), "Creative Commons Attribution 4.0 International (CC BY 4.0)") {
+			t.Errorf("%s should contain CC BY 4.0 text", name)
+		}
+		if strings.Contains(string(data), "ShareAlike") {
+			t.Errorf("%s should not contain CC BY-SA text", name)
+		}
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+}
+
+func TestDefaultLicense_UnknownRightsWarns(t *testing.T) {
+	inDir := t.TempDir() // No LICENSE.md
+	outDir := t.TempDir()
+	os.MkdirAll(filepath.Join(inDir, "bible"), 0755)
+
+	h, err := handler.Lookup("Translation Words")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	var warnings []string
+	opts := handler.Options{Warn: func(msg string) { warnings = append(warnings, msg) }}
+	if _, err := h.Convert(context.Background(), twManifestWithRights("Freely Given"), inDir, outDir, opts); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "ingredients", "LICENSE.md"))
+	if err != nil {
+		t.Fatalf("reading ingredients/LICENSE.md: %v", err)
+	}
+	if !strings.Contains(string(data), "Creative Commons Attribution-ShareAlike 4.0") {
+		t.Error("unmatched rights should fall back to CC BY-SA 4.0")
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "Freely Given") {
+		t.Errorf("warnings = %v; want one mentioning the declared rights", warnings)
+	}
+}
+
+func TestDefaultLicense_ExistingLicenseNoWarning(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+	os.MkdirAll(filepath.Join(inDir, "bible"), 0755)
+	os.WriteFile(filepath.Join(inDir, "LICENSE.md"), []byte("Custom license\n"), 0644)
+
+	h, err := handler.Lookup("Translation Words")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	var warnings []string
+	opts := handler.Options{Warn: func(msg string) { warnings = append(warnings, msg) }}
+	if _, err := h.Convert(context.Background(), twManifestWithRights("Freely Given"), inDir, outDir, opts); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("no warning expected when the RC has its own LICENSE.md: %v", warnings)
+	}
+}
+
+// --- BOM stripping tests ---
+
+func tnManifest() *rc.Manifest {
+	return &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "TSV Translation Notes",
+			Identifier: "tn",
+			Title:      "Test TN",
+			Rights:     "CC BY-SA 4.0",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+		Projects: []rc.Project{
+			{Identifier: "gen", Path: "./tn_GEN.tsv", Sort: 1, Title: "Genesis"},
+		},
+	}
+}
+
+func TestTN_StripBOM(t *testing.T) {
+	tsvContent := "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n1:1\tabcd\t\t\tword\t1\tA note\n"
+
+	tests := []struct {
+		name     string
+		stripBOM bool
+		want     string
+	}{
+		{"stripped", true, tsvContent},
+		{"default keeps BOM", false, "\uFEFF" + tsvContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inDir := t.TempDir()
+			outDir := t.TempDir()
+			os.WriteFile(filepath.Join(inDir, "tn_GEN.tsv"), []byte("\uFEFF"+tsvContent), 0644)
+			os.WriteFile(filepath.Join(inDir, "LICENSE.md"), []byte("License"), 0644)
+
+			h, err := handler.Lookup("TSV Translation Notes")
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+			metadata, err := h.Convert(context.Background(), tnManifest(), inDir, outDir, handler.Options{StripBOM: tt.stripBOM})
+			if err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+
+			outPath := filepath.Join(outDir, "ingredients", "GEN.tsv")
+			data, err := os.ReadFile(outPath)
+			if err != nil {
+				t.Fatalf("reading output TSV: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("output = %q; want %q", data, tt.want)
+			}
+
+			want, err := sb.ComputeIngredient(outPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := metadata.Ingredients["ingredients/GEN.tsv"]
+			if got.Checksum.MD5 != want.Checksum.MD5 || got.Size != want.Size {
+				t.Errorf("ingredient %s/%d does not match output %s/%d", got.Checksum.MD5, got.Size, want.Checksum.MD5, want.Size)
+			}
+		})
+	}
+}
+
+func TestTWL_StripBOMWithLinkRewrite(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	manifest := writeTWLManifest(t, inDir)
+	tsvContent := "\uFEFFReference\tID\tTags\tOrigWords\tOccurrence\tTWLink\n" +
+		"1:1\tabcd\t\tword\t1\trc://*/tw/dict/bible/names/adam\n"
+	os.WriteFile(filepath.Join(inDir, "twl_GEN.tsv"), []byte(tsvContent), 0644)
+	os.MkdirAll(filepath.Join(inDir, "en_tw", "bible", "names"), 0755)
+	os.WriteFile(filepath.Join(inDir, "en_tw", "bible", "names", "adam.md"), []byte("# Adam\n"), 0644)
+
+	h, err := handler.Lookup("TSV Translation Words Links")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if _, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{StripBOM: true}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "ingredients", "GEN.tsv"))
+	if err != nil {
+		t.Fatalf("reading output TSV: %v", err)
+	}
+	want := "Reference\tID\tTags\tOrigWords\tOccurrence\tTWLink\n1:1\tabcd\t\tword\t1\t./payload/names/adam.md\n"
+	if string(data) != want {
+		t.Errorf("output = %q; want %q", data, want)
+	}
+}
+
+func TestOBS_StripBOMLeavesBinaryFiles(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(inDir, "content"), 0755)
+	os.WriteFile(filepath.Join(inDir, "content", "01.md"), []byte("\uFEFF# Story 1\n"), 0644)
+	image := []byte("\uFEFFnot really a jpeg")
+	os.WriteFile(filepath.Join(inDir, "content", "01.jpg"), image, 0644)
+
+	h, err := handler.Lookup("Open Bible Stories")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if _, err := h.Convert(context.Background(), hindiOBSManifest(), inDir, outDir, handler.Options{StripBOM: true}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	md, err := os.ReadFile(filepath.Join(outDir, "ingredients", "content", "01.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(md) != "# Story 1\n" {
+		t.Errorf("01.md = %q; want BOM stripped", md)
+	}
+	jpg, err := os.ReadFile(filepath.Join(outDir, "ingredients", "content", "01.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(jpg) != string(image) {
+		t.Errorf("01.jpg = %q; binary files should be copied byte-for-byte", jpg)
+	}
+}
+
+// --- Manifest mapping tests ---
+
+func TestMapManifest(t *testing.T) {
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Identifier: "ult",
+			Title:      "unfoldingWord Literal Text",
+			Issued:     "2023-05-01",
+			Publisher:  "unfoldingWord",
+			Rights:     "CC BY-SA 4.0",
+			Language:   rc.Language{Identifier: "ar", Title: "العربية", Direction: "rtl"},
+		},
+	}
+	date := time.Date(2024, 2, 3, 4, 5, 6, 7_000_000, time.FixedZone("EST", -5*60*60))
+
+	tests := []struct {
+		name          string
+		opts          handler.MetadataOptions
+		wantAbbr      string
+		wantAuthority string
+		wantStatement string
+	}{
+		{
+			name:          "abbreviation from identifier",
+			opts:          handler.MetadataOptions{IDAuthority: "uWBurritos", Date: date},
+			wantAbbr:      "ULT",
+			wantAuthority: "https://git.door43.org/uW",
+			wantStatement: "\u00a9 unfoldingWord 2023, CC BY-SA 4.0",
+		},
+		{
+			name:          "explicit abbreviation and OBS copyright",
+			opts:          handler.MetadataOptions{IDAuthority: "BurritoTruck", Abbreviation: "OBS", OBSCopyright: true, Date: date},
+			wantAbbr:      "OBS",
+			wantAuthority: "https://git.door43.org/BurritoTruck",
+			wantStatement: "Copyright \u00a9 2023 by unfoldingWord",
+		},
+		{
+			name:          "copyright override",
+			opts:          handler.MetadataOptions{IDAuthority: "uWBurritos", CopyrightStatement: "حقوق النشر", Date: date},
+			wantAbbr:      "ULT",
+			wantAuthority: "https://git.door43.org/uW",
+			wantStatement: "حقوق النشر",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := handler.MapManifest(manifest, tt.opts)
+
+			if got := m.IDAuthorities[tt.opts.IDAuthority].ID; got != tt.wantAuthority {
+				t.Errorf("ID authority = %q; want %q", got, tt.wantAuthority)
+			}
+			if got := m.Identification.Abbreviation["en"]; got != tt.wantAbbr {
+				t.Errorf("abbreviation = %q; want %q", got, tt.wantAbbr)
+			}
+			entry, ok := m.Identification.Primary[tt.opts.IDAuthority][tt.wantAbbr]
+			if !ok {
+				t.Fatalf("no primary entry for %s/%s", tt.opts.IDAuthority, tt.wantAbbr)
+			}
+			if entry.Timestamp != "2024-02-03T09:05:06.007Z" || m.Meta.DateCreated != entry.Timestamp {
+				t.Errorf("timestamp = %q, dateCreated = %q; want the UTC date", entry.Timestamp, m.Meta.DateCreated)
+			}
+			if m.Identification.Name["en"] != "unfoldingWord Literal Text" {
+				t.Errorf("name = %q", m.Identification.Name["en"])
+			}
+			if len(m.Languages) != 1 || m.Languages[0].Tag != "ar" || m.Languages[0].ScriptDirection != "rtl" {
+				t.Errorf("languages = %+v", m.Languages)
+			}
+			if len(m.Copyright.ShortStatements) != 1 {
+				t.Fatalf("copyright = %+v", m.Copyright)
+			}
+			if cs := m.Copyright.ShortStatements[0]; cs.Statement != tt.wantStatement || cs.Lang != "ar" {
+				t.Errorf("copyright = %+v; want %q in ar", cs, tt.wantStatement)
+			}
+			if len(m.Ingredients) != 0 {
+				t.Errorf("ingredients = %v; want none", m.Ingredients)
+			}
+		})
+	}
+}
+
+func TestMapManifest_Generator(t *testing.T) {
+	manifest := &rc.Manifest{DublinCore: rc.DublinCore{Identifier: "tn"}}
+
+	m := handler.MapManifest(manifest, handler.MetadataOptions{IDAuthority: "uWBurritos"})
+	if m.Meta.Generator.SoftwareName != "go-rc2sb" {
+		t.Errorf("default generator = %+v", m.Meta.Generator)
+	}
+
+	gen := sb.Generator{SoftwareName: "door43-pipeline", SoftwareVersion: "2.1"}
+	m = handler.MapManifest(manifest, handler.MetadataOptions{IDAuthority: "uWBurritos", Generator: gen})
+	if m.Meta.Generator != gen {
+		t.Errorf("generator = %+v; want %+v", m.Meta.Generator, gen)
+	}
+}
+
+func TestBible_CurrentScopeAggregatesAllBooks(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Aligned Bible",
+			Identifier: "ult",
+			Title:      "Test Bible",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+	}
+	codes := []string{"GEN", "EXO", "RUT", "MAT", "REV"}
+	for i, code := range codes {
+		name := fmt.Sprintf("%02d-%s.usfm", i+1, code)
+		os.WriteFile(filepath.Join(inDir, name), []byte("\\id "+code+"\n"), 0644)
+		manifest.Projects = append(manifest.Projects, rc.Project{
+			Identifier: strings.ToLower(code), Path: "./" + name, Sort: i + 1,
+		})
+	}
+	// A project whose file is missing is not covered
+	manifest.Projects = append(manifest.Projects, rc.Project{Identifier: "jhn", Path: "./44-JHN.usfm"})
+
+	h, err := handler.Lookup("Aligned Bible")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	scope := metadata.Type.FlavorType.CurrentScope
+	if len(scope) != len(codes) {
+		t.Errorf("currentScope = %v; want exactly %v", scope, codes)
+	}
+	for _, code := range codes {
+		if chapters, ok := scope[code]; !ok || len(chapters) != 0 {
+			t.Errorf("currentScope[%s] = %v, %v; want the whole book", code, chapters, ok)
+		}
+	}
+	if !reflect.DeepEqual(scope, metadata.AggregateScope()) {
+		t.Errorf("currentScope %v differs from AggregateScope() %v", scope, metadata.AggregateScope())
+	}
+}
+
+// --- Unreadable source tests ---
+
+func twTestManifest() *rc.Manifest {
+	return &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Translation Words",
+			Identifier: "tw",
+			Title:      "Test TW",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+	}
+}
+
+func TestTW_UnreadableSourceFile(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("file permissions are not enforced for root")
+	}
+	inDir := t.TempDir()
+	os.MkdirAll(filepath.Join(inDir, "bible", "kt"), 0755)
+	srcPath := filepath.Join(inDir, "bible", "kt", "god.md")
+	os.WriteFile(srcPath, []byte("# God\n"), 0644)
+	if err := os.Chmod(srcPath, 0000); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(srcPath, 0644) })
+
+	h, err := handler.Lookup("Translation Words")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	_, err = h.Convert(context.Background(), twTestManifest(), inDir, t.TempDir(), handler.Options{})
+	if err == nil {
+		t.Fatal("expected error for unreadable source file")
+	}
+	for _, want := range []string{srcPath, "ingredients/kt/god.md", "not readable", "permission denied"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("error %v does not wrap fs.ErrPermission", err)
+	}
+}
+
+// failingFS fails to open the named files with the given errors.
+type failingFS struct {
+	fs.FS
+	fail map[string]error
+}
+
+func (f failingFS) Open(name string) (fs.File, error) {
+	if err, ok := f.fail[name]; ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return f.FS.Open(name)
+}
+
+func TestTW_SourceErrorsDistinguishNotFoundAndPermission(t *testing.T) {
+	files := fstest.MapFS{
+		"bible/kt/god.md":      {Data: []byte("# God\n")},
+		"bible/names/adam.md":  {Data: []byte("# Adam\n")},
+		"bible/other/bread.md": {Data: []byte("# Bread\n")},
+	}
+	inDir := filepath.Join("repos", "en_tw")
+
+	tests := []struct {
+		name  string
+		fail  string
+		err   error
+		wants []string
+	}{
+		{
+			name:  "file permission denied",
+			fail:  "bible/kt/god.md",
+			err:   fs.ErrPermission,
+			wants: []string{filepath.Join(inDir, "bible", "kt", "god.md"), "ingredients/kt/god.md", "source not readable"},
+		},
+		{
+			name:  "file not found",
+			fail:  "bible/names/adam.md",
+			err:   fs.ErrNotExist,
+			wants: []string{filepath.Join(inDir, "bible", "names", "adam.md"), "ingredients/names/adam.md", "source not found"},
+		},
+		{
+			name:  "directory permission denied",
+			fail:  "bible/other",
+			err:   fs.ErrPermission,
+			wants: []string{filepath.Join(inDir, "bible", "other"), "source not readable"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := handler.Lookup("Translation Words")
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+			opts := handler.Options{FS: failingFS{FS: files, fail: map[string]error{tt.fail: tt.err}}}
+			_, err = h.Convert(context.Background(), twTestManifest(), inDir, t.TempDir(), opts)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, want := range tt.wants {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error %v does not wrap %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// --- Source path recording tests ---
+
+func TestTN_RecordSources(t *testing.T) {
+	inDir := t.TempDir()
+	os.WriteFile(filepath.Join(inDir, "tn_GEN.tsv"), []byte("Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n"), 0644)
+	os.WriteFile(filepath.Join(inDir, "LICENSE.md"), []byte("License"), 0644)
+
+	h, err := handler.Lookup("TSV Translation Notes")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+
+	metadata, err := h.Convert(context.Background(), tnManifest(), inDir, t.TempDir(), handler.Options{RecordSources: true})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if got := metadata.Ingredients["ingredients/GEN.tsv"].Source; got != "tn_GEN.tsv" {
+		t.Errorf("ingredients/GEN.tsv source = %q; want tn_GEN.tsv", got)
+	}
+	if got := metadata.Ingredients["ingredients/LICENSE.md"].Source; got != "LICENSE.md" {
+		t.Errorf("ingredients/LICENSE.md source = %q; want LICENSE.md", got)
+	}
+
+	// Off by default, so metadata.json is unchanged
+	metadata, err = h.Convert(context.Background(), tnManifest(), inDir, t.TempDir(), handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	data, err := metadata.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "x-source") {
+		t.Errorf("metadata.json records sources by default:\n%s", data)
+	}
+}
+
+func TestTWL_RecordSourcesForPayload(t *testing.T) {
+	inDir := t.TempDir()
+	os.WriteFile(filepath.Join(inDir, "twl_GEN.tsv"), []byte("Reference\tID\tTags\tOrigWords\tOccurrence\tTWLink\n1:1\ta\t\tw\t1\trc://*/tw/dict/bible/kt/god\n"), 0644)
+	os.MkdirAll(filepath.Join(inDir, "en_tw", "bible", "kt"), 0755)
+	os.WriteFile(filepath.Join(inDir, "en_tw", "bible", "kt", "god.md"), []byte("# God\n"), 0644)
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "TSV Translation Words Links",
+			Identifier: "twl",
+			Title:      "Test TWL",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+		Projects: []rc.Project{{Identifier: "gen", Path: "./twl_GEN.tsv", Sort: 1}},
+	}
+	h, err := handler.Lookup("TSV Translation Words Links")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, t.TempDir(), handler.Options{RecordSources: true})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	want := map[string]string{
+		"ingredients/GEN.tsv":           "twl_GEN.tsv",
+		"ingredients/payload/kt/god.md": "en_tw/bible/kt/god.md",
+		"ingredients/LICENSE.md":        "", // embedded default
+	}
+	for key, source := range want {
+		ing, ok := metadata.Ingredients[key]
+		if !ok {
+			t.Errorf("missing ingredient %s", key)
+			continue
+		}
+		if ing.Source != source {
+			t.Errorf("%s source = %q; want %q", key, ing.Source, source)
+		}
+	}
+}
+
+func TestBible_AlternateUSFMFilenames(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Bible",
+			Identifier: "ulb",
+			Title:      "Test Bible",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+	}
+	files := map[string]string{"41_MRK.usfm": "mrk", "MAT_40.usfm": "mat", "LUK.usfm": "luk", "67-XXA.usfm": "xxa"}
+	for name, id := range files {
+		os.WriteFile(filepath.Join(inDir, name), []byte("\\id "+strings.ToUpper(id)+"\n"), 0644)
+		manifest.Projects = append(manifest.Projects, rc.Project{Identifier: id, Path: "./" + name})
+	}
+
+	h, err := handler.Lookup("Bible")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	for _, key := range []string{"ingredients/MRK.usfm", "ingredients/MAT.usfm", "ingredients/LUK.usfm", "ingredients/XXA.usfm"} {
+		if _, ok := metadata.Ingredients[key]; !ok {
+			t.Errorf("missing ingredient %s", key)
+		}
+	}
+	if ing := metadata.Ingredients["ingredients/MRK.usfm"]; ing.Scope == nil || ing.Scope["MRK"] == nil {
+		t.Errorf("MRK.usfm scope = %v; want MRK", ing.Scope)
+	}
+}
+
+func TestMapManifest_LanguageNames(t *testing.T) {
+	tests := []struct {
+		lang rc.Language
+		want map[string]string
+	}{
+		{rc.Language{Identifier: "hi", Title: "हिन्दी"}, map[string]string{"en": "Hindi", "hi": "हिन्दी"}},
+		{rc.Language{Identifier: "en", Title: "English"}, map[string]string{"en": "English"}},
+		// Unknown languages fall back to the manifest title
+		{rc.Language{Identifier: "xyz", Title: "Xyzish"}, map[string]string{"en": "Xyzish"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang.Identifier, func(t *testing.T) {
+			manifest := &rc.Manifest{DublinCore: rc.DublinCore{Identifier: "tn", Language: tt.lang}}
+			m := handler.MapManifest(manifest, handler.MetadataOptions{IDAuthority: "uWBurritos"})
+			if len(m.Languages) != 1 || !reflect.DeepEqual(m.Languages[0].Name, tt.want) {
+				t.Errorf("languages = %+v; want name %v", m.Languages, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapManifest_LanguageScript(t *testing.T) {
+	tests := []struct {
+		lang rc.Language
+		want string
+	}{
+		{rc.Language{Identifier: "hbo", Title: "Ancient Hebrew", Direction: "rtl"}, "Hebr"},
+		{rc.Language{Identifier: "el-x-koine", Title: "Koine Greek", Direction: "ltr"}, "Grek"},
+		// Unknown scripts are left out
+		{rc.Language{Identifier: "xyz", Title: "Xyzish", Direction: "ltr"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang.Identifier, func(t *testing.T) {
+			manifest := &rc.Manifest{DublinCore: rc.DublinCore{Identifier: "uhb", Language: tt.lang}}
+			m := handler.MapManifest(manifest, handler.MetadataOptions{IDAuthority: "uWBurritos"})
+			if len(m.Languages) != 1 || m.Languages[0].Script != tt.want {
+				t.Fatalf("languages = %+v; want script %q", m.Languages, tt.want)
+			}
+
+			data, err := json.Marshal(m.Languages[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hasScript := strings.Contains(string(data), `"script"`); hasScript != (tt.want != "") {
+				t.Errorf("language JSON = %s; want script only when known", data)
+			}
+		})
+	}
+}
+
+func TestTN_LocalizedNamesFromSiblingUSFM(t *testing.T) {
+	// hi_tn is checked out beside hi_irv and hi_ult
+	root := t.TempDir()
+	inDir := filepath.Join(root, "hi_tn")
+	for dir, toc1 := range map[string]string{"hi_irv": "IRV Genesis", "hi_ult": "ULT Genesis", "explicit": "Explicit Genesis"} {
+		os.MkdirAll(filepath.Join(root, dir), 0755)
+		usfm := "\\id GEN\n\\toc1 " + toc1 + "\n\\toc2 Genesis\n\\toc3 Gen\n"
+		os.WriteFile(filepath.Join(root, dir, "01-GEN.usfm"), []byte(usfm), 0644)
+	}
+	os.MkdirAll(inDir, 0755)
+	tsvContent := "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n1:1\tabcd\t\t\tword\t1\tA note\n"
+	os.WriteFile(filepath.Join(inDir, "tn_GEN.tsv"), []byte(tsvContent), 0644)
+	os.WriteFile(filepath.Join(inDir, "LICENSE.md"), []byte("License"), 0644)
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "TSV Translation Notes",
+			Identifier: "tn",
+			Title:      "Hindi TN",
+			Issued:     "2024-01-01",
+			Publisher:  "test",
+			Rights:     "CC BY-SA 4.0",
+			Language:   rc.Language{Identifier: "hi", Title: "Hindi", Direction: "ltr"},
+		},
+		Projects: []rc.Project{
+			{Identifier: "gen", Path: "./tn_GEN.tsv", Sort: 1, Title: "Manifest Genesis"},
+		},
+	}
+
+	h, err := handler.Lookup("TSV Translation Notes")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    handler.Options
+		want    string
+		sibling bool // whether a sibling is chosen and logged
+	}{
+		{"default siblings prefer irv", handler.Options{}, "IRV Genesis", true},
+		{"configured siblings", handler.Options{USFMSiblings: []string{"ult", "irv"}}, "ULT Genesis", true},
+		{"no sibling matches", handler.Options{USFMSiblings: []string{"ulb"}}, "Manifest Genesis", false},
+		{"search turned off", handler.Options{USFMSiblings: []string{}}, "Manifest Genesis", false},
+		{"explicit USFMPath wins", handler.Options{USFMPath: filepath.Join(root, "explicit")}, "Explicit Genesis", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			tt.opts.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+			metadata, err := h.Convert(context.Background(), manifest, inDir, t.TempDir(), tt.opts)
+			if err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+			if got := metadata.LocalizedNames["book-gen"].Long["hi"]; got != tt.want {
+				t.Errorf("Long[hi] = %q; want %q", got, tt.want)
+			}
+			if logged := strings.Contains(logs.String(), "sibling USFM"); logged != tt.sibling {
+				t.Errorf("sibling logged = %v; want %v (log %q)", logged, tt.sibling, logs.String())
+			}
+		})
+	}
+}
+
+func TestOBSTW_CopiesOBSArticles(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	for _, name := range []string{"kt/god.md", "other/bread.md", "names/abraham.md"} {
+		path := filepath.Join(inDir, "obs", filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("# "+name+"\n"), 0644)
+	}
+	// A bible/ tree is not part of an OBS TW repo
+	os.MkdirAll(filepath.Join(inDir, "bible", "kt"), 0755)
+	os.WriteFile(filepath.Join(inDir, "bible", "kt", "grace.md"), []byte("# Grace\n"), 0644)
+	os.WriteFile(filepath.Join(inDir, "LICENSE.md"), []byte("License"), 0644)
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "OBS Translation Words",
+			Identifier: "obs-tw",
+			Title:      "Test OBS TW",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+	}
+
+	h, err := handler.Lookup("OBS Translation Words")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	for _, key := range []string{"ingredients/kt/god.md", "ingredients/other/bread.md", "ingredients/names/abraham.md", "ingredients/LICENSE.md"} {
+		if _, ok := metadata.Ingredients[key]; !ok {
+			t.Errorf("ingredient %s not found", key)
+		}
+		if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(key))); err != nil {
+			t.Errorf("file %s not written: %v", key, err)
+		}
+	}
+	if _, ok := metadata.Ingredients["ingredients/kt/grace.md"]; ok {
+		t.Error("bible/ article should not be copied for OBS Translation Words")
+	}
+	if metadata.Type.FlavorType.Flavor.Name != "x-peripheralArticles" {
+		t.Errorf("flavor = %q; want x-peripheralArticles", metadata.Type.FlavorType.Flavor.Name)
+	}
+	if metadata.Identification.Abbreviation["en"] != "OBSTW" {
+		t.Errorf("abbreviation = %v; want OBSTW", metadata.Identification.Abbreviation)
+	}
+}
+
+func TestTW_ExtraRootDirs(t *testing.T) {
+	inDir := t.TempDir()
+
+	files := map[string]string{
+		"bible/kt/god.md":          "# God\n",
+		".apps/tc/settings.json":   "{}\n",
+		".vscode/settings.json":    "{}\n",
+		".github/workflows/ci.yml": "on: push\n",
+		".git/config":              "[core]\n",
+		"LICENSE.md":               "License",
+	}
+	for name, content := range files {
+		path := filepath.Join(inDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Translation Words",
+			Identifier: "tw",
+			Title:      "Test TW",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+	}
+	h, err := handler.Lookup("Translation Words")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+
+	outDir := t.TempDir()
+	opts := handler.Options{ExtraRootDirs: []string{"./.apps/", ".git", ".missing"}}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, opts)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if _, ok := metadata.Ingredients[".apps/tc/settings.json"]; !ok {
+		t.Error(".apps/tc/settings.json should be recorded as an ingredient")
+	}
+	if _, err := os.Stat(filepath.Join(outDir, ".apps", "tc", "settings.json")); err != nil {
+		t.Errorf(".apps/tc/settings.json not copied: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, ".git")); !os.IsNotExist(err) {
+		t.Error(".git should not be copied even when listed")
+	}
+	if _, err := os.Stat(filepath.Join(outDir, ".vscode")); !os.IsNotExist(err) {
+		t.Error(".vscode should not be copied when not listed")
+	}
+	for key := range metadata.Ingredients {
+		if strings.HasPrefix(key, ".git/") || strings.HasPrefix(key, ".github/") || strings.HasPrefix(key, ".vscode/") {
+			t.Errorf("unexpected ingredient %s", key)
+		}
+	}
+
+	// An extra root path outside the repository is an error
+	opts = handler.Options{ExtraRootDirs: []string{"../elsewhere"}}
+	if _, err := h.Convert(context.Background(), manifest, inDir, t.TempDir(), opts); err == nil {
+		t.Error("expected error for an extra root path outside the repository")
+	}
+}
+
+func TestLicense_AlternateNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"LICENSE.txt", map[string]string{"LICENSE.txt": "Text License\n"}, "Text License\n"},
+		{"LICENSE", map[string]string{"LICENSE": "Plain License\n"}, "Plain License\n"},
+		{"LICENSE.md preferred", map[string]string{"LICENSE.md": "Markdown License\n", "LICENSE.txt": "Text License\n"}, "Markdown License\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inDir := t.TempDir()
+			outDir := t.TempDir()
+			// OBS content at the repository root, beside the license
+			os.WriteFile(filepath.Join(inDir, "01.md"), []byte("# Story 1\n"), 0644)
+			for name, content := range tt.files {
+				os.WriteFile(filepath.Join(inDir, name), []byte(content), 0644)
+			}
+
+			manifest := &rc.Manifest{
+				DublinCore: rc.DublinCore{
+					Subject:    "Open Bible Stories",
+					Identifier: "obs",
+					Rights:     "Freely Given",
+					Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+				},
+				Projects: []rc.Project{{Identifier: "obs", Path: "."}},
+			}
+			h, err := handler.Lookup("Open Bible Stories")
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+			var warnings []string
+			opts := handler.Options{Warn: func(msg string) { warnings = append(warnings, msg) }}
+			metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, opts)
+			if err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+
+			for _, name := range []string{"LICENSE.md", "ingredients/LICENSE.md"} {
+				data, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != tt.want {
+					t.Errorf("%s = %q; want %q", name, data, tt.want)
+				}
+			}
+			// The license is not OBS content, and needs no default
+			for key := range metadata.Ingredients {
+				if strings.HasPrefix(key, "ingredients/content/LICENSE") {
+					t.Errorf("license copied as content: %s", key)
+				}
+			}
+			if len(warnings) > 0 {
+				t.Errorf("warnings = %v; want none", warnings)
+			}
+		})
+	}
+}
+
+func TestSN_PerBookIngredientsAndScope(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	tsvContent := "Reference\tID\tTags\tQuote\tOccurrence\tNote\n1:1\tabcd\t\tword\t1\tA study note\n"
+	os.WriteFile(filepath.Join(inDir, "sn_GEN.tsv"), []byte(tsvContent), 0644)
+	os.WriteFile(filepath.Join(inDir, "sn_MAT.tsv"), []byte(tsvContent), 0644)
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "TSV Study Notes",
+			Identifier: "sn",
+			Title:      "Test SN",
+			Rights:     "CC BY-SA 4.0",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+		Projects: []rc.Project{
+			{Identifier: "gen", Path: "./sn_GEN.tsv", Sort: 1, Title: "Genesis"},
+			{Identifier: "mat", Path: "./sn_MAT.tsv", Sort: 41, Title: "Matthew"},
+		},
+	}
+
+	h, err := handler.Lookup("TSV Study Notes")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if got := metadata.Type.FlavorType; got.Name != "parascriptural" || got.Flavor.Name != "x-bcvnotes" {
+		t.Errorf("flavorType = %s/%s; want parascriptural/x-bcvnotes", got.Name, got.Flavor.Name)
+	}
+	if abbr := metadata.Identification.Abbreviation["en"]; abbr != "SN" {
+		t.Errorf("abbreviation = %q; want SN", abbr)
+	}
+	for key, book := range map[string]string{"ingredients/GEN.tsv": "GEN", "ingredients/MAT.tsv": "MAT"} {
+		ing, ok := metadata.Ingredients[key]
+		if !ok {
+			t.Errorf("missing ingredient %s", key)
+			continue
+		}
+		if !reflect.DeepEqual(ing.Scope, map[string][]string{book: {}}) {
+			t.Errorf("%s scope = %v; want %s", key, ing.Scope, book)
+		}
+		if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(key))); err != nil {
+			t.Errorf("%s not written: %v", key, err)
+		}
+	}
+	wantScope := map[string][]string{"GEN": {}, "MAT": {}}
+	if !reflect.DeepEqual(metadata.Type.FlavorType.CurrentScope, wantScope) {
+		t.Errorf("currentScope = %v; want %v", metadata.Type.FlavorType.CurrentScope, wantScope)
+	}
+	if _, ok := metadata.LocalizedNames["book-mat"]; !ok {
+		t.Error("localizedNames lacks book-mat")
+	}
+}
+
+func TestMapManifest_Category(t *testing.T) {
+	manifest := &rc.Manifest{DublinCore: rc.DublinCore{Identifier: "ult"}}
+
+	if m := handler.MapManifest(manifest, handler.MetadataOptions{IDAuthority: "uWBurritos"}); m.Meta.Category != "source" {
+		t.Errorf("default category = %q; want source", m.Meta.Category)
+	}
+	if m := handler.MapManifest(manifest, handler.MetadataOptions{IDAuthority: "uWBurritos", Category: "derived"}); m.Meta.Category != "derived" {
+		t.Errorf("category = %q; want derived", m.Meta.Category)
+	}
+}
+
+func TestBible_AlignedIndicator(t *testing.T) {
+	tests := []struct {
+		name string
+		usfm string
+		want bool
+	}{
+		{"plain", "\\id GEN\n\\c 1\n\\v 1 In the beginning\n", false},
+		{"aligned milestones", "\\id GEN\n\\c 1\n\\v 1 \\zaln-s |x-strong=\"b:H7225\" x-lemma=\"רֵאשִׁית\"\\*\\w In|x-occurrence=\"1\" x-occurrences=\"1\"\\w*\\zaln-e\\*\n", true},
+		{"word attributes", "\\id GEN\n\\c 1\n\\v 1 \\w beginning|x-occurrence=\"1\"\\w*\n", true},
+		{"word without attributes", "\\id GEN\n\\c 1\n\\v 1 \\w beginning\\w*\n", false},
+		// The marker straddles the end of the first 32KB read of the copy
+		{"milestone split across reads", "\\id GEN\n\\c 1\n\\v 1 " + strings.Repeat("a", 32768-len("\\id GEN\n\\c 1\n\\v 1 ")-3) + "\\zaln-s |x-occurrence=\"1\"\\*\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inDir, outDir := t.TempDir(), t.TempDir()
+			if err := os.WriteFile(filepath.Join(inDir, "01-GEN.usfm"), []byte(tt.usfm), 0644); err != nil {
+				t.Fatal(err)
+			}
+			manifest := &rc.Manifest{
+				DublinCore: rc.DublinCore{Subject: "Aligned Bible", Identifier: "ult", Language: rc.Language{Identifier: "en"}},
+				Projects:   []rc.Project{{Identifier: "gen", Path: "./01-GEN.usfm"}},
+			}
+
+			h, err := handler.Lookup("Aligned Bible")
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+			metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+			if err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+			if got := metadata.Type.FlavorType.Flavor.Aligned; got != tt.want {
+				t.Errorf("Aligned = %v; want %v", got, tt.want)
+			}
+
+			data, err := metadata.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(string(data), `"x-aligned": true`); got != tt.want {
+				t.Errorf("metadata.json has x-aligned = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersification(t *testing.T) {
+	inDir, outDir := t.TempDir(), t.TempDir()
+	for name, content := range map[string]string{
+		"01-GEN.usfm": "\\id GEN\n",
+		"02-EXO.usfm": "\\id EXO\n",
+		"08-RUT.usfm": "\\id RUT\n",
+	} {
+		if err := os.WriteFile(filepath.Join(inDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{Subject: "Bible", Identifier: "ult", Rights: "CC BY-SA 4.0", Language: rc.Language{Identifier: "en"}},
+		Projects: []rc.Project{
+			{Identifier: "gen", Path: "./01-GEN.usfm", Versification: "eng"},
+			{Identifier: "exo", Path: "./02-EXO.usfm", Versification: "klingon"},
+			{Identifier: "rut", Path: "./08-RUT.usfm"},
+		},
+	}
+
+	var warnings []string
+	h, err := handler.Lookup("Bible")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{
+		Warn: func(msg string) { warnings = append(warnings, msg) },
+	})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	for key, want := range map[string]string{"ingredients/GEN.usfm": "eng", "ingredients/EXO.usfm": "klingon", "ingredients/RUT.usfm": ""} {
+		if got := metadata.Ingredients[key].Versification; got != want {
+			t.Errorf("%s versification = %q; want %q", key, got, want)
+		}
+	}
+	data, err := metadata.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"x-versification": "eng"`) {
+		t.Error("metadata.json has no x-versification")
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `unknown versification "klingon"`) {
+		t.Errorf("warnings = %q; want one for the unknown versification", warnings)
+	}
+}
+
+func TestResourceLocalizedName(t *testing.T) {
+	tests := []struct {
+		name, subject, identifier, title, lang string
+		files                                  map[string]string
+		project                                rc.Project
+		key                                    string
+		want                                   sb.LocalizedName
+	}{
+		{
+			name: "Hindi TN", subject: "TSV Translation Notes", identifier: "tn", title: "हिंदी अनुवाद नोट्स", lang: "hi",
+			files:   map[string]string{"tn_GEN.tsv": "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n"},
+			project: rc.Project{Identifier: "gen", Path: "./tn_GEN.tsv"},
+			key:     "resource-tn",
+			want: sb.LocalizedName{
+				Abbr:  map[string]string{"hi": "TN", "en": "TN"},
+				Short: map[string]string{"hi": "हिंदी अनुवाद नोट्स", "en": "Translation Notes"},
+				Long:  map[string]string{"hi": "हिंदी अनुवाद नोट्स", "en": "Translation Notes"},
+			},
+		},
+		{
+			name: "English TA", subject: "Translation Academy", identifier: "ta", title: "unfoldingWord Translation Academy", lang: "en",
+			files:   map[string]string{"intro/ta-intro/01.md": "# Intro\n"},
+			project: rc.Project{Identifier: "intro", Path: "./intro"},
+			key:     "resource-ta",
+			want: sb.LocalizedName{
+				Abbr:  map[string]string{"en": "TA"},
+				Short: map[string]string{"en": "unfoldingWord Translation Academy"},
+				Long:  map[string]string{"en": "unfoldingWord Translation Academy"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inDir, outDir := t.TempDir(), t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(inDir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			manifest := &rc.Manifest{
+				DublinCore: rc.DublinCore{
+					Subject:    tt.subject,
+					Identifier: tt.identifier,
+					Title:      tt.title,
+					Issued:     "2024-01-01",
+					Publisher:  "test",
+					Rights:     "CC BY-SA 4.0",
+					Language:   rc.Language{Identifier: tt.lang, Direction: "ltr"},
+				},
+				Projects: []rc.Project{tt.project},
+			}
+
+			h, err := handler.Lookup(tt.subject)
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+			metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+			if err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+			if got := metadata.LocalizedNames[tt.key]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("localizedNames[%q] = %+v; want %+v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapManifest_LanguageEnrichment(t *testing.T) {
+	tests := []struct {
+		lang rc.Language
+		want sb.LanguageEntry
+	}{
+		{rc.Language{Identifier: "hi", Title: "हिन्दी", Direction: "ltr"},
+			sb.LanguageEntry{Tag: "hi", Script: "Deva", ScriptDirection: "ltr", NumberingSystem: "latn"}},
+		{rc.Language{Identifier: "ar", Title: "العربية", Direction: "rtl"},
+			sb.LanguageEntry{Tag: "ar", Script: "Arab", ScriptDirection: "rtl", NumberingSystem: "arab"}},
+		{rc.Language{Identifier: "pt-br", Title: "Português", Direction: "ltr"},
+			sb.LanguageEntry{Tag: "pt-br", Script: "Latn", ScriptDirection: "ltr", Region: "BR", NumberingSystem: "latn"}},
+		// Unknown languages are left without script, region, or numbering system
+		{rc.Language{Identifier: "qaa-x-mytribe", Title: "My Tribe", Direction: "ltr"},
+			sb.LanguageEntry{Tag: "qaa-x-mytribe", ScriptDirection: "ltr"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang.Identifier, func(t *testing.T) {
+			manifest := &rc.Manifest{DublinCore: rc.DublinCore{Identifier: "tn", Language: tt.lang}}
+			m := handler.MapManifest(manifest, handler.MetadataOptions{IDAuthority: "uWBurritos"})
+			if len(m.Languages) != 1 {
+				t.Fatalf("languages = %+v; want one", m.Languages)
+			}
+			got := m.Languages[0]
+			got.Name = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("language = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanRCLinks(t *testing.T) {
+	inDir := t.TempDir()
+	files := map[string]string{
+		"tn_GEN.tsv": "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n" +
+			"1:1\tabcd\t\trc://*/ta/man/translate/figs-metaphor\t\t0\tSee [[rc://en/tw/dict/bible/kt/god]].\n",
+		"twl_GEN.txt":          "Reference\tID\tTags\tOrigWords\tOccurrence\tTWLink\n1:1\ta001\t\tword\t1\trc://*/tw/dict/bible/kt/god\n",
+		"content/01.md":        "# Creation\n\nSee (rc://en/tn/help/gen/01/01), too.\n",
+		"README.txt":           "Not TSV: rc://en/tw/dict/bible/kt/love\n",
+		"manifest.yaml":        "relation: rc://en/tw\n",
+		".git/notes/links.md":  "rc://en/tw/dict/bible/kt/sin\n",
+		"intro/ta-intro/01.md": "no links here\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(inDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	links, err := handler.ScanRCLinks(inDir)
+	if err != nil {
+		t.Fatalf("ScanRCLinks failed: %v", err)
+	}
+	want := []handler.RCLink{
+		{File: "content/01.md", Line: 3, Link: "rc://en/tn/help/gen/01/01", Language: "en", Resource: "tn", Type: "help", Path: "gen/01/01"},
+		{File: "tn_GEN.tsv", Line: 2, Link: "rc://*/ta/man/translate/figs-metaphor", Language: "*", Resource: "ta", Type: "man", Path: "translate/figs-metaphor"},
+		{File: "tn_GEN.tsv", Line: 2, Link: "rc://en/tw/dict/bible/kt/god", Language: "en", Resource: "tw", Type: "dict", Path: "bible/kt/god"},
+		{File: "twl_GEN.txt", Line: 2, Link: "rc://*/tw/dict/bible/kt/god", Language: "*", Resource: "tw", Type: "dict", Path: "bible/kt/god"},
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("links =\n%+v\nwant\n%+v", links, want)
+	}
+
+	if _, err := handler.ScanRCLinks(filepath.Join(inDir, "missing")); err == nil {
+		t.Error("expected error for a missing directory")
+	}
+}
+
+func TestMapManifest_Authority(t *testing.T) {
+	manifest := &rc.Manifest{DublinCore: rc.DublinCore{Identifier: "ult"}}
+
+	m := handler.MapManifest(manifest, handler.MetadataOptions{IDAuthority: handler.AuthorityCommunity})
+	if got := m.IDAuthorities["BurritoTruck"]; got.ID != "https://git.door43.org/BurritoTruck" {
+		t.Errorf("default community authority = %+v", got)
+	}
+
+	m = handler.MapManifest(manifest, handler.MetadataOptions{
+		IDAuthority: handler.AuthorityUW,
+		Authority:   handler.IDAuthority{Key: "myOrg", URL: "https://git.example.org/myOrg"},
+	})
+	want := map[string]sb.IDAuthority{"myOrg": {ID: "https://git.example.org/myOrg", Name: map[string]string{"en": "myOrg"}}}
+	if !reflect.DeepEqual(m.IDAuthorities, want) {
+		t.Errorf("idAuthorities = %+v; want %+v", m.IDAuthorities, want)
+	}
+	if _, ok := m.Identification.Primary["myOrg"]["ULT"]; !ok || len(m.Identification.Primary) != 1 {
+		t.Errorf("identification.primary = %+v; want only myOrg/ULT", m.Identification.Primary)
+	}
+}
+
+func TestOBS_StoryNames(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(inDir, "content"), 0755)
+	os.WriteFile(filepath.Join(inDir, "content", "01.md"), []byte("\ufeff# 1. La Création\n\n![OBS Image](https://cdn.door43.org/obs/jpg/360px/obs-en-01-01.jpg)\n\nAu commencement...\n\n_Une histoire biblique tirée de : Genèse 1-2_\n"), 0644)
+	os.WriteFile(filepath.Join(inDir, "content", "02.md"), []byte("No heading here\n"), 0644)
+	os.WriteFile(filepath.Join(inDir, "content", "front.md"), []byte("# Histoires bibliques ouvertes\n"), 0644)
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Open Bible Stories",
+			Identifier: "obs",
+			Title:      "Histoires bibliques ouvertes",
+			Language:   rc.Language{Identifier: "fr", Title: "Français", Direction: "ltr"},
+		},
+		Projects: []rc.Project{{Identifier: "obs", Path: "./content"}},
+	}
+
+	h, err := handler.Lookup("Open Bible Stories")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	want := sb.LocalizedName{
+		Abbr:  map[string]string{"fr": "1", "en": "1"},
+		Short: map[string]string{"fr": "1. La Création", "en": "Story 1"},
+		Long:  map[string]string{"fr": "1. La Création", "en": "Story 1"},
+	}
+	if got := metadata.LocalizedNames["obs-01"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("obs-01 = %+v; want %+v", got, want)
+	}
+	for _, key := range []string{"obs-02", "obs-front"} {
+		if ln, ok := metadata.LocalizedNames[key]; ok {
+			t.Errorf("%s = %+v; want no entry", key, ln)
+		}
+	}
+}
+
+func TestOBS_StoryNamesWhileCopying(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(inDir, "content"), 0755)
+	// The heading straddles the end of the first 32KB read of the copy
+	os.WriteFile(filepath.Join(inDir, "content", "03.md"), []byte(strings.Repeat("\n", 32766)+"# 3. Split\n"), 0644)
+	os.WriteFile(filepath.Join(inDir, "content", "04.md"), []byte("# 4. No newline"), 0644)
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Open Bible Stories",
+			Identifier: "obs",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+		Projects: []rc.Project{{Identifier: "obs", Path: "./content"}},
+	}
+
+	h, err := handler.Lookup("Open Bible Stories")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	for key, want := range map[string]string{"obs-03": "3. Split", "obs-04": "4. No newline"} {
+		if got := metadata.LocalizedNames[key].Short["en"]; got != want {
+			t.Errorf("%s short name = %q; want %q", key, got, want)
+		}
+	}
+}
+
+func TestTWL_BinaryTSVCopiedVerbatim(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	manifest := writeTWLManifest(t, inDir)
+
+	// Not UTF-8 text, but with a link and line endings the rewrite would touch
+	binary := []byte("Reference\tTWLink\r\n1:1\trc://*/tw/dict/bible/kt/god\x00\xff\xfe\x89PNG\r\n\x1a\n")
+	os.WriteFile(filepath.Join(inDir, "twl_GEN.tsv"), binary, 0644)
+	os.WriteFile(filepath.Join(inDir, "LICENSE.md"), []byte("License"), 0644)
+	godPath := filepath.Join(inDir, "en_tw", "bible", "kt", "god.md")
+	os.MkdirAll(filepath.Dir(godPath), 0755)
+	os.WriteFile(godPath, []byte("# God\n"), 0644)
+
+	h, err := handler.Lookup("TSV Translation Words Links")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "ingredients", "GEN.tsv"))
+	if err != nil {
+		t.Fatalf("Reading output TSV: %v", err)
+	}
+	if !bytes.Equal(data, binary) {
+		t.Errorf("GEN.tsv = %q; want a byte-exact copy %q", data, binary)
+	}
+	ing := metadata.Ingredients["ingredients/GEN.tsv"]
+	if ing.Size != int64(len(binary)) || ing.Scope["GEN"] == nil {
+		t.Errorf("ingredient = %+v; want the size of the copy and GEN scope", ing)
+	}
+}
+
+func TestTWL_LinkRewriteLongUTF8(t *testing.T) {
+	// Multi-byte runes across the start of the file that is checked for
+	// UTF-8 text, whichever byte it ends on, do not make the file binary
+	for _, pad := range []string{"", "x"} {
+		t.Run(fmt.Sprintf("pad %d", len(pad)), func(t *testing.T) {
+			inDir := t.TempDir()
+			outDir := t.TempDir()
+
+			manifest := writeTWLManifest(t, inDir)
+			note := strings.Repeat("é", 6000)
+			tsvContent := "Reference\tID\tTWLink\tNote" + pad + "\n" +
+				"1:1\ta001\trc://*/tw/dict/bible/kt/god\t" + note + "\n"
+			os.WriteFile(filepath.Join(inDir, "twl_GEN.tsv"), []byte(tsvContent), 0644)
+			godPath := filepath.Join(inDir, "en_tw", "bible", "kt", "god.md")
+			os.MkdirAll(filepath.Dir(godPath), 0755)
+			os.WriteFile(godPath, []byte("# God\n"), 0644)
+
+			h, err := handler.Lookup("TSV Translation Words Links")
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+			if _, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{}); err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(outDir, "ingredients", "GEN.tsv"))
+			if err != nil {
+				t.Fatalf("Reading output TSV: %v", err)
+			}
+			want := strings.Replace(tsvContent, "rc://*/tw/dict/bible/kt/god", "./payload/kt/god.md", 1)
+			if string(data) != want {
+				t.Errorf("rewritten TSV has %d bytes; want %d with the link rewritten", len(data), len(want))
+			}
+		})
+	}
+}
+
+func TestTWL_LinkRewriteLongLine(t *testing.T) {
+	// A row far longer than any fixed line buffer (e.g., a note with an
+	// embedded article) is rewritten, not rejected as too long
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	manifest := writeTWLManifest(t, inDir)
+	note := strings.Repeat("In the beginning God created the heavens and the earth. ", 3<<20/56)
+	tsvContent := "Reference\tID\tTWLink\tNote\n" +
+		"1:1\ta001\trc://*/tw/dict/bible/kt/god\t" + note + "\n" +
+		"1:2\ta002\trc://*/tw/dict/bible/kt/god\tShort note\n"
+	os.WriteFile(filepath.Join(inDir, "twl_GEN.tsv"), []byte(tsvContent), 0644)
+	godPath := filepath.Join(inDir, "en_tw", "bible", "kt", "god.md")
+	os.MkdirAll(filepath.Dir(godPath), 0755)
+	os.WriteFile(godPath, []byte("# God\n"), 0644)
+
+	h, err := handler.Lookup("TSV Translation Words Links")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "ingredients", "GEN.tsv"))
+	if err != nil {
+		t.Fatalf("Reading output TSV: %v", err)
+	}
+	want := strings.ReplaceAll(tsvContent, "rc://*/tw/dict/bible/kt/god", "./payload/kt/god.md")
+	if string(data) != want {
+		t.Errorf("rewritten TSV has %d bytes; want %d with the links rewritten", len(data), len(want))
+	}
+	if got := metadata.Ingredients["ingredients/GEN.tsv"].Size; got != int64(len(want)) {
+		t.Errorf("ingredient size = %d; want %d", got, len(want))
+	}
+}
+
+func TestSPDXLicense(t *testing.T) {
+	tests := []struct {
+		rights string
+		want   string
+		wantOK bool
+	}{
+		{"CC BY-SA 4.0", "CC-BY-SA-4.0", true},
+		{"CC-BY-SA-4.0", "CC-BY-SA-4.0", true},
+		{"cc by-sa 4.0", "CC-BY-SA-4.0", true},
+		{"CC BY-SA v4", "CC-BY-SA-4.0", true},
+		{"Creative Commons Attribution-ShareAlike 4.0 International License", "CC-BY-SA-4.0", true},
+		{"CC BY 4.0", "CC-BY-4.0", true},
+		{"CC BY-NC-SA 4.0", "CC-BY-NC-SA-4.0", true},
+		{"CC0", "CC0-1.0", true},
+		{"CC0 1.0", "CC0-1.0", true},
+		{"Public Domain", "CC-PDDC", true},
+		{"Freely Given", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := handler.SPDXLicense(tt.rights)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("SPDXLicense(%q) = %q, %v; want %q, %v", tt.rights, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestBuildCopyright_SPDX(t *testing.T) {
+	manifest := &rc.Manifest{DublinCore: rc.DublinCore{Publisher: "unfoldingWord", Issued: "2024", Rights: "cc by-sa 4.0"}}
+	if got := handler.BuildCopyright(manifest, false, "").SPDX; got != "CC-BY-SA-4.0" {
+		t.Errorf("SPDX = %q; want CC-BY-SA-4.0", got)
+	}
+	manifest.DublinCore.Rights = "All rights reserved"
+	if got := handler.BuildCopyright(manifest, false, "").SPDX; got != "" {
+		t.Errorf("SPDX = %q; want none for unrecognized rights", got)
+	}
+}
+
+func TestOBS_SeparateFrontAndBackProjects(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+
+	// The front matter is inside the story content, the back matter beside it
+	os.MkdirAll(filepath.Join(inDir, "content", "front"), 0755)
+	os.WriteFile(filepath.Join(inDir, "content", "01.md"), []byte("# 1. The Creation\n"), 0644)
+	os.WriteFile(filepath.Join(inDir, "content", "front", "intro.md"), []byte("# Open Bible Stories\n"), 0644)
+	os.MkdirAll(filepath.Join(inDir, "back_matter"), 0755)
+	os.WriteFile(filepath.Join(inDir, "back_matter", "intro.md"), []byte("# About\n"), 0644)
+
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Open Bible Stories",
+			Identifier: "obs",
+			Title:      "Test OBS",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+		Projects: []rc.Project{
+			{Identifier: "front", Path: "./content/front", Sort: 0},
+			{Identifier: "obs", Path: "./content", Sort: 1},
+			{Identifier: "back", Path: "./back_matter", Sort: 2},
+		},
+	}
+
+	h, err := handler.Lookup("Open Bible Stories")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, outDir, handler.Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	var got []string
+	for key := range metadata.Ingredients {
+		if strings.HasPrefix(key, "ingredients/content/") {
+			got = append(got, key)
+		}
+	}
+	slices.Sort(got)
+	want := []string{
+		"ingredients/content/01.md",
+		"ingredients/content/back/intro.md",
+		"ingredients/content/front/intro.md",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("content ingredients = %v; want %v", got, want)
+	}
+	for _, key := range want {
+		if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(key))); err != nil {
+			t.Errorf("%s not written: %v", key, err)
+		}
+	}
+
+	// Only the stories are named
+	var names []string
+	for key := range metadata.LocalizedNames {
+		if strings.HasPrefix(key, "obs-") {
+			names = append(names, key)
+		}
+	}
+	if !slices.Equal(names, []string{"obs-01"}) {
+		t.Errorf("story names = %v; want [obs-01]", names)
+	}
+}
+
+func TestIncludeSourceManifest(t *testing.T) {
+	const manifestYAML = "dublin_core:\n  identifier: test\n"
+	const mediaYAML = "projects: []\n"
+	tests := []struct {
+		name     string
+		manifest *rc.Manifest
+		files    map[string]string
+	}{
+		{
+			name: "OBS",
+			manifest: &rc.Manifest{
+				DublinCore: rc.DublinCore{
+					Subject:    "Open Bible Stories",
+					Identifier: "obs",
+					Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+				},
+			},
+			files: map[string]string{"content/01.md": "# Story"},
+		},
+		{
+			name: "TA",
+			manifest: &rc.Manifest{
+				DublinCore: rc.DublinCore{
+					Subject:    "Translation Academy",
+					Identifier: "ta",
+					Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+				},
+				Projects: []rc.Project{{Identifier: "intro"}},
+			},
+			files: map[string]string{"intro/01.md": "# Intro"},
+		},
+	}
+	for _, tt := range tests {
+		for _, include := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/%v", tt.name, include), func(t *testing.T) {
+				inDir := t.TempDir()
+				outDir := t.TempDir()
+				files := map[string]string{"manifest.yaml": manifestYAML, "media.yaml": mediaYAML}
+				maps.Copy(files, tt.files)
+				for name, content := range files {
+					path := filepath.Join(inDir, filepath.FromSlash(name))
+					os.MkdirAll(filepath.Dir(path), 0755)
+					os.WriteFile(path, []byte(content), 0644)
+				}
+
+				h, err := handler.Lookup(tt.manifest.DublinCore.Subject)
+				if err != nil {
+					t.Fatalf("Lookup failed: %v", err)
+				}
+				metadata, err := h.Convert(context.Background(), tt.manifest, inDir, outDir, handler.Options{IncludeSourceManifest: include})
+				if err != nil {
+					t.Fatalf("Convert failed: %v", err)
+				}
+
+				for name, content := range map[string]string{"manifest.yaml": manifestYAML, "media.yaml": mediaYAML} {
+					key := "ingredients/source/" + name
+					ing, ok := metadata.Ingredients[key]
+					data, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(key)))
+					if !include {
+						if ok || err == nil {
+							t.Errorf("%s copied without IncludeSourceManifest", key)
+						}
+						continue
+					}
+					if !ok {
+						t.Fatalf("%s is not an ingredient", key)
+					}
+					if err != nil || string(data) != content {
+						t.Errorf("%s = %q, %v; want %q", key, data, err, content)
+					}
+					want := sb.Ingredient{
+						Checksum: sb.Checksum{MD5: fmt.Sprintf("%x", md5.Sum([]byte(content)))},
+						MimeType: ing.MimeType,
+						Size:     int64(len(content)),
+						Role:     "x-source-manifest",
+					}
+					if !reflect.DeepEqual(ing, want) {
+						t.Errorf("%s = %+v; want %+v", key, ing, want)
+					}
+				}
+				// The manifest never reaches the root
+				if _, err := os.Stat(filepath.Join(outDir, "manifest.yaml")); !os.IsNotExist(err) {
+					t.Error("manifest.yaml should not be copied to the output root")
+				}
+			})
+		}
+	}
+}
+
+func TestBible_OriginalLanguageLocalizedNames(t *testing.T) {
+	tests := []struct {
+		subject  string
+		lang     rc.Language
+		project  rc.Project
+		usfm     string
+		wantTag  string
+		wantLong string
+	}{
+		{
+			subject:  "Greek New Testament",
+			lang:     rc.Language{Identifier: "el-x-koine", Title: "Koine Greek", Direction: "ltr"},
+			project:  rc.Project{Identifier: "mat", Path: "./41-MAT.usfm", Sort: 40},
+			usfm:     "\\id MAT\n\\toc1 Κατὰ Μαθθαῖον\n\\toc2 Μαθθαῖον\n\\toc3 Μαθ\n\\c 1\n",
+			wantTag:  "grc",
+			wantLong: "Κατὰ Μαθθαῖον",
+		},
+		{
+			subject:  "Hebrew Old Testament",
+			lang:     rc.Language{Identifier: "hbo", Title: "Ancient Hebrew", Direction: "rtl"},
+			project:  rc.Project{Identifier: "gen", Path: "./01-GEN.usfm", Sort: 1},
+			usfm:     "\\id GEN\n\\toc1 בְּרֵאשִׁית\n\\toc2 בְּרֵאשִׁית\n\\toc3 בְּרֵא\n\\c 1\n",
+			wantTag:  "hbo",
+			wantLong: "בְּרֵאשִׁית",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			inDir := t.TempDir()
+			os.WriteFile(filepath.Join(inDir, filepath.Base(tt.project.Path)), []byte(tt.usfm), 0644)
+			manifest := &rc.Manifest{
+				DublinCore: rc.DublinCore{
+					Subject:    tt.subject,
+					Identifier: "orig",
+					Title:      "Original",
+					Rights:     "CC BY-SA 4.0",
+					Language:   tt.lang,
+				},
+				Projects: []rc.Project{tt.project},
+			}
+
+			h, err := handler.Lookup(tt.subject)
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+			metadata, err := h.Convert(context.Background(), manifest, inDir, t.TempDir(), handler.Options{})
+			if err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+
+			ln := metadata.LocalizedNames["book-"+tt.project.Identifier]
+			if got := ln.Long[tt.wantTag]; got != tt.wantLong {
+				t.Errorf("Long[%s] = %q; want %q", tt.wantTag, got, tt.wantLong)
+			}
+			if _, ok := ln.Long[tt.lang.Identifier]; ok && tt.lang.Identifier != tt.wantTag {
+				t.Errorf("Long has the manifest language %q; want only %q", tt.lang.Identifier, tt.wantTag)
+			}
+			if ln.Long["en"] == "" {
+				t.Error("Long[en] is empty; want the English fallback")
+			}
+		})
+	}
+}
+
+func TestBible_FrontMatterProject(t *testing.T) {
+	inDir := t.TempDir()
+	os.WriteFile(filepath.Join(inDir, "A0-FRT.usfm"), []byte("\\id FRT\n\\toc1 Préface\n\\p Front matter\n"), 0644)
+	os.WriteFile(filepath.Join(inDir, "01-GEN.usfm"), []byte("\\id GEN\n\\c 1\n\\v 1 In the beginning\n"), 0644)
+	os.WriteFile(filepath.Join(inDir, "A9-XYZ.usfm"), []byte("\\id XYZ\n"), 0644)
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Aligned Bible",
+			Identifier: "ult",
+			Title:      "Test",
+			Rights:     "CC BY-SA 4.0",
+			Language:   rc.Language{Identifier: "fr", Title: "Français", Direction: "ltr"},
+		},
+		Projects: []rc.Project{
+			{Identifier: "frt", Path: "./A0-FRT.usfm", Title: "Avant-propos"},
+			{Identifier: "gen", Path: "./01-GEN.usfm", Sort: 1},
+			{Identifier: "xyz", Path: "./A9-XYZ.usfm"},
+		},
+	}
+
+	h, err := handler.Lookup("Aligned Bible")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	var warnings []string
+	opts := handler.Options{Warn: func(msg string) { warnings = append(warnings, msg) }}
+	metadata, err := h.Convert(context.Background(), manifest, inDir, t.TempDir(), opts)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	wantWarnings := []string{
+		`project "frt" is front matter, not a book of the Bible; copying ingredients/FRT.usfm without a scope`,
+		`project "xyz" is not a recognized book; copying ingredients/XYZ.usfm without a scope or localized name`,
+	}
+	if !slices.Equal(warnings, wantWarnings) {
+		t.Errorf("warnings = %q; want %q", warnings, wantWarnings)
+	}
+
+	ing, ok := metadata.Ingredients["ingredients/FRT.usfm"]
+	if !ok {
+		t.Fatal("ingredients/FRT.usfm is missing")
+	}
+	if ing.Scope != nil || ing.Role != handler.PeripheralRole {
+		t.Errorf("FRT ingredient scope %v, role %q; want no scope and role %q", ing.Scope, ing.Role, handler.PeripheralRole)
+	}
+	if _, ok := metadata.Type.FlavorType.CurrentScope["FRT"]; ok {
+		t.Error("currentScope lists FRT")
+	}
+
+	ln, ok := metadata.LocalizedNames["matter-frt"]
+	if !ok {
+		t.Fatalf("localizedNames has no matter-frt: %v", slices.Collect(maps.Keys(metadata.LocalizedNames)))
+	}
+	if ln.Long["fr"] != "Préface" || ln.Short["fr"] != "Avant-propos" || ln.Long["en"] != "Front Matter" {
+		t.Errorf("matter-frt = %+v; want the USFM and project names with an English fallback", ln)
+	}
+	if _, ok := metadata.LocalizedNames["book-frt"]; ok {
+		t.Error("localizedNames has book-frt; want matter-frt only")
+	}
+}
+
+// --- Copy benchmarks ---
+
+// benchmarkFile creates a synthetic source file for the copy benchmarks, 1GB
+// or, with -short, 64MB, and returns the directory holding it and its size.
+func benchmarkFile(b *testing.B) (string, int64) {
+	b.Helper()
+	size := int64(1 << 30)
+	if testing.Short() {
+		size = 64 << 20
+	}
+	dir := b.TempDir()
+	f, err := os.Create(filepath.Join(dir, "big.usfm"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	line := []byte("\\v 1 In the beginning God created the heavens and the earth.\n")
+	buf := bytes.Repeat(line, (1<<20)/len(line)+1)[:1<<20]
+	for written := int64(0); written < size; written += int64(len(buf)) {
+		if _, err := f.Write(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		b.Fatal(err)
+	}
+	return dir, size
+}
+
+// BenchmarkCopyFileAndComputeIngredient copies and checksums in one pass.
+func BenchmarkCopyFileAndComputeIngredient(b *testing.B) {
+	inDir, size := benchmarkFile(b)
+	out := handler.DirOutput(b.TempDir())
+	b.SetBytes(size)
+	for b.Loop() {
+		if _, err := handler.CopyFileAndComputeIngredient(context.Background(), os.DirFS(inDir), "big.usfm", out, "ingredients/GEN.usfm"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCopyThenComputeIngredient is the two-pass baseline: copy, then
+// reopen the copy to checksum it.
+func BenchmarkCopyThenComputeIngredient(b *testing.B) {
+	inDir, size := benchmarkFile(b)
+	dst := filepath.Join(b.TempDir(), "GEN.usfm")
+	b.SetBytes(size)
+	for b.Loop() {
+		if err := handler.CopyFile(context.Background(), os.DirFS(inDir), "big.usfm", dst); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := sb.ComputeIngredient(dst); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkTWTreeCopy converts a generated TW repo of 10k articles (1k with
+// -short), walking and copying the whole bible/ tree.
+func BenchmarkTWTreeCopy(b *testing.B) {
+	n := 10000
+	if testing.Short() {
+		n = 1000
+	}
+	inDir := b.TempDir()
+	for i := range n {
+		name := filepath.Join(inDir, "bible", []string{"kt", "names", "other"}[i%3], fmt.Sprintf("word%05d.md", i))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(strings.Repeat("# Word\n\nA definition of the word.\n", 20)), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	manifest := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "Translation Words",
+			Identifier: "tw",
+			Title:      "Test TW",
+			Language:   rc.Language{Identifier: "en", Title: "English", Direction: "ltr"},
+		},
+	}
+	h, err := handler.Lookup("Translation Words")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for b.Loop() {
+		metadata, err := h.Convert(context.Background(), manifest, inDir, b.TempDir(), handler.Options{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(metadata.Ingredients) < n {
+			b.Fatalf("%d ingredients; want at least %d", len(metadata.Ingredients), n)
+		}
+	}
+}
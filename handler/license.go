@@ -0,0 +1,121 @@
+package handler
+
+import (
+	_ "embed"
+	"regexp"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/rc"
+)
+
+// defaultLicense is the embedded CC BY-SA 4.0 LICENSE.md used as a fallback
+// when the RC repository does not include its own LICENSE.md file.
+//
+//go:embed default_license.md
+var defaultLicense []byte
+
+//go:embed default_license_cc_by.md
+var defaultLicenseCCBY []byte
+
+// embeddedLicenses maps SPDX license identifiers (see SPDXLicense) to the
+// embedded LICENSE.md for that license.
+var embeddedLicenses = map[string][]byte{
+	"CC-BY-SA-4.0": defaultLicense,
+	"CC-BY-4.0":    defaultLicenseCCBY,
+}
+
+// spdxLicenses maps normalized rights declarations (see normalizeRights) to
+// SPDX license identifiers.
+var spdxLicenses = map[string]string{
+	"CC0":          "CC0-1.0",
+	"CC01.0":       "CC0-1.0",
+	"CCBY3.0":      "CC-BY-3.0",
+	"CCBY4.0":      "CC-BY-4.0",
+	"CCBYSA3.0":    "CC-BY-SA-3.0",
+	"CCBYSA4.0":    "CC-BY-SA-4.0",
+	"CCBYND4.0":    "CC-BY-ND-4.0",
+	"CCBYNC4.0":    "CC-BY-NC-4.0",
+	"CCBYNCSA4.0":  "CC-BY-NC-SA-4.0",
+	"CCBYNCND4.0":  "CC-BY-NC-ND-4.0",
+	"PUBLICDOMAIN": "CC-PDDC",
+	"MIT":          "MIT",
+}
+
+// rightsWords rewrites the words of spelled-out Creative Commons license
+// names to their abbreviations, after upper-casing.
+var rightsWords = strings.NewReplacer(
+	"CREATIVE COMMONS", "CC",
+	"ATTRIBUTION", "BY",
+	"SHARE-ALIKE", "SA",
+	"SHAREALIKE", "SA",
+	"SHARE ALIKE", "SA",
+	"NONCOMMERCIAL", "NC",
+	"NON-COMMERCIAL", "NC",
+	"NODERIVATIVES", "ND",
+	"NODERIVS", "ND",
+	"INTERNATIONAL", "",
+	"LICENSE", "",
+	"LICENCE", "",
+	"GENERIC", "",
+)
+
+// ccVersionRegexp matches a normalized Creative Commons BY declaration with
+// its version, which may be prefixed with "V" and lack its ".0".
+var ccVersionRegexp = regexp.MustCompile(`^(CCBY[A-Z]*?)V?(\d)(\.0)?$`)
+
+// normalizeRights reduces a rights declaration such as "CC BY-SA 4.0",
+// "CC-BY-SA-4.0", "cc by-sa v4", or "Creative Commons Attribution-ShareAlike
+// 4.0 International" to a form suitable for looking up licenses (e.g.,
+// "CCBYSA4.0"): upper case, with spelled-out words abbreviated, a Creative
+// Commons version in full, and anything but letters, digits, and dots removed.
+func normalizeRights(rights string) string {
+	rights = rightsWords.Replace(strings.ToUpper(rights))
+	rights = strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' {
+			return r
+		}
+		return -1
+	}, rights)
+	return ccVersionRegexp.ReplaceAllString(strings.TrimSuffix(rights, "."), "$1$2.0")
+}
+
+// SPDXLicense returns the SPDX license identifier (e.g., "CC-BY-SA-4.0") for
+// an RC rights declaration (e.g., "CC BY-SA 4.0"), and whether the
+// declaration is recognized. Creative Commons licenses are matched in any
+// case, with or without hyphens, spelled out, and with a version like "4".
+func SPDXLicense(rights string) (string, bool) {
+	id, ok := spdxLicenses[normalizeRights(rights)]
+	return id, ok
+}
+
+// DefaultLicense returns the LICENSE.md written for an RC repository that
+// has no license file of its own: the embedded license matching its declared
+// rights, or CC BY-SA 4.0 if none matches.
+func DefaultLicense(rights string) []byte {
+	if id, ok := SPDXLicense(rights); ok {
+		if license, ok := embeddedLicenses[id]; ok {
+			return license
+		}
+	}
+	return defaultLicense
+}
+
+// defaultLicenseFor returns the LICENSE.md to use when the RC repository in
+// src has no license file: the embedded license matching the manifest's
+// declared rights, or CC BY-SA 4.0 with a warning if no embedded license
+// matches.
+func defaultLicenseFor(src rcSource, manifest *rc.Manifest, opts Options) []byte {
+	if src.license() != "" {
+		return defaultLicense
+	}
+	rights := manifest.DublinCore.Rights
+	if id, ok := SPDXLicense(rights); ok {
+		if license, ok := embeddedLicenses[id]; ok {
+			src.report.substitute(SubstitutedLicense, "LICENSE.md", "the embedded "+id+" license")
+			return license
+		}
+	}
+	opts.warn("no embedded license matches rights %q; using the default CC BY-SA 4.0 LICENSE.md", rights)
+	src.report.substitute(SubstitutedLicense, "LICENSE.md", "the default CC BY-SA 4.0 license")
+	return defaultLicense
+}
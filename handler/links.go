@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// RCLink is an rc:// link found in an RC repository by ScanRCLinks.
+type RCLink struct {
+	// File is the slash-separated path of the file the link is in, relative
+	// to the repository root (e.g., "tn_GEN.tsv").
+	File string
+
+	// Line is the line number of the link in File, starting at 1.
+	Line int
+
+	// Link is the link as written (e.g., "rc://*/tw/dict/bible/kt/god").
+	Link string
+
+	// Language, Resource, and Type are the first three parts of the link
+	// (e.g., "*", "tw", and "dict"), and Path is the rest (e.g.,
+	// "bible/kt/god"). Parts the link does not have are "".
+	Language, Resource, Type, Path string
+}
+
+// rcLinkRegexp matches an rc:// link in TSV or Markdown text, up to the
+// whitespace, quote, bracket, or parenthesis that ends it.
+var rcLinkRegexp = regexp.MustCompile(`rc://[^\s"'<>()\[\]|\\]+`)
+
+// ScanRCLinks returns the rc:// links (e.g., to TW, TA, or TN articles) in
+// the TSV and Markdown files of the RC repository at inDir, in order of file
+// path and then line. Tab-separated .txt files are scanned as TSV files, and
+// .git is skipped.
+func ScanRCLinks(inDir string) ([]RCLink, error) {
+	fsys := os.DirFS(inDir)
+	var links []RCLink
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name == ".git" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(path.Ext(name)) {
+		case ".tsv", ".md", ".txt":
+		default:
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(path.Ext(name), ".txt") && !sb.IsTabSeparated(data) {
+			return nil
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+		for line := 1; scanner.Scan(); line++ {
+			for _, link := range rcLinkRegexp.FindAllString(scanner.Text(), -1) {
+				links = append(links, parseRCLink(name, line, strings.TrimRight(link, ".,;:")))
+			}
+		}
+		return scanner.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning rc:// links: %w", err)
+	}
+	return links, nil
+}
+
+// parseRCLink returns the RCLink for link, found in file at line.
+func parseRCLink(file string, line int, link string) RCLink {
+	parts := append(strings.SplitN(strings.TrimPrefix(link, "rc://"), "/", 4), "", "", "")
+	return RCLink{
+		File:     file,
+		Line:     line,
+		Link:     link,
+		Language: parts[0],
+		Resource: parts[1],
+		Type:     parts[2],
+		Path:     parts[3],
+	}
+}
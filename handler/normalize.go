@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/md5"
+	"io"
+	"path"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer applies Unicode NFC normalization to text ingredients (Markdown,
+// TSV, USFM, and plain text) and to ingredient keys as files are copied, so
+// that the SB's meta.normalization of "NFC" holds for content edited on
+// systems that write NFD (e.g., macOS). It records the ingredients it
+// changed.
+//
+// A nil *Normalizer leaves files and keys unchanged.
+type Normalizer struct {
+	changed []string
+	seen    map[string]bool
+}
+
+// NewNormalizer returns a Normalizer that has changed no files.
+func NewNormalizer() *Normalizer {
+	return &Normalizer{seen: make(map[string]bool)}
+}
+
+// Changed returns the (normalized) keys of the ingredients whose content or
+// key was changed by normalization, in the order they were copied.
+func (n *Normalizer) Changed() []string {
+	if n == nil {
+		return nil
+	}
+	return n.changed
+}
+
+// record records key as changed.
+func (n *Normalizer) record(key string) {
+	if !n.seen[key] {
+		n.seen[key] = true
+		n.changed = append(n.changed, key)
+	}
+}
+
+// key returns key in NFC, recording it as changed if it was not.
+func (n *Normalizer) key(key string) string {
+	if n == nil || norm.NFC.IsNormalString(key) {
+		return key
+	}
+	key = norm.NFC.String(key)
+	n.record(key)
+	return key
+}
+
+// normalizesContent reports whether the content of the ingredient key is
+// normalized: n is not nil and key is a text file.
+func (n *Normalizer) normalizesContent(key string) bool {
+	if n == nil {
+		return false
+	}
+	switch strings.ToLower(path.Ext(key)) {
+	case ".md", ".tsv", ".usfm", ".txt":
+		return true
+	}
+	return false
+}
+
+// reader returns a reader for the contents of r in NFC if the content of the
+// ingredient key is normalized, otherwise r itself. The returned function is
+// to be called once all of r has been read; it records key as changed if
+// normalization changed the content.
+func (n *Normalizer) reader(key string, r io.Reader) (io.Reader, func()) {
+	if !n.normalizesContent(key) {
+		return r, func() {}
+	}
+	original, normalized := md5.New(), md5.New()
+	nfc := io.TeeReader(norm.NFC.Reader(io.TeeReader(r, original)), normalized)
+	return nfc, func() {
+		if !bytes.Equal(original.Sum(nil), normalized.Sum(nil)) {
+			n.record(key)
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/unfoldingWord/go-rc2sb/rc"
@@ -27,7 +31,14 @@ func (h *obsHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir,
 		return nil, err
 	}
 
-	m := BuildBaseMetadata(manifest, "BurritoTruck", "OBS")
+	m := MapManifest(manifest, MetadataOptions{
+		IDAuthority:        AuthorityCommunity,
+		Authority:          opts.IDAuthority,
+		Abbreviation:       "OBS",
+		OBSCopyright:       true,
+		CopyrightStatement: opts.CopyrightStatement,
+		Date:               opts.date(),
+	})
 
 	// Set type - OBS uses gloss/textStories
 	m.Type = sb.Type{
@@ -40,153 +51,287 @@ func (h *obsHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir,
 		},
 	}
 
-	// OBS uses a different copyright format
-	m.Copyright = BuildCopyright(manifest, true)
+	src := newRCSource(inDir, opts)
+	src.headings = &storyHeadings{titles: make(map[string]string)}
+	out := newOutput(outDir, opts)
+	license := defaultLicenseFor(src, manifest, opts)
 
 	// Copy common root files (README.md, .gitignore, .gitea, .github)
-	if err := CopyCommonRootFiles(inDir, outDir, m); err != nil {
+	if err := copyCommonRootFiles(ctx, src, out, m); err != nil {
 		return nil, err
 	}
 
 	// Copy LICENSE.md to root (uses embedded default if RC doesn't have one).
-	if err := CopyLicenseToRoot(inDir, outDir); err != nil {
+	if err := copyLicenseToRoot(ctx, src, out, license); err != nil {
 		return nil, fmt.Errorf("copying root LICENSE.md: %w", err)
 	}
 
-	// Determine the content directory from the manifest project path.
-	// OBS has a single project whose path is typically "./content" but may be "."
-	// when the markdown files live in the repository root.
-	contentPath := "content"
-	if len(manifest.Projects) > 0 {
-		p := strings.TrimPrefix(manifest.Projects[0].Path, "./")
-		if p != "" {
-			contentPath = p
-		}
+	// Determine the content directory from the manifest project paths.
+	// The stories are in the "obs" project, whose path is typically
+	// "./content" but may be "." when the markdown files live in the
+	// repository root. The front and back matter are usually part of it, but
+	// a manifest may list them as separate "front" and "back" projects.
+	contentPath, matter := obsProjectPaths(manifest)
+
+	// Matter projects inside the story content are copied on their own
+	skip := make(map[string]bool)
+	for _, p := range matter {
+		skip[p.path] = true
 	}
 
 	if contentPath == "." {
 		// Content lives in the repo root — copy everything except known
 		// non-content files (manifest.yaml, media.yaml, README.md, LICENSE.md,
 		// .gitignore, and dot-directories like .git, .gitea, .github).
-		if err := copyOBSRootContent(inDir, outDir, m); err != nil {
+		if err := copyOBSRootContent(ctx, src, out, m, skip); err != nil {
 			return nil, err
 		}
 	} else {
 		// Content lives in a subdirectory — copy everything in it.
-		contentDir := filepath.Join(inDir, contentPath)
-		if err := copyContentDir(contentDir, outDir, m); err != nil {
+		if err := copyContentDir(ctx, src, contentPath, "ingredients/content/", out, m, skip); err != nil {
 			return nil, err
 		}
 	}
 
+	for _, p := range matter {
+		if err := copyOBSMatter(ctx, src, p.identifier, p.path, out, m); err != nil {
+			return nil, fmt.Errorf("copying OBS %s project %s: %w", p.identifier, p.path, err)
+		}
+	}
+
 	// Copy LICENSE.md to ingredients/LICENSE.md (uses embedded default if RC doesn't have one).
-	licIng, err := CopyLicenseIngredient(inDir, outDir)
-	if err != nil {
+	if err := addLicenseIngredient(ctx, m, src, out, license); err != nil {
 		return nil, fmt.Errorf("copying ingredients/LICENSE.md: %w", err)
 	}
-	m.Ingredients["ingredients/LICENSE.md"] = licIng
+
+	addOBSStoryNames(src.headings, manifest.DublinCore.Language.Identifier, m)
 
 	return m, nil
 }
 
-// copyContentDir recursively copies content files to ingredients/content/.
-func copyContentDir(contentDir, outDir string, m *sb.Metadata) error {
-	return filepath.Walk(contentDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// obsStoryRegexp matches the name of an OBS story file (e.g., "01.md").
+var obsStoryRegexp = regexp.MustCompile(`^(\d{2})\.md$`)
+
+// addOBSStoryNames adds to m a localized name keyed "obs-NN" for each story
+// ingredients/content/NN.md whose first heading (e.g., "1. The Creation")
+// headings noted as it was copied, using that heading as the name in lang.
+// For a language other than English, "Story N" is added as the English name.
+// Stories without a heading are skipped.
+func addOBSStoryNames(headings *storyHeadings, lang string, m *sb.Metadata) {
+	if lang == "" {
+		lang = "en"
+	}
+	for key, title := range headings.titles {
+		match := obsStoryRegexp.FindStringSubmatch(strings.TrimPrefix(key, "ingredients/content/"))
+		if match == nil {
+			continue
 		}
-		if info.IsDir() {
-			return nil
+		number := strings.TrimPrefix(match[1], "0")
+		ln := sb.LocalizedName{
+			Abbr:  map[string]string{lang: number},
+			Short: map[string]string{lang: title},
+			Long:  map[string]string{lang: title},
+		}
+		if lang != "en" {
+			ln.Abbr["en"] = number
+			ln.Short["en"] = "Story " + number
+			ln.Long["en"] = "Story " + number
 		}
+		m.LocalizedNames["obs-"+match[1]] = ln
+	}
+}
 
-		relPath, err := filepath.Rel(contentDir, path)
-		if err != nil {
-			return err
+// maxHeadingLine bounds the length of a line a headingScanner looks for a
+// heading in; the rest of a longer line is ignored.
+const maxHeadingLine = 4096
+
+// storyHeadings notes the first heading of each OBS story
+// (ingredients/content/NN.md) copied through it.
+//
+// A nil *storyHeadings notes nothing.
+type storyHeadings struct {
+	titles map[string]string // ingredient key -> heading text
+}
+
+// reader returns a reader for the contents of r that scans them for a
+// heading as they are read, if key is an OBS story. The returned function is
+// to be called once all of r has been read; it notes the heading, if any.
+func (h *storyHeadings) reader(key string, r io.Reader) (io.Reader, func()) {
+	rest, ok := strings.CutPrefix(key, "ingredients/content/")
+	if h == nil || !ok || !obsStoryRegexp.MatchString(rest) {
+		return r, func() {}
+	}
+	s := &headingScanner{}
+	return io.TeeReader(r, s), func() {
+		if s.title == "" {
+			// The last line may have no newline
+			s.title = markdownTitle(s.line)
+		}
+		if s.title != "" {
+			h.titles[key] = s.title
+		}
+	}
+}
+
+// headingScanner is written the content of a Markdown file and keeps the
+// text of its first heading.
+type headingScanner struct {
+	title string
+	line  []byte // the start of the current line
+}
+
+// Write scans the lines of p for a heading until one is found.
+func (s *headingScanner) Write(p []byte) (int, error) {
+	for rest := p; s.title == "" && len(rest) > 0; {
+		chunk, after, complete := bytes.Cut(rest, []byte("\n"))
+		if n := maxHeadingLine - len(s.line); n > 0 {
+			s.line = append(s.line, chunk[:min(len(chunk), n)]...)
+		}
+		if !complete {
+			break
+		}
+		s.title = markdownTitle(s.line)
+		s.line = s.line[:0]
+		rest = after
+	}
+	return len(p), nil
+}
+
+// markdownTitle returns the text of the first ATX heading (e.g., "# Title")
+// in the Markdown data, or "" if it has none.
+func markdownTitle(data []byte) string {
+	for line := range strings.Lines(strings.TrimPrefix(string(data), "\ufeff")) {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+		if title := strings.TrimSpace(strings.TrimLeft(line, "#")); title != "" {
+			return title
 		}
+	}
+	return ""
+}
 
-		ingredientKey := "ingredients/content/" + filepath.ToSlash(relPath)
+// obsProject is a front or back matter project of an OBS manifest.
+type obsProject struct {
+	identifier string // "front" or "back"
+	path       string // slash-separated, relative to the repository root
+}
 
-		ing, err := CopyFileAndComputeIngredient(path, outDir, ingredientKey)
+// obsProjectPaths returns the path of the story content in the OBS manifest
+// (the "obs" project, or the first project that is not front or back matter;
+// "content" if there is none) and the front and back matter projects listed
+// separately from it.
+func obsProjectPaths(manifest *rc.Manifest) (string, []obsProject) {
+	contentPath := ""
+	var matter []obsProject
+	for _, p := range manifest.Projects {
+		projectPath := strings.TrimPrefix(p.Path, "./")
+		if projectPath != "" {
+			projectPath = path.Clean(projectPath)
+		}
+		switch id := strings.ToLower(p.Identifier); {
+		case id == "front" || id == "back":
+			if projectPath != "" && projectPath != "." {
+				matter = append(matter, obsProject{identifier: id, path: projectPath})
+			}
+		case contentPath == "" || id == "obs":
+			contentPath = projectPath
+		}
+	}
+	if contentPath == "" {
+		contentPath = "content"
+	}
+	// A matter project at the story content path is not separate
+	matter = slices.DeleteFunc(matter, func(p obsProject) bool { return p.path == contentPath })
+	return contentPath, matter
+}
+
+// copyOBSMatter copies the front or back matter project at projectPath in
+// src to ingredients/content/{identifier}/, so that, e.g., a "front" project
+// at "front_matter" with intro.md gives ingredients/content/front/intro.md.
+// A project that is a single file (e.g., "front.md") is copied to
+// ingredients/content/ under its own name, as it is in a root-level layout.
+func copyOBSMatter(ctx context.Context, src rcSource, identifier, projectPath string, out Output, m *sb.Metadata) error {
+	info, err := fs.Stat(src.fsys, projectPath)
+	if err != nil {
+		return sourceError(err)
+	}
+	if !info.IsDir() {
+		return addFileIngredient(ctx, m, src, projectPath, out, "ingredients/content/"+path.Base(projectPath), nil)
+	}
+	return copyContentDir(ctx, src, projectPath, "ingredients/content/"+identifier+"/", out, m, nil)
+}
+
+// copyContentDir recursively copies content files from the directory contentDir
+// in src to keyPrefix (e.g., "ingredients/content/"), skipping the
+// directories in skip.
+func copyContentDir(ctx context.Context, src rcSource, contentDir, keyPrefix string, out Output, m *sb.Metadata, skip map[string]bool) error {
+	return fs.WalkDir(src.fsys, contentDir, func(name string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return fmt.Errorf("copying content file %s: %w", relPath, err)
+			return walkError(src, name, err)
 		}
-		m.Ingredients[ingredientKey] = ing
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if skip[name] && name != contentDir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if linksToDir(src.fsys, name, d) {
+			return nil
+		}
+
+		relPath := relName(contentDir, name)
 
-		return nil
+		ingredientKey := keyPrefix + relPath
+
+		return addFileIngredient(ctx, m, src, name, out, ingredientKey, nil)
 	})
 }
 
 // copyOBSRootContent copies OBS content from the repo root when the manifest
 // project path is ".". It copies all files and directories except known
-// non-content entries: *.yaml files, README.md, LICENSE.md, .gitignore,
-// and dot-directories (.git, .gitea, .github). This handles both flat layouts
+// non-content entries: *.yaml files, README.md, the license (LICENSE.md,
+// LICENSE, or LICENSE.txt), .gitignore, and dot-directories (.git, .gitea, .github). This handles both flat layouts
 // (numbered .md files, front.md, back.md) and layouts with subdirectories
-// (front/, back/).
-func copyOBSRootContent(inDir, outDir string, m *sb.Metadata) error {
-	entries, err := os.ReadDir(inDir)
+// (front/, back/). Entries in skip are not copied.
+func copyOBSRootContent(ctx context.Context, src rcSource, out Output, m *sb.Metadata, skip map[string]bool) error {
+	entries, err := fs.ReadDir(src.fsys, ".")
 	if err != nil {
-		return fmt.Errorf("reading OBS root directory: %w", err)
+		return fmt.Errorf("reading OBS root directory %s: %w", src.path("."), sourceError(err))
 	}
 
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		name := entry.Name()
 
-		if isOBSExcludedEntry(name, entry.IsDir()) {
+		if isOBSExcludedEntry(name, entry.IsDir()) || skip[name] {
 			continue
 		}
 
-		srcPath := filepath.Join(inDir, name)
-
 		if entry.IsDir() {
 			// Recursively copy the subdirectory into ingredients/content/{dir}/
-			// We walk the subdirectory and prefix each relative path with the
-			// directory name so that e.g. front/intro.md maps to
+			// so that e.g. front/intro.md maps to
 			// ingredients/content/front/intro.md.
-			if err := copyOBSSubdir(srcPath, name, outDir, m); err != nil {
+			if err := copyContentDir(ctx, src, name, "ingredients/content/"+name+"/", out, m, skip); err != nil {
 				return fmt.Errorf("copying OBS content directory %s: %w", name, err)
 			}
 		} else {
 			ingredientKey := "ingredients/content/" + name
-			ing, err := CopyFileAndComputeIngredient(srcPath, outDir, ingredientKey)
-			if err != nil {
+			if err := addFileIngredient(ctx, m, src, name, out, ingredientKey, nil); err != nil {
 				return fmt.Errorf("copying OBS content file %s: %w", name, err)
 			}
-			m.Ingredients[ingredientKey] = ing
 		}
 	}
 
 	return nil
 }
 
-// copyOBSSubdir recursively copies a subdirectory from the OBS root into
-// ingredients/content/{dirName}/. For example, a file front/intro.md is
-// copied to ingredients/content/front/intro.md.
-func copyOBSSubdir(srcDir, dirName, outDir string, m *sb.Metadata) error {
-	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			return nil
-		}
-
-		relPath, err := filepath.Rel(srcDir, path)
-		if err != nil {
-			return err
-		}
-
-		ingredientKey := "ingredients/content/" + dirName + "/" + filepath.ToSlash(relPath)
-
-		ing, err := CopyFileAndComputeIngredient(path, outDir, ingredientKey)
-		if err != nil {
-			return fmt.Errorf("copying %s/%s: %w", dirName, relPath, err)
-		}
-		m.Ingredients[ingredientKey] = ing
-
-		return nil
-	})
-}
-
 // isOBSExcludedEntry returns true if the given root-level entry should be
 // excluded from OBS content copying. Excluded entries are repository metadata
 // and infrastructure files that are not part of the OBS content itself.
@@ -201,8 +346,9 @@ func isOBSExcludedEntry(name string, isDir bool) bool {
 	}
 	// Exclude known root-level non-content files
 	switch name {
-	case "README.md", "LICENSE.md", ".gitignore":
+	case "README.md", ".gitignore":
 		return true
 	}
-	return false
+	// Exclude the license, which is copied as LICENSE.md on its own
+	return slices.Contains(LicenseNames, name)
 }
@@ -3,7 +3,7 @@ package handler
 import (
 	"context"
 	"fmt"
-	"path/filepath"
+	"path"
 	"strings"
 
 	"github.com/unfoldingWord/go-rc2sb/rc"
@@ -32,7 +32,13 @@ func (h *obsTSVHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDi
 		return nil, err
 	}
 
-	m := BuildBaseMetadata(manifest, "BurritoTruck", h.config.abbreviation)
+	m := MapManifest(manifest, MetadataOptions{
+		IDAuthority:        AuthorityCommunity,
+		Authority:          opts.IDAuthority,
+		Abbreviation:       h.config.abbreviation,
+		CopyrightStatement: opts.CopyrightStatement,
+		Date:               opts.date(),
+	})
 
 	// Set type
 	m.Type = sb.Type{
@@ -45,15 +51,11 @@ func (h *obsTSVHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDi
 	}
 
 	// Set copyright
-	m.Copyright = BuildCopyright(manifest, false)
-
 	// Set OBS localized names
-	m.LocalizedNames = map[string]sb.LocalizedName{
-		"book-obs": {
-			Abbr:  map[string]string{"en": "OBS"},
-			Short: map[string]string{"en": "OBS"},
-			Long:  map[string]string{"en": "OBS"},
-		},
+	m.LocalizedNames["book-obs"] = sb.LocalizedName{
+		Abbr:  map[string]string{"en": "OBS"},
+		Short: map[string]string{"en": "OBS"},
+		Long:  map[string]string{"en": "OBS"},
 	}
 
 	// Find the TSV file from projects
@@ -61,32 +63,31 @@ func (h *obsTSVHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDi
 		return nil, fmt.Errorf("no projects found in manifest for %s", h.config.subject)
 	}
 
+	src := newRCSource(inDir, opts)
+	out := newOutput(outDir, opts)
+	license := defaultLicenseFor(src, manifest, opts)
 	project := manifest.Projects[0]
-	tsvPath := filepath.Join(inDir, strings.TrimPrefix(project.Path, "./"))
+	tsvName := projectFile(project.Path)
 
 	// The SB ingredient key strips the prefix (e.g., "sn_OBS.tsv" -> "OBS.tsv")
-	tsvFilename := filepath.Base(tsvPath)
+	tsvFilename := path.Base(tsvName)
 	sbFilename := strings.TrimPrefix(tsvFilename, h.config.tsvPrefix)
 	ingredientKey := "ingredients/" + sbFilename
 
 	// Copy TSV file
-	ing, err := CopyFileAndComputeIngredient(tsvPath, outDir, ingredientKey)
-	if err != nil {
+	if err := addFileIngredient(ctx, m, src, tsvName, out, ingredientKey, nil); err != nil {
 		return nil, fmt.Errorf("copying TSV file: %w", err)
 	}
-	m.Ingredients[ingredientKey] = ing
 
 	// Copy common root files (README.md, .gitignore, .gitea, .github)
-	if err := CopyCommonRootFiles(inDir, outDir, m); err != nil {
+	if err := copyCommonRootFiles(ctx, src, out, m); err != nil {
 		return nil, err
 	}
 
 	// Copy LICENSE.md
-	licIng, err := CopyLicenseIngredient(inDir, outDir)
-	if err != nil {
+	if err := addLicenseIngredient(ctx, m, src, out, license); err != nil {
 		return nil, fmt.Errorf("copying LICENSE.md: %w", err)
 	}
-	m.Ingredients["ingredients/LICENSE.md"] = licIng
 
 	return m, nil
 }
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// zipModTime is the modification time recorded for every zip entry, so that
+// archives of the same content are reproducible. It is the earliest time the
+// zip format's MS-DOS timestamps can represent.
+var zipModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Output is a destination that SB files are written to.
+type Output interface {
+	// Create creates the file name, a slash-separated path relative to the
+	// SB root, and returns a writer for its contents. The caller must close
+	// the writer before creating the next file.
+	Create(name string) (io.WriteCloser, error)
+}
+
+// DirOutput returns an Output that writes files beneath dir on disk,
+// creating any necessary directories.
+func DirOutput(dir string) Output {
+	return dirOutput{dir: dir}
+}
+
+type dirOutput struct {
+	dir string
+}
+
+func (o dirOutput) Create(name string) (io.WriteCloser, error) {
+	path := filepath.Join(o.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating directory for %s: %w", path, err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating destination %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// ZipOutput returns an Output that writes each file as an entry in zw.
+// Entries are written in the order they are created, all with the same
+// fixed modification time.
+func ZipOutput(zw *zip.Writer) Output {
+	return zipOutput{zw: zw}
+}
+
+type zipOutput struct {
+	zw *zip.Writer
+}
+
+func (o zipOutput) Create(name string) (io.WriteCloser, error) {
+	w, err := o.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: zipModTime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating zip entry %s: %w", name, err)
+	}
+	return nopWriteCloser{w}, nil
+}
+
+// nopWriteCloser adds a no-op Close to an io.Writer. Zip entries are
+// finished implicitly when the next entry is created or the archive closed.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newOutput returns the output for a conversion: opts.Output when set,
+// otherwise outDir on disk.
+func newOutput(outDir string, opts Options) Output {
+	if opts.Output != nil {
+		return opts.Output
+	}
+	return DirOutput(outDir)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -15,11 +16,19 @@ func Register(h Handler) {
 	registry[h.Subject()] = h
 }
 
-// Lookup returns the handler for the given subject, or an error if not found.
+// ErrUnsupportedSubject is returned (wrapped) by Lookup for a subject with no
+// registered handler.
+var ErrUnsupportedSubject = errors.New("unsupported subject")
+
+// Lookup returns the handler for the given subject, or an error wrapping
+// ErrUnsupportedSubject if not found.
 func Lookup(subject string) (Handler, error) {
 	h, ok := registry[subject]
 	if !ok {
-		return nil, fmt.Errorf("unsupported subject %q; supported subjects: %s", subject, supportedSubjects())
+		if suggestion := suggestSubject(subject); suggestion != "" {
+			return nil, fmt.Errorf("%w %q (did you mean %q?); supported subjects: %s", ErrUnsupportedSubject, subject, suggestion, supportedSubjects())
+		}
+		return nil, fmt.Errorf("%w %q; supported subjects: %s", ErrUnsupportedSubject, subject, supportedSubjects())
 	}
 	return h, nil
 }
@@ -37,3 +46,38 @@ func SupportedSubjects() []string {
 func supportedSubjects() string {
 	return strings.Join(SupportedSubjects(), ", ")
 }
+
+// suggestSubject returns the registered subject closest to subject, ignoring
+// case, or "" if none is within a few edits of it.
+func suggestSubject(subject string) string {
+	const maxDistance = 3
+	best, bestDistance := "", maxDistance+1
+	for _, s := range SupportedSubjects() {
+		if d := editDistance(strings.ToLower(subject), strings.ToLower(s)); d < bestDistance {
+			best, bestDistance = s, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,88 @@
+package handler
+
+// The kinds of substitutions and skips a Report records.
+const (
+	// SubstitutedLicense is an embedded default LICENSE.md used because the
+	// RC has no license file.
+	SubstitutedLicense = "license"
+
+	// SkippedProject is a manifest project whose file or directory is
+	// missing.
+	SkippedProject = "project"
+
+	// SkippedRootFile is a common root file (README.md or .gitignore) that the
+	// RC does not have.
+	SkippedRootFile = "root file"
+
+	// SkippedPayload is a TWL payload that was not found, so links were not
+	// rewritten.
+	SkippedPayload = "payload"
+)
+
+// Substitution is something a conversion put in place of content missing
+// from the RC.
+type Substitution struct {
+	// Kind is what was substituted (e.g., SubstitutedLicense).
+	Kind string
+
+	// Name is the file substituted (e.g., "LICENSE.md"), and Detail what
+	// was used in its place (e.g., "the default CC BY-SA 4.0 license").
+	Name   string
+	Detail string
+}
+
+// Skip is something a conversion left out.
+type Skip struct {
+	// Kind is what was skipped (e.g., SkippedProject).
+	Kind string
+
+	// Name names what was skipped (e.g., the project identifier "gen"), and
+	// Reason says why.
+	Name   string
+	Reason string
+}
+
+// Report collects what a conversion substituted and skipped, so that
+// automation can tell them from warnings.
+//
+// A nil *Report records nothing.
+type Report struct {
+	substitutions []Substitution
+	skips         []Skip
+}
+
+// NewReport returns a Report that has recorded nothing.
+func NewReport() *Report {
+	return &Report{}
+}
+
+// Substitutions returns the substitutions recorded, in the order they were
+// made.
+func (r *Report) Substitutions() []Substitution {
+	if r == nil {
+		return nil
+	}
+	return r.substitutions
+}
+
+// Skips returns the skips recorded, in the order they were made.
+func (r *Report) Skips() []Skip {
+	if r == nil {
+		return nil
+	}
+	return r.skips
+}
+
+// substitute records a substitution.
+func (r *Report) substitute(kind, name, detail string) {
+	if r != nil {
+		r.substitutions = append(r.substitutions, Substitution{Kind: kind, Name: name, Detail: detail})
+	}
+}
+
+// skip records a skip.
+func (r *Report) skip(kind, name, reason string) {
+	if r != nil {
+		r.skips = append(r.skips, Skip{Kind: kind, Name: name, Reason: reason})
+	}
+}
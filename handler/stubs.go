@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// StubDetector flags ingredients that are empty or too small to be useful,
+// such as a zero-byte file, a TSV with only a header row, or a USFM stub
+// with no \c chapter marker, as files are copied. Such files produce
+// valid-looking burritos with no content.
+//
+// A nil *StubDetector flags nothing.
+type StubDetector struct {
+	stubs []Stub
+}
+
+// Stub is an ingredient flagged by a StubDetector.
+type Stub struct {
+	// Key is the ingredient key (e.g., "ingredients/GEN.tsv").
+	Key string
+
+	// Empty is set if the ingredient has no bytes at all, and Problem says
+	// what is wrong with it (e.g., "has only a header row").
+	Empty   bool
+	Problem string
+}
+
+// NewStubDetector returns a StubDetector that has flagged no files.
+func NewStubDetector() *StubDetector {
+	return &StubDetector{}
+}
+
+// Stubs returns the ingredients flagged, in the order they were copied.
+func (d *StubDetector) Stubs() []Stub {
+	if d == nil {
+		return nil
+	}
+	return d.stubs
+}
+
+// reader returns a reader for the contents of r that scans them as they are
+// read. The returned function is to be called once all of r has been read;
+// it flags the ingredient key if the content was empty or a stub. Files
+// outside ingredients/ (e.g., .gitignore at the SB root) are not flagged.
+func (d *StubDetector) reader(key string, r io.Reader) (io.Reader, func()) {
+	if d == nil || !strings.HasPrefix(key, "ingredients/") {
+		return r, func() {}
+	}
+	s := &stubScanner{kind: strings.ToLower(path.Ext(key))}
+	return io.TeeReader(r, s), func() {
+		switch {
+		case s.size == 0:
+			d.stubs = append(d.stubs, Stub{Key: key, Empty: true, Problem: "is empty"})
+		case s.kind == ".tsv" && s.rows() <= 1:
+			d.stubs = append(d.stubs, Stub{Key: key, Problem: "has only a header row"})
+		case s.kind == ".usfm" && !s.chapter:
+			d.stubs = append(d.stubs, Stub{Key: key, Problem: `has no \c chapter marker`})
+		}
+	}
+}
+
+// usfmChapterRegexp matches a USFM \c marker.
+var usfmChapterRegexp = regexp.MustCompile(`\\c\s`)
+
+// stubScanner is written the content of an ingredient of kind, its
+// lower-cased extension, and notes its size, its non-blank lines for a TSV,
+// and whether a USFM has a \c marker.
+type stubScanner struct {
+	kind    string
+	size    int64
+	lines   int
+	content bool // the current line has non-blank content
+	chapter bool
+	tail    []byte // the end of the last write, for a \c split across writes
+}
+
+// Write notes the size and content of p.
+func (s *stubScanner) Write(p []byte) (int, error) {
+	s.size += int64(len(p))
+	switch s.kind {
+	case ".tsv":
+		for _, b := range p {
+			switch b {
+			case '\n':
+				if s.content {
+					s.lines++
+				}
+				s.content = false
+			case ' ', '\t', '\r':
+			default:
+				s.content = true
+			}
+		}
+	case ".usfm":
+		if s.chapter {
+			break
+		}
+		// A marker may be split across writes
+		edge := append(s.tail, p[:min(len(p), 2)]...)
+		s.chapter = usfmChapterRegexp.Match(edge) || usfmChapterRegexp.Match(p)
+		if len(p) >= 2 {
+			s.tail = append(s.tail[:0], p[len(p)-2:]...)
+		} else {
+			s.tail = edge[max(0, len(edge)-2):]
+		}
+	}
+	return len(p), nil
+}
+
+// rows returns the number of non-blank lines of a TSV.
+func (s *stubScanner) rows() int {
+	if s.content {
+		return s.lines + 1
+	}
+	return s.lines
+}
@@ -21,6 +21,9 @@ func init() {
 	// Translation Words
 	handler.Register(handler.NewTWHandler())
 
+	// OBS Translation Words
+	handler.Register(handler.NewOBSTWHandler())
+
 	// Translation Academy
 	handler.Register(handler.NewTAHandler())
 
@@ -30,6 +33,9 @@ func init() {
 	// TSV Translation Questions
 	handler.Register(handler.NewTQHandler())
 
+	// TSV Study Notes
+	handler.Register(handler.NewSNHandler())
+
 	// TSV Translation Words Links
 	handler.Register(handler.NewTWLHandler())
 
@@ -3,8 +3,6 @@ package handler
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/unfoldingWord/go-rc2sb/rc"
 	"github.com/unfoldingWord/go-rc2sb/sb"
@@ -26,7 +24,13 @@ func (h *taHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir, o
 		return nil, err
 	}
 
-	m := BuildBaseMetadata(manifest, "uWBurritos", "TA")
+	m := MapManifest(manifest, MetadataOptions{
+		IDAuthority:        AuthorityUW,
+		Authority:          opts.IDAuthority,
+		Abbreviation:       "TA",
+		CopyrightStatement: opts.CopyrightStatement,
+		Date:               opts.date(),
+	})
 
 	// Set type - peripheral/x-peripheralArticles
 	m.Type = sb.Type{
@@ -38,16 +42,17 @@ func (h *taHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir, o
 		},
 	}
 
-	m.Copyright = BuildCopyright(manifest, false)
-	m.LocalizedNames = map[string]sb.LocalizedName{}
+	src := newRCSource(inDir, opts)
+	out := newOutput(outDir, opts)
+	license := defaultLicenseFor(src, manifest, opts)
 
 	// Copy common root files (README.md, .gitignore, .gitea, .github)
-	if err := CopyCommonRootFiles(inDir, outDir, m); err != nil {
+	if err := copyCommonRootFiles(ctx, src, out, m); err != nil {
 		return nil, err
 	}
 
 	// Copy LICENSE.md to root (uses embedded default if RC doesn't have one).
-	if err := CopyLicenseToRoot(inDir, outDir); err != nil {
+	if err := copyLicenseToRoot(ctx, src, out, license); err != nil {
 		return nil, fmt.Errorf("copying root LICENSE.md: %w", err)
 	}
 
@@ -58,23 +63,21 @@ func (h *taHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir, o
 			return nil, err
 		}
 
-		projectDir := filepath.Join(inDir, project.Identifier)
-		if _, err := os.Stat(projectDir); os.IsNotExist(err) {
+		if !src.exists(project.Identifier) {
+			src.skipProject(project, project.Identifier)
 			continue
 		}
 
 		destPrefix := "ingredients/" + project.Identifier
-		if err := copyTreeToIngredients(projectDir, outDir, destPrefix, m); err != nil {
+		if err := copyTreeToIngredients(ctx, src, project.Identifier, out, destPrefix, m); err != nil {
 			return nil, fmt.Errorf("copying project %s: %w", project.Identifier, err)
 		}
 	}
 
 	// Copy LICENSE.md to ingredients/LICENSE.md (uses embedded default if RC doesn't have one).
-	licIng, err := CopyLicenseIngredient(inDir, outDir)
-	if err != nil {
+	if err := addLicenseIngredient(ctx, m, src, out, license); err != nil {
 		return nil, fmt.Errorf("copying ingredients/LICENSE.md: %w", err)
 	}
-	m.Ingredients["ingredients/LICENSE.md"] = licIng
 
 	return m, nil
 }
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"path"
+	"sync"
+)
+
+// SourceTracker records the files of an RC repository that a conversion
+// copied, so that the files it left out can be reported.
+//
+// A nil *SourceTracker records nothing.
+type SourceTracker struct {
+	mu   sync.Mutex
+	used map[string]bool
+}
+
+// NewSourceTracker returns a SourceTracker that has recorded no files.
+func NewSourceTracker() *SourceTracker {
+	return &SourceTracker{used: make(map[string]bool)}
+}
+
+// Used reports whether the file name, a slash-separated path relative to the
+// repository root (e.g., "tn_GEN.tsv"), was copied, or is in a directory
+// that was used as a whole.
+func (t *SourceTracker) Used(name string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for name = path.Clean(name); name != "."; name = path.Dir(name) {
+		if t.used[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// record records the file name as copied, or the directory name as used as a
+// whole (e.g., a TW payload of which only some articles are copied).
+func (t *SourceTracker) record(name string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.used[path.Clean(name)] = true
+}
@@ -3,8 +3,7 @@ package handler
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
 
 	"github.com/unfoldingWord/go-rc2sb/rc"
 	"github.com/unfoldingWord/go-rc2sb/sb"
@@ -12,13 +11,26 @@ import (
 
 // NewTWHandler creates a new Translation Words handler.
 func NewTWHandler() Handler {
-	return &twHandler{}
+	return &twHandler{subject: "Translation Words", abbreviation: "TW", root: "bible"}
 }
 
-type twHandler struct{}
+// NewOBSTWHandler creates a new OBS Translation Words handler, for TW repos
+// whose articles are for Open Bible Stories and live under obs/ rather than
+// bible/.
+func NewOBSTWHandler() Handler {
+	return &twHandler{subject: "OBS Translation Words", abbreviation: "OBSTW", root: "obs"}
+}
+
+// twHandler converts Translation Words repos, copying the articles under root
+// (e.g., bible/{kt,other,names}/*.md) to ingredients/.
+type twHandler struct {
+	subject      string
+	abbreviation string
+	root         string
+}
 
 func (h *twHandler) Subject() string {
-	return "Translation Words"
+	return h.subject
 }
 
 func (h *twHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir, outDir string, opts Options) (*sb.Metadata, error) {
@@ -26,7 +38,13 @@ func (h *twHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir, o
 		return nil, err
 	}
 
-	m := BuildBaseMetadata(manifest, "uWBurritos", "TW")
+	m := MapManifest(manifest, MetadataOptions{
+		IDAuthority:        AuthorityUW,
+		Authority:          opts.IDAuthority,
+		Abbreviation:       h.abbreviation,
+		CopyrightStatement: opts.CopyrightStatement,
+		Date:               opts.date(),
+	})
 
 	// Set type - peripheral/x-peripheralArticles
 	m.Type = sb.Type{
@@ -38,59 +56,52 @@ func (h *twHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir, o
 		},
 	}
 
-	m.Copyright = BuildCopyright(manifest, false)
-	m.LocalizedNames = map[string]sb.LocalizedName{}
+	src := newRCSource(inDir, opts)
+	out := newOutput(outDir, opts)
+	license := defaultLicenseFor(src, manifest, opts)
 
 	// Copy common root files (README.md, .gitignore, .gitea, .github)
-	if err := CopyCommonRootFiles(inDir, outDir, m); err != nil {
+	if err := copyCommonRootFiles(ctx, src, out, m); err != nil {
 		return nil, err
 	}
 
 	// Copy LICENSE.md to root (uses embedded default if RC doesn't have one).
-	if err := CopyLicenseToRoot(inDir, outDir); err != nil {
+	if err := copyLicenseToRoot(ctx, src, out, license); err != nil {
 		return nil, fmt.Errorf("copying root LICENSE.md: %w", err)
 	}
 
-	// Copy bible/ contents to ingredients/
-	// Structure: bible/{kt,other,names}/*.md and bible/config.yaml
-	bibleDir := filepath.Join(inDir, "bible")
-	if err := copyTreeToIngredients(bibleDir, outDir, "ingredients", m); err != nil {
-		return nil, fmt.Errorf("copying bible directory: %w", err)
+	// Copy bible/ (or obs/) contents to ingredients/
+	// Structure: bible/{kt,other,names}/*.md and bible/config.yaml; any
+	// further category directories (e.g., bible/figs/) are copied the same way
+	if err := copyTreeToIngredients(ctx, src, h.root, out, "ingredients", m); err != nil {
+		return nil, fmt.Errorf("copying %s directory: %w", h.root, err)
 	}
 
 	// Copy LICENSE.md to ingredients/LICENSE.md (uses embedded default if RC doesn't have one).
-	licIng, err := CopyLicenseIngredient(inDir, outDir)
-	if err != nil {
+	if err := addLicenseIngredient(ctx, m, src, out, license); err != nil {
 		return nil, fmt.Errorf("copying ingredients/LICENSE.md: %w", err)
 	}
-	m.Ingredients["ingredients/LICENSE.md"] = licIng
 
 	return m, nil
 }
 
-// copyTreeToIngredients recursively copies a directory tree into the ingredients directory.
-func copyTreeToIngredients(srcDir, outDir, destPrefix string, m *sb.Metadata) error {
-	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+// copyTreeToIngredients recursively copies the directory root in src into the ingredients directory.
+func copyTreeToIngredients(ctx context.Context, src rcSource, root string, out Output, destPrefix string, m *sb.Metadata) error {
+	return fs.WalkDir(src.fsys, root, func(name string, d fs.DirEntry, err error) error {
 		if err != nil {
+			return walkError(src, name, err)
+		}
+		if err := ctx.Err(); err != nil {
 			return err
 		}
-		if info.IsDir() {
+		if d.IsDir() || linksToDir(src.fsys, name, d) {
 			return nil
 		}
 
-		relPath, err := filepath.Rel(srcDir, path)
-		if err != nil {
-			return err
-		}
-
-		ingredientKey := destPrefix + "/" + filepath.ToSlash(relPath)
+		relPath := relName(root, name)
 
-		ing, err := CopyFileAndComputeIngredient(path, outDir, ingredientKey)
-		if err != nil {
-			return fmt.Errorf("copying %s: %w", relPath, err)
-		}
-		m.Ingredients[ingredientKey] = ing
+		ingredientKey := destPrefix + "/" + relPath
 
-		return nil
+		return addFileIngredient(ctx, m, src, name, out, ingredientKey, nil)
 	})
 }
@@ -2,12 +2,19 @@ package handler
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/unfoldingWord/go-rc2sb/books"
 	"github.com/unfoldingWord/go-rc2sb/rc"
@@ -17,9 +24,19 @@ import (
 // twLinkRegexp parses RC links like "rc://*/tw/dict/bible/other/creation"
 var twLinkRegexp = regexp.MustCompile(`rc://[^/]*/tw/dict/bible/([^/]+)/([^/\t]+)`)
 
-// twLinkReplaceRegexp matches a TWLink column value at end of a TSV line for replacement.
-// Matches: \trc://<anything>/tw/dict/bible/<category>/<article> at end of line
-var twLinkReplaceRegexp = regexp.MustCompile(`\trc://[^/]+/tw/dict/bible/([^\t]+)$`)
+// twLinkFieldRegexp matches a complete TWLink field value for replacement.
+// Matches: rc://<anything>/tw/dict/bible/<category>/<article>
+var twLinkFieldRegexp = regexp.MustCompile(`^rc://[^/]+/tw/dict/bible/(.+)$`)
+
+// twLinkColumn is the TSV header name of the column holding TW article links.
+const twLinkColumn = "TWLink"
+
+// Ingredient roles distinguishing the per-book link tables from the TW
+// articles they link to.
+const (
+	twlLinksRole   = "x-links"
+	twlPayloadRole = "x-payload"
+)
 
 // NewTWLHandler creates a new TSV Translation Words Links handler.
 func NewTWLHandler() Handler {
@@ -37,10 +54,15 @@ func (h *twlHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir,
 		return nil, err
 	}
 
-	m := BuildBaseMetadata(manifest, "uWBurritos", "TW")
+	m := MapManifest(manifest, MetadataOptions{
+		IDAuthority:        AuthorityUW,
+		Authority:          opts.IDAuthority,
+		Abbreviation:       "TW",
+		CopyrightStatement: opts.CopyrightStatement,
+		Date:               opts.date(),
+	})
 
 	// Set type - parascriptural/x-bcvarticles
-	currentScope := make(map[string][]string)
 	m.Type = sb.Type{
 		FlavorType: sb.FlavorType{
 			Name: "parascriptural",
@@ -50,26 +72,54 @@ func (h *twlHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir,
 		},
 	}
 
-	m.Copyright = BuildCopyright(manifest, false)
-
 	lang := manifest.DublinCore.Language.Identifier
-
-	// Determine payload source: explicit PayloadPath option, or auto-detect <lang>_tw/ in inDir
-	var twBibleDir string
+	src := newRCSource(inDir, opts)
+	out := newOutput(outDir, opts)
+	license := defaultLicenseFor(src, manifest, opts)
+	usfmPath := usfmDir(inDir, lang, opts)
+
+	// Determine payload source: explicit PayloadPath option, or auto-detect
+	// <lang>_tw/ in inDir, then beside it
+	var twBible rcSource
+	var err error
 	if opts.PayloadPath != "" {
-		twBibleDir = filepath.Join(opts.PayloadPath, "bible")
+		twBible, err = src.onDisk(opts.PayloadPath).sub("bible")
 	} else {
-		twBibleDir = filepath.Join(inDir, lang+"_tw", "bible")
+		twBible, err = src.sub(lang + "_tw/bible")
+		if err != nil || !twBible.exists(".") {
+			if dir := siblingTWDir(inDir, lang, opts); dir != "" {
+				twBible, err = src.onDisk(dir).sub("bible")
+			}
+		}
+	}
+	hasPayload := err == nil && twBible.exists(".")
+	if hasPayload {
+		if opts.PayloadPath == "" {
+			// A payload in inDir is not content left out
+			src.use(lang + "_tw")
+		}
+		opts.debug("using TW payload", "dir", twBible.path("."))
+	} else {
+		opts.debug("no TW payload found; copying TSV files without link rewriting")
+		src.report.skip(SkippedPayload, lang+"_tw", "not found; TSV files copied without link rewriting")
 	}
 
-	_, twDirErr := os.Stat(twBibleDir)
-	hasPayload := twDirErr == nil
-
-	// If payload exists, copy the TW bible/ tree to ingredients/payload/
+	// If payload exists, copy the TW bible/ tree to ingredients/payload/, or
+	// only the articles the selected books link to
 	if hasPayload {
-		if err := copyTreeToIngredients(twBibleDir, outDir, "ingredients/payload", m); err != nil {
+		if len(opts.Books) > 0 {
+			err = copyLinkedArticles(ctx, manifest, src, twBible, out, m, opts)
+		} else {
+			err = copyTreeToIngredients(ctx, twBible, ".", out, "ingredients/payload", m)
+		}
+		if err != nil {
 			return nil, fmt.Errorf("copying TW payload: %w", err)
 		}
+		for key := range m.Ingredients {
+			if strings.HasPrefix(key, "ingredients/payload/") {
+				setIngredientRole(m, key, twlPayloadRole)
+			}
+		}
 	}
 
 	// Process each project (TSV file per book)
@@ -77,30 +127,36 @@ func (h *twlHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir,
 		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
+		if !opts.includesBook(project.Identifier) {
+			continue
+		}
 
-		srcPath := filepath.Join(inDir, strings.TrimPrefix(project.Path, "./"))
-		if _, err := os.Stat(srcPath); os.IsNotExist(err) {
+		srcName := projectFile(project.Path)
+		if !src.exists(srcName) {
+			src.skipProject(project, srcName)
 			continue
 		}
-		srcFilename := filepath.Base(srcPath)
+		srcFilename := path.Base(srcName)
 
 		// Strip "twl_" prefix: "twl_GEN.tsv" -> "GEN.tsv"
 		destFilename := strings.TrimPrefix(srcFilename, "twl_")
 		ingredientKey := "ingredients/" + destFilename
+		if !src.allows(ingredientKey) {
+			// Filtered out: leave the book out of scope and localized names too
+			continue
+		}
 
 		// Get book code for scope
 		bookID := strings.ToLower(project.Identifier)
 		bookCode := books.CodeFromProjectID(bookID)
 
 		scope := map[string][]string{bookCode: {}}
-		currentScope[bookCode] = []string{}
 
-		// Add localized name: try USFM from USFMPath, then manifest title, then English
+		// Add localized name: try USFM from USFMPath or a sibling USFM
+		// repository, then manifest title, then English
 		var usfmNames *books.LocalizedBookNames
-		if opts.USFMPath != "" {
-			if usfmFile := books.FindUSFMFile(opts.USFMPath, bookID); usfmFile != "" {
-				usfmNames = books.ParseUSFMBookNames(usfmFile)
-			}
+		if usfmPath != "" {
+			usfmNames = opts.USFMNames.BookNames(usfmPath, bookID)
 		}
 		key, localizedName := books.LocalizedNameEntryWithNames(bookID, lang, project.Title, usfmNames)
 		if key != "" {
@@ -109,102 +165,186 @@ func (h *twlHandler) Convert(ctx context.Context, manifest *rc.Manifest, inDir,
 
 		if hasPayload {
 			// Copy TSV file with rc:// link rewriting, then compute ingredient
-			ing, err := copyTSVWithLinkRewrite(srcPath, outDir, ingredientKey, scope)
+			if err := m.ClaimIngredient(ingredientKey, src.path(srcName)); err != nil {
+				return nil, err
+			}
+			ing, err := copyTSVWithLinkRewrite(ctx, src, srcName, out, ingredientKey, scope, src.stripsBOM(srcName))
 			if err != nil {
 				return nil, fmt.Errorf("copying %s with link rewrite: %w", srcFilename, err)
 			}
+			ing.Source = src.sourceName(srcName)
 			m.Ingredients[ingredientKey] = ing
 		} else {
 			// Copy TSV file as-is (no payload, no link rewriting)
-			ing, err := CopyFileWithScope(srcPath, outDir, ingredientKey, scope)
-			if err != nil {
+			if err := addFileIngredient(ctx, m, src, srcName, out, ingredientKey, scope); err != nil {
 				return nil, fmt.Errorf("copying %s: %w", srcFilename, err)
 			}
-			m.Ingredients[ingredientKey] = ing
 		}
+		setIngredientRole(m, ingredientKey, twlLinksRole)
+		setVersification(m, ingredientKey, project, opts)
 	}
 
-	// Set the currentScope
-	m.Type.FlavorType.CurrentScope = currentScope
+	// The currentScope spans every book copied as an ingredient
+	m.Type.FlavorType.CurrentScope = m.AggregateScope()
 
 	// Copy common root files (README.md, .gitignore, .gitea, .github)
-	if err := CopyCommonRootFiles(inDir, outDir, m); err != nil {
+	if err := copyCommonRootFiles(ctx, src, out, m); err != nil {
 		return nil, err
 	}
 
 	// Copy LICENSE.md to ingredients/
-	licIng, err := CopyLicenseIngredient(inDir, outDir)
-	if err != nil {
+	if err := addLicenseIngredient(ctx, m, src, out, license); err != nil {
 		return nil, fmt.Errorf("copying LICENSE.md: %w", err)
 	}
-	m.Ingredients["ingredients/LICENSE.md"] = licIng
 
 	return m, nil
 }
 
+// copyLinkedArticles copies to ingredients/payload/ the TW articles in twBible
+// linked to from the TWLink column of the TSV files of the projects converted
+// under opts.Books. Linked articles missing from twBible are skipped.
+func copyLinkedArticles(ctx context.Context, manifest *rc.Manifest, src, twBible rcSource, out Output, m *sb.Metadata, opts Options) error {
+	linked := make(map[string]bool)
+	for _, project := range manifest.Projects {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		srcName := projectFile(project.Path)
+		if !opts.includesBook(project.Identifier) || !src.exists(srcName) ||
+			!src.allows("ingredients/"+strings.TrimPrefix(path.Base(srcName), "twl_")) {
+			continue
+		}
+		data, err := fs.ReadFile(src.fsys, srcName)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", src.path(srcName), sourceError(err))
+		}
+		lines := strings.Split(string(data), "\n")
+		linkCol := twLinkColumnIndex(lines[0])
+		for _, line := range lines[1:] {
+			fields := strings.Split(strings.TrimSuffix(line, "\r"), "\t")
+			if linkCol < len(fields) {
+				if match := twLinkFieldRegexp.FindStringSubmatch(fields[linkCol]); match != nil {
+					linked[match[1]+".md"] = true
+				}
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(linked)) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !fs.ValidPath(name) || !twBible.exists(name) {
+			continue
+		}
+		if err := addFileIngredient(ctx, m, twBible, name, out, "ingredients/payload/"+name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setIngredientRole sets the role of the ingredient key, if it was copied.
+func setIngredientRole(m *sb.Metadata, key, role string) {
+	if ing, ok := m.Ingredients[key]; ok {
+		ing.Role = role
+		m.Ingredients[key] = ing
+	}
+}
+
 // copyTSVWithLinkRewrite copies a TSV file while replacing rc:// TWLink references
 // with relative payload paths (e.g., rc://*/tw/dict/bible/names/peter -> ./payload/names/peter.md).
+// The TWLink column is located by its index in the header row, and only that field
+// is rewritten; all other fields are preserved byte-for-byte. Fields are split on
+// tabs with no quote handling, so quotes inside fields are left untouched.
+// If stripBOM is set, a leading UTF-8 BOM is dropped from the header row.
+// A file whose start is not UTF-8 text (e.g., a binary file named .tsv) is
+// copied verbatim, without rewriting.
 // The ingredient checksum/size is computed after the rewrite.
-func copyTSVWithLinkRewrite(srcPath, outDir, ingredientKey string, scope map[string][]string) (sb.Ingredient, error) {
+func copyTSVWithLinkRewrite(ctx context.Context, src rcSource, srcName string, out Output, ingredientKey string, scope map[string][]string, stripBOM bool) (sb.Ingredient, error) {
 	// Read the source file
-	inFile, err := os.Open(srcPath)
+	inFile, err := src.fsys.Open(srcName)
 	if err != nil {
-		return sb.Ingredient{}, fmt.Errorf("opening %s: %w", srcPath, err)
+		return sb.Ingredient{}, fmt.Errorf("copying %s to %s: %w", src.path(srcName), ingredientKey, sourceError(err))
 	}
 	defer inFile.Close()
+	src.use(srcName)
 
-	// Create the destination file
-	dstPath := filepath.Join(outDir, ingredientKey)
-	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
-		return sb.Ingredient{}, fmt.Errorf("creating directory for %s: %w", dstPath, err)
+	// A file that is not UTF-8 text is copied verbatim rather than mangled
+	br := bufio.NewReaderSize(inFile, textSniffLen)
+	head, err := br.Peek(textSniffLen)
+	if err != nil && err != io.EOF {
+		return sb.Ingredient{}, fmt.Errorf("copying %s to %s: %w", src.path(srcName), ingredientKey, sourceError(err))
+	}
+	if !isUTF8Text(head, err == nil) {
+		ing, err := copyToOutput(ctx, src, srcName, out, ingredientKey, false)
+		if err != nil {
+			return sb.Ingredient{}, err
+		}
+		ing.Scope = scope
+		return ing, nil
 	}
 
-	outFile, err := os.Create(dstPath)
+	// Create the destination file
+	outFile, err := out.Create(ingredientKey)
 	if err != nil {
-		return sb.Ingredient{}, fmt.Errorf("creating %s: %w", dstPath, err)
+		return sb.Ingredient{}, err
 	}
 	defer outFile.Close()
 
-	scanner := bufio.NewScanner(inFile)
-	scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer for large lines
-	writer := bufio.NewWriter(outFile)
+	// Checksum the rewritten content as it is written
+	ingWriter := src.ingredientWriter(ingredientKey)
 
-	first := true
-	for scanner.Scan() {
-		line := scanner.Text()
+	r, checked := src.encoding.reader(ingredientKey, br)
+	r, normalized := src.normalizer.reader(ingredientKey, r)
+	r, scanned := src.stubs.reader(ingredientKey, r)
+	// Lines are read whole, however long (e.g., a note with an embedded article)
+	reader := bufio.NewReader(r)
+	writer := bufio.NewWriter(io.MultiWriter(outFile, ingWriter))
 
-		if !first {
+	first := true
+	linkCol := -1
+	newline := false // the last line read ended with a newline
+	for {
+		if err := ctx.Err(); err != nil {
+			return sb.Ingredient{}, err
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return sb.Ingredient{}, fmt.Errorf("copying %s to %s: %w", src.path(srcName), ingredientKey, sourceError(err))
+		}
+		if line == "" {
+			break
+		}
+		line, newline = strings.CutSuffix(line, "\n")
+		line = strings.TrimSuffix(line, "\r")
+
+		rewritten := line
+		if first {
+			if stripBOM {
+				line = strings.TrimPrefix(line, "\uFEFF")
+				rewritten = line
+			}
+			// Locate the TWLink column from the header row
+			linkCol = twLinkColumnIndex(line)
+		} else {
 			if _, err := writer.WriteString("\n"); err != nil {
 				return sb.Ingredient{}, err
 			}
+			// Replace the rc:// link in the TWLink column with a ./payload/ path
+			rewritten = rewriteTWLinkField(line, linkCol)
 		}
 		first = false
 
-		// Replace rc:// links in TWLink column with ./payload/ paths
-		rewritten := twLinkReplaceRegexp.ReplaceAllString(line, "\t./payload/$1.md")
-
 		if _, err := writer.WriteString(rewritten); err != nil {
 			return sb.Ingredient{}, err
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return sb.Ingredient{}, fmt.Errorf("reading %s: %w", srcPath, err)
-	}
-
 	// Write trailing newline if original file had one
-	srcInfo, err := os.Stat(srcPath)
-	if err == nil && srcInfo.Size() > 0 {
-		// Check if original file ends with newline
-		f, err := os.Open(srcPath)
-		if err == nil {
-			buf := make([]byte, 1)
-			f.Seek(srcInfo.Size()-1, 0)
-			f.Read(buf)
-			f.Close()
-			if buf[0] == '\n' {
-				writer.WriteString("\n")
-			}
+	if newline {
+		if _, err := writer.WriteString("\n"); err != nil {
+			return sb.Ingredient{}, err
 		}
 	}
 
@@ -215,6 +355,86 @@ func copyTSVWithLinkRewrite(srcPath, outDir, ingredientKey string, scope map[str
 		return sb.Ingredient{}, err
 	}
 
-	// Compute ingredient from the rewritten file
-	return sb.ComputeIngredientWithScope(dstPath, scope)
+	// Ingredient for the rewritten content
+	ing := ingWriter.Ingredient()
+	checked()
+	normalized()
+	scanned()
+	ing.Scope = scope
+	return ing, nil
+}
+
+// textSniffLen is how much of a TSV file is checked to tell whether it is
+// UTF-8 text before its links are rewritten.
+const textSniffLen = 8000
+
+// isUTF8Text reports whether head, the start of a file, is UTF-8 text: valid
+// UTF-8 with no NUL bytes. If truncated is set, head was cut from a longer
+// file, and a rune split at its end is ignored.
+func isUTF8Text(head []byte, truncated bool) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return false
+	}
+	if truncated {
+		i := len(head) - 1
+		for i > 0 && i > len(head)-utf8.UTFMax && !utf8.RuneStart(head[i]) {
+			i--
+		}
+		if i >= 0 && !utf8.FullRune(head[i:]) {
+			head = head[:i]
+		}
+	}
+	return utf8.Valid(head)
+}
+
+// twLinkColumnIndex returns the index of the TWLink column in a TSV header row.
+// If the header has no TWLink column, the last column is assumed, matching the
+// standard TWL layout.
+func twLinkColumnIndex(header string) int {
+	header = strings.TrimPrefix(strings.TrimSuffix(header, "\r"), "\uFEFF")
+	fields := strings.Split(header, "\t")
+	for i, name := range fields {
+		if strings.TrimSpace(name) == twLinkColumn {
+			return i
+		}
+	}
+	return len(fields) - 1
+}
+
+// rewriteTWLinkField rewrites the rc:// link in field col of a TSV line to a
+// ./payload/ path. The line is returned unchanged if it has no such field or
+// the field is not a TW article link.
+func rewriteTWLinkField(line string, col int) string {
+	body := strings.TrimSuffix(line, "\r")
+	fields := strings.Split(body, "\t")
+	if col < 0 || col >= len(fields) {
+		return line
+	}
+	match := twLinkFieldRegexp.FindStringSubmatch(fields[col])
+	if match == nil {
+		return line
+	}
+	fields[col] = "./payload/" + match[1] + ".md"
+	return strings.Join(fields, "\t") + line[len(body):]
+}
+
+// siblingTWDir returns the <lang>_tw directory beside inDir (e.g., ../en_tw
+// for en_twl) if it has a bible/ directory, or "" if it has none or the
+// repository is read from opts.FS and so has no siblings.
+func siblingTWDir(inDir, lang string, opts Options) string {
+	if opts.FS != nil || inDir == "" || lang == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(inDir)
+	if err != nil {
+		return ""
+	}
+	dir := filepath.Join(filepath.Dir(abs), lang+"_tw")
+	if dir == abs {
+		return ""
+	}
+	if info, err := os.Stat(filepath.Join(dir, "bible")); err != nil || !info.IsDir() {
+		return ""
+	}
+	return dir
 }
@@ -0,0 +1,43 @@
+package handler
+
+import "path/filepath"
+
+// DefaultUSFMSiblings lists the Bible resource identifiers, in order of
+// preference, that TSV handlers look for beside the RC repository when no
+// USFMPath is given (e.g., hi_irv beside hi_tn).
+var DefaultUSFMSiblings = []string{"irv", "ult", "ulb", "glt", "reg"}
+
+// usfmDir returns the directory TSV handlers read localized book names from:
+// opts.USFMPath if set, otherwise the first sibling of inDir named
+// <lang>_<id>, for each id in opts.USFMSiblings (or DefaultUSFMSiblings),
+// that contains USFM files. It returns "" if there is none, or if the
+// repository is read from opts.FS and so has no siblings.
+func usfmDir(inDir, lang string, opts Options) string {
+	if opts.USFMPath != "" {
+		return opts.USFMPath
+	}
+	if opts.FS != nil || inDir == "" || lang == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(inDir)
+	if err != nil {
+		return ""
+	}
+
+	siblings := opts.USFMSiblings
+	if siblings == nil {
+		siblings = DefaultUSFMSiblings
+	}
+	parent := filepath.Dir(abs)
+	for _, id := range siblings {
+		dir := filepath.Join(parent, lang+"_"+id)
+		if dir == abs {
+			continue
+		}
+		if matches, _ := filepath.Glob(filepath.Join(dir, "*.usfm")); len(matches) > 0 {
+			opts.info("using sibling USFM repository for localized book names", "dir", dir)
+			return dir
+		}
+	}
+	return ""
+}
@@ -0,0 +1,208 @@
+// Package languages provides English names, autonyms, scripts, and numbering
+// systems for common languages, used to fill in SB language entries.
+package languages
+
+import (
+	"slices"
+	"strings"
+)
+
+// LanguageInfo holds the names, script, and numbering system of a single
+// language.
+type LanguageInfo struct {
+	Tag     string // BCP 47 language tag (e.g., "hi")
+	English string // name in English (e.g., "Hindi")
+	Autonym string // name in the language itself (e.g., "हिन्दी")
+	Script  string // usual ISO 15924 script code (e.g., "Deva"), or "" if it varies
+
+	// NumberingSystem is the CLDR default numbering system (e.g., "latn" or
+	// "arab"), or "" if CLDR does not have the language.
+	NumberingSystem string
+}
+
+// AllLanguages lists the languages with known names and scripts, chiefly
+// Door43 gateway and original languages.
+var AllLanguages = []LanguageInfo{
+	{Tag: "am", English: "Amharic", Autonym: "አማርኛ", Script: "Ethi", NumberingSystem: "latn"},
+	{Tag: "ar", English: "Arabic", Autonym: "العربية", Script: "Arab", NumberingSystem: "arab"},
+	{Tag: "as", English: "Assamese", Autonym: "অসমীয়া", Script: "Beng", NumberingSystem: "beng"},
+	{Tag: "bn", English: "Bengali", Autonym: "বাংলা", Script: "Beng", NumberingSystem: "beng"},
+	{Tag: "ceb", English: "Cebuano", Autonym: "Cebuano", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "de", English: "German", Autonym: "Deutsch", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "el", English: "Greek", Autonym: "Ελληνικά", Script: "Grek", NumberingSystem: "latn"},
+	{Tag: "en", English: "English", Autonym: "English", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "es", English: "Spanish", Autonym: "español", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "es-419", English: "Latin American Spanish", Autonym: "español latinoamericano", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "fa", English: "Persian", Autonym: "فارسی", Script: "Arab", NumberingSystem: "arabext"},
+	{Tag: "fr", English: "French", Autonym: "français", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "grc", English: "Ancient Greek", Autonym: "Ἑλληνική", Script: "Grek"},
+	{Tag: "gu", English: "Gujarati", Autonym: "ગુજરાતી", Script: "Gujr", NumberingSystem: "latn"},
+	{Tag: "ha", English: "Hausa", Autonym: "Hausa", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "hbo", English: "Ancient Hebrew", Autonym: "עברית קדומה", Script: "Hebr"},
+	{Tag: "he", English: "Hebrew", Autonym: "עברית", Script: "Hebr", NumberingSystem: "latn"},
+	{Tag: "hi", English: "Hindi", Autonym: "हिन्दी", Script: "Deva", NumberingSystem: "latn"},
+	{Tag: "id", English: "Indonesian", Autonym: "Bahasa Indonesia", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "ig", English: "Igbo", Autonym: "Asụsụ Igbo", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "ilo", English: "Ilocano", Autonym: "Ilokano", Script: "Latn"},
+	{Tag: "it", English: "Italian", Autonym: "italiano", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "ja", English: "Japanese", Autonym: "日本語", Script: "Jpan", NumberingSystem: "latn"},
+	{Tag: "km", English: "Khmer", Autonym: "ភាសាខ្មែរ", Script: "Khmr", NumberingSystem: "latn"},
+	{Tag: "kn", English: "Kannada", Autonym: "ಕನ್ನಡ", Script: "Knda", NumberingSystem: "latn"},
+	{Tag: "ko", English: "Korean", Autonym: "한국어", Script: "Kore", NumberingSystem: "latn"},
+	{Tag: "lo", English: "Lao", Autonym: "ລາວ", Script: "Laoo", NumberingSystem: "latn"},
+	{Tag: "ml", English: "Malayalam", Autonym: "മലയാളം", Script: "Mlym", NumberingSystem: "latn"},
+	{Tag: "mr", English: "Marathi", Autonym: "मराठी", Script: "Deva", NumberingSystem: "deva"},
+	{Tag: "ms", English: "Malay", Autonym: "Bahasa Melayu", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "my", English: "Burmese", Autonym: "မြန်မာဘာသာ", Script: "Mymr", NumberingSystem: "mymr"},
+	{Tag: "ne", English: "Nepali", Autonym: "नेपाली", Script: "Deva", NumberingSystem: "deva"},
+	{Tag: "nl", English: "Dutch", Autonym: "Nederlands", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "or", English: "Odia", Autonym: "ଓଡ଼ିଆ", Script: "Orya", NumberingSystem: "latn"},
+	{Tag: "pa", English: "Punjabi", Autonym: "ਪੰਜਾਬੀ", Script: "Guru", NumberingSystem: "latn"},
+	{Tag: "pt", English: "Portuguese", Autonym: "português", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "pt-br", English: "Brazilian Portuguese", Autonym: "português brasileiro", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "ru", English: "Russian", Autonym: "русский", Script: "Cyrl", NumberingSystem: "latn"},
+	{Tag: "sw", English: "Swahili", Autonym: "Kiswahili", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "ta", English: "Tamil", Autonym: "தமிழ்", Script: "Taml", NumberingSystem: "latn"},
+	{Tag: "te", English: "Telugu", Autonym: "తెలుగు", Script: "Telu", NumberingSystem: "latn"},
+	{Tag: "th", English: "Thai", Autonym: "ไทย", Script: "Thai", NumberingSystem: "latn"},
+	{Tag: "tl", English: "Tagalog", Autonym: "Tagalog", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "tpi", English: "Tok Pisin", Autonym: "Tok Pisin", Script: "Latn"},
+	{Tag: "tr", English: "Turkish", Autonym: "Türkçe", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "ur", English: "Urdu", Autonym: "اردو", Script: "Arab", NumberingSystem: "latn"},
+	{Tag: "vi", English: "Vietnamese", Autonym: "Tiếng Việt", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "yo", English: "Yoruba", Autonym: "Yorùbá", Script: "Latn", NumberingSystem: "latn"},
+	{Tag: "zh", English: "Chinese", Autonym: "中文", NumberingSystem: "latn"},
+}
+
+// languageByTag is a lookup map from lowercase tag to LanguageInfo.
+var languageByTag map[string]*LanguageInfo
+
+func init() {
+	languageByTag = make(map[string]*LanguageInfo, len(AllLanguages))
+	for i := range AllLanguages {
+		l := &AllLanguages[i]
+		languageByTag[l.Tag] = l
+	}
+}
+
+// ByTag returns the LanguageInfo for a language tag (e.g., "hi"), or nil if
+// not found. Tags are matched case-insensitively. A tag with subtags that is
+// not listed itself (e.g., "hi-Latn") is not matched by its primary language,
+// since its script or region may change the name.
+func ByTag(tag string) *LanguageInfo {
+	return languageByTag[strings.ToLower(tag)]
+}
+
+// Names returns the SB name map for the language tag: its English name under
+// "en" and its autonym under tag itself (e.g., {"en": "Hindi", "hi": "हिन्दी"}).
+// If the language is unknown, title (e.g., the RC manifest's language title)
+// is returned under "en".
+func Names(tag, title string) map[string]string {
+	l := ByTag(tag)
+	if l == nil {
+		return map[string]string{"en": title}
+	}
+	return map[string]string{"en": l.English, tag: l.Autonym}
+}
+
+// Script returns the ISO 15924 script code for the language tag: the tag's
+// own script subtag if it has one (e.g., "Latn" for "hi-Latn"), or else the
+// usual script of the language (e.g., "Hebr" for "hbo"). It returns "" if the
+// script is unknown or, as for "zh", the language is written in several.
+func Script(tag string) string {
+	subtags := strings.Split(tag, "-")
+	for _, subtag := range subtags[1:] {
+		if len(subtag) == 4 && isAlpha(subtag) {
+			return strings.ToUpper(subtag[:1]) + strings.ToLower(subtag[1:])
+		}
+	}
+	if l := ByTag(tag); l != nil {
+		return l.Script
+	}
+	if l := ByTag(subtags[0]); l != nil {
+		return l.Script
+	}
+	return ""
+}
+
+// Region returns the region subtag of the language tag, in uppercase (e.g.,
+// "BR" for "pt-br", or "419" for "es-419"), or "" if it has none. The region
+// is never guessed from the language.
+func Region(tag string) string {
+	for _, subtag := range strings.Split(tag, "-")[1:] {
+		switch {
+		case len(subtag) == 1:
+			// Extensions and private use (e.g., "el-x-koine") follow
+			return ""
+		case len(subtag) == 2 && isAlpha(subtag):
+			return strings.ToUpper(subtag)
+		case len(subtag) == 3 && strings.Trim(subtag, "0123456789") == "":
+			return subtag
+		}
+	}
+	return ""
+}
+
+// NumberingSystem returns the CLDR numbering system for the language tag:
+// the tag's own "-u-nu-" extension if it has one (e.g., "deva" for
+// "hi-u-nu-deva"), or else the default numbering system of the language
+// (e.g., "arab" for "ar"). It returns "" if the language is unknown or the
+// tag names a script other than the language's usual one.
+func NumberingSystem(tag string) string {
+	subtags := strings.Split(strings.ToLower(tag), "-")
+	for i, subtag := range subtags {
+		if subtag == "x" {
+			break
+		}
+		if subtag == "nu" && i > 1 && subtags[i-1] == "u" && i+1 < len(subtags) {
+			return subtags[i+1]
+		}
+	}
+	l := ByTag(tag)
+	if l == nil {
+		l = ByTag(subtags[0])
+	}
+	if l == nil || Script(tag) != l.Script {
+		return ""
+	}
+	return l.NumberingSystem
+}
+
+// rtlScripts lists the ISO 15924 codes of the scripts written right to left.
+var rtlScripts = []string{"Adlm", "Arab", "Hebr", "Mand", "Nkoo", "Rohg", "Samr", "Syrc", "Thaa"}
+
+// ScriptDirection returns the SB scriptDirection, "ltr" or "rtl", for an RC
+// manifest's language direction dir (e.g., "LTR", "Right-to-Left", or
+// " rtl") and tag. If dir is empty or unrecognized, the direction of the
+// tag's script is used (e.g., "rtl" for "ar" or "he"), or "ltr" if the script
+// is unknown. ok is false if dir is set but unrecognized (e.g.,
+// "bidirectional").
+func ScriptDirection(tag, dir string) (direction string, ok bool) {
+	normalized := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '_' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(dir)))
+	switch normalized {
+	case "ltr", "lefttoright":
+		return "ltr", true
+	case "rtl", "righttoleft":
+		return "rtl", true
+	}
+	direction = "ltr"
+	if slices.Contains(rtlScripts, Script(tag)) {
+		direction = "rtl"
+	}
+	return direction, normalized == ""
+}
+
+// isAlpha reports whether s consists only of ASCII letters.
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
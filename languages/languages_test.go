@@ -0,0 +1,173 @@
+package languages_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unfoldingWord/go-rc2sb/languages"
+)
+
+func TestByTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"hi", "Hindi"},
+		{"HI", "Hindi"},
+		{"es-419", "Latin American Spanish"},
+		{"pt-BR", "Brazilian Portuguese"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			l := languages.ByTag(tt.tag)
+			if l == nil || l.English != tt.want {
+				t.Errorf("ByTag(%q) = %+v; want %s", tt.tag, l, tt.want)
+			}
+		})
+	}
+
+	for _, tag := range []string{"", "xyz", "hi-Latn"} {
+		if l := languages.ByTag(tag); l != nil {
+			t.Errorf("ByTag(%q) = %+v; want nil", tag, l)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		tag, title string
+		want       map[string]string
+	}{
+		{"hi", "Hindi", map[string]string{"en": "Hindi", "hi": "हिन्दी"}},
+		{"pt-BR", "Português", map[string]string{"en": "Brazilian Portuguese", "pt-BR": "português brasileiro"}},
+		{"en", "English", map[string]string{"en": "English"}},
+		{"xyz", "Xyzish", map[string]string{"en": "Xyzish"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := languages.Names(tt.tag, tt.title); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Names(%q, %q) = %v; want %v", tt.tag, tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllLanguages_UniqueLowercaseTags(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, l := range languages.AllLanguages {
+		if seen[l.Tag] {
+			t.Errorf("duplicate tag %q", l.Tag)
+		}
+		seen[l.Tag] = true
+		if languages.ByTag(l.Tag) == nil {
+			t.Errorf("tag %q is not found by ByTag; tags must be lowercase", l.Tag)
+		}
+		if l.English == "" || l.Autonym == "" {
+			t.Errorf("%q is missing a name: %+v", l.Tag, l)
+		}
+	}
+}
+
+func TestScript(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"hbo", "Hebr"},
+		{"grc", "Grek"},
+		{"hi", "Deva"},
+		{"es-419", "Latn"},
+		{"pt-PT", "Latn"}, // region only: the primary language's script
+		{"hi-Latn", "Latn"},
+		{"sr-cyrl-RS", "Cyrl"},
+		{"zh", ""},
+		{"xyz", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := languages.Script(tt.tag); got != tt.want {
+				t.Errorf("Script(%q) = %q; want %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegion(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"pt-br", "BR"},
+		{"es-419", "419"},
+		{"sr-Cyrl-RS", "RS"},
+		{"hi", ""},
+		{"el-x-koine", ""},
+		{"qaa-x-mytribe", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := languages.Region(tt.tag); got != tt.want {
+				t.Errorf("Region(%q) = %q; want %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumberingSystem(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"hi", "latn"},
+		{"ar", "arab"},
+		{"fa", "arabext"},
+		{"pt-BR", "latn"},
+		{"hi-u-nu-deva", "deva"},
+		{"hi-Latn", ""}, // not the usual script of Hindi
+		{"ar-Latn", ""},
+		{"hbo", ""},
+		{"qaa-x-mytribe", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := languages.NumberingSystem(tt.tag); got != tt.want {
+				t.Errorf("NumberingSystem(%q) = %q; want %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptDirection(t *testing.T) {
+	tests := []struct {
+		tag, dir string
+		want     string
+		wantOK   bool
+	}{
+		{"en", "ltr", "ltr", true},
+		{"en", "LTR", "ltr", true},
+		{"he", "rtl ", "rtl", true},
+		{"en", "Left-to-Right", "ltr", true},
+		{"ur", "Right to Left", "rtl", true},
+		// Empty: the direction of the language's script
+		{"en", "", "ltr", true},
+		{"ar", "", "rtl", true},
+		{"fa", "", "rtl", true},
+		{"hbo", "", "rtl", true},
+		{"hi-Arab", "", "rtl", true},
+		{"qaa-x-mytribe", "", "ltr", true},
+		// Unrecognized: likewise, but reported
+		{"en", "bidirectional", "ltr", false},
+		{"ur", "bidirectional", "rtl", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag+" "+tt.dir, func(t *testing.T) {
+			got, ok := languages.ScriptDirection(tt.tag, tt.dir)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ScriptDirection(%q, %q) = %q, %v; want %q, %v", tt.tag, tt.dir, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package rc2sb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/unfoldingWord/go-rc2sb/handler"
+	"github.com/unfoldingWord/go-rc2sb/rc"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// mergeInput is one RC repository of a ConvertMerged call.
+type mergeInput struct {
+	dir     string
+	subject string
+
+	// prefix is the directory beneath ingredients/ that the repository's
+	// ingredients are moved to, or "" to keep their keys.
+	prefix string
+}
+
+// key returns the merged ingredient key for the ingredient key of in.
+func (in mergeInput) key(key string) string {
+	if in.prefix == "" {
+		return key
+	}
+	return "ingredients/" + in.prefix + "/" + strings.TrimPrefix(key, "ingredients/")
+}
+
+// ConvertMerged converts the RC repositories in inDirs into a single SB in
+// outDir. The first is the primary: it is converted as by Convert, and its
+// identification, type, and copyright are those of the SB. Each other
+// repository is converted with its own handler and its ingredients added:
+//
+//   - a repository of the primary's subject that is split by book (e.g., an
+//     Old and a New Testament Bible, or TN for different books) keeps its
+//     ingredient keys, and no key may be produced twice with different
+//     content;
+//   - a repository of another subject is a parascriptural attachment, whose
+//     ingredients are moved beneath ingredients/<identifier>/ (e.g.,
+//     ingredients/GEN.tsv of a TN to ingredients/tn/GEN.tsv).
+//
+// Languages, localizedNames, and currentScope are merged, the primary taking
+// precedence. Only ingredients are taken from the other repositories; their
+// root files, such as README.md, are left out.
+//
+// Combinations that make no single burrito are rejected before anything is
+// converted: a Bible anywhere but first, two repositories of a subject that is
+// not split by book (e.g., two OBS), or two attachments with the same
+// identifier. opts applies to every repository, but for SubjectOverride,
+// which applies to the primary only. Warnings from the other repositories are
+// prefixed with their directory.
+func ConvertMerged(ctx context.Context, inDirs []string, outDir string, opts Options) (Result, error) {
+	// Check context
+	if err := ctx.Err(); err != nil {
+		return Result{}, fmt.Errorf("context error: %w", err)
+	}
+
+	start := time.Now()
+	inputs, err := planMerge(inDirs, opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, err := Convert(ctx, inDirs[0], outDir, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	m, err := sb.LoadMetadata(outDir)
+	if err != nil {
+		return Result{}, err
+	}
+
+	opts.SubjectOverride = ""
+	for _, in := range inputs[1:] {
+		r, err := mergeRC(ctx, in, m, outDir, opts)
+		if err != nil {
+			return Result{}, err
+		}
+		for _, w := range r.Warnings {
+			result.Warnings = append(result.Warnings, in.dir+": "+w)
+		}
+		for _, key := range r.Excluded {
+			result.Excluded = append(result.Excluded, in.key(key))
+		}
+		for _, key := range r.Normalized {
+			result.Normalized = append(result.Normalized, in.key(key))
+		}
+	}
+
+	if err := m.WriteToFile(outDir); err != nil {
+		return Result{}, err
+	}
+	if err := refreshBagItManifest(outDir, m); err != nil {
+		return Result{}, err
+	}
+	result.setIngredients(m)
+	result.Written = append(result.Written, result.IngredientKeys...)
+	slices.Sort(result.Written)
+	result.Written = slices.Compact(result.Written)
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// planMerge reads the manifest of each of inDirs and returns how each is
+// merged, or an error if they cannot make a single burrito.
+func planMerge(inDirs []string, opts Options) ([]mergeInput, error) {
+	if len(inDirs) == 0 {
+		return nil, errors.New("no RC repositories to merge")
+	}
+
+	var inputs []mergeInput
+	prefixes := make(map[string]string)
+	for i, dir := range inDirs {
+		manifest, err := rc.LoadManifest(dir)
+		if err != nil {
+			return nil, err
+		}
+		in := mergeInput{dir: dir, subject: manifest.DublinCore.Subject}
+		if i == 0 && opts.SubjectOverride != "" {
+			in.subject = opts.SubjectOverride
+		}
+		layout, ok := rcLayouts[in.subject]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w %q", dir, handler.ErrUnsupportedSubject, in.subject)
+		}
+
+		if i > 0 {
+			primary := inputs[0]
+			switch {
+			case in.subject == primary.subject && layout.kind != bookFiles:
+				return nil, fmt.Errorf("cannot merge %s and %s: both are %s, which is not split by book", primary.dir, dir, in.subject)
+			case in.subject == primary.subject:
+				// Another part of the same resource
+			case layout.format == "text/usfm3":
+				return nil, fmt.Errorf("cannot merge %s: a Bible (%s) can only be the primary, first, repository", dir, in.subject)
+			default:
+				in.prefix = strings.ToLower(manifest.DublinCore.Identifier)
+				if in.prefix == "" || !validPrefix(in.prefix) {
+					return nil, fmt.Errorf("cannot merge %s: its identifier %q cannot name an ingredient directory", dir, manifest.DublinCore.Identifier)
+				}
+				if prev, ok := prefixes[in.prefix]; ok {
+					return nil, fmt.Errorf("cannot merge %s and %s: both have the identifier %q", prev, dir, in.prefix)
+				}
+				prefixes[in.prefix] = dir
+			}
+		}
+		inputs = append(inputs, in)
+	}
+	return inputs, nil
+}
+
+// validPrefix reports whether the identifier id can be used as a single
+// directory name beneath ingredients/.
+func validPrefix(id string) bool {
+	return id != "." && id != ".." && !strings.ContainsAny(id, `/\`)
+}
+
+// mergeRC converts the repository in into a temporary directory and adds its
+// ingredients, languages, localized names, and scope to m, copying the
+// ingredient files to outDir.
+func mergeRC(ctx context.Context, in mergeInput, m *sb.Metadata, outDir string, opts Options) (Result, error) {
+	tmpDir, err := os.MkdirTemp("", "rc2sb-merge-")
+	if err != nil {
+		return Result{}, fmt.Errorf("creating conversion directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	result, err := Convert(ctx, in.dir, tmpDir, opts)
+	if err != nil {
+		return Result{}, fmt.Errorf("converting %s: %w", in.dir, err)
+	}
+	part, err := sb.LoadMetadata(tmpDir)
+	if err != nil {
+		return Result{}, err
+	}
+
+	fsys := os.DirFS(tmpDir)
+	for _, key := range slices.Sorted(maps.Keys(part.Ingredients)) {
+		ing := part.Ingredients[key]
+		merged := in.key(key)
+		if prev, ok := m.Ingredients[merged]; ok {
+			if prev.Checksum == ing.Checksum {
+				// The same file, such as a shared LICENSE.md
+				continue
+			}
+			return Result{}, fmt.Errorf("cannot merge %s: its ingredient %s differs from one already merged", in.dir, merged)
+		}
+		if err := handler.CopyFile(ctx, fsys, key, filepath.Join(outDir, filepath.FromSlash(merged))); err != nil {
+			return Result{}, fmt.Errorf("copying %s: %w", merged, err)
+		}
+		m.Ingredients[merged] = ing
+	}
+
+	for _, lang := range part.Languages {
+		if !slices.ContainsFunc(m.Languages, func(l sb.LanguageEntry) bool { return l.Tag == lang.Tag }) {
+			m.Languages = append(m.Languages, lang)
+		}
+	}
+	for name, ln := range part.LocalizedNames {
+		if _, ok := m.LocalizedNames[name]; !ok {
+			if m.LocalizedNames == nil {
+				m.LocalizedNames = make(map[string]sb.LocalizedName)
+			}
+			m.LocalizedNames[name] = ln
+		}
+	}
+	for book, chapters := range part.Type.FlavorType.CurrentScope {
+		if _, ok := m.Type.FlavorType.CurrentScope[book]; !ok {
+			if m.Type.FlavorType.CurrentScope == nil {
+				m.Type.FlavorType.CurrentScope = make(map[string][]string)
+			}
+			m.Type.FlavorType.CurrentScope[book] = chapters
+		}
+	}
+	return result, nil
+}
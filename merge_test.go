@@ -0,0 +1,168 @@
+package rc2sb_test
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// mergeBibleFiles is a two-book Bible repository.
+var mergeBibleFiles = map[string]string{
+	"manifest.yaml": roundTripManifest("Aligned Bible", "ult", "gen ./01-GEN.usfm", "mat ./41-MAT.usfm"),
+	"01-GEN.usfm":   "\\id GEN\n\\toc1 Genesis\n\\c 1\n\\v 1 In the beginning\n",
+	"41-MAT.usfm":   "\\id MAT\n\\toc1 Matthew\n\\c 1\n\\v 1 The book\n",
+	"LICENSE.md":    "License\n",
+	"README.md":     "# ULT\n",
+}
+
+func TestConvertMerged_BibleWithTN(t *testing.T) {
+	ctx := context.Background()
+	bibleDir, tnDir, outDir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeRepoFiles(t, bibleDir, mergeBibleFiles)
+	writeRepoFiles(t, tnDir, compareTNFiles)
+
+	result, err := rc2sb.ConvertMerged(ctx, []string{bibleDir, tnDir}, outDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("ConvertMerged failed: %v", err)
+	}
+	if result.Subject != "Aligned Bible" || result.Identifier != "ult" {
+		t.Errorf("result = %s %s; want the primary's subject and identifier", result.Subject, result.Identifier)
+	}
+
+	m, err := sb.LoadMetadata(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantKeys := []string{
+		"ingredients/GEN.usfm",
+		"ingredients/LICENSE.md",
+		"ingredients/MAT.usfm",
+		"ingredients/tn/GEN.tsv",
+		"ingredients/tn/LICENSE.md",
+		"ingredients/tn/MAT.tsv",
+	}
+	var keys []string
+	for key, ing := range m.Ingredients {
+		keys = append(keys, key)
+		want, err := sb.ComputeIngredient(filepath.Join(outDir, filepath.FromSlash(key)))
+		if err != nil {
+			t.Errorf("ingredient %s: %v", key, err)
+		} else if ing.Checksum != want.Checksum || ing.Size != want.Size {
+			t.Errorf("ingredient %s = %+v; want checksum and size of its file %+v", key, ing, want)
+		}
+	}
+	slices.Sort(keys)
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("ingredients = %v; want %v", keys, wantKeys)
+	}
+	if result.Ingredients != len(wantKeys) {
+		t.Errorf("result.Ingredients = %d; want %d", result.Ingredients, len(wantKeys))
+	}
+
+	// The TN keeps its scopes beneath its prefix
+	if scope := m.Ingredients["ingredients/tn/MAT.tsv"].Scope; !reflect.DeepEqual(scope, map[string][]string{"MAT": {}}) {
+		t.Errorf("tn/MAT.tsv scope = %v", scope)
+	}
+	// Identification and flavor come from the Bible
+	if m.Type.FlavorType.Flavor.Name != "textTranslation" || m.Identification.Abbreviation["en"] != "ULT" {
+		t.Errorf("type %q, abbreviation %q; want the Bible's", m.Type.FlavorType.Flavor.Name, m.Identification.Abbreviation["en"])
+	}
+	if len(m.Languages) != 1 || m.Languages[0].Tag != "en" {
+		t.Errorf("languages = %+v; want en once", m.Languages)
+	}
+	if _, ok := m.Type.FlavorType.CurrentScope["MAT"]; !ok {
+		t.Errorf("currentScope = %v; want MAT", m.Type.FlavorType.CurrentScope)
+	}
+	// Only the primary's root files are kept
+	if data, err := os.ReadFile(filepath.Join(outDir, "README.md")); err != nil || string(data) != "# ULT\n" {
+		t.Errorf("README.md = %q, %v; want the Bible's", data, err)
+	}
+}
+
+func TestConvertMerged_PerTestamentBibles(t *testing.T) {
+	otDir, ntDir, outDir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeRepoFiles(t, otDir, map[string]string{
+		"manifest.yaml": roundTripManifest("Aligned Bible", "ult", "gen ./01-GEN.usfm"),
+		"01-GEN.usfm":   mergeBibleFiles["01-GEN.usfm"],
+		"LICENSE.md":    "License\n",
+	})
+	writeRepoFiles(t, ntDir, map[string]string{
+		"manifest.yaml": roundTripManifest("Aligned Bible", "ult", "mat ./41-MAT.usfm"),
+		"41-MAT.usfm":   mergeBibleFiles["41-MAT.usfm"],
+		"LICENSE.md":    "License\n",
+	})
+
+	if _, err := rc2sb.ConvertMerged(context.Background(), []string{otDir, ntDir}, outDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("ConvertMerged failed: %v", err)
+	}
+	m, err := sb.LoadMetadata(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"ingredients/GEN.usfm", "ingredients/MAT.usfm", "ingredients/LICENSE.md"} {
+		if _, ok := m.Ingredients[key]; !ok {
+			t.Errorf("missing ingredient %s", key)
+		}
+	}
+	if len(m.Ingredients) != 3 {
+		t.Errorf("ingredients = %v; want 3", m.Ingredients)
+	}
+	if books := m.UnscopedBooks(); books != nil || len(m.Type.FlavorType.CurrentScope) != 2 {
+		t.Errorf("currentScope = %v, unscoped %v; want GEN and MAT", m.Type.FlavorType.CurrentScope, books)
+	}
+	if _, ok := m.LocalizedNames["book-mat"]; !ok {
+		t.Error("localizedNames lacks book-mat")
+	}
+}
+
+func TestConvertMerged_RejectsCombinations(t *testing.T) {
+	bibleDir, tnDir, otherTNDir := t.TempDir(), t.TempDir(), t.TempDir()
+	obsDir, otherOBSDir := t.TempDir(), t.TempDir()
+	writeRepoFiles(t, bibleDir, mergeBibleFiles)
+	writeRepoFiles(t, tnDir, compareTNFiles)
+	writeRepoFiles(t, obsDir, archiveOBSFiles)
+	writeRepoFiles(t, otherOBSDir, archiveOBSFiles)
+	otherTN := maps.Clone(compareTNFiles)
+	otherTN["tn_GEN.tsv"] = strings.Replace(otherTN["tn_GEN.tsv"], "Note", "Other note", 1)
+	writeRepoFiles(t, otherTNDir, otherTN)
+
+	tests := []struct {
+		name   string
+		inDirs []string
+		want   string
+	}{
+		{"none", nil, "no RC repositories"},
+		{"Bible as attachment", []string{tnDir, bibleDir}, "can only be the primary"},
+		{"two OBS", []string{obsDir, otherOBSDir}, "not split by book"},
+		{"same attachment twice", []string{bibleDir, tnDir, tnDir}, `both have the identifier "tn"`},
+		{"conflicting book", []string{tnDir, otherTNDir}, "ingredients/GEN.tsv differs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rc2sb.ConvertMerged(context.Background(), tt.inDirs, t.TempDir(), rc2sb.Options{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v; want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertMerged_BagItManifest(t *testing.T) {
+	bibleDir, tnDir, outDir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeRepoFiles(t, bibleDir, mergeBibleFiles)
+	writeRepoFiles(t, tnDir, compareTNFiles)
+
+	if _, err := rc2sb.ConvertMerged(context.Background(), []string{bibleDir, tnDir}, outDir, rc2sb.Options{BagItManifest: true}); err != nil {
+		t.Fatalf("ConvertMerged failed: %v", err)
+	}
+	// The manifest lists the merged TN too, not only the primary Bible
+	verifyBagItManifest(t, outDir)
+}
@@ -1,5 +1,13 @@
 package rc2sb
 
+import (
+	"log/slog"
+	"time"
+
+	"github.com/unfoldingWord/go-rc2sb/books"
+	"github.com/unfoldingWord/go-rc2sb/handler"
+)
+
 // Options configures the RC to SB conversion.
 type Options struct {
 	// PayloadPath is the path to a Translation Words directory (e.g., "/path/to/en_tw")
@@ -9,8 +17,10 @@ type Options struct {
 	// relative ./payload/ paths.
 	//
 	// If empty, the TWL handler auto-detects a <lang>_tw/ subdirectory inside
-	// the input RC repo directory (where <lang> is the manifest's language identifier).
-	// If neither is found, no payload is created and TSV files are copied as-is.
+	// the input RC repo directory (where <lang> is the manifest's language identifier),
+	// and failing that a <lang>_tw/ sibling of it (e.g., ../en_tw beside en_twl).
+	// The precedence is PayloadPath, then inDir/<lang>_tw, then ../<lang>_tw.
+	// If none is found, no payload is created and TSV files are copied as-is.
 	PayloadPath string
 
 	// USFMPath is the path to a directory containing USFM files for localized
@@ -20,25 +30,335 @@ type Options struct {
 	// For Bible/USFM handlers, the USFM files in the input RC repo are used
 	// directly, so this option is not needed.
 	//
-	// If empty, TSV handlers will use project titles from the manifest,
-	// falling back to English names from the books package.
+	// If empty, TSV handlers look beside the input RC repo directory for a
+	// sibling <lang>_<id> directory containing USFM files (e.g., hi_irv beside
+	// hi_tn), for each id in USFMSiblings, and use the first one found. If
+	// there is none, they use project titles from the manifest, falling back
+	// to English names from the books package.
 	USFMPath string
+
+	// USFMSiblings lists, in order of preference, the Bible resource
+	// identifiers tried for a sibling USFM directory when USFMPath is empty.
+	// If nil, handler.DefaultUSFMSiblings ("irv", "ult", "ulb", "glt", "reg")
+	// is used; an empty, non-nil list turns the search off. Siblings are not
+	// searched for conversions from an fs.FS via ConvertFS.
+	USFMSiblings []string
+
+	// USFMNames, if set, caches the localized book names read from the USFM
+	// directory (USFMPath or a sibling), so that conversions sharing it list
+	// the directory once and open each USFM file at most once. ConvertAll
+	// shares one cache across its jobs when this is nil; a single Convert
+	// uses its own. A cache does not see later edits to the USFM files, so
+	// it should not outlive a batch. Create one with books.NewUSFMNameCache.
+	USFMNames *books.USFMNameCache
+
+	// CopyrightStatement is an optional localized copyright statement used as
+	// the SB copyright short statement in place of the one generated from the
+	// manifest's publisher, issued year, and rights. Either way, the statement
+	// is tagged with the manifest's language identifier.
+	CopyrightStatement string
+
+	// SubjectOverride, if set, is used in place of the manifest's
+	// dublin_core.subject to select the handler, for repos whose subject is
+	// missing or misspelled. It must be one of handler.SupportedSubjects().
+	// The manifest's original subject is reported in a warning.
+	SubjectOverride string
+
+	// ConfidentialCheckingLevels lists the RC checking levels (e.g., "1" for
+	// unreviewed drafts) for which the SB metadata is marked confidential.
+	// If empty, confidential is always false.
+	ConfidentialCheckingLevels []string
+
+	// MimeOverrides maps file extensions (e.g., ".usfm", in any case) or
+	// glob patterns matched against ingredient keys, as in IncludeGlobs
+	// (e.g., "ingredients/notes/*.txt"), to the MIME type recorded for the
+	// ingredients they match (e.g., "text/x-usfm"), in place of the built-in
+	// type (see sb.MIMETypeForExt). A matching pattern takes precedence over
+	// an extension, and patterns are tried in sorted order.
+	MimeOverrides map[string]string
+
+	// Category is the SB meta.category: "source", "derived" (e.g., for an
+	// aligned or otherwise derived product), or "template". If empty,
+	// "source" is used.
+	Category string
+
+	// IncludeGlobs, if non-empty, restricts the files copied as ingredients to
+	// those whose ingredient key (e.g., "ingredients/GEN.usfm") matches one of
+	// these patterns. Patterns use path.Match syntax per segment, plus "**" to
+	// match any number of directories (e.g., "ingredients/**/*.md").
+	// ingredients/LICENSE.md is always included.
+	IncludeGlobs []string
+
+	// ExcludeGlobs lists patterns, in the same syntax as IncludeGlobs, for
+	// files that are never copied as ingredients (e.g., "**/*.bak").
+	ExcludeGlobs []string
+
+	// StripBOM removes a leading UTF-8 byte order mark from text ingredients
+	// (.md, .tsv, and .usfm files) as they are copied, so the checksums in
+	// metadata.json describe the BOM-free content. Other files are copied
+	// byte-for-byte. Off by default, so text ingredients are copied exactly.
+	StripBOM bool
+
+	// SHA256Checksums records a SHA-256 checksum ("sha256") beside the MD5
+	// of every ingredient in metadata.json. Both are computed as the file is
+	// copied, so each source file is still read only once. Off by default,
+	// since MD5 is all that Scripture Burrito readers require.
+	SHA256Checksums bool
+
+	// RecordSources records in each ingredient's x-source field the path of
+	// the file it was copied from, relative to the RC repository (e.g.,
+	// "tn_GEN.tsv" for ingredients/GEN.tsv), so edits can be mapped back.
+	// Files from PayloadPath are recorded relative to that directory.
+	// Off by default, so metadata.json is unchanged.
+	RecordSources bool
+
+	// ExtraRootFiles and ExtraRootDirs list files and directories at the root
+	// of the RC repo (e.g., ".apps" for Door43 app config, or ".vscode") to
+	// copy to the SB root, beside README.md, .gitignore, .gitea, and .github.
+	// Unlike those, they are recorded as ingredients under their own paths
+	// (e.g., ".apps/config.yaml") and are subject to IncludeGlobs and
+	// ExcludeGlobs. Entries that do not exist are skipped, and .git is never
+	// copied.
+	ExtraRootFiles []string
+	ExtraRootDirs  []string
+
+	// IncludeSourceManifest, if set, carries the RC's manifest.yaml, and its
+	// media.yaml if it has one, into the SB as ingredients/source/manifest.yaml
+	// and ingredients/source/media.yaml, recorded as ingredients with the role
+	// "x-source-manifest", for auditing and reverse conversion. Otherwise
+	// neither reaches the output.
+	IncludeSourceManifest bool
+
+	// Strict, if set, makes the conversion fail, with an error wrapping
+	// ErrStrict, on an ingredient that is too small to be useful for its kind:
+	// a TSV (e.g., of TN, TQ, or TWL) with only a header row, or a USFM with
+	// no \c chapter marker. Otherwise each is reported in Result.Warnings.
+	// An empty (zero-byte) ingredient is always reported in a warning. Strict
+	// also warns of an ingredient whose MIME type is not the one for its
+	// extension (see sb.MIMETypeForExt), unless MimeOverrides sets it.
+	Strict bool
+
+	// IgnoreUnreferenced, if set, turns off the warnings otherwise added to
+	// Result.Warnings for files of the RC repository that were not converted
+	// and that no manifest project includes (e.g., a USFM file missing from
+	// the projects), with their sizes. manifest.yaml, media.yaml, and files
+	// and directories whose names start with "." (e.g., .git) are not
+	// reported.
+	IgnoreUnreferenced bool
+
+	// SkipDuplicateProjects, if set, converts a manifest in which projects
+	// repeat the identifier or path of an earlier project (see
+	// rc.Manifest.DuplicateProjects) by skipping the repeats, each reported in
+	// Result.Warnings. Otherwise such a manifest is an error, since one
+	// project's ingredient would overwrite the other's.
+	SkipDuplicateProjects bool
+
+	// Books, if non-empty, restricts the conversion of a subject split by
+	// book (a Bible, TN, TQ, SN, or TWL) to the projects whose identifier is
+	// one of these book IDs or codes, in any case (e.g., "gen" or "MAT").
+	// currentScope and localizedNames list only those books, and a TWL
+	// payload holds only the articles they link to. Requested books with no
+	// project in the manifest are reported in a warning. For other subjects
+	// the option is ignored, with a warning.
+	Books []string
+
+	// BagItManifest, if set, writes a BagIt payload manifest,
+	// manifest-md5.txt, to the SB root beside metadata.json, listing the MD5
+	// checksum and key of every ingredient ("<md5>  <key>" per line, sorted by
+	// key), so fixity can be checked with standard tools (e.g., md5sum -c).
+	// It is not itself recorded as an ingredient, since it describes them.
+	// SplitByBook, ConvertMerged, and UpdateMetadata keep an existing
+	// manifest up to date.
+	BagItManifest bool
+
+	// FixedTimestamp, if not zero, is recorded as meta.dateCreated and the
+	// identification timestamp in place of the time of conversion, so that
+	// converting the same RC twice gives byte-identical output.
+	FixedTimestamp time.Time
+
+	// Resume, if set, resumes an interrupted conversion to an output
+	// directory: a file already in it with the content the conversion would
+	// write is left as it is, and only missing or changed files are
+	// written. Every input file is still read, so the metadata is the same
+	// as that of a fresh conversion. Files left as they were are listed in
+	// Result.Reused. It has no effect on ConvertToZip.
+	Resume bool
+
+	// TranscodeLatin1, if set, converts text files (Markdown, TSV, USFM, and
+	// YAML) that are evidently Latin-1, rather than UTF-8, to UTF-8 as they
+	// are copied, so checksums are of the converted bytes. A file is taken to
+	// be Latin-1 if its first non-ASCII byte does not start a UTF-8 sequence.
+	// Each file transcoded is reported in Result.Warnings. Whether or not it
+	// is set, text files that are not valid UTF-8 are reported in
+	// Result.Warnings with the offset of their first invalid byte.
+	TranscodeLatin1 bool
+
+	// NormalizeUnicode, if set, normalizes text ingredients (Markdown, TSV,
+	// USFM, and plain text) and ingredient keys to Unicode NFC as they are
+	// copied, making meta.normalization's "NFC" true of files edited where
+	// NFD is written (e.g., macOS). Checksums are of the normalized bytes.
+	// The ingredients it changed are listed in Result.Normalized.
+	NormalizeUnicode bool
+
+	// NoProvenance, if set, omits meta.x-provenance, which otherwise records
+	// the git commit, branch, tag, and origin of an input directory that is
+	// a git work tree, with the rc2sb version and the payload and USFM files
+	// used, so that a published SB can be traced to its source.
+	NoProvenance bool
+
+	// SourceCommit and SourceRef, if set, are recorded in meta.x-provenance
+	// as the commit hash and branch the RC was converted from, in place of
+	// those read from the input directory's .git, e.g., in a CI checkout
+	// without one or for ConvertFS. A SourceRef of "refs/tags/<name>" is
+	// recorded as a tag instead.
+	SourceCommit string
+	SourceRef    string
+
+	// LanguageOverrides corrects the SB language entries, keyed by language
+	// tag (e.g., "hi", in any case), where the built-in table is wrong or has
+	// no entry for the language. See LanguageOverride.
+	LanguageOverrides map[string]LanguageOverride
+
+	// Abbreviations maps locales to abbreviations of the resource (e.g.,
+	// {"hi": "इ.रि.सं."}), merged over identification.abbreviation, whose
+	// "en" entry is derived from the RC identifier (e.g., "IRV").
+	Abbreviations map[string]string
+
+	// DeriveAbbreviation, if set, takes an abbreviation in the resource's
+	// language from the start of a manifest title like "IRV - Indian Revised
+	// Version" or "इ.रि.सं. - इंडियन रिवाइज्ड संस्करण" and adds it to
+	// identification.abbreviation under the manifest language. It is not
+	// done for English resources, and Abbreviations takes precedence.
+	DeriveAbbreviation bool
+
+	// IDAuthority, if its Key is set, replaces the default Door43 ID
+	// authority ("uWBurritos" for unfoldingWord resources, "BurritoTruck" for
+	// OBS) in idAuthorities and identification.primary, so that an
+	// organization can publish burritos under its own authority.
+	// See IDAuthority.
+	IDAuthority IDAuthority
+
+	// Fetcher fetches the repository for ConvertGit. If nil, GitCLI is used
+	// without authentication.
+	Fetcher Fetcher
+
+	// Logger, if set, receives progress and diagnostics: the major conversion
+	// steps (manifest loaded, payload detected, ingredients copied) and each
+	// file written at debug level, excluded files at info level, and warnings
+	// at warn level.
+	// If nil, nothing is logged.
+	Logger *slog.Logger
+}
+
+// LanguageOverride holds corrections to an SB language entry. Each field
+// that is set replaces the one derived from the manifest and language tag.
+type LanguageOverride struct {
+	// Name maps locales to names of the language (e.g., {"en": "Hindi"}).
+	Name map[string]string
+
+	// Script is the ISO 15924 script code (e.g., "Deva").
+	Script string
+
+	// ScriptDirection is "ltr" or "rtl".
+	ScriptDirection string
+
+	// Region is the region code (e.g., "IN").
+	Region string
+
+	// NumberingSystem is the CLDR numbering system (e.g., "deva").
+	NumberingSystem string
+}
+
+// IDAuthority is an SB ID authority.
+type IDAuthority struct {
+	// Key is the name the authority is recorded under in idAuthorities and
+	// identification.primary (e.g., "myOrg").
+	Key string
+
+	// URL is the authority's ID (e.g., "https://git.example.org/myOrg").
+	// It is required when Key is set.
+	URL string
+
+	// Name maps locales to names of the authority (e.g., {"en": "My Org"}).
+	// If empty, Key is used as the English name.
+	Name map[string]string
 }
 
 // Result holds information about a completed conversion.
 type Result struct {
-	// Subject is the RC subject that was converted.
+	// Subject is the RC subject that was converted: SubjectOverride if set,
+	// otherwise the manifest's subject.
 	Subject string
 
 	// Identifier is the RC identifier (e.g., "obs", "ult", "tn").
 	Identifier string
 
 	// InDir is the input RC directory that was converted.
+	// It is empty for conversions from an fs.FS via ConvertFS.
 	InDir string
 
 	// OutDir is the output SB directory that was created.
+	// It is empty for conversions streamed to a zip via ConvertToZip.
 	OutDir string
 
+	// Commit is the git commit that was converted by ConvertGit.
+	// It is empty for other conversions.
+	Commit string
+
 	// Ingredients is the number of ingredient files in the SB output.
 	Ingredients int
+
+	// IngredientKeys lists the keys of the ingredients in the SB output,
+	// sorted, and TotalBytes is the sum of their sizes. They are taken from
+	// metadata.json, and are empty for ConvertSBToRC.
+	IngredientKeys []string
+	TotalBytes     int64
+
+	// ContentIngredients, PayloadIngredients, and RootIngredients count the
+	// ingredients by kind: content beneath ingredients/ (including
+	// ingredients/LICENSE.md), a TWL payload beneath ingredients/payload/,
+	// and files recorded at the SB root by ExtraRootFiles and ExtraRootDirs.
+	ContentIngredients int
+	PayloadIngredients int
+	RootIngredients    int
+
+	// Duration is how long the conversion took.
+	Duration time.Duration
+
+	// Written lists every file written to the SB output, sorted, as
+	// slash-separated paths relative to OutDir (or the zip root): the
+	// ingredients, metadata.json, and root files such as README.md and
+	// LICENSE.md. Files Resume left as they were are listed in Reused
+	// instead. It is empty for ConvertSBToRC.
+	Written []string
+
+	// Excluded lists the ingredient keys of files left out of the SB output
+	// by IncludeGlobs and ExcludeGlobs.
+	Excluded []string
+
+	// Reused lists the files, as slash-separated paths relative to OutDir,
+	// that Resume found up to date and left unwritten.
+	Reused []string
+
+	// Normalized lists the ingredient keys of files whose content or key was
+	// changed by NormalizeUnicode.
+	Normalized []string
+
+	// Warnings lists non-fatal problems found during conversion, such as a
+	// declared license with no matching embedded default LICENSE.md.
+	Warnings []string
+
+	// Substitutions lists what was put in place of content missing from the
+	// RC, such as an embedded default LICENSE.md, and Skips what was left
+	// out, such as a project whose file is missing, in the order they were
+	// made. Unlike Warnings, they have a Kind for automation to act on.
+	Substitutions []Substitution
+	Skips         []Skip
 }
+
+// Substitution is something a conversion put in place of content missing
+// from the RC; see handler.Substitution.
+type Substitution = handler.Substitution
+
+// Skip is something a conversion left out; see handler.Skip.
+type Skip = handler.Skip
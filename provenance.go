@@ -0,0 +1,181 @@
+package rc2sb
+
+import (
+	"bufio"
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// readProvenance returns the provenance of a conversion of the RC repository
+// at inDir with opts: its git commit, branch, tag, and origin, read from the
+// .git directory without running git unless given by opts.SourceCommit and
+// opts.SourceRef, and the options that shaped the SB. It returns nil if
+// inDir is not the root of a git work tree and opts gives neither. An inDir
+// of "" has no work tree, e.g., for ConvertFS.
+func readProvenance(inDir string, opts Options) *sb.Provenance {
+	var gitDir, commonDir string
+	if inDir != "" {
+		gitDir, commonDir = findGitDir(inDir)
+	}
+	var head []byte
+	if gitDir != "" {
+		head, _ = os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	}
+	if head == nil && opts.SourceCommit == "" && opts.SourceRef == "" {
+		return nil
+	}
+
+	p := &sb.Provenance{Version: sb.ReadBuildInfo().Version}
+	var refs map[string]string
+	if head != nil {
+		refs = readRefs(commonDir)
+		if ref, ok := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: "); ok {
+			p.Branch = strings.TrimPrefix(ref, "refs/heads/")
+			p.Commit = refs[ref]
+		} else {
+			p.Commit = strings.TrimSpace(string(head))
+		}
+		p.Origin = originURL(commonDir)
+	}
+	if opts.SourceCommit != "" {
+		p.Commit = opts.SourceCommit
+	}
+	if ref := opts.SourceRef; ref != "" {
+		if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+			p.Branch, p.Tag = "", tag
+		} else {
+			p.Branch = strings.TrimPrefix(ref, "refs/heads/")
+		}
+	}
+	if p.Tag == "" && p.Commit != "" {
+		var tags []string
+		for ref, commit := range refs {
+			if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok && commit == p.Commit {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) > 0 {
+			p.Tag = slices.Max(tags)
+		}
+	}
+	if opts.PayloadPath != "" {
+		p.Payload = filepath.Base(opts.PayloadPath)
+	}
+	if opts.USFMPath != "" {
+		p.USFM = filepath.Base(opts.USFMPath)
+	}
+	return p
+}
+
+// findGitDir returns the git directory of the work tree rooted at dir and
+// the directory holding its refs and config, which differ for a linked work
+// tree, or "" if dir has no .git.
+func findGitDir(dir string) (gitDir, commonDir string) {
+	gitDir = filepath.Join(dir, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return "", ""
+	}
+	if !info.IsDir() {
+		// A linked work tree or submodule: "gitdir: <path>"
+		data, err := os.ReadFile(gitDir)
+		if err != nil {
+			return "", ""
+		}
+		path, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+		if !ok {
+			return "", ""
+		}
+		gitDir = resolvePath(dir, path)
+	}
+	commonDir = gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = resolvePath(gitDir, strings.TrimSpace(string(data)))
+	}
+	return gitDir, commonDir
+}
+
+// resolvePath returns path, resolved against dir if it is relative.
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// readRefs returns the commit hashes of the branches and tags in the git
+// directory dir, by full ref name (e.g., "refs/heads/master"), from
+// packed-refs and then loose ref files. Annotated tags are peeled to their
+// commits where packed-refs records it.
+func readRefs(dir string) map[string]string {
+	refs := make(map[string]string)
+	if data, err := os.ReadFile(filepath.Join(dir, "packed-refs")); err == nil {
+		var last string
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if peeled, ok := strings.CutPrefix(line, "^"); ok && last != "" {
+				refs[last] = peeled
+				continue
+			}
+			hash, ref, ok := strings.Cut(line, " ")
+			if !ok || strings.HasPrefix(line, "#") {
+				continue
+			}
+			refs[ref] = hash
+			last = ref
+		}
+	}
+	for _, kind := range []string{"heads", "tags"} {
+		root := filepath.Join(dir, "refs", kind)
+		filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(dir, path)
+			refs[filepath.ToSlash(rel)] = strings.TrimSpace(string(data))
+			return nil
+		})
+	}
+	return refs
+}
+
+// originURL returns the URL of the origin remote in the config of the git
+// directory dir, with any user name and password removed, or "" if it has
+// none.
+func originURL(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "config"))
+	if err != nil {
+		return ""
+	}
+	inOrigin := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inOrigin = line == `[remote "origin"]`
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inOrigin || !ok || strings.TrimSpace(key) != "url" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			u.User = nil
+			value = u.String()
+		}
+		return value
+	}
+	return ""
+}
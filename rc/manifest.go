@@ -2,9 +2,15 @@
 package rc
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,6 +20,10 @@ type Manifest struct {
 	DublinCore DublinCore `yaml:"dublin_core"`
 	Checking   Checking   `yaml:"checking"`
 	Projects   []Project  `yaml:"projects"`
+
+	// projectLines holds the line of each project in the manifest.yaml it
+	// was decoded from, or is nil if it was not decoded.
+	projectLines []int
 }
 
 // DublinCore holds the dublin_core metadata from the RC manifest.
@@ -69,19 +79,220 @@ type Project struct {
 
 // LoadManifest reads and parses a manifest.yaml file from the given directory.
 func LoadManifest(dir string) (*Manifest, error) {
-	path := filepath.Join(dir, "manifest.yaml")
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("not a valid Resource Container: manifest.yaml not found in %s", dir)
-		}
-		return nil, fmt.Errorf("reading manifest.yaml: %w", err)
+	return loadManifest(os.DirFS(dir), dir)
+}
+
+// LoadManifestFS reads and parses the manifest.yaml file at the root of fsys.
+func LoadManifestFS(fsys fs.FS) (*Manifest, error) {
+	return loadManifest(fsys, "the input file system")
+}
+
+// ManifestError reports that an RC's manifest.yaml is missing or could not be
+// read or parsed. Err wraps fs.ErrNotExist if the file is missing.
+type ManifestError struct {
+	// Op is the operation that failed: "reading", "parsing", or
+	// "validating".
+	Op string
+
+	// Location describes where manifest.yaml was looked for.
+	Location string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ManifestError) Error() string {
+	if e.Op == "reading" && errors.Is(e.Err, fs.ErrNotExist) {
+		return "not a valid Resource Container: manifest.yaml not found in " + e.Location
 	}
+	return e.Op + " manifest.yaml: " + e.Err.Error()
+}
+
+func (e *ManifestError) Unwrap() error {
+	return e.Err
+}
 
+// DecodeManifest parses a manifest.yaml read from r (e.g., an HTTP response
+// body or a zip entry). An empty manifest.yaml gives an empty Manifest.
+// Errors are *ManifestError values.
+func DecodeManifest(r io.Reader) (*Manifest, error) {
 	var m Manifest
-	if err := yaml.Unmarshal(data, &m); err != nil {
-		return nil, fmt.Errorf("parsing manifest.yaml: %w", err)
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return &m, nil
+		}
+		return nil, &ManifestError{Op: "parsing", Err: err}
 	}
-
+	if err := doc.Decode(&m); err != nil {
+		return nil, &ManifestError{Op: "parsing", Err: err}
+	}
+	m.projectLines = projectLines(&doc)
 	return &m, nil
 }
+
+// projectLines returns the line of each entry of the projects sequence of
+// the manifest.yaml document doc.
+func projectLines(doc *yaml.Node) []int {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "projects" || root.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		var lines []int
+		for _, project := range root.Content[i+1].Content {
+			lines = append(lines, project.Line)
+		}
+		return lines
+	}
+	return nil
+}
+
+// DuplicateProject is a project of a manifest whose identifier or path
+// repeats that of an earlier project, usually by a copy-paste error.
+type DuplicateProject struct {
+	// Field is the field that repeats: "identifier" or "path".
+	Field string
+
+	// Value is the repeated value.
+	Value string
+
+	// Index is the index of the project in Manifest.Projects, and First
+	// that of the earlier project.
+	Index, First int
+
+	// Line and FirstLine are the lines of the projects in manifest.yaml, or
+	// 0 if the manifest was not decoded from one.
+	Line, FirstLine int
+}
+
+// String describes the duplicate, e.g., "project 2 (line 25) repeats the
+// identifier "gen" of project 1 (line 20)", numbering projects from 1.
+func (d DuplicateProject) String() string {
+	where := func(index, line int) string {
+		if line > 0 {
+			return fmt.Sprintf("project %d (line %d)", index+1, line)
+		}
+		return fmt.Sprintf("project %d", index+1)
+	}
+	return fmt.Sprintf("%s repeats the %s %q of %s", where(d.Index, d.Line), d.Field, d.Value, where(d.First, d.FirstLine))
+}
+
+// DuplicateProjects returns the projects of m whose identifier (in any case)
+// or path (ignoring a leading "./") repeats that of an earlier project, in
+// order. A project that repeats both is returned once, for its identifier.
+func (m *Manifest) DuplicateProjects() []DuplicateProject {
+	line := func(i int) int {
+		if i < len(m.projectLines) {
+			return m.projectLines[i]
+		}
+		return 0
+	}
+	var dups []DuplicateProject
+	identifiers := make(map[string]int)
+	paths := make(map[string]int)
+	for i, project := range m.Projects {
+		id := strings.ToLower(project.Identifier)
+		projectPath := path.Clean(strings.TrimPrefix(project.Path, "./"))
+		if first, ok := identifiers[id]; ok && id != "" {
+			dups = append(dups, DuplicateProject{Field: "identifier", Value: project.Identifier, Index: i, First: first, Line: line(i), FirstLine: line(first)})
+		} else if first, ok := paths[projectPath]; ok && project.Path != "" {
+			dups = append(dups, DuplicateProject{Field: "path", Value: project.Path, Index: i, First: first, Line: line(i), FirstLine: line(first)})
+		}
+		if _, ok := identifiers[id]; !ok {
+			identifiers[id] = i
+		}
+		if _, ok := paths[projectPath]; !ok {
+			paths[projectPath] = i
+		}
+	}
+	return dups
+}
+
+// Validate reports a *ManifestError if two projects of m have the same
+// identifier or path (see DuplicateProjects), which would make one
+// project's ingredient overwrite the other's. Handlers may assume a valid
+// manifest's projects are unique.
+func (m *Manifest) Validate() error {
+	dups := m.DuplicateProjects()
+	if len(dups) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(dups))
+	for i, d := range dups {
+		msgs[i] = d.String()
+	}
+	return &ManifestError{Op: "validating", Err: errors.New(strings.Join(msgs, "; "))}
+}
+
+// RemoveDuplicateProjects removes from m the projects DuplicateProjects
+// returns, keeping the first of each, and returns them.
+func (m *Manifest) RemoveDuplicateProjects() []DuplicateProject {
+	dups := m.DuplicateProjects()
+	if len(dups) == 0 {
+		return nil
+	}
+	drop := make(map[int]bool)
+	for _, d := range dups {
+		drop[d.Index] = true
+	}
+	var projects []Project
+	var lines []int
+	for i, project := range m.Projects {
+		if drop[i] {
+			continue
+		}
+		projects = append(projects, project)
+		if i < len(m.projectLines) {
+			lines = append(lines, m.projectLines[i])
+		}
+	}
+	m.Projects, m.projectLines = projects, lines
+	return dups
+}
+
+// loadManifest reads manifest.yaml from fsys. The location describes fsys in
+// error messages.
+func loadManifest(fsys fs.FS, location string) (*Manifest, error) {
+	f, err := fsys.Open("manifest.yaml")
+	if err != nil {
+		return nil, &ManifestError{Op: "reading", Location: location, Err: err}
+	}
+	defer f.Close()
+
+	m, err := DecodeManifest(f)
+	var manifestErr *ManifestError
+	if errors.As(err, &manifestErr) {
+		manifestErr.Location = location
+	}
+	return m, err
+}
+
+// Marshal serializes the manifest as YAML, exactly as written to manifest.yaml.
+func (m *Manifest) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(m); err != nil {
+		return nil, fmt.Errorf("marshaling manifest.yaml: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("marshaling manifest.yaml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteToFile serializes the manifest as YAML and writes it to manifest.yaml in dir.
+func (m *Manifest) WriteToFile(dir string) error {
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), data, 0644); err != nil {
+		return fmt.Errorf("writing manifest.yaml: %w", err)
+	}
+	return nil
+}
@@ -1,8 +1,12 @@
 package rc_test
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/unfoldingWord/go-rc2sb/rc"
@@ -17,6 +21,13 @@ func TestLoadManifest_MissingFile(t *testing.T) {
 	if got := err.Error(); got == "" {
 		t.Error("error message should not be empty")
 	}
+	var manifestErr *rc.ManifestError
+	if !errors.As(err, &manifestErr) || manifestErr.Op != "reading" {
+		t.Errorf("error = %#v; want a *rc.ManifestError for reading", err)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("error = %v; want it to wrap fs.ErrNotExist", err)
+	}
 }
 
 func TestLoadManifest_InvalidYAML(t *testing.T) {
@@ -29,6 +40,10 @@ func TestLoadManifest_InvalidYAML(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for invalid YAML")
 	}
+	var manifestErr *rc.ManifestError
+	if !errors.As(err, &manifestErr) || manifestErr.Op != "parsing" {
+		t.Errorf("error = %#v; want a *rc.ManifestError for parsing", err)
+	}
 }
 
 func TestLoadManifest_Valid(t *testing.T) {
@@ -83,3 +98,129 @@ projects:
 		t.Errorf("Projects count = %d; want 1", len(m.Projects))
 	}
 }
+
+func TestManifest_WriteToFile(t *testing.T) {
+	dir := t.TempDir()
+	want := &rc.Manifest{
+		DublinCore: rc.DublinCore{
+			Subject:    "TSV Translation Notes",
+			Identifier: "tn",
+			Language:   rc.Language{Identifier: "hi", Title: "हिन्दी", Direction: "ltr"},
+		},
+		Projects: []rc.Project{{Categories: []string{"bible-ot"}, Identifier: "gen", Path: "./tn_GEN.tsv", Sort: 1}},
+	}
+	if err := want.WriteToFile(dir); err != nil {
+		t.Fatalf("WriteToFile failed: %v", err)
+	}
+	got, err := rc.LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	if !reflect.DeepEqual(got.DublinCore.Language, want.DublinCore.Language) || got.DublinCore.Subject != want.DublinCore.Subject ||
+		got.DublinCore.Identifier != want.DublinCore.Identifier || !reflect.DeepEqual(got.Projects, want.Projects) {
+		t.Errorf("LoadManifest after WriteToFile = %+v; want %+v", got, want)
+	}
+}
+
+func TestDecodeManifest(t *testing.T) {
+	m, err := rc.DecodeManifest(strings.NewReader(`dublin_core:
+  identifier: 'tn'
+  subject: 'TSV Translation Notes'
+projects:
+  - identifier: 'gen'
+    path: './tn_GEN.tsv'
+  - identifier: 'exo'
+    path: './tn_EXO.tsv'
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.DublinCore.Subject != "TSV Translation Notes" || m.DublinCore.Identifier != "tn" {
+		t.Errorf("dublin_core = %+v", m.DublinCore)
+	}
+	if len(m.Projects) != 2 || m.Projects[1].Path != "./tn_EXO.tsv" {
+		t.Errorf("projects = %+v", m.Projects)
+	}
+
+	if m, err := rc.DecodeManifest(strings.NewReader("")); err != nil || m.DublinCore.Subject != "" {
+		t.Errorf("DecodeManifest(empty) = %+v, %v; want an empty manifest", m, err)
+	}
+
+	_, err = rc.DecodeManifest(strings.NewReader("{\t\tinvalid:\n\t[broken"))
+	var manifestErr *rc.ManifestError
+	if !errors.As(err, &manifestErr) || manifestErr.Op != "parsing" {
+		t.Errorf("error = %#v; want a *rc.ManifestError for parsing", err)
+	}
+}
+
+func TestManifest_DuplicateProjects(t *testing.T) {
+	const header = `dublin_core:
+  identifier: 'ult'
+  subject: 'Aligned Bible'
+projects:
+`
+	tests := []struct {
+		name     string
+		projects string
+		want     rc.DuplicateProject
+		wantMsg  string
+	}{
+		{
+			name: "identifier",
+			projects: `  - identifier: 'gen'
+    path: './01-GEN.usfm'
+  - identifier: 'exo'
+    path: './02-EXO.usfm'
+  - identifier: 'GEN'
+    path: './02-GEN.usfm'
+`,
+			want:    rc.DuplicateProject{Field: "identifier", Value: "GEN", Index: 2, First: 0, Line: 9, FirstLine: 5},
+			wantMsg: `project 3 (line 9) repeats the identifier "GEN" of project 1 (line 5)`,
+		},
+		{
+			name: "path",
+			projects: `  - identifier: 'gen'
+    path: './01-GEN.usfm'
+  - identifier: 'exo'
+    path: '01-GEN.usfm'
+`,
+			want:    rc.DuplicateProject{Field: "path", Value: "01-GEN.usfm", Index: 1, First: 0, Line: 7, FirstLine: 5},
+			wantMsg: `project 2 (line 7) repeats the path "01-GEN.usfm" of project 1 (line 5)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rc.DecodeManifest(strings.NewReader(header + tt.projects))
+			if err != nil {
+				t.Fatalf("DecodeManifest failed: %v", err)
+			}
+			dups := m.DuplicateProjects()
+			if len(dups) != 1 || dups[0] != tt.want {
+				t.Fatalf("DuplicateProjects() = %+v; want %+v", dups, tt.want)
+			}
+			if dups[0].String() != tt.wantMsg {
+				t.Errorf("String() = %q; want %q", dups[0].String(), tt.wantMsg)
+			}
+
+			err = m.Validate()
+			var manifestErr *rc.ManifestError
+			if !errors.As(err, &manifestErr) || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Validate() = %v; want a *ManifestError with %q", err, tt.wantMsg)
+			}
+
+			n := len(m.Projects)
+			if removed := m.RemoveDuplicateProjects(); len(removed) != 1 || len(m.Projects) != n-1 || m.Projects[0].Identifier != "gen" {
+				t.Errorf("RemoveDuplicateProjects() = %+v, leaving %+v", removed, m.Projects)
+			}
+			if err := m.Validate(); err != nil {
+				t.Errorf("Validate() after RemoveDuplicateProjects = %v", err)
+			}
+		})
+	}
+
+	// Without manifest.yaml, projects are numbered without lines
+	m := &rc.Manifest{Projects: []rc.Project{{Identifier: "gen"}, {Identifier: "gen"}}}
+	if dups := m.DuplicateProjects(); len(dups) != 1 || dups[0].String() != `project 2 repeats the identifier "gen" of project 1` {
+		t.Errorf("DuplicateProjects() = %v", dups)
+	}
+}
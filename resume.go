@@ -0,0 +1,119 @@
+package rc2sb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/unfoldingWord/go-rc2sb/handler"
+)
+
+// resumeOutput is an Output for Options.Resume that writes files beneath dir
+// on disk like handler.DirOutput, but leaves a file that already has the
+// content being written untouched, appending its name to reused. Content is
+// compared as it is written, and the file is only written from the first
+// byte that differs, so a file that is up to date is read but never written.
+type resumeOutput struct {
+	dir    string
+	reused *[]string
+}
+
+func (o resumeOutput) Create(name string) (io.WriteCloser, error) {
+	path := filepath.Join(o.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating directory for %s: %w", path, err)
+	}
+	_, err := os.Stat(path)
+	existed := err == nil
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("creating destination %s: %w", path, err)
+	}
+	return &resumeWriter{f: f, name: name, existed: existed, out: o}, nil
+}
+
+// resumeWriter writes a file for resumeOutput. Until the content written
+// differs from the file's, it only reads; from then on, it writes.
+type resumeWriter struct {
+	f        *os.File
+	name     string
+	existed  bool
+	out      resumeOutput
+	off      int64
+	diverged bool
+	buf      []byte
+}
+
+func (w *resumeWriter) Write(p []byte) (int, error) {
+	written := 0
+	if !w.diverged {
+		if cap(w.buf) < len(p) {
+			w.buf = make([]byte, len(p))
+		}
+		n, err := io.ReadFull(w.f, w.buf[:len(p)])
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, err
+		}
+		same := commonPrefix(p, w.buf[:n])
+		w.off += int64(same)
+		if same == len(p) {
+			return len(p), nil
+		}
+		w.diverged = true
+		if _, err := w.f.Seek(w.off, io.SeekStart); err != nil {
+			return same, err
+		}
+		written, p = same, p[same:]
+	}
+	n, err := w.f.Write(p)
+	w.off += int64(n)
+	return written + n, err
+}
+
+// commonPrefix returns the length of the longest common prefix of a and b.
+func commonPrefix(a, b []byte) int {
+	n := min(len(a), len(b))
+	for i := range n {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// Close truncates any content of the file beyond what was written and
+// closes it, recording it as reused if it was not changed.
+func (w *resumeWriter) Close() error {
+	if w.f == nil {
+		return nil
+	}
+	f := w.f
+	w.f = nil
+	if !w.diverged {
+		// A longer file also differs
+		if n, _ := f.Read(make([]byte, 1)); n > 0 {
+			w.diverged = true
+		}
+	}
+	if w.diverged {
+		if err := f.Truncate(w.off); err != nil {
+			f.Close()
+			return err
+		}
+	} else if w.existed {
+		*w.out.reused = append(*w.out.reused, w.name)
+	}
+	return f.Close()
+}
+
+// newDirOutput returns the output for a conversion to outDir on disk:
+// a resumeOutput recording reused files in reused if opts.Resume is set,
+// otherwise handler.DirOutput.
+func newDirOutput(outDir string, opts Options, reused *[]string) handler.Output {
+	if opts.Resume {
+		return resumeOutput{dir: outDir, reused: reused}
+	}
+	return handler.DirOutput(outDir)
+}
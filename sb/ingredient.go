@@ -1,8 +1,11 @@
 package sb
 
 import (
+	"bytes"
 	"crypto/md5"
+	"crypto/sha256"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
@@ -30,6 +33,17 @@ func MIMETypeForExt(ext string) string {
 	}
 }
 
+// IsTabSeparated reports whether content, the start of a file, looks like
+// tab-separated values: its first line has a tab.
+func IsTabSeparated(content []byte) bool {
+	line, _, _ := bytes.Cut(content, []byte("\n"))
+	return bytes.IndexByte(line, '\t') >= 0
+}
+
+// sniffLen is how much of a .txt file IngredientWriter keeps to tell whether
+// it is tab-separated.
+const sniffLen = 4096
+
 // ComputeIngredient computes the Ingredient (MD5 checksum, size, MIME type) for a file.
 func ComputeIngredient(filePath string) (Ingredient, error) {
 	f, err := os.Open(filePath)
@@ -38,21 +52,86 @@ func ComputeIngredient(filePath string) (Ingredient, error) {
 	}
 	defer f.Close()
 
-	h := md5.New()
-	size, err := io.Copy(h, f)
+	ing, err := ComputeIngredientFromReader(f, filePath)
 	if err != nil {
 		return Ingredient{}, fmt.Errorf("reading file %s: %w", filePath, err)
 	}
+	return ing, nil
+}
 
-	ext := filepath.Ext(filePath)
+// ComputeIngredientFromReader computes the Ingredient for content read from r
+// until EOF. The MIME type is determined as by NewIngredientWriter.
+func ComputeIngredientFromReader(r io.Reader, name string) (Ingredient, error) {
+	w := NewIngredientWriter(name)
+	if _, err := io.Copy(w, r); err != nil {
+		return Ingredient{}, err
+	}
+	return w.Ingredient(), nil
+}
+
+// IngredientWriter computes an Ingredient from the bytes written to it,
+// so that content can be checksummed while it is being written elsewhere
+// (e.g., through an io.MultiWriter).
+type IngredientWriter struct {
+	name   string
+	hash   hash.Hash
+	sha256 hash.Hash // nil unless WithSHA256 was called
+	size   int64
+
+	// head is the start of the content of a .txt file, to sniff
+	head []byte
+}
+
+// NewIngredientWriter returns an IngredientWriter for content that will be
+// stored as name. The MIME type is determined from the extension of name,
+// except that a .txt file whose first line has a tab (e.g., tn_GEN.txt) is
+// text/tab-separated-values.
+func NewIngredientWriter(name string) *IngredientWriter {
+	return &IngredientWriter{name: name, hash: md5.New()}
+}
 
-	return Ingredient{
+// WithSHA256 makes w compute a SHA-256 checksum alongside the MD5, in the
+// same pass, and returns w. It must be called before anything is written.
+func (w *IngredientWriter) WithSHA256() *IngredientWriter {
+	w.sha256 = sha256.New()
+	return w
+}
+
+// Write adds p to the checksum and size. It never returns an error.
+func (w *IngredientWriter) Write(p []byte) (int, error) {
+	w.hash.Write(p)
+	if w.sha256 != nil {
+		w.sha256.Write(p)
+	}
+	w.size += int64(len(p))
+	if n := sniffLen - len(w.head); n > 0 && strings.EqualFold(filepath.Ext(w.name), ".txt") {
+		w.head = append(w.head, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// Ingredient returns the Ingredient for the bytes written so far.
+func (w *IngredientWriter) Ingredient() Ingredient {
+	ing := Ingredient{
 		Checksum: Checksum{
-			MD5: fmt.Sprintf("%x", h.Sum(nil)),
+			MD5: fmt.Sprintf("%x", w.hash.Sum(nil)),
 		},
-		MimeType: MIMETypeForExt(ext),
-		Size:     size,
-	}, nil
+		MimeType: w.mimeType(),
+		Size:     w.size,
+	}
+	if w.sha256 != nil {
+		ing.Checksum.SHA256 = fmt.Sprintf("%x", w.sha256.Sum(nil))
+	}
+	return ing
+}
+
+// mimeType returns the MIME type of the content written so far.
+func (w *IngredientWriter) mimeType() string {
+	ext := filepath.Ext(w.name)
+	if strings.EqualFold(ext, ".txt") && IsTabSeparated(w.head) {
+		return "text/tab-separated-values"
+	}
+	return MIMETypeForExt(ext)
 }
 
 // ComputeIngredientWithScope computes the Ingredient and attaches the given scope.
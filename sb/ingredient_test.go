@@ -1,6 +1,9 @@
 package sb_test
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -85,3 +88,46 @@ func TestComputeIngredientWithScope(t *testing.T) {
 		t.Error("Scope should contain GEN")
 	}
 }
+
+func TestIngredientWriter_SniffsTabSeparatedTxt(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"tn_GEN.txt", "Reference\tID\tNote\n1:1\tabcd\tNote\n", "text/tab-separated-values"},
+		{"NOTES.TXT", "Reference\tID\n", "text/tab-separated-values"},
+		{"LICENSE.txt", "License\nwith\ta tab on a later line\n", "text/plain"},
+		{"GEN.md", "Reference\tID\n", "text/markdown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sb.NewIngredientWriter(tt.name)
+			// Write a byte at a time to check the content is sniffed across writes
+			for i := range len(tt.content) {
+				w.Write([]byte{tt.content[i]})
+			}
+			if got := w.Ingredient().MimeType; got != tt.want {
+				t.Errorf("MimeType = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngredientWriter_WithSHA256(t *testing.T) {
+	content := []byte("# Genesis\n")
+	w := sb.NewIngredientWriter("GEN.md")
+	w.Write(content)
+	if got := w.Ingredient().Checksum.SHA256; got != "" {
+		t.Errorf("SHA256 = %q without WithSHA256; want none", got)
+	}
+
+	w = sb.NewIngredientWriter("GEN.md").WithSHA256()
+	w.Write(content[:3])
+	w.Write(content[3:])
+	got := w.Ingredient().Checksum
+	if want := fmt.Sprintf("%x", sha256.Sum256(content)); got.SHA256 != want {
+		t.Errorf("SHA256 = %q; want %q", got.SHA256, want)
+	}
+	if want := fmt.Sprintf("%x", md5.Sum(content)); got.MD5 != want {
+		t.Errorf("MD5 = %q; want %q", got.MD5, want)
+	}
+}
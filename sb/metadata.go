@@ -4,8 +4,12 @@ package sb
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 )
 
 // Metadata represents the top-level structure of an SB metadata.json file.
@@ -20,6 +24,10 @@ type Metadata struct {
 	LocalizedNames map[string]LocalizedName   `json:"localizedNames,omitempty"`
 	Ingredients    map[string]Ingredient      `json:"ingredients"`
 	Copyright      Copyright                  `json:"copyright"`
+
+	// ingredientSources maps each claimed ingredient key to the source it was
+	// produced from. It is used to detect collisions and is not serialized.
+	ingredientSources map[string]string
 }
 
 // Meta holds the meta section of an SB metadata file.
@@ -30,6 +38,35 @@ type Meta struct {
 	DefaultLocale string    `json:"defaultLocale"`
 	DateCreated   string    `json:"dateCreated"`
 	Normalization string    `json:"normalization"`
+
+	// Provenance records where the SB was converted from, if known. SB has
+	// no field for this, so it is an extension.
+	Provenance *Provenance `json:"x-provenance,omitempty"`
+}
+
+// Provenance records the source of a converted SB: the git commit of the RC
+// repository and how it was converted.
+type Provenance struct {
+	// Commit is the hash of the commit the RC work tree had checked out.
+	Commit string `json:"commit,omitempty"`
+
+	// Branch is the branch checked out, if any (e.g., "master").
+	Branch string `json:"branch,omitempty"`
+
+	// Tag is a tag pointing at Commit, if any (e.g., "v80").
+	Tag string `json:"tag,omitempty"`
+
+	// Origin is the URL of the repository's origin remote, without any
+	// credentials (e.g., "https://git.door43.org/unfoldingWord/en_tn").
+	Origin string `json:"origin,omitempty"`
+
+	// Version is the version of go-rc2sb that converted the SB.
+	Version string `json:"rc2sbVersion"`
+
+	// Payload and USFM are the base names of the TWL payload and USFM
+	// directories used, if any (e.g., "en_tw" and "en_ult").
+	Payload string `json:"payload,omitempty"`
+	USFM    string `json:"usfm,omitempty"`
 }
 
 // Generator identifies the software that created the SB.
@@ -63,7 +100,10 @@ type PrimaryEntry struct {
 type LanguageEntry struct {
 	Tag             string            `json:"tag"`
 	Name            map[string]string `json:"name"`
+	Script          string            `json:"script,omitempty"` // ISO 15924 code (e.g., "Hebr"), if known
 	ScriptDirection string            `json:"scriptDirection"`
+	Region          string            `json:"x-region,omitempty"`        // region subtag of Tag (e.g., "BR"), if any
+	NumberingSystem string            `json:"numberingSystem,omitempty"` // CLDR numbering system (e.g., "arab"), if known
 }
 
 // Type holds the type section with flavorType.
@@ -85,6 +125,11 @@ type Flavor struct {
 	TranslationType string `json:"translationType,omitempty"`
 	Audience        string `json:"audience,omitempty"`
 	ProjectType     string `json:"projectType,omitempty"`
+
+	// Aligned marks scripture whose USFM is aligned word by word to the
+	// original language (\zaln-s milestones or \w attributes). SB has no
+	// field for this, so it is an extension.
+	Aligned bool `json:"x-aligned,omitempty"`
 }
 
 // LocalizedName holds localized name entries for a book or resource.
@@ -100,16 +145,30 @@ type Ingredient struct {
 	MimeType string            `json:"mimeType"`
 	Size     int64             `json:"size"`
 	Scope    map[string][]string `json:"scope,omitempty"`
+	Role     string            `json:"role,omitempty"`
+	Source   string            `json:"x-source,omitempty"` // RC-relative source path, if recorded
+
+	// Versification names the versification of a book ingredient (e.g.,
+	// "ufw" or "eng"), if its RC project declares one.
+	Versification string `json:"x-versification,omitempty"`
 }
 
 // Checksum holds the checksum(s) for an ingredient.
 type Checksum struct {
 	MD5 string `json:"md5"`
+
+	// SHA256 is recorded only when asked for (see
+	// IngredientWriter.WithSHA256), since MD5 is what readers expect.
+	SHA256 string `json:"sha256,omitempty"`
 }
 
 // Copyright holds the copyright information.
 type Copyright struct {
 	ShortStatements []CopyrightStatement `json:"shortStatements"`
+
+	// SPDX is the SPDX identifier of the license (e.g., "CC-BY-SA-4.0"),
+	// if it is known. SB has no field for this, so it is an extension.
+	SPDX string `json:"x-spdx,omitempty"`
 }
 
 // CopyrightStatement holds a single copyright statement.
@@ -128,7 +187,7 @@ func NewMetadata() *Metadata {
 			Category:      "source",
 			Generator: Generator{
 				SoftwareName:    "go-rc2sb",
-				SoftwareVersion: "0.0.1",
+				SoftwareVersion: ReadBuildInfo().Version,
 				UserName:        "",
 			},
 			DefaultLocale: "en",
@@ -141,14 +200,134 @@ func NewMetadata() *Metadata {
 	}
 }
 
-// WriteToFile serializes the metadata as JSON and writes it to metadata.json in dir.
-func (m *Metadata) WriteToFile(dir string) error {
+// ClaimIngredient records that the ingredient key is produced from source.
+// It returns an error naming both sources if the key was already claimed by a
+// different source, so that callers can refuse to overwrite an earlier file.
+// Claiming the same key again for the same source is not an error.
+func (m *Metadata) ClaimIngredient(key, source string) error {
+	if m.ingredientSources == nil {
+		m.ingredientSources = make(map[string]string)
+	}
+	if prev, ok := m.ingredientSources[key]; ok && prev != source {
+		return fmt.Errorf("ingredient %s would be produced from both %s and %s", key, prev, source)
+	}
+	m.ingredientSources[key] = source
+	return nil
+}
+
+// WalkIngredients calls fn for each ingredient in sorted key order,
+// stopping at and returning the first error fn returns.
+func (m *Metadata) WalkIngredients(fn func(key string, ing Ingredient) error) error {
+	for _, key := range slices.Sorted(maps.Keys(m.Ingredients)) {
+		if err := fn(key, m.Ingredients[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AggregateScope returns the resource-level scope covered by all of m's
+// ingredients: every book in any ingredient's scope, with the union of the
+// chapters listed for it. A book is listed with no chapters (the whole book)
+// if any ingredient covers it whole. It returns nil if no ingredient has a
+// scope.
+func (m *Metadata) AggregateScope() map[string][]string {
+	var scope map[string][]string
+	m.WalkIngredients(func(_ string, ing Ingredient) error {
+		for book, chapters := range ing.Scope {
+			if scope == nil {
+				scope = make(map[string][]string)
+			}
+			prev, seen := scope[book]
+			switch {
+			case !seen:
+				scope[book] = append([]string{}, chapters...)
+			case len(prev) == 0 || len(chapters) == 0:
+				scope[book] = []string{}
+			default:
+				for _, c := range chapters {
+					if !slices.Contains(prev, c) {
+						prev = append(prev, c)
+					}
+				}
+				scope[book] = prev
+			}
+		}
+		return nil
+	})
+	return scope
+}
+
+// UnscopedBooks returns, sorted, the books in m's currentScope that no
+// ingredient's scope covers, e.g., because the book's file was missing and
+// left out of the conversion. It returns nil if no ingredient has a scope, as
+// for flavors such as OBS whose currentScope is only a convention.
+func (m *Metadata) UnscopedBooks() []string {
+	covered := m.AggregateScope()
+	if covered == nil {
+		return nil
+	}
+	var books []string
+	for book := range m.Type.FlavorType.CurrentScope {
+		if _, ok := covered[book]; !ok {
+			books = append(books, book)
+		}
+	}
+	slices.Sort(books)
+	return books
+}
+
+// MIMEMismatches returns, sorted, the keys of the ingredients of m whose
+// MIME type is not the one MIMETypeForExt gives for the key's extension,
+// e.g., because it was set by hand or overridden by mistake. A .txt
+// ingredient may also be text/tab-separated-values, as IngredientWriter
+// records for tab-separated content.
+func (m *Metadata) MIMEMismatches() []string {
+	var keys []string
+	for key, ing := range m.Ingredients {
+		ext := filepath.Ext(key)
+		if ing.MimeType == MIMETypeForExt(ext) {
+			continue
+		}
+		if strings.EqualFold(ext, ".txt") && ing.MimeType == "text/tab-separated-values" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Marshal serializes the metadata as indented JSON with a trailing newline,
+// exactly as written to metadata.json.
+func (m *Metadata) Marshal() ([]byte, error) {
 	data, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
-		return fmt.Errorf("marshaling metadata.json: %w", err)
+		return nil, fmt.Errorf("marshaling metadata.json: %w", err)
 	}
 	// Add trailing newline
-	data = append(data, '\n')
+	return append(data, '\n'), nil
+}
+
+// WriteTo serializes the metadata as JSON and writes it to w.
+func (m *Metadata) WriteTo(w io.Writer) (int64, error) {
+	data, err := m.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	if err != nil {
+		return int64(n), fmt.Errorf("writing metadata.json: %w", err)
+	}
+	return int64(n), nil
+}
+
+// WriteToFile serializes the metadata as JSON and writes it to metadata.json in dir.
+func (m *Metadata) WriteToFile(dir string) error {
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
 
 	path := filepath.Join(dir, "metadata.json")
 	if err := os.WriteFile(path, data, 0644); err != nil {
@@ -156,3 +335,16 @@ func (m *Metadata) WriteToFile(dir string) error {
 	}
 	return nil
 }
+
+// LoadMetadata reads and parses the metadata.json file in dir.
+func LoadMetadata(dir string) (*Metadata, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return nil, fmt.Errorf("reading metadata.json: %w", err)
+	}
+	m := NewMetadata()
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parsing metadata.json: %w", err)
+	}
+	return m, nil
+}
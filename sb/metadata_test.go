@@ -2,8 +2,11 @@ package sb_test
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/unfoldingWord/go-rc2sb/sb"
@@ -99,3 +102,144 @@ func TestMetadata_JSONRoundTrip(t *testing.T) {
 		t.Errorf("ingredient size = %d; want 1234", ing.Size)
 	}
 }
+
+func TestMetadata_ClaimIngredient(t *testing.T) {
+	m := sb.NewMetadata()
+
+	if err := m.ClaimIngredient("ingredients/GEN.tsv", "rc/tn_GEN.tsv"); err != nil {
+		t.Fatalf("first claim failed: %v", err)
+	}
+	if err := m.ClaimIngredient("ingredients/GEN.tsv", "rc/tn_GEN.tsv"); err != nil {
+		t.Errorf("re-claiming with the same source should succeed: %v", err)
+	}
+
+	err := m.ClaimIngredient("ingredients/GEN.tsv", "rc/GEN.tsv")
+	if err == nil {
+		t.Fatal("expected error when claiming a key from a different source")
+	}
+	for _, want := range []string{"ingredients/GEN.tsv", "rc/tn_GEN.tsv", "rc/GEN.tsv"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should mention %q", err, want)
+		}
+	}
+}
+
+func TestMetadata_WalkIngredients(t *testing.T) {
+	m := sb.NewMetadata()
+	for _, key := range []string{"ingredients/REV.tsv", "ingredients/GEN.tsv", "LICENSE.md", "ingredients/content/01.md", "ingredients/EXO.tsv"} {
+		m.Ingredients[key] = sb.Ingredient{Size: int64(len(key))}
+	}
+
+	var got []string
+	err := m.WalkIngredients(func(key string, ing sb.Ingredient) error {
+		if ing.Size != int64(len(key)) {
+			t.Errorf("ingredient %s passed with wrong value", key)
+		}
+		got = append(got, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkIngredients failed: %v", err)
+	}
+	want := []string{"LICENSE.md", "ingredients/EXO.tsv", "ingredients/GEN.tsv", "ingredients/REV.tsv", "ingredients/content/01.md"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("walk order = %v; want %v", got, want)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = m.WalkIngredients(func(string, sb.Ingredient) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("WalkIngredients returned %v after %d calls; want stop after 1", err, calls)
+	}
+}
+
+func TestMetadata_AggregateScope(t *testing.T) {
+	m := sb.NewMetadata()
+	if got := m.AggregateScope(); got != nil {
+		t.Errorf("AggregateScope() with no scoped ingredients = %v; want nil", got)
+	}
+
+	m.Ingredients["ingredients/GEN.usfm"] = sb.Ingredient{Scope: map[string][]string{"GEN": {}}}
+	m.Ingredients["ingredients/EXO_1.usfm"] = sb.Ingredient{Scope: map[string][]string{"EXO": {"1"}}}
+	m.Ingredients["ingredients/EXO_2.usfm"] = sb.Ingredient{Scope: map[string][]string{"EXO": {"2", "1"}}}
+	m.Ingredients["ingredients/GEN_intro.usfm"] = sb.Ingredient{Scope: map[string][]string{"GEN": {"1"}}}
+	m.Ingredients["ingredients/LICENSE.md"] = sb.Ingredient{}
+
+	want := map[string][]string{"EXO": {"1", "2"}, "GEN": {}}
+	if got := m.AggregateScope(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AggregateScope() = %v; want %v", got, want)
+	}
+	if got := m.Ingredients["ingredients/EXO_1.usfm"].Scope["EXO"]; len(got) != 1 {
+		t.Errorf("AggregateScope modified an ingredient's scope: %v", got)
+	}
+}
+
+func TestMetadata_UnscopedBooks(t *testing.T) {
+	m := sb.NewMetadata()
+	m.Type.FlavorType.CurrentScope = map[string][]string{"GEN": {}}
+	if got := m.UnscopedBooks(); got != nil {
+		t.Errorf("UnscopedBooks() with no scoped ingredients = %v; want nil", got)
+	}
+
+	// EXO and LEV are listed, but their files were missing
+	m.Type.FlavorType.CurrentScope = map[string][]string{"GEN": {}, "LEV": {}, "EXO": {}}
+	m.Ingredients["ingredients/GEN.usfm"] = sb.Ingredient{Scope: map[string][]string{"GEN": {}}}
+	m.Ingredients["ingredients/LICENSE.md"] = sb.Ingredient{}
+
+	want := []string{"EXO", "LEV"}
+	if got := m.UnscopedBooks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("UnscopedBooks() = %v; want %v", got, want)
+	}
+
+	m.Type.FlavorType.CurrentScope = m.AggregateScope()
+	if got := m.UnscopedBooks(); got != nil {
+		t.Errorf("UnscopedBooks() with currentScope from AggregateScope = %v; want nil", got)
+	}
+}
+
+func TestLoadMetadata(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := sb.LoadMetadata(dir); err == nil {
+		t.Error("expected error for a missing metadata.json")
+	}
+
+	want := sb.NewMetadata()
+	want.Type.FlavorType.Flavor.Name = "x-bcvnotes"
+	want.Ingredients["ingredients/GEN.tsv"] = sb.Ingredient{Size: 3, Scope: map[string][]string{"GEN": {}}}
+	if err := want.WriteToFile(dir); err != nil {
+		t.Fatal(err)
+	}
+	got, err := sb.LoadMetadata(dir)
+	if err != nil {
+		t.Fatalf("LoadMetadata failed: %v", err)
+	}
+	if !reflect.DeepEqual(got.Ingredients, want.Ingredients) || !reflect.DeepEqual(got.Type, want.Type) {
+		t.Errorf("LoadMetadata = %+v; want %+v", got, want)
+	}
+
+	os.WriteFile(filepath.Join(dir, "metadata.json"), []byte("{"), 0644)
+	if _, err := sb.LoadMetadata(dir); err == nil {
+		t.Error("expected error for malformed metadata.json")
+	}
+}
+
+func TestMetadata_MIMEMismatches(t *testing.T) {
+	m := sb.NewMetadata()
+	m.Ingredients["ingredients/GEN.usfm"] = sb.Ingredient{MimeType: "text/plain"}
+	m.Ingredients["ingredients/tn_GEN.txt"] = sb.Ingredient{MimeType: "text/tab-separated-values"}
+	if got := m.MIMEMismatches(); got != nil {
+		t.Errorf("MIMEMismatches() = %v; want nil", got)
+	}
+
+	// Set by hand to the type of another extension
+	m.Ingredients["ingredients/EXO.tsv"] = sb.Ingredient{MimeType: "text/markdown"}
+	m.Ingredients["ingredients/01.md"] = sb.Ingredient{MimeType: "text/plain"}
+	want := []string{"ingredients/01.md", "ingredients/EXO.tsv"}
+	if got := m.MIMEMismatches(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MIMEMismatches() = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package sb
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// modulePath is the import path of the go-rc2sb module.
+const modulePath = "github.com/unfoldingWord/go-rc2sb"
+
+// devVersion is reported when the build carries no module version,
+// e.g., for go run, go test, or a go build inside the repository.
+const devVersion = "0.1.0"
+
+// BuildInfo describes the build of go-rc2sb that is running.
+type BuildInfo struct {
+	// Version is the module version (e.g., "v1.2.0").
+	Version string
+
+	// Revision is the VCS revision the binary was built from, if known.
+	Revision string
+
+	// Date is the VCS commit time of Revision in RFC 3339 format, if known.
+	Date string
+}
+
+// ReadBuildInfo returns the go-rc2sb build information embedded in the
+// running binary, whether go-rc2sb is the main module or a dependency.
+// Its Version is recorded as the metadata generator's software version.
+func ReadBuildInfo() BuildInfo {
+	return readBuildInfo()
+}
+
+var readBuildInfo = sync.OnceValue(func() BuildInfo {
+	info := BuildInfo{Version: devVersion}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	mod := &bi.Main
+	for _, dep := range bi.Deps {
+		if dep.Path == modulePath {
+			mod = dep
+		}
+	}
+	if mod.Path == modulePath && mod.Version != "" && mod.Version != "(devel)" {
+		info.Version = mod.Version
+	}
+
+	// VCS settings are only stamped for the main module
+	if mod == &bi.Main {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Revision = s.Value
+			case "vcs.time":
+				info.Date = s.Value
+			}
+		}
+	}
+	return info
+})
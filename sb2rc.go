@@ -0,0 +1,533 @@
+package rc2sb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/books"
+	"github.com/unfoldingWord/go-rc2sb/handler"
+	"github.com/unfoldingWord/go-rc2sb/rc"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// layoutKind says how the ingredients of an SB map to the projects of an RC.
+type layoutKind int
+
+const (
+	// bookFiles has one file per book at the top of ingredients/
+	// (e.g., ingredients/GEN.tsv), each a project of its own.
+	bookFiles layoutKind = iota
+
+	// singleFile has one TSV file at the top of ingredients/ (e.g.,
+	// ingredients/OBS.tsv), the only project.
+	singleFile
+
+	// articleTree has a tree of articles beneath sbDir, copied to rcDir,
+	// the only project.
+	articleTree
+
+	// projectDirs has a directory beneath ingredients/ for each project.
+	projectDirs
+)
+
+// rcLayout describes how the SB produced from one RC subject maps back to
+// that RC.
+type rcLayout struct {
+	kind layoutKind
+
+	// identifier is the dublin_core.identifier, or "" to use the SB
+	// abbreviation in lower case (e.g., "ult" for a Bible).
+	identifier string
+
+	rcType string // dublin_core.type
+	format string // dublin_core.format
+
+	// prefix is put before the name of each book or single file (e.g., "tn_"
+	// for ingredients/GEN.tsv -> tn_GEN.tsv).
+	prefix string
+
+	// ext is the extension of book and single files.
+	ext string
+
+	// sbDir and rcDir are the ingredient directory and RC directory of an
+	// article tree, and project the identifier of its project.
+	sbDir, rcDir, project string
+
+	// payload marks TSV Translation Words Links, whose ingredients/payload/
+	// is left out and whose ./payload/ links are turned back into rc:// links.
+	payload bool
+}
+
+// rcLayouts lists the layout of each RC subject ConvertSBToRC can produce.
+var rcLayouts = map[string]rcLayout{
+	"Open Bible Stories":            {kind: articleTree, identifier: "obs", rcType: "book", format: "text/markdown", sbDir: "ingredients/content", rcDir: "content", project: "obs"},
+	"Aligned Bible":                 {kind: bookFiles, rcType: "bundle", format: "text/usfm3", ext: ".usfm"},
+	"Bible":                         {kind: bookFiles, rcType: "bundle", format: "text/usfm3", ext: ".usfm"},
+	"Hebrew Old Testament":          {kind: bookFiles, identifier: "uhb", rcType: "bundle", format: "text/usfm3", ext: ".usfm"},
+	"Greek New Testament":           {kind: bookFiles, identifier: "ugnt", rcType: "bundle", format: "text/usfm3", ext: ".usfm"},
+	"Translation Words":             {kind: articleTree, identifier: "tw", rcType: "dict", format: "text/markdown", sbDir: "ingredients", rcDir: "bible", project: "bible"},
+	"OBS Translation Words":         {kind: articleTree, identifier: "obs-tw", rcType: "dict", format: "text/markdown", sbDir: "ingredients", rcDir: "obs", project: "obs"},
+	"Translation Academy":           {kind: projectDirs, identifier: "ta", rcType: "man", format: "text/markdown"},
+	"TSV Translation Notes":         {kind: bookFiles, identifier: "tn", rcType: "help", format: "text/tsv", prefix: "tn_", ext: ".tsv"},
+	"TSV Translation Questions":     {kind: bookFiles, identifier: "tq", rcType: "help", format: "text/tsv", prefix: "tq_", ext: ".tsv"},
+	"TSV Study Notes":               {kind: bookFiles, identifier: "sn", rcType: "help", format: "text/tsv", prefix: "sn_", ext: ".tsv"},
+	"TSV Translation Words Links":   {kind: bookFiles, identifier: "twl", rcType: "help", format: "text/tsv", prefix: "twl_", ext: ".tsv", payload: true},
+	"TSV OBS Study Notes":           {kind: singleFile, identifier: "obs-sn", rcType: "help", format: "text/tsv", prefix: "sn_", ext: ".tsv", project: "obs"},
+	"TSV OBS Study Questions":       {kind: singleFile, identifier: "obs-sq", rcType: "help", format: "text/tsv", prefix: "sq_", ext: ".tsv", project: "obs"},
+	"TSV OBS Translation Notes":     {kind: singleFile, identifier: "obs-tn", rcType: "help", format: "text/tsv", prefix: "tn_", ext: ".tsv", project: "obs"},
+	"TSV OBS Translation Questions": {kind: singleFile, identifier: "obs-tq", rcType: "help", format: "text/tsv", prefix: "tq_", ext: ".tsv", project: "obs"},
+}
+
+// taProjects is the usual order of the Translation Academy projects.
+var taProjects = []string{"intro", "process", "translate", "checking"}
+
+// ConvertSBToRC converts a Scripture Burrito at sbDir, such as one written by
+// Convert, back to a Resource Container in outDir. It covers the flavors this
+// package produces. The RC subject is chosen from the SB flavor (and, where
+// several subjects share a flavor, its abbreviation), or is
+// opts.SubjectOverride if set; no other option is used but opts.Logger.
+//
+// manifest.yaml is rebuilt from metadata.json: dublin_core from the
+// identification, language, and copyright, and projects from the ingredients
+// and their scopes. Ingredients are renamed back to RC conventions (e.g.,
+// ingredients/GEN.tsv to tn_GEN.tsv, or ingredients/content/ to content/),
+// or to their recorded x-source path if there is one. Files at the SB root,
+// such as LICENSE.md and README.md, are copied to the RC root. The TWL payload
+// is left out, and its ./payload/ links are turned back into rc:// links, as
+// is a source manifest carried by Options.IncludeSourceManifest.
+//
+// The manifest is only as complete as the SB metadata: fields with no SB
+// counterpart (e.g., contributors or checking) are left empty, and the issued
+// date is reduced to the year in the copyright statement.
+func ConvertSBToRC(ctx context.Context, sbDir, outDir string, opts Options) (Result, error) {
+	// Check context
+	if err := ctx.Err(); err != nil {
+		return Result{}, fmt.Errorf("context error: %w", err)
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	m, err := sb.LoadMetadata(sbDir)
+	if err != nil {
+		return Result{}, err
+	}
+
+	subject := opts.SubjectOverride
+	if subject == "" {
+		if subject, err = subjectForFlavor(m); err != nil {
+			return Result{}, err
+		}
+	}
+	layout, ok := rcLayouts[subject]
+	if !ok {
+		return Result{}, fmt.Errorf("%w %q for SB to RC conversion", handler.ErrUnsupportedSubject, subject)
+	}
+
+	fsys := os.DirFS(sbDir)
+	manifest := rebuildManifest(m, subject, layout)
+	if manifest.DublinCore.Rights == "" {
+		// The OBS copyright statement leaves out the rights
+		manifest.DublinCore.Rights = rightsFromLicense(fsys)
+	}
+	files, err := rcFiles(m, layout)
+	if err != nil {
+		return Result{}, err
+	}
+	manifest.Projects = rcProjects(m, layout, files)
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return Result{}, fmt.Errorf("creating output directory: %w", err)
+	}
+	out := handler.DirOutput(outDir)
+
+	// Copy the ingredients to their RC paths
+	for _, key := range slices.Sorted(maps.Keys(files)) {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		name := files[key]
+		logger.Debug("writing file", "name", name)
+		if layout.payload && path.Ext(name) == ".tsv" {
+			err = copyTWLUnrewritten(fsys, key, out, name)
+		} else {
+			err = handler.CopyFile(ctx, fsys, key, filepath.Join(outDir, filepath.FromSlash(name)))
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("copying %s: %w", key, err)
+		}
+	}
+
+	// Copy the SB root files (LICENSE.md, README.md, .gitignore, ...) to the RC root
+	if err := copySBRootFiles(ctx, fsys, outDir, logger); err != nil {
+		return Result{}, err
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "LICENSE.md")); errors.Is(err, fs.ErrNotExist) {
+		if _, ok := m.Ingredients["ingredients/LICENSE.md"]; ok {
+			if err := handler.CopyFile(ctx, fsys, "ingredients/LICENSE.md", filepath.Join(outDir, "LICENSE.md")); err != nil {
+				return Result{}, fmt.Errorf("copying LICENSE.md: %w", err)
+			}
+		}
+	}
+
+	if err := manifest.WriteToFile(outDir); err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Subject:     subject,
+		Identifier:  manifest.DublinCore.Identifier,
+		InDir:       sbDir,
+		OutDir:      outDir,
+		Ingredients: len(files),
+	}, nil
+}
+
+// subjectForFlavor returns the RC subject the SB metadata m was converted from.
+func subjectForFlavor(m *sb.Metadata) (string, error) {
+	abbr := strings.ToUpper(m.Identification.Abbreviation["en"])
+	switch flavor := m.Type.FlavorType.Flavor.Name; flavor {
+	case "textStories":
+		return "Open Bible Stories", nil
+	case "textTranslation":
+		switch abbr {
+		case "UHB":
+			return "Hebrew Old Testament", nil
+		case "UGNT":
+			return "Greek New Testament", nil
+		}
+		return "Aligned Bible", nil
+	case "x-bcvnotes":
+		if abbr == "SN" {
+			return "TSV Study Notes", nil
+		}
+		return "TSV Translation Notes", nil
+	case "x-bcvquestions":
+		return "TSV Translation Questions", nil
+	case "x-bcvarticles":
+		return "TSV Translation Words Links", nil
+	case "x-obsnotes":
+		if abbr == "OBSSN" {
+			return "TSV OBS Study Notes", nil
+		}
+		return "TSV OBS Translation Notes", nil
+	case "x-obsquestions":
+		if abbr == "OBSSQ" {
+			return "TSV OBS Study Questions", nil
+		}
+		return "TSV OBS Translation Questions", nil
+	case "x-peripheralArticles":
+		switch abbr {
+		case "TA":
+			return "Translation Academy", nil
+		case "OBSTW":
+			return "OBS Translation Words", nil
+		}
+		return "Translation Words", nil
+	default:
+		return "", fmt.Errorf("%w: no RC subject for SB flavor %q", handler.ErrUnsupportedSubject, flavor)
+	}
+}
+
+// copyrightPatterns match the copyright statements written by
+// handler.BuildCopyright, capturing the publisher, year, and rights.
+var (
+	copyrightPattern    = regexp.MustCompile(`^\x{00a9} (.*) (\d{4}), (.*)$`)
+	obsCopyrightPattern = regexp.MustCompile(`^Copyright \x{00a9} (\d{4}) by (.*)$`)
+)
+
+// rebuildManifest returns the RC manifest for m, without projects.
+func rebuildManifest(m *sb.Metadata, subject string, layout rcLayout) *rc.Manifest {
+	identifier := layout.identifier
+	if identifier == "" {
+		identifier = strings.ToLower(m.Identification.Abbreviation["en"])
+	}
+
+	dc := rc.DublinCore{
+		ConformsTo:  "rc0.2",
+		Format:      layout.format,
+		Identifier:  identifier,
+		Subject:     subject,
+		Title:       m.Identification.Name["en"],
+		Description: m.Identification.Description["en"],
+		Type:        layout.rcType,
+	}
+	for _, entries := range m.Identification.Primary {
+		for _, entry := range entries {
+			dc.Version = entry.Revision
+		}
+	}
+	if len(m.Languages) > 0 {
+		l := m.Languages[0]
+		title := l.Name[l.Tag]
+		if title == "" {
+			title = l.Name["en"]
+		}
+		dc.Language = rc.Language{Identifier: l.Tag, Title: title, Direction: l.ScriptDirection}
+	}
+	if len(m.Copyright.ShortStatements) > 0 {
+		statement := m.Copyright.ShortStatements[0].Statement
+		if match := copyrightPattern.FindStringSubmatch(statement); match != nil {
+			dc.Publisher, dc.Issued, dc.Rights = match[1], match[2], match[3]
+		} else if match := obsCopyrightPattern.FindStringSubmatch(statement); match != nil {
+			dc.Issued, dc.Publisher = match[1], match[2]
+		}
+	}
+	return &rc.Manifest{DublinCore: dc}
+}
+
+// licenseRightsPattern matches the rights in the heading of a Creative
+// Commons LICENSE.md, e.g., "... International (CC BY-SA 4.0)".
+var licenseRightsPattern = regexp.MustCompile(`(?m)^#.*\((CC[ -]BY[^)]*)\)\s*$`)
+
+// rightsFromLicense returns the rights declared in the heading of the SB's
+// LICENSE.md in fsys, or "" if there is none.
+func rightsFromLicense(fsys fs.FS) string {
+	for _, name := range []string{"LICENSE.md", "ingredients/LICENSE.md"} {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			continue
+		}
+		if match := licenseRightsPattern.FindSubmatch(data); match != nil {
+			return string(match[1])
+		}
+	}
+	return ""
+}
+
+// rcFiles maps the key of each ingredient of m to copy to its RC path.
+// Ingredients outside ingredients/ are copied with the SB root files instead.
+func rcFiles(m *sb.Metadata, layout rcLayout) (map[string]string, error) {
+	files := make(map[string]string)
+	from := make(map[string]string)
+	err := m.WalkIngredients(func(key string, ing sb.Ingredient) error {
+		rel, ok := strings.CutPrefix(key, "ingredients/")
+		if !ok || rel == "LICENSE.md" || (layout.payload && strings.HasPrefix(rel, "payload/")) {
+			return nil
+		}
+		if ing.Role == handler.SourceManifestRole {
+			// The RC manifest carried by Options.IncludeSourceManifest
+			return nil
+		}
+		if !fs.ValidPath(key) {
+			return fmt.Errorf("invalid ingredient path %q", key)
+		}
+
+		name := ing.Source
+		if name == "" {
+			name = rcName(layout, key, rel)
+		}
+		name = path.Clean(name)
+		if !fs.ValidPath(name) || name == "." {
+			return fmt.Errorf("ingredient %s has an invalid source path %q", key, name)
+		}
+		if prev, ok := from[name]; ok {
+			return fmt.Errorf("ingredients %s and %s would both be written to %s", prev, key, name)
+		}
+		from[name] = key
+		files[key] = name
+		return nil
+	})
+	return files, err
+}
+
+// rcName returns the RC path of the ingredient key, whose path beneath
+// ingredients/ is rel.
+func rcName(layout rcLayout, key, rel string) string {
+	switch layout.kind {
+	case bookFiles, singleFile:
+		if strings.Contains(rel, "/") || path.Ext(rel) != layout.ext {
+			break
+		}
+		if layout.kind == bookFiles && layout.ext == ".usfm" {
+			return usfmFilename(strings.TrimSuffix(rel, layout.ext))
+		}
+		return layout.prefix + rel
+	case articleTree:
+		if rest, ok := strings.CutPrefix(key, layout.sbDir+"/"); ok {
+			return layout.rcDir + "/" + rest
+		}
+	}
+	return rel
+}
+
+// usfmFilename returns the RC file name of the USFM book code, numbered as
+// on Door43 (e.g., "01-GEN.usfm", or "41-MAT.usfm" since 40 is skipped).
+func usfmFilename(code string) string {
+	b := books.ByCode(code)
+	if b == nil {
+		return code + ".usfm"
+	}
+	n := b.Sort
+	if n >= 40 {
+		n++
+	}
+	return fmt.Sprintf("%02d-%s.usfm", n, b.Code)
+}
+
+// rcProjects returns the manifest projects for the ingredients of m, given
+// the RC path of each in files.
+func rcProjects(m *sb.Metadata, layout rcLayout, files map[string]string) []rc.Project {
+	var projects []rc.Project
+	switch layout.kind {
+	case bookFiles:
+		lang := ""
+		if len(m.Languages) > 0 {
+			lang = m.Languages[0].Tag
+		}
+		for _, key := range slices.Sorted(maps.Keys(files)) {
+			rel := strings.TrimPrefix(key, "ingredients/")
+			if strings.Contains(rel, "/") || path.Ext(rel) != layout.ext {
+				continue
+			}
+			code := strings.ToUpper(strings.TrimSuffix(rel, layout.ext))
+			project := rc.Project{Identifier: strings.ToLower(code), Path: "./" + files[key]}
+			if b := books.ByCode(code); b != nil {
+				project.Identifier = b.ID
+				project.Sort = b.Sort
+				project.Title = bookTitle(m, b, lang)
+				project.Versification = "ufw"
+				if v := m.Ingredients[key].Versification; v != "" {
+					project.Versification = v
+				}
+				project.Categories = []string{"bible-ot"}
+				if b.Sort >= 40 {
+					project.Categories = []string{"bible-nt"}
+				}
+			}
+			projects = append(projects, project)
+		}
+		slices.SortStableFunc(projects, func(a, b rc.Project) int { return a.Sort - b.Sort })
+	case singleFile:
+		for _, key := range slices.Sorted(maps.Keys(files)) {
+			rel := strings.TrimPrefix(key, "ingredients/")
+			if !strings.Contains(rel, "/") && path.Ext(rel) == layout.ext {
+				projects = append(projects, rc.Project{Identifier: layout.project, Path: "./" + files[key], Title: m.Identification.Name["en"]})
+				break
+			}
+		}
+	case articleTree:
+		projects = append(projects, rc.Project{Identifier: layout.project, Path: "./" + layout.rcDir, Title: m.Identification.Name["en"]})
+	case projectDirs:
+		dirs := make(map[string]bool)
+		for _, name := range files {
+			if dir, _, ok := strings.Cut(name, "/"); ok {
+				dirs[dir] = true
+			}
+		}
+		var ordered []string
+		for _, dir := range taProjects {
+			if dirs[dir] {
+				ordered = append(ordered, dir)
+				delete(dirs, dir)
+			}
+		}
+		ordered = append(ordered, slices.Sorted(maps.Keys(dirs))...)
+		for i, dir := range ordered {
+			projects = append(projects, rc.Project{Identifier: dir, Path: "./" + dir, Sort: i + 1, Title: dir})
+		}
+	}
+	return projects
+}
+
+// bookTitle returns the project title of book b: its localized short name in
+// lang, or else in English.
+func bookTitle(m *sb.Metadata, b *books.BookInfo, lang string) string {
+	ln := m.LocalizedNames["book-"+b.ID]
+	if title := ln.Short[lang]; title != "" {
+		return title
+	}
+	if title := ln.Short["en"]; title != "" {
+		return title
+	}
+	return b.Short
+}
+
+// copySBRootFiles copies every file at the SB root in fsys, other than
+// metadata.json, its BagIt manifest, and the ingredients/ directory, to the
+// same path in outDir.
+func copySBRootFiles(ctx context.Context, fsys fs.FS, outDir string, logger *slog.Logger) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		switch {
+		case name == "ingredients" || name == ".git":
+			return fs.SkipDir
+		case d.IsDir() || name == "metadata.json" || name == bagItManifest:
+			return nil
+		}
+		logger.Debug("writing file", "name", name)
+		if err := handler.CopyFile(ctx, fsys, name, filepath.Join(outDir, filepath.FromSlash(name))); err != nil {
+			return fmt.Errorf("copying root file %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// payloadLinkPattern matches a TWL link rewritten to the payload by Convert.
+var payloadLinkPattern = regexp.MustCompile(`^\./payload/(.+)\.md$`)
+
+// copyTWLUnrewritten copies the TWL TSV file name from fsys to dstName in
+// out, turning ./payload/ links in its TWLink column back into rc:// links.
+func copyTWLUnrewritten(fsys fs.FS, name string, out handler.Output, dstName string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	w, err := out.Create(dstName)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(unrewriteTWLLinks(data)); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// unrewriteTWLLinks returns the TWL TSV data with the ./payload/ links in its
+// TWLink column turned back into rc:// links. Line endings are kept as they
+// are.
+func unrewriteTWLLinks(data []byte) []byte {
+	lines := bytes.Split(data, []byte("\n"))
+	col := -1
+	for i, line := range lines {
+		body := bytes.TrimSuffix(line, []byte("\r"))
+		fields := strings.Split(string(body), "\t")
+		if i == 0 {
+			// Locate the TWLink column from the header row, as Convert does
+			fields = strings.Split(strings.TrimPrefix(string(body), "\uFEFF"), "\t")
+			col = len(fields) - 1
+			if j := slices.Index(fields, "TWLink"); j >= 0 {
+				col = j
+			}
+			continue
+		}
+		if col >= len(fields) {
+			continue
+		}
+		if match := payloadLinkPattern.FindStringSubmatch(fields[col]); match != nil {
+			fields[col] = "rc://*/tw/dict/bible/" + match[1]
+			lines[i] = append([]byte(strings.Join(fields, "\t")), line[len(body):]...)
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
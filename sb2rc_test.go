@@ -0,0 +1,255 @@
+package rc2sb_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+	"github.com/unfoldingWord/go-rc2sb/handler"
+	"github.com/unfoldingWord/go-rc2sb/rc"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// roundTripManifest returns a manifest.yaml for the subject with the given
+// projects, each an "identifier path" pair.
+func roundTripManifest(subject, identifier string, projects ...string) string {
+	var b strings.Builder
+	b.WriteString("dublin_core:\n")
+	b.WriteString("  subject: '" + subject + "'\n")
+	b.WriteString("  identifier: '" + identifier + "'\n")
+	b.WriteString("  title: 'Round Trip'\n")
+	b.WriteString("  issued: '2024'\n")
+	b.WriteString("  publisher: 'unfoldingWord'\n")
+	b.WriteString("  rights: 'CC BY-SA 4.0'\n")
+	b.WriteString("  language:\n    identifier: 'en'\n    title: 'English'\n    direction: 'ltr'\n")
+	b.WriteString("projects:\n")
+	for _, p := range projects {
+		id, path, _ := strings.Cut(p, " ")
+		b.WriteString("  - identifier: '" + id + "'\n    path: '" + path + "'\n")
+	}
+	return b.String()
+}
+
+func TestConvertSBToRC_RoundTrip(t *testing.T) {
+	const tsvHeader = "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n"
+	tests := []struct {
+		name  string
+		files map[string]string
+
+		// dropped lists the files that are not expected back, e.g., the TWL payload
+		dropped []string
+	}{
+		{"TN", map[string]string{
+			"manifest.yaml": roundTripManifest("TSV Translation Notes", "tn", "gen ./tn_GEN.tsv", "mat ./tn_MAT.tsv"),
+			"tn_GEN.tsv":    tsvHeader + "1:1\tabcd\t\t\t\t0\tNote\n",
+			"tn_MAT.tsv":    tsvHeader + "1:1\tefgh\t\t\t\t0\tNote\r\n",
+			"LICENSE.md":    "License\n",
+			"README.md":     "# TN\n",
+		}, nil},
+		{"TWL with payload", convertFSTestFiles, []string{"en_tw/bible/kt/god.md"}},
+		{"Bible", map[string]string{
+			"manifest.yaml": roundTripManifest("Aligned Bible", "ult", "gen ./01-GEN.usfm", "mat ./41-MAT.usfm"),
+			"01-GEN.usfm":   "\\id GEN\n\\toc1 Genesis\n\\c 1\n\\v 1 In the beginning\n",
+			"41-MAT.usfm":   "\\id MAT\n\\toc1 Matthew\n\\c 1\n\\v 1 The book\n",
+			"LICENSE.md":    "License\n",
+		}, nil},
+		{"OBS", map[string]string{
+			"manifest.yaml":          roundTripManifest("Open Bible Stories", "obs", "obs ./content"),
+			"content/01.md":          "# 1. The Creation\n",
+			"content/front/title.md": "Open Bible Stories\n",
+			"LICENSE.md":             "# License\n\n## Creative Commons Attribution-ShareAlike 4.0 International (CC BY-SA 4.0)\n",
+			"README.md":              "# OBS\n",
+		}, nil},
+		{"TW", map[string]string{
+			"manifest.yaml":          roundTripManifest("Translation Words", "tw", "bible ./bible"),
+			"bible/kt/god.md":        "# God\n",
+			"bible/names/abraham.md": "# Abraham\n",
+			"bible/config.yaml":      "kt: {}\n",
+			"LICENSE.md":             "License\n",
+		}, nil},
+		{"TA", map[string]string{
+			"manifest.yaml":                 roundTripManifest("Translation Academy", "ta", "intro ./intro", "translate ./translate"),
+			"intro/ta-intro/01.md":          "Intro\n",
+			"translate/figs-metaphor/01.md": "Metaphor\n",
+			"translate/toc.yaml":            "title: Translate\n",
+			"LICENSE.md":                    "License\n",
+		}, nil},
+		{"SN", map[string]string{
+			"manifest.yaml": roundTripManifest("TSV Study Notes", "sn", "gen ./sn_GEN.tsv"),
+			"sn_GEN.tsv":    tsvHeader + "1:1\tabcd\t\t\t\t0\tStudy note\n",
+			"LICENSE.md":    "License\n",
+		}, nil},
+		{"OBS TSV", map[string]string{
+			"manifest.yaml": roundTripManifest("TSV OBS Study Notes", "obs-sn", "obs ./sn_OBS.tsv"),
+			"sn_OBS.tsv":    tsvHeader + "1:1\tabcd\t\t\t\t0\tNote\n",
+			"LICENSE.md":    "License\n",
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			inDir, sbDir, rcDir := t.TempDir(), t.TempDir(), t.TempDir()
+			writeRepoFiles(t, inDir, tt.files)
+
+			if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{}); err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+			verifyCompare(t, inDir, sbDir)
+			result, err := rc2sb.ConvertSBToRC(ctx, sbDir, rcDir, rc2sb.Options{})
+			if err != nil {
+				t.Fatalf("ConvertSBToRC failed: %v", err)
+			}
+
+			want, err := rc.LoadManifest(inDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := rc.LoadManifest(rcDir)
+			if err != nil {
+				t.Fatalf("loading rebuilt manifest: %v", err)
+			}
+			if result.Subject != want.DublinCore.Subject || result.Identifier != want.DublinCore.Identifier {
+				t.Errorf("result = %s %s; want %s %s", result.Subject, result.Identifier, want.DublinCore.Subject, want.DublinCore.Identifier)
+			}
+			compareManifests(t, want, got)
+
+			for name, content := range tt.files {
+				if name == "manifest.yaml" || contains(tt.dropped, name) {
+					continue
+				}
+				data, err := os.ReadFile(filepath.Join(rcDir, filepath.FromSlash(name)))
+				if err != nil {
+					t.Errorf("file %s not restored: %v", name, err)
+					continue
+				}
+				if string(data) != content {
+					t.Errorf("file %s = %q; want %q", name, data, content)
+				}
+			}
+			for _, name := range tt.dropped {
+				if _, err := os.Stat(filepath.Join(rcDir, filepath.FromSlash(name))); !os.IsNotExist(err) {
+					t.Errorf("file %s should not be restored", name)
+				}
+			}
+		})
+	}
+}
+
+// compareManifests reports the differences between the RC manifest want and
+// the manifest got rebuilt from its SB that the SB preserves.
+func compareManifests(t *testing.T, want, got *rc.Manifest) {
+	t.Helper()
+	w, g := want.DublinCore, got.DublinCore
+	for _, f := range []struct{ name, want, got string }{
+		{"subject", w.Subject, g.Subject},
+		{"identifier", w.Identifier, g.Identifier},
+		{"title", w.Title, g.Title},
+		{"publisher", w.Publisher, g.Publisher},
+		{"rights", w.Rights, g.Rights},
+		{"issued year", w.Issued[:4], g.Issued},
+		{"language", w.Language.Identifier, g.Language.Identifier},
+		{"language title", w.Language.Title, g.Language.Title},
+		{"direction", w.Language.Direction, g.Language.Direction},
+	} {
+		if f.got != f.want {
+			t.Errorf("%s = %q; want %q", f.name, f.got, f.want)
+		}
+	}
+
+	project := func(p rc.Project) string { return p.Identifier + " " + p.Path }
+	var wantProjects, gotProjects []string
+	for _, p := range want.Projects {
+		wantProjects = append(wantProjects, project(p))
+	}
+	for _, p := range got.Projects {
+		gotProjects = append(gotProjects, project(p))
+	}
+	if !reflect.DeepEqual(gotProjects, wantProjects) {
+		t.Errorf("projects = %v; want %v", gotProjects, wantProjects)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestConvertSBToRC_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := rc2sb.ConvertSBToRC(ctx, t.TempDir(), t.TempDir(), rc2sb.Options{}); err == nil {
+		t.Error("expected error for a directory with no metadata.json")
+	}
+
+	sbDir := t.TempDir()
+	m := sb.NewMetadata()
+	m.Type.FlavorType.Flavor.Name = "x-unknown"
+	if err := m.WriteToFile(sbDir); err != nil {
+		t.Fatal(err)
+	}
+	_, err := rc2sb.ConvertSBToRC(ctx, sbDir, t.TempDir(), rc2sb.Options{})
+	if !errors.Is(err, handler.ErrUnsupportedSubject) {
+		t.Errorf("error = %v; want ErrUnsupportedSubject for an unknown flavor", err)
+	}
+
+	// An ingredient may not escape the output directory
+	m.Type.FlavorType.Flavor.Name = "x-bcvnotes"
+	m.Ingredients["ingredients/GEN.tsv"] = sb.Ingredient{Source: "../GEN.tsv"}
+	if err := m.WriteToFile(sbDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rc2sb.ConvertSBToRC(ctx, sbDir, t.TempDir(), rc2sb.Options{}); err == nil {
+		t.Error("expected error for an ingredient source outside the RC")
+	}
+}
+
+func TestConvertSBToRC_SkipsSourceManifest(t *testing.T) {
+	ctx := context.Background()
+	inDir, sbDir, rcDir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, archiveOBSFiles)
+
+	opts := rc2sb.Options{IncludeSourceManifest: true, RecordSources: true}
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, opts); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	verifyCompare(t, inDir, sbDir)
+	if _, err := rc2sb.ConvertSBToRC(ctx, sbDir, rcDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("ConvertSBToRC failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(rcDir, "source")); !os.IsNotExist(err) {
+		t.Error("source manifest should not be restored as an RC file")
+	}
+	manifest, err := rc.LoadManifest(rcDir)
+	if err != nil {
+		t.Fatalf("loading rebuilt manifest: %v", err)
+	}
+	for _, p := range manifest.Projects {
+		if strings.Contains(p.Path, "source") {
+			t.Errorf("project %s %s is the source manifest", p.Identifier, p.Path)
+		}
+	}
+}
+
+func TestConvertSBToRC_SkipsBagItManifest(t *testing.T) {
+	ctx := context.Background()
+	inDir, sbDir, rcDir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, archiveOBSFiles)
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{BagItManifest: true}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if _, err := rc2sb.ConvertSBToRC(ctx, sbDir, rcDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("ConvertSBToRC failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(rcDir, "manifest-md5.txt")); !os.IsNotExist(err) {
+		t.Errorf("manifest-md5.txt copied to the RC: %v", err)
+	}
+}
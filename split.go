@@ -0,0 +1,186 @@
+package rc2sb
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/books"
+	"github.com/unfoldingWord/go-rc2sb/handler"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// payloadLinkRegexp matches a TWL link rewritten to the payload, e.g.,
+// ./payload/kt/god.md, capturing the article's path beneath the payload.
+var payloadLinkRegexp = regexp.MustCompile(`\./payload/([^\t\r\n]+\.md)`)
+
+// SplitByBook splits the scripture or parascriptural SB at sbDir into one SB
+// per book of its currentScope, written to outRoot/<code> (e.g.,
+// outRoot/GEN), and returns their directories in canonical book order. Each
+// holds the ingredients whose scope includes its book, the unscoped ones
+// (e.g., ingredients/LICENSE.md), and the other root files, such as
+// README.md. Payload articles beneath ingredients/payload/ are only kept by
+// the books whose TSV ingredients link to them.
+//
+// Each SB's metadata.json is that of sbDir, with the identification
+// abbreviation suffixed by the book code (e.g., TN-GEN), and currentScope and
+// localizedNames reduced to its book.
+func SplitByBook(ctx context.Context, sbDir, outRoot string) ([]string, error) {
+	// Check context
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	m, err := sb.LoadMetadata(sbDir)
+	if err != nil {
+		return nil, err
+	}
+	switch m.Type.FlavorType.Name {
+	case "scripture", "parascriptural":
+	default:
+		return nil, fmt.Errorf("cannot split a %q burrito by book", m.Type.FlavorType.Name)
+	}
+	if len(m.Type.FlavorType.CurrentScope) == 0 {
+		return nil, errors.New("cannot split by book: currentScope lists no books")
+	}
+
+	fsys := os.DirFS(sbDir)
+	var dirs []string
+	for _, code := range sortedBookCodes(m.Type.FlavorType.CurrentScope) {
+		if !validPrefix(code) {
+			return nil, fmt.Errorf("cannot split by book: invalid book code %q", code)
+		}
+		dir := filepath.Join(outRoot, code)
+		if err := splitBook(ctx, fsys, m, code, dir); err != nil {
+			return nil, fmt.Errorf("splitting %s: %w", code, err)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+// splitBook writes to dir the SB holding the book code of the SB m read from
+// fsys.
+func splitBook(ctx context.Context, fsys fs.FS, m *sb.Metadata, code, dir string) error {
+	// Start from a copy of m
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	child := sb.NewMetadata()
+	if err := json.Unmarshal(data, child); err != nil {
+		return fmt.Errorf("copying metadata.json: %w", err)
+	}
+
+	// Keep the book's ingredients and the unscoped ones but the payload
+	keep := make(map[string]bool)
+	for key, ing := range m.Ingredients {
+		switch {
+		case len(ing.Scope) > 0:
+			_, keep[key] = ing.Scope[code]
+		case !strings.HasPrefix(key, "ingredients/payload/"):
+			keep[key] = true
+		}
+	}
+
+	// Keep the payload articles the book's TSV files link to
+	for _, key := range slices.Sorted(maps.Keys(keep)) {
+		if !keep[key] || m.Ingredients[key].MimeType != "text/tab-separated-values" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, key)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", key, err)
+		}
+		for _, match := range payloadLinkRegexp.FindAllSubmatch(data, -1) {
+			article := "ingredients/payload/" + string(match[1])
+			if _, ok := m.Ingredients[article]; ok {
+				keep[article] = true
+			}
+		}
+	}
+
+	for key := range child.Ingredients {
+		if !keep[key] {
+			delete(child.Ingredients, key)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(child.Ingredients)) {
+		if !fs.ValidPath(key) {
+			return fmt.Errorf("invalid ingredient path %q", key)
+		}
+		if err := handler.CopyFile(ctx, fsys, key, filepath.Join(dir, filepath.FromSlash(key))); err != nil {
+			return fmt.Errorf("copying %s: %w", key, err)
+		}
+	}
+
+	// Copy the root files that are not ingredients, such as README.md, but
+	// not a git repository's history
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case (name == "ingredients" || name == ".git") && d.IsDir():
+			return fs.SkipDir
+		case d.IsDir() || name == "metadata.json":
+			return nil
+		}
+		if _, ok := m.Ingredients[name]; ok {
+			return nil
+		}
+		return handler.CopyFile(ctx, fsys, name, filepath.Join(dir, filepath.FromSlash(name)))
+	})
+	if err != nil {
+		return err
+	}
+
+	// Identify the SB as the book's
+	for authority, entries := range child.Identification.Primary {
+		renamed := make(map[string]sb.PrimaryEntry)
+		for abbr, entry := range entries {
+			renamed[abbr+"-"+code] = entry
+		}
+		child.Identification.Primary[authority] = renamed
+	}
+	for lang, abbr := range child.Identification.Abbreviation {
+		child.Identification.Abbreviation[lang] = abbr + "-" + code
+	}
+	child.Type.FlavorType.CurrentScope = map[string][]string{code: m.Type.FlavorType.CurrentScope[code]}
+	for name := range child.LocalizedNames {
+		if strings.HasPrefix(name, "book-") && !strings.EqualFold(name, "book-"+code) {
+			delete(child.LocalizedNames, name)
+		}
+	}
+
+	if err := child.WriteToFile(dir); err != nil {
+		return err
+	}
+	// The parent's BagIt manifest, copied above, lists the other books too
+	return refreshBagItManifest(dir, child)
+}
+
+// sortedBookCodes returns the book codes of scope in canonical order, any
+// that are not Bible books last.
+func sortedBookCodes(scope map[string][]string) []string {
+	order := func(code string) int {
+		if b := books.ByCode(code); b != nil {
+			return b.Sort
+		}
+		return len(books.AllBooks) + 1
+	}
+	codes := slices.Collect(maps.Keys(scope))
+	slices.SortFunc(codes, func(a, b string) int {
+		return cmp.Or(cmp.Compare(order(a), order(b)), strings.Compare(a, b))
+	})
+	return codes
+}
@@ -0,0 +1,141 @@
+package rc2sb_test
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+func TestSplitByBook(t *testing.T) {
+	ctx := context.Background()
+	files := maps.Clone(compareTNFiles)
+	files["README.md"] = "# TN\n"
+	inDir, sbDir, outRoot := t.TempDir(), t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, files)
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	dirs, err := rc2sb.SplitByBook(ctx, sbDir, outRoot)
+	if err != nil {
+		t.Fatalf("SplitByBook failed: %v", err)
+	}
+	if want := []string{filepath.Join(outRoot, "GEN"), filepath.Join(outRoot, "MAT")}; !reflect.DeepEqual(dirs, want) {
+		t.Fatalf("dirs = %v; want %v", dirs, want)
+	}
+	for _, code := range []string{"GEN", "MAT"} {
+		dir := filepath.Join(outRoot, code)
+		m := loadGeneratedMetadata(t, dir)
+		verifyInternalConsistency(t, m, dir)
+
+		keys := slices.Sorted(maps.Keys(m.Ingredients))
+		want := []string{"ingredients/" + code + ".tsv", "ingredients/LICENSE.md"}
+		slices.Sort(want)
+		if !reflect.DeepEqual(keys, want) {
+			t.Errorf("%s: ingredients = %v; want %v", code, keys, want)
+		}
+		if got := m.Type.FlavorType.CurrentScope; !reflect.DeepEqual(got, map[string][]string{code: {}}) {
+			t.Errorf("%s: currentScope = %v", code, got)
+		}
+		if len(m.UnscopedBooks()) != 0 {
+			t.Errorf("%s: currentScope lists books with no ingredient: %v", code, m.UnscopedBooks())
+		}
+		// The book's name and the resource's own
+		if _, ok := m.LocalizedNames["book-"+strings.ToLower(code)]; !ok || len(m.LocalizedNames) != 2 {
+			t.Errorf("%s: localizedNames = %v", code, m.LocalizedNames)
+		}
+		if got := m.Identification.Abbreviation["en"]; got != "TN-"+code {
+			t.Errorf("%s: abbreviation = %q; want TN-%s", code, got, code)
+		}
+		if _, ok := m.Identification.Primary["uWBurritos"]["TN-"+code]; !ok {
+			t.Errorf("%s: primary = %v", code, m.Identification.Primary)
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, "README.md")); err != nil || string(data) != "# TN\n" {
+			t.Errorf("%s: README.md = %q, %v", code, data, err)
+		}
+	}
+}
+
+func TestSplitByBook_KeepsLinkedPayload(t *testing.T) {
+	const header = "Reference\tID\tTags\tOrigWords\tOccurrence\tTWLink\n"
+	ctx := context.Background()
+	inDir, sbDir, outRoot := t.TempDir(), t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, map[string]string{
+		"manifest.yaml":           roundTripManifest("TSV Translation Words Links", "twl", "gen ./twl_GEN.tsv", "mat ./twl_MAT.tsv"),
+		"twl_GEN.tsv":             header + "1:1\ta001\t\tword\t1\trc://*/tw/dict/bible/kt/god\n",
+		"twl_MAT.tsv":             header + "1:1\tc001\t\tword\t1\trc://*/tw/dict/bible/kt/jesus\n",
+		"LICENSE.md":              "License\n",
+		"en_tw/bible/kt/god.md":   "# God\n",
+		"en_tw/bible/kt/jesus.md": "# Jesus\n",
+	})
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if _, err := rc2sb.SplitByBook(ctx, sbDir, outRoot); err != nil {
+		t.Fatalf("SplitByBook failed: %v", err)
+	}
+	for code, article := range map[string]string{"GEN": "god", "MAT": "jesus"} {
+		dir := filepath.Join(outRoot, code)
+		m := loadGeneratedMetadata(t, dir)
+		verifyInternalConsistency(t, m, dir)
+
+		keys := slices.Sorted(maps.Keys(m.Ingredients))
+		want := []string{"ingredients/" + code + ".tsv", "ingredients/LICENSE.md", "ingredients/payload/kt/" + article + ".md"}
+		slices.Sort(want)
+		if !reflect.DeepEqual(keys, want) {
+			t.Errorf("%s: ingredients = %v; want %v", code, keys, want)
+		}
+	}
+}
+
+func TestSplitByBook_Errors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := rc2sb.SplitByBook(ctx, t.TempDir(), t.TempDir()); err == nil {
+		t.Error("expected error for a directory with no metadata.json")
+	}
+
+	inDir, sbDir := t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, archiveOBSFiles)
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if _, err := rc2sb.SplitByBook(ctx, sbDir, t.TempDir()); err == nil {
+		t.Error("expected error for an OBS burrito")
+	}
+
+	m := sb.NewMetadata()
+	m.Type.FlavorType.Name = "scripture"
+	if err := m.WriteToFile(sbDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rc2sb.SplitByBook(ctx, sbDir, t.TempDir()); err == nil {
+		t.Error("expected error for a burrito with no currentScope")
+	}
+}
+
+func TestSplitByBook_BagItManifest(t *testing.T) {
+	ctx := context.Background()
+	inDir, sbDir, outRoot := t.TempDir(), t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, compareTNFiles)
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{BagItManifest: true}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	dirs, err := rc2sb.SplitByBook(ctx, sbDir, outRoot)
+	if err != nil {
+		t.Fatalf("SplitByBook failed: %v", err)
+	}
+	// Each book's manifest lists only that book's ingredients
+	for _, dir := range dirs {
+		verifyBagItManifest(t, dir)
+	}
+}
@@ -0,0 +1,106 @@
+package rc2sb
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/handler"
+	"github.com/unfoldingWord/go-rc2sb/rc"
+)
+
+// unreferencedFiles returns a warning for each file of the RC repository in
+// fsys that the conversion tracked by tracker did not copy and that no
+// manifest project includes, such as a USFM file missing from the projects
+// or a directory someone added. manifest.yaml, media.yaml, and files and
+// directories whose names start with "." (e.g., .git) are not reported, nor
+// is skip, the output directory if it is inside the repository. A top-level
+// directory none of whose files were converted is reported once.
+func unreferencedFiles(fsys fs.FS, manifest *rc.Manifest, tracker *handler.SourceTracker, skip string) ([]string, error) {
+	var projects []string
+	for _, project := range manifest.Projects {
+		name := path.Clean(strings.TrimPrefix(project.Path, "./"))
+		if name == "." {
+			// The whole repository
+			return nil, nil
+		}
+		projects = append(projects, name)
+	}
+	referenced := func(name string) bool {
+		if tracker.Used(name) {
+			return true
+		}
+		for _, project := range projects {
+			if name == project || strings.HasPrefix(name, project+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	type dirTotals struct {
+		files, unreferenced int
+		size                int64
+	}
+	var unreferenced []string
+	sizes := make(map[string]int64)
+	dirs := make(map[string]*dirTotals)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") || name == skip {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || name == "manifest.yaml" || name == "media.yaml" {
+			return nil
+		}
+
+		top, _, inDir := strings.Cut(name, "/")
+		if inDir && dirs[top] == nil {
+			dirs[top] = &dirTotals{}
+		}
+		if inDir {
+			dirs[top].files++
+		}
+		if referenced(name) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		unreferenced = append(unreferenced, name)
+		sizes[name] = info.Size()
+		if inDir {
+			dirs[top].unreferenced++
+			dirs[top].size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing unreferenced files: %w", err)
+	}
+
+	var warnings []string
+	reported := make(map[string]bool)
+	for _, name := range unreferenced {
+		top, _, inDir := strings.Cut(name, "/")
+		if totals := dirs[top]; inDir && totals.unreferenced == totals.files {
+			if !reported[top] {
+				reported[top] = true
+				warnings = append(warnings, fmt.Sprintf("unreferenced directory %s/ (%d files, %d bytes) was not converted; no manifest project includes it", top, totals.files, totals.size))
+			}
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("unreferenced file %s (%d bytes) was not converted; no manifest project includes it", name, sizes[name]))
+	}
+	return warnings, nil
+}
@@ -0,0 +1,142 @@
+package rc2sb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+// UpdateMetadata refreshes metadata.json in the SB at sbDir after its
+// ingredients were edited in place, without converting the RC again. The
+// checksum and size of every ingredient are recomputed from its file (with a
+// SHA-256 checksum if it had one), keeping its scope, role, MIME type,
+// source, and versification; files beneath ingredients/ with no entry are
+// added (with a SHA-256 checksum if any existing ingredient had one), and
+// entries whose file is gone are dropped, each with a warning. dateCreated is
+// set to opts.FixedTimestamp, or to the current time if it is zero. Warnings
+// are logged to opts.Logger; no other option is used.
+func UpdateMetadata(ctx context.Context, sbDir string, opts Options) error {
+	// Check context
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	m, err := sb.LoadMetadata(sbDir)
+	if err != nil {
+		return err
+	}
+
+	anySHA256 := false
+	for _, ing := range m.Ingredients {
+		if ing.Checksum.SHA256 != "" {
+			anySHA256 = true
+			break
+		}
+	}
+
+	// Recompute the existing ingredients
+	for _, key := range slices.Sorted(maps.Keys(m.Ingredients)) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !fs.ValidPath(key) {
+			return fmt.Errorf("invalid ingredient path %q", key)
+		}
+		old := m.Ingredients[key]
+		ing, err := computeIngredient(filepath.Join(sbDir, filepath.FromSlash(key)), old.Checksum.SHA256 != "")
+		if errors.Is(err, fs.ErrNotExist) {
+			logger.Warn(fmt.Sprintf("ingredient %s has no file; dropping it", key))
+			delete(m.Ingredients, key)
+			continue
+		} else if err != nil {
+			return err
+		}
+		if old.MimeType != "" {
+			ing.MimeType = old.MimeType
+		}
+		ing.Scope, ing.Role, ing.Source, ing.Versification = old.Scope, old.Role, old.Source, old.Versification
+		m.Ingredients[key] = ing
+	}
+
+	// Add the files beneath ingredients/ that have no entry
+	err = filepath.WalkDir(filepath.Join(sbDir, "ingredients"), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(sbDir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if _, ok := m.Ingredients[key]; ok {
+			return nil
+		}
+		ing, err := computeIngredient(p, anySHA256)
+		if err != nil {
+			return err
+		}
+		logger.Warn(fmt.Sprintf("adding new file %s as an ingredient", key))
+		m.Ingredients[key] = ing
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, book := range m.UnscopedBooks() {
+		logger.Warn(fmt.Sprintf("currentScope lists %s, but no ingredient has that scope", book))
+	}
+
+	created := opts.FixedTimestamp
+	if created.IsZero() {
+		created = time.Now()
+	}
+	m.Meta.DateCreated = created.UTC().Format("2006-01-02T15:04:05.000Z")
+	if err := m.WriteToFile(sbDir); err != nil {
+		return err
+	}
+	return refreshBagItManifest(sbDir, m)
+}
+
+// computeIngredient computes the Ingredient for the file at p, as by
+// sb.ComputeIngredient, with a SHA-256 checksum as well if withSHA256 is set.
+func computeIngredient(p string, withSHA256 bool) (sb.Ingredient, error) {
+	if !withSHA256 {
+		return sb.ComputeIngredient(p)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return sb.Ingredient{}, fmt.Errorf("opening file %s: %w", p, err)
+	}
+	defer f.Close()
+
+	w := sb.NewIngredientWriter(p).WithSHA256()
+	if _, err := io.Copy(w, f); err != nil {
+		return sb.Ingredient{}, fmt.Errorf("reading file %s: %w", p, err)
+	}
+	return w.Ingredient(), nil
+}
@@ -0,0 +1,196 @@
+package rc2sb_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+	"github.com/unfoldingWord/go-rc2sb/sb"
+)
+
+func TestUpdateMetadata_RefreshesEditedIngredient(t *testing.T) {
+	ctx := context.Background()
+	inDir, sbDir := t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, compareTNFiles)
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	before, err := sb.LoadMetadata(sbDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fix a typo in one TSV
+	edited := filepath.Join(sbDir, "ingredients", "GEN.tsv")
+	data, err := os.ReadFile(edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(edited, bytes.Replace(data, []byte("Note"), []byte("Fixed note"), 1), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	if err := rc2sb.UpdateMetadata(ctx, sbDir, rc2sb.Options{Logger: slog.New(slog.NewTextHandler(&logs, nil))}); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	if logs.Len() > 0 {
+		t.Errorf("logged %q; want no warnings", logs.String())
+	}
+	after, err := sb.LoadMetadata(sbDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := sb.ComputeIngredientWithScope(edited, before.Ingredients["ingredients/GEN.tsv"].Scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := after.Ingredients["ingredients/GEN.tsv"]; !reflect.DeepEqual(got, want) || got.Checksum == before.Ingredients["ingredients/GEN.tsv"].Checksum {
+		t.Errorf("edited ingredient = %+v; want %+v", got, want)
+	}
+
+	// Nothing else changes, apart from dateCreated
+	after.Ingredients["ingredients/GEN.tsv"] = before.Ingredients["ingredients/GEN.tsv"]
+	after.Meta.DateCreated = before.Meta.DateCreated
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("metadata changed beyond the edited ingredient:\n got %+v\nwant %+v", after, before)
+	}
+}
+
+func TestUpdateMetadata_KeepsSHA256(t *testing.T) {
+	ctx := context.Background()
+	inDir, sbDir := t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, compareTNFiles)
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{SHA256Checksums: true}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	edited := filepath.Join(sbDir, "ingredients", "GEN.tsv")
+	data, err := os.ReadFile(edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte("Note"), []byte("Fixed note"), 1)
+	if err := os.WriteFile(edited, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeRepoFiles(t, sbDir, map[string]string{"ingredients/notes/extra.md": "# Extra\n"})
+	if err := rc2sb.UpdateMetadata(ctx, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	m, err := sb.LoadMetadata(sbDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Ingredients["ingredients/GEN.tsv"].Checksum.SHA256, fmt.Sprintf("%x", sha256.Sum256(data)); got != want {
+		t.Errorf("sha256 = %q; want %q", got, want)
+	}
+	// A file added alongside ingredients with SHA-256 checksums gets one too
+	if got, want := m.Ingredients["ingredients/notes/extra.md"].Checksum.SHA256, fmt.Sprintf("%x", sha256.Sum256([]byte("# Extra\n"))); got != want {
+		t.Errorf("added file sha256 = %q; want %q", got, want)
+	}
+}
+
+func TestUpdateMetadata_AddsAndDropsFiles(t *testing.T) {
+	ctx := context.Background()
+	inDir, sbDir := t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, compareTNFiles)
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if err := os.Remove(filepath.Join(sbDir, "ingredients", "MAT.tsv")); err != nil {
+		t.Fatal(err)
+	}
+	writeRepoFiles(t, sbDir, map[string]string{"ingredients/notes/extra.md": "# Extra\n"})
+
+	var logs bytes.Buffer
+	if err := rc2sb.UpdateMetadata(ctx, sbDir, rc2sb.Options{Logger: slog.New(slog.NewTextHandler(&logs, nil))}); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	m, err := sb.LoadMetadata(sbDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Ingredients["ingredients/MAT.tsv"]; ok {
+		t.Error("ingredients/MAT.tsv still listed after its file was removed")
+	}
+	if ing, ok := m.Ingredients["ingredients/notes/extra.md"]; !ok || ing.Size != int64(len("# Extra\n")) || ing.MimeType != "text/markdown" {
+		t.Errorf("new ingredient = %+v, %v", ing, ok)
+	}
+	for _, want := range []string{
+		"ingredient ingredients/MAT.tsv has no file",
+		"adding new file ingredients/notes/extra.md",
+		"currentScope lists MAT",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs %q do not contain %q", logs.String(), want)
+		}
+	}
+}
+
+func TestUpdateMetadata_FixedTimestamp(t *testing.T) {
+	ctx := context.Background()
+	inDir, sbDir := t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, compareTNFiles)
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	opts := rc2sb.Options{FixedTimestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	if err := rc2sb.UpdateMetadata(ctx, sbDir, opts); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	m, err := sb.LoadMetadata(sbDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Meta.DateCreated != "2024-03-01T12:00:00.000Z" {
+		t.Errorf("dateCreated = %q; want the fixed timestamp", m.Meta.DateCreated)
+	}
+}
+
+func TestUpdateMetadata_NoMetadata(t *testing.T) {
+	if err := rc2sb.UpdateMetadata(context.Background(), t.TempDir(), rc2sb.Options{}); err == nil {
+		t.Error("expected error for a directory with no metadata.json")
+	}
+}
+
+func TestUpdateMetadata_BagItManifest(t *testing.T) {
+	ctx := context.Background()
+	inDir, sbDir := t.TempDir(), t.TempDir()
+	writeRepoFiles(t, inDir, compareTNFiles)
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{BagItManifest: true}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	writeRepoFiles(t, sbDir, map[string]string{
+		"ingredients/GEN.tsv":        "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n1:1\tabcd\t\t\t\t\tEdited\n",
+		"ingredients/notes/extra.md": "# Extra\n",
+	})
+
+	if err := rc2sb.UpdateMetadata(ctx, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	verifyBagItManifest(t, sbDir)
+
+	// An SB without a manifest does not gain one
+	sbDir = t.TempDir()
+	if _, err := rc2sb.Convert(ctx, inDir, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if err := rc2sb.UpdateMetadata(ctx, sbDir, rc2sb.Options{}); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(sbDir, "manifest-md5.txt")); !os.IsNotExist(err) {
+		t.Errorf("manifest-md5.txt written to an SB that had none: %v", err)
+	}
+}
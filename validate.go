@@ -0,0 +1,296 @@
+package rc2sb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/unfoldingWord/go-rc2sb/books"
+	"github.com/unfoldingWord/go-rc2sb/handler"
+	"github.com/unfoldingWord/go-rc2sb/languages"
+	"github.com/unfoldingWord/go-rc2sb/rc"
+)
+
+// LintFinding is one problem found by ValidateRC.
+type LintFinding struct {
+	// Rule identifies the check that found the problem (e.g.,
+	// "project-path"); see ValidateRC for the rules.
+	Rule string
+
+	Severity Severity
+
+	// Path is the file the finding is about, relative to inDir, or "" if it
+	// is about the repository as a whole.
+	Path string
+
+	Message string
+}
+
+// String formats the finding as "severity: path: message [rule]".
+func (f LintFinding) String() string {
+	return fmt.Sprintf("%s [%s]", CheckIssue{Severity: f.Severity, Path: f.Path, Message: f.Message}, f.Rule)
+}
+
+// LintReport is the result of ValidateRC.
+type LintReport struct {
+	// Subject is the subject the repository would be converted as, and
+	// Identifier its dublin_core.identifier. Both are empty if the manifest
+	// could not be read.
+	Subject    string
+	Identifier string
+
+	// Findings lists the problems found, in the order they were found.
+	Findings []LintFinding
+}
+
+// OK reports whether no finding has SeverityError.
+func (r LintReport) OK() bool {
+	return !slices.ContainsFunc(r.Findings, func(f LintFinding) bool { return f.Severity == SeverityError })
+}
+
+// ValidateRC lints the RC repository at inDir, e.g., before a release,
+// without converting it or writing anything. It makes the checks of Check,
+// which only look for what would make a conversion fail or leave out
+// content, and checks the content as well. Each finding names its rule:
+//
+//   - manifest: manifest.yaml is missing or does not parse (error)
+//   - manifest-required: a dublin_core field a release needs (identifier,
+//     title, language identifier and title, version, issued, publisher,
+//     rights, or conformsto) is empty (warning)
+//   - subject: the subject (or opts.SubjectOverride) is not supported (error)
+//   - language-direction: the language direction is not ltr or rtl (warning)
+//   - rights: the rights are not a recognized open license (warning)
+//   - license: no license file is present (warning)
+//   - project-duplicate: a project repeats the identifier or path of an
+//     earlier one (error, or warning with opts.SkipDuplicateProjects)
+//   - project-path: a project path is invalid or does not exist (error)
+//   - project-book: a project of a subject split by book (a Bible, TN, TQ,
+//     SN, or TWL) is not a recognized book; see books.RegisterBook (warning)
+//   - tsv-header: a TSV header has blank or repeated columns (warning)
+//   - tsv-columns: a TSV row has a different number of columns than the
+//     header (warning)
+//   - tsv-expected-columns: a TSV header lacks a column of its subject's
+//     format (e.g., SupportReference for TN) (warning)
+//   - tsv-reference: a TSV reference is beyond its book (warning)
+//   - tsv-id: a TSV ID is repeated within a file (warning)
+//   - usfm-id: a USFM file does not start with the \id of its book (warning)
+//   - usfm-chapter: a USFM \c marker is beyond its book (warning)
+//   - rc-link: an rc:// link is to a resource that is neither the
+//     repository's nor one of its dublin_core.relation entries (warning)
+//
+// Problems are returned in the report; the error is only non-nil if the
+// check itself could not be done, e.g., because ctx was canceled.
+func ValidateRC(ctx context.Context, inDir string, opts Options) (LintReport, error) {
+	return lint(ctx, inDir, opts, true)
+}
+
+// lint makes the checks of ValidateRC, leaving out those of the content
+// (manifest-required, project-book, tsv-expected-columns, tsv-id, and
+// rc-link) unless content is set.
+func lint(ctx context.Context, inDir string, opts Options, content bool) (LintReport, error) {
+	// Check context
+	if err := ctx.Err(); err != nil {
+		return LintReport{}, fmt.Errorf("context error: %w", err)
+	}
+
+	var report LintReport
+	add := func(rule string, severity Severity, name, format string, args ...any) {
+		report.Findings = append(report.Findings, LintFinding{Rule: rule, Severity: severity, Path: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	manifest, err := rc.LoadManifest(inDir)
+	if err != nil {
+		var manifestErr *rc.ManifestError
+		if !errors.As(err, &manifestErr) {
+			return LintReport{}, err
+		}
+		add("manifest", SeverityError, "manifest.yaml", "%v", err)
+		return report, nil
+	}
+	report.Identifier = manifest.DublinCore.Identifier
+
+	report.Subject = manifest.DublinCore.Subject
+	if opts.SubjectOverride != "" {
+		report.Subject = opts.SubjectOverride
+	}
+	if _, err := handler.Lookup(report.Subject); err != nil {
+		add("subject", SeverityError, "manifest.yaml", "%v", err)
+	}
+
+	lang := manifest.DublinCore.Language
+	if direction, ok := languages.ScriptDirection(lang.Identifier, lang.Direction); !ok {
+		add("language-direction", SeverityWarning, "manifest.yaml", "unrecognized language direction %q; %q will be used", lang.Direction, direction)
+	}
+	if _, ok := handler.SPDXLicense(manifest.DublinCore.Rights); !ok && manifest.DublinCore.Rights != "" {
+		add("rights", SeverityWarning, "manifest.yaml", "unrecognized rights %q; the content may not be openly licensed", manifest.DublinCore.Rights)
+	}
+	if content {
+		for _, field := range missingManifestFields(manifest) {
+			add("manifest-required", SeverityWarning, "manifest.yaml", "dublin_core %s is empty", field)
+		}
+	}
+
+	fsys := os.DirFS(inDir)
+	if !slices.ContainsFunc(handler.LicenseNames, func(name string) bool {
+		_, err := fs.Stat(fsys, name)
+		return err == nil
+	}) {
+		add("license", SeverityWarning, "LICENSE.md", "missing; the default license will be used")
+	}
+
+	for _, d := range manifest.DuplicateProjects() {
+		severity := SeverityError
+		if opts.SkipDuplicateProjects {
+			severity = SeverityWarning
+		}
+		add("project-duplicate", severity, "manifest.yaml", "%s", d)
+	}
+
+	var expected []string
+	byBook := false
+	if content {
+		expected = tsvColumns[report.Subject]
+		layout, ok := rcLayouts[report.Subject]
+		byBook = ok && layout.kind == bookFiles
+	}
+	for _, project := range manifest.Projects {
+		if err := ctx.Err(); err != nil {
+			return report, fmt.Errorf("context error: %w", err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(project.Path, "./"))
+		if !fs.ValidPath(name) || name == "." {
+			add("project-path", SeverityError, "manifest.yaml", "project %q has an invalid path %q", project.Identifier, project.Path)
+			continue
+		}
+		if byBook && !books.IsBookID(project.Identifier) {
+			add("project-book", SeverityWarning, "manifest.yaml", "project %q is not a recognized book", project.Identifier)
+		}
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			add("project-path", SeverityError, name, "project %q: %v", project.Identifier, sourceProblem(err))
+			continue
+		}
+		if info.IsDir() {
+			continue
+		}
+
+		book := checkedBook(project)
+		ext := strings.ToLower(path.Ext(name))
+		if ext == ".txt" && sniffTSV(fsys, name) {
+			ext = ".tsv"
+		}
+		switch ext {
+		case ".tsv":
+			for _, problem := range checkTSV(fsys, name, book, expected) {
+				add(problem.rule, SeverityWarning, name, "%s", problem.message)
+			}
+		case ".usfm":
+			if problem := checkUSFM(fsys, name, project.Identifier); problem != "" {
+				add("usfm-id", SeverityWarning, name, "%s", problem)
+			}
+			for _, problem := range checkUSFMChapters(fsys, name, book) {
+				add("usfm-chapter", SeverityWarning, name, "%s", problem)
+			}
+		}
+	}
+
+	if content {
+		links, err := handler.ScanRCLinks(inDir)
+		if err != nil {
+			return report, err
+		}
+		for _, problem := range unresolvedLinks(links, manifest) {
+			add("rc-link", SeverityWarning, problem.file, "%s", problem.message)
+		}
+	}
+
+	return report, nil
+}
+
+// missingManifestFields returns the names of the dublin_core fields of
+// manifest that a release needs but are empty.
+func missingManifestFields(manifest *rc.Manifest) []string {
+	dc := manifest.DublinCore
+	var missing []string
+	for _, field := range []struct{ name, value string }{
+		{"conformsto", dc.ConformsTo},
+		{"identifier", dc.Identifier},
+		{"title", dc.Title},
+		{"language.identifier", dc.Language.Identifier},
+		{"language.title", dc.Language.Title},
+		{"version", dc.Version},
+		{"issued", dc.Issued},
+		{"publisher", dc.Publisher},
+		{"rights", dc.Rights},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			missing = append(missing, field.name)
+		}
+	}
+	return missing
+}
+
+// tsvColumns lists the columns of the TSV formats of the Door43 subjects.
+var tsvColumns = map[string][]string{
+	"TSV Translation Notes":         {"Reference", "ID", "Tags", "SupportReference", "Quote", "Occurrence", "Note"},
+	"TSV Study Notes":               {"Reference", "ID", "Tags", "SupportReference", "Quote", "Occurrence", "Note"},
+	"TSV OBS Translation Notes":     {"Reference", "ID", "Tags", "SupportReference", "Quote", "Occurrence", "Note"},
+	"TSV OBS Study Notes":           {"Reference", "ID", "Tags", "SupportReference", "Quote", "Occurrence", "Note"},
+	"TSV Translation Questions":     {"Reference", "ID", "Tags", "Quote", "Occurrence", "Question", "Response"},
+	"TSV OBS Translation Questions": {"Reference", "ID", "Tags", "Quote", "Occurrence", "Question", "Response"},
+	"TSV OBS Study Questions":       {"Reference", "ID", "Tags", "Quote", "Occurrence", "Question", "Response"},
+	"TSV Translation Words Links":   {"Reference", "ID", "Tags", "OrigWords", "Occurrence", "TWLink"},
+}
+
+// linkProblem is an rc-link finding in file.
+type linkProblem struct {
+	file, message string
+}
+
+// unresolvedLinks returns a problem for each file and resource of the links
+// that are neither to manifest's own resource nor to one of its relations
+// (e.g., "en/tw" or "en/ta?v=80"), naming the first such link.
+func unresolvedLinks(links []handler.RCLink, manifest *rc.Manifest) []linkProblem {
+	own := manifest.DublinCore.Identifier
+	resolves := func(link handler.RCLink) bool {
+		if strings.EqualFold(link.Resource, own) {
+			return true
+		}
+		return slices.ContainsFunc(manifest.DublinCore.Relation, func(relation string) bool {
+			relation, _, _ = strings.Cut(relation, "?")
+			lang, resource, ok := strings.Cut(relation, "/")
+			if !ok {
+				return false
+			}
+			return strings.EqualFold(resource, link.Resource) && (link.Language == "*" || strings.EqualFold(lang, link.Language))
+		})
+	}
+
+	type fileResource struct{ file, resource string }
+	var problems []linkProblem
+	first := make(map[fileResource]int)
+	counts := make(map[fileResource]int)
+	for _, link := range links {
+		if resolves(link) {
+			continue
+		}
+		key := fileResource{link.File, strings.ToLower(link.Language + "/" + link.Resource)}
+		if _, ok := first[key]; !ok {
+			first[key] = len(problems)
+			problems = append(problems, linkProblem{file: link.File, message: fmt.Sprintf("line %d: %s is to resource %q, which is not a dublin_core relation", link.Line, link.Link, link.Resource)})
+		}
+		counts[key]++
+	}
+	for key, i := range first {
+		if n := counts[key]; n > 1 {
+			problems[i].message += fmt.Sprintf(" (%d links)", n)
+		}
+	}
+	return problems
+}
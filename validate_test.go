@@ -0,0 +1,209 @@
+package rc2sb_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	rc2sb "github.com/unfoldingWord/go-rc2sb"
+)
+
+const lintTNHeader = "Reference\tID\tTags\tSupportReference\tQuote\tOccurrence\tNote\n"
+
+// lintRepoFiles is a small TN repo ready for release, with no findings.
+func lintRepoFiles() map[string]string {
+	return map[string]string{
+		"manifest.yaml": `dublin_core:
+  conformsto: 'rc0.2'
+  subject: 'TSV Translation Notes'
+  identifier: 'tn'
+  title: 'Test TN'
+  version: '80'
+  issued: '2024-01-01'
+  publisher: 'unfoldingWord'
+  rights: 'CC BY-SA 4.0'
+  relation:
+    - 'en/ta?v=80'
+    - 'en/tw'
+  language:
+    identifier: 'en'
+    title: 'English'
+    direction: 'ltr'
+projects:
+  - identifier: 'gen'
+    path: './tn_GEN.tsv'
+`,
+		"LICENSE.md": "# License\n",
+		"tn_GEN.tsv": lintTNHeader +
+			"1:1\tabcd\t\trc://*/ta/man/translate/figs-metaphor\t\t0\tSee [[rc://en/tw/dict/bible/kt/god]]\n" +
+			"1:2\tefgh\t\t\t\t0\tNote\n",
+	}
+}
+
+// lintBibleFiles is a small Bible repo ready for release, with no findings.
+func lintBibleFiles() map[string]string {
+	files := lintRepoFiles()
+	files["manifest.yaml"] = strings.NewReplacer(
+		"TSV Translation Notes", "Aligned Bible",
+		"'tn'", "'ult'",
+		"./tn_GEN.tsv", "./01-GEN.usfm",
+	).Replace(files["manifest.yaml"])
+	delete(files, "tn_GEN.tsv")
+	files["01-GEN.usfm"] = "\\id GEN\n\\c 1\n\\v 1 In the beginning\n"
+	return files
+}
+
+func TestValidateRC_Clean(t *testing.T) {
+	for name, files := range map[string]map[string]string{"TN": lintRepoFiles(), "Bible": lintBibleFiles()} {
+		t.Run(name, func(t *testing.T) {
+			inDir := t.TempDir()
+			writeRepoFiles(t, inDir, files)
+
+			report, err := rc2sb.ValidateRC(context.Background(), inDir, rc2sb.Options{})
+			if err != nil {
+				t.Fatalf("ValidateRC failed: %v", err)
+			}
+			if !report.OK() || len(report.Findings) != 0 {
+				t.Errorf("findings = %v; want none", report.Findings)
+			}
+		})
+	}
+}
+
+func TestValidateRC_Rules(t *testing.T) {
+	replace := func(files map[string]string, name, old, new string) map[string]string {
+		files[name] = strings.Replace(files[name], old, new, 1)
+		return files
+	}
+	tests := []struct {
+		rule     string
+		files    map[string]string
+		severity rc2sb.Severity
+		path     string
+
+		// content marks a rule Check leaves out
+		content bool
+	}{
+		{"manifest", replace(lintRepoFiles(), "manifest.yaml", "dublin_core:", "dublin_core: ["), rc2sb.SeverityError, "manifest.yaml", false},
+		{"manifest-required", replace(lintRepoFiles(), "manifest.yaml", "  version: '80'\n", ""), rc2sb.SeverityWarning, "manifest.yaml", true},
+		{"subject", replace(lintRepoFiles(), "manifest.yaml", "TSV Translation Notes", "TSV Translation Nots"), rc2sb.SeverityError, "manifest.yaml", false},
+		{"language-direction", replace(lintRepoFiles(), "manifest.yaml", "'ltr'", "'sideways'"), rc2sb.SeverityWarning, "manifest.yaml", false},
+		{"rights", replace(lintRepoFiles(), "manifest.yaml", "CC BY-SA 4.0", "Freely Given"), rc2sb.SeverityWarning, "manifest.yaml", false},
+		{"license", func() map[string]string {
+			files := lintRepoFiles()
+			delete(files, "LICENSE.md")
+			return files
+		}(), rc2sb.SeverityWarning, "LICENSE.md", false},
+		{"project-path", replace(lintRepoFiles(), "manifest.yaml", "./tn_GEN.tsv", "./tn_EXO.tsv"), rc2sb.SeverityError, "tn_EXO.tsv", false},
+		{"project-duplicate", replace(lintRepoFiles(), "manifest.yaml", "    path: './tn_GEN.tsv'\n", "    path: './tn_GEN.tsv'\n  - identifier: 'gen'\n    path: './tn_GEN.tsv'\n"), rc2sb.SeverityError, "manifest.yaml", false},
+		{"project-book", replace(lintRepoFiles(), "manifest.yaml", "identifier: 'gen'", "identifier: 'intro'"), rc2sb.SeverityWarning, "manifest.yaml", true},
+		{"tsv-header", func() map[string]string {
+			// A ninth column, named like the seventh
+			files := lintRepoFiles()
+			files["tn_GEN.tsv"] = strings.Replace(strings.ReplaceAll(files["tn_GEN.tsv"], "\n", "\t\n"), "Note\t\n", "Note\tNote\n", 1)
+			return files
+		}(), rc2sb.SeverityWarning, "tn_GEN.tsv", false},
+		{"tsv-columns", replace(lintRepoFiles(), "tn_GEN.tsv", "\t0\tNote\n", "\t0\tNote\textra\n"), rc2sb.SeverityWarning, "tn_GEN.tsv", false},
+		{"tsv-expected-columns", replace(lintRepoFiles(), "tn_GEN.tsv", "SupportReference", "SupportRef"), rc2sb.SeverityWarning, "tn_GEN.tsv", true},
+		{"tsv-reference", replace(lintRepoFiles(), "tn_GEN.tsv", "1:2\t", "51:2\t"), rc2sb.SeverityWarning, "tn_GEN.tsv", false},
+		{"tsv-id", replace(lintRepoFiles(), "tn_GEN.tsv", "\tefgh\t", "\tabcd\t"), rc2sb.SeverityWarning, "tn_GEN.tsv", true},
+		{"usfm-id", replace(lintBibleFiles(), "01-GEN.usfm", `\id GEN`, `\id EXO`), rc2sb.SeverityWarning, "01-GEN.usfm", false},
+		{"usfm-chapter", replace(lintBibleFiles(), "01-GEN.usfm", `\c 1`, `\c 51`), rc2sb.SeverityWarning, "01-GEN.usfm", false},
+		{"rc-link", replace(lintRepoFiles(), "tn_GEN.tsv", "\t0\tNote\n", "\t0\tSee rc://*/tq/help/gen/01/02 and rc://*/tq/help/gen/01/03\n"), rc2sb.SeverityWarning, "tn_GEN.tsv", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			inDir := t.TempDir()
+			writeRepoFiles(t, inDir, tt.files)
+
+			report, err := rc2sb.ValidateRC(context.Background(), inDir, rc2sb.Options{})
+			if err != nil {
+				t.Fatalf("ValidateRC failed: %v", err)
+			}
+			if len(report.Findings) != 1 {
+				t.Fatalf("findings = %v; want one %s finding", report.Findings, tt.rule)
+			}
+			f := report.Findings[0]
+			if f.Rule != tt.rule || f.Severity != tt.severity || f.Path != tt.path {
+				t.Errorf("finding = %+v; want rule %s, severity %s, path %s", f, tt.rule, tt.severity, tt.path)
+			}
+			if report.OK() != (tt.severity != rc2sb.SeverityError) {
+				t.Errorf("OK() = %v with a %s", report.OK(), tt.severity)
+			}
+			if !strings.HasSuffix(f.String(), " ["+tt.rule+"]") {
+				t.Errorf("String() = %q; want the rule at the end", f.String())
+			}
+
+			// Check makes the same check, or leaves out one of the content
+			check, err := rc2sb.Check(context.Background(), inDir, rc2sb.Options{})
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			if tt.content {
+				if len(check.Issues) != 0 {
+					t.Errorf("Check issues = %v; want none", check.Issues)
+				}
+			} else if len(check.Issues) != 1 || check.Issues[0].Message != f.Message {
+				t.Errorf("Check issues = %v; want %q", check.Issues, f.Message)
+			}
+		})
+	}
+}
+
+func TestValidateRC_TSVWithBOM(t *testing.T) {
+	files := lintRepoFiles()
+	files["tn_GEN.tsv"] = "\ufeff" + files["tn_GEN.tsv"]
+	inDir := t.TempDir()
+	writeRepoFiles(t, inDir, files)
+
+	report, err := rc2sb.ValidateRC(context.Background(), inDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("ValidateRC failed: %v", err)
+	}
+	if len(report.Findings) != 0 {
+		t.Errorf("findings = %v; want none", report.Findings)
+	}
+
+	// The Reference column is still found, so references are checked
+	files["tn_GEN.tsv"] = strings.Replace(files["tn_GEN.tsv"], "1:2\t", "51:2\t", 1)
+	writeRepoFiles(t, inDir, files)
+	report, err = rc2sb.ValidateRC(context.Background(), inDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("ValidateRC failed: %v", err)
+	}
+	if len(report.Findings) != 1 || report.Findings[0].Rule != "tsv-reference" {
+		t.Errorf("findings = %v; want one tsv-reference finding", report.Findings)
+	}
+}
+
+func TestValidateRC_TSVLongLine(t *testing.T) {
+	files := lintRepoFiles()
+	files["tn_GEN.tsv"] = strings.Replace(files["tn_GEN.tsv"], "\t0\tNote\n", "\t0\t"+strings.Repeat("Note ", 3<<20/5)+"\n", 1)
+	inDir := t.TempDir()
+	writeRepoFiles(t, inDir, files)
+
+	report, err := rc2sb.ValidateRC(context.Background(), inDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("ValidateRC failed: %v", err)
+	}
+	if len(report.Findings) != 0 {
+		t.Errorf("findings = %v; want none", report.Findings)
+	}
+}
+
+func TestValidateRC_LinkCount(t *testing.T) {
+	inDir := t.TempDir()
+	files := lintRepoFiles()
+	files["tn_GEN.tsv"] += "1:3\tijkl\t\t\t\t0\trc://*/tq/help/gen/01/03\n" +
+		"1:4\tmnop\t\t\t\t0\trc://*/tq/help/gen/01/04\n"
+	writeRepoFiles(t, inDir, files)
+
+	report, err := rc2sb.ValidateRC(context.Background(), inDir, rc2sb.Options{})
+	if err != nil {
+		t.Fatalf("ValidateRC failed: %v", err)
+	}
+	want := `line 4: rc://*/tq/help/gen/01/03 is to resource "tq", which is not a dublin_core relation (2 links)`
+	if len(report.Findings) != 1 || report.Findings[0].Message != want {
+		t.Errorf("findings = %v; want %q", report.Findings, want)
+	}
+}
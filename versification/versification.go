@@ -0,0 +1,50 @@
+// Package versification provides the chapter and verse counts of Bible books
+// under common versification schemes, for checking that references fall
+// within a book.
+package versification
+
+import "strings"
+
+// Scheme is a versification: the number of verses in each chapter of each
+// book.
+type Scheme struct {
+	// Name is the scheme's name, e.g., "eng".
+	Name string
+
+	// verses maps each uppercase book code to its verse count per chapter.
+	verses map[string][]int
+}
+
+// schemes maps each scheme name to its Scheme.
+var schemes = map[string]*Scheme{
+	eng.Name: eng,
+}
+
+// Lookup returns the scheme named name (e.g., "eng", in any case), or nil if
+// there is none.
+func Lookup(name string) *Scheme {
+	return schemes[strings.ToLower(name)]
+}
+
+// Chapters returns the number of chapters of the book code (e.g., "GEN", in
+// any case), or 0 if the scheme does not have the book.
+func (s *Scheme) Chapters(code string) int {
+	return len(s.verses[strings.ToUpper(code)])
+}
+
+// Verses returns the number of verses in chapter of the book code, or 0 if
+// the scheme does not have the book or the chapter.
+func (s *Scheme) Verses(code string, chapter int) int {
+	counts := s.verses[strings.ToUpper(code)]
+	if chapter < 1 || chapter > len(counts) {
+		return 0
+	}
+	return counts[chapter-1]
+}
+
+// Contains reports whether chapter and verse are within the book code. A
+// verse of 0 stands for the chapter as a whole (e.g., its introduction).
+func (s *Scheme) Contains(code string, chapter, verse int) bool {
+	n := s.Verses(code, chapter)
+	return n > 0 && verse >= 0 && verse <= n
+}
@@ -0,0 +1,60 @@
+package versification
+
+import (
+	"testing"
+
+	"github.com/unfoldingWord/go-rc2sb/books"
+)
+
+func TestEng_Totals(t *testing.T) {
+	s := Lookup("ENG")
+	if s == nil {
+		t.Fatal(`Lookup("ENG") = nil`)
+	}
+
+	// Book totals of the KJV, with the eng scheme's extra verses
+	want := map[string]int{"GEN": 1533, "PSA": 2461, "MAL": 55, "MAT": 1071, "3JN": 15, "REV": 405}
+	total := 0
+	for _, b := range books.AllBooks {
+		if s.Chapters(b.Code) == 0 {
+			t.Errorf("no chapters for %s", b.Code)
+		}
+		sum := 0
+		for ch := 1; ch <= s.Chapters(b.Code); ch++ {
+			sum += s.Verses(b.Code, ch)
+		}
+		if w, ok := want[b.Code]; ok && sum != w {
+			t.Errorf("%s has %d verses; want %d", b.Code, sum, w)
+		}
+		total += sum
+	}
+	if total != 31104 {
+		t.Errorf("total verses = %d; want 31104", total)
+	}
+}
+
+func TestScheme_Contains(t *testing.T) {
+	s := Lookup("eng")
+	tests := []struct {
+		code           string
+		chapter, verse int
+		want           bool
+	}{
+		{"GEN", 1, 1, true},
+		{"gen", 50, 26, true},
+		{"GEN", 50, 27, false},
+		{"GEN", 51, 1, false},
+		{"GEN", 1, 0, true},
+		{"GEN", 0, 1, false},
+		{"PSA", 119, 176, true},
+		{"XYZ", 1, 1, false},
+	}
+	for _, tt := range tests {
+		if got := s.Contains(tt.code, tt.chapter, tt.verse); got != tt.want {
+			t.Errorf("Contains(%s, %d, %d) = %v; want %v", tt.code, tt.chapter, tt.verse, got, tt.want)
+		}
+	}
+	if Lookup("klingon") != nil {
+		t.Error(`Lookup("klingon") != nil`)
+	}
+}